@@ -18,9 +18,11 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/database/seeding"
 	"github.com/ashirt-ops/ashirt-server/internal/emailservices"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
 	"github.com/ashirt-ops/ashirt-server/internal/server"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
 	"github.com/ashirt-ops/ashirt-server/internal/workers"
 	"github.com/go-chi/chi/v5"
 
@@ -68,11 +70,20 @@ func main() {
 	if err != nil {
 		logging.Fatal(logger, "store setup error", "error", err)
 	}
-	logger.Info("Using Storage", "type", contentStore.Name())
+	contentStore, err = confighelpers.WrapContentStore(contentStore, config.AllStoreConfig(), db, logger.With("service", "content-store"))
+	if err != nil {
+		logging.Fatal(logger, "store setup error", "error", err)
+	}
+	logger.Info("Using Storage", "type", contentStore.Name(),
+		"root", config.AllStoreConfig().Root,
+		"deduplicate", config.AllStoreConfig().Deduplicate,
+		"encryptionKeyID", config.AllStoreConfig().EncryptionKeyID,
+		"encryptionRequired", config.AllStoreConfig().EncryptionRequired,
+	)
 
 	if seedFiles {
 		logger.Info("Adding files to storage")
-		if contentStore.Name() != "local" {
+		if contentStore.Name() != "dev" {
 			seedEvidenceFiles(db, contentStore, logger)
 		}
 	}
@@ -107,6 +118,33 @@ func main() {
 		logger.Warn("No Emailer selected")
 	}
 
+	enhancementservices.SetExecWorkerPaths(config.ExecWorkerPaths())
+	serviceWorkerRunner := workers.MakeServiceWorkerRunner(db, contentStore, logger.With("service", "service-worker-runner"))
+	serviceWorkerRunner.MaxConcurrencyPerWorker = config.ServiceWorkerConcurrency()
+	serviceWorkerRunner.MaxAttempts = config.ServiceWorkerMaxAttempts()
+	serviceWorkerRunner.RetryBackoff = config.ServiceWorkerBackoff()
+	serviceWorkerRunner.StaleAfter = config.ServiceWorkerStaleAfter()
+	serviceWorkerRunner.Callbacks = enhancementservices.CallbackConfig{
+		BaseURL: config.ServiceWorkerCallbackURL(),
+		Expiry:  config.ServiceWorkerCallbackExpiry(),
+	}
+	serviceWorkerRunner.Start()
+
+	webhookWorker := workers.MakeWebhookWorker(db, logger.With("service", "webhook-worker"))
+	webhookWorker.MaxAttempts = config.WebhookMaxAttempts()
+	webhookWorker.DisableAfter = config.WebhookDisableAfter()
+	webhookWorker.Start()
+
+	contentSizeBackfill := workers.MakeContentSizeBackfill(db, contentStore, logger.With("service", "content-size-backfill"))
+	contentSizeBackfill.Start()
+
+	if config.ContentReconcileInterval() > 0 {
+		reconciler := workers.MakeContentReconciler(db, contentStore, logger.With("service", "content-reconciler"))
+		reconciler.Interval = config.ContentReconcileInterval()
+		reconciler.GracePeriod = config.ContentOrphanGracePeriod()
+		reconciler.Start()
+	}
+
 	r := chi.NewRouter()
 
 	r.Route("/web", func(r chi.Router) {
@@ -116,6 +154,9 @@ func main() {
 				UseSecureCookies: config.UseSecureCookies(),
 				AuthSchemes:      schemes,
 				Logger:           logger,
+				RateLimit:        config.RateLimit(),
+				RateLimitBurst:   config.RateLimitBurst(),
+				MaxRequestSize:   config.MaxRequestSize(),
 			},
 		)
 	})
@@ -126,11 +167,36 @@ func main() {
 		)
 	})
 
+	if config.SCIMEnabled() {
+		scimScheme, err := findSCIMAuthScheme(schemes, config.SCIMAuthScheme())
+		if err != nil {
+			logging.Fatal(logger, "SCIM setup error", "error", err)
+		}
+		r.Route("/scim/v2", func(r chi.Router) {
+			server.SCIM(r, db, server.SCIMConfig{
+				Token:      config.SCIMToken(),
+				AuthScheme: scimScheme,
+				Logger:     logger,
+			})
+		})
+		logger.Info("SCIM provisioning enabled", "authScheme", scimScheme.Name)
+	}
+
 	logger.Info("starting Web server", "port", config.Port())
 	serveErr := http.ListenAndServe(":"+config.Port(), r)
 	logging.Fatal(logger, "server shutting down", "err", serveErr)
 }
 
+// findSCIMAuthScheme identifies the loaded auth scheme that SCIM provisioned users log in with
+func findSCIMAuthScheme(schemes []authschemes.AuthScheme, name string) (services.SCIMAuthScheme, error) {
+	for _, scheme := range schemes {
+		if scheme.Name() == name {
+			return services.SCIMAuthScheme{Name: scheme.Name(), Type: scheme.Type()}, nil
+		}
+	}
+	return services.SCIMAuthScheme{}, fmt.Errorf("SCIM auth scheme %v is not enabled", name)
+}
+
 func handleAuthType(cfg config.AuthInstanceConfig) (authschemes.AuthScheme, error) {
 	appConfig := config.AllAppConfig()
 	if cfg.Type == "oidc" {
@@ -171,6 +237,9 @@ func startEmailServices(db *database.Connection, logger *slog.Logger) {
 		emailLogger.Info("Staring emailer")
 		emailWorker := workers.MakeEmailWorker(db, emailServicer, logger.With("service", "email-worker"))
 		emailWorker.Start()
+
+		queryDigestWorker := workers.MakeQueryDigestWorker(db, logger.With("service", "query-digest-worker"))
+		queryDigestWorker.Start()
 	}
 }
 
@@ -0,0 +1,129 @@
+// ashirt-storage provides offline maintenance tasks for the configured content store. It reads the
+// same DB_* and STORE_* environment variables as the ashirt server.
+//
+// Usage:
+//
+//	ashirt-storage reencrypt
+//	ashirt-storage migrate [-state file] [-concurrency n]
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/config/confighelpers"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const usage = `usage: ashirt-storage <command>
+
+commands:
+  reencrypt    re-encrypt all evidence content with the active encryption key (STORE_ENCRYPTION_KEY_ID)
+  migrate      copy all evidence content from the source store (SOURCE_STORE_*) to the store (STORE_*),
+               then report content in the source store that is not referenced by any evidence
+`
+
+func main() {
+	logger := logging.SetupStdoutLogging()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := config.LoadStorageToolConfig(); err != nil {
+		logging.Fatal(logger, "Unable to start due to configuration error", "error", err, "action", "exiting")
+	}
+	// migrations are never run by this tool
+	db, err := database.NewConnection(config.DBUri(), "")
+	if err != nil {
+		logging.Fatal(logger, "Unable to connect to database", "error", err, "action", "exiting")
+	}
+
+	switch cmd := os.Args[1]; cmd {
+	case "reencrypt":
+		err = reencrypt(db, logger)
+	case "migrate":
+		err = migrate(db, logger, os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %v\n\n%v", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logging.Fatal(logger, "command failed", "command", os.Args[1], "error", err)
+	}
+}
+
+// reencrypt re-encrypts every piece of evidence content with the active key. Content that is already
+// encrypted with the active key is skipped, so this can safely be re-run after a failure.
+func reencrypt(db *database.Connection, logger *slog.Logger) error {
+	cfg := config.AllStoreConfig()
+	if cfg.EncryptionKeyID == "" {
+		return errors.New("no active encryption key provided (STORE_ENCRYPTION_KEY_ID)")
+	}
+	baseStore, err := confighelpers.ChooseContentStoreType(cfg)
+	if err != nil {
+		return err
+	}
+	store, err := confighelpers.EncryptedContentStore(baseStore, cfg)
+	if err != nil {
+		return err
+	}
+
+	keys, err := evidenceContentKeys(db)
+	if err != nil {
+		return err
+	}
+
+	var reencrypted, skipped, failed int
+	for _, key := range keys {
+		changed, err := store.Reencrypt(key)
+		switch {
+		case err != nil:
+			failed++
+			logger.Error("Unable to re-encrypt content", "key", key, "error", err)
+		case changed:
+			reencrypted++
+		default:
+			skipped++
+		}
+	}
+	logger.Info("Re-encryption complete", "keyID", cfg.EncryptionKeyID,
+		"reencrypted", reencrypted, "alreadyCurrent", skipped, "failed", failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%v content keys could not be re-encrypted", failed)
+	}
+	return nil
+}
+
+// evidenceContentKeys retrieves the (unique) keys for all full and thumbnail evidence content
+func evidenceContentKeys(db *database.Connection) ([]string, error) {
+	var evidenceData []struct {
+		FullKey  string `db:"full_image_key"`
+		ThumbKey string `db:"thumb_image_key"`
+	}
+	err := db.Select(&evidenceData, sq.Select("full_image_key", "thumb_image_key").
+		From("evidence").
+		OrderBy("id"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch evidence: %w", err)
+	}
+
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, evi := range evidenceData {
+		for _, key := range []string{evi.FullKey, evi.ThumbKey} {
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/config/confighelpers"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"golang.org/x/sync/errgroup"
+)
+
+// migrate copies all evidence content from the source store (SOURCE_STORE_*) to the destination
+// store (STORE_*). Content is copied as-is, so encrypted or deduplicated content remains so.
+func migrate(db *database.Connection, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	statePath := flags.String("state", "ashirt-storage-migration.state", "file used to record migrated keys, so that an interrupted migration can be resumed")
+	concurrency := flags.Int("concurrency", 4, "number of objects to copy at once")
+	flags.Parse(args)
+
+	srcCfg, dstCfg := config.AllSourceStoreConfig(), config.AllStoreConfig()
+	if srcCfg.Type == "" || dstCfg.Type == "" {
+		return errors.New("both a source (SOURCE_STORE_TYPE) and destination (STORE_TYPE) store must be provided")
+	}
+	src, err := confighelpers.ChooseContentStoreType(srcCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create source store: %w", err)
+	}
+	dst, err := confighelpers.ChooseContentStoreType(dstCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create destination store: %w", err)
+	}
+
+	keys, err := evidenceContentKeys(db)
+	if err != nil {
+		return err
+	}
+
+	state, err := openMigrationState(*statePath)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+
+	logger.Info("Starting migration", "from", src.Name(), "to", dst.Name(), "keys", len(keys), "alreadyMigrated", state.Count())
+	result := migrateContent(src, dst, keys, state, *concurrency, logger)
+	logger.Info("Migration complete", "copied", result.Copied, "skipped", result.Skipped, "failed", result.Failed)
+
+	if err := reportOrphans(src, keys, logger); err != nil {
+		logger.Warn("Unable to report orphaned content", "error", err)
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%v content keys could not be migrated. Re-run to retry", result.Failed)
+	}
+	return nil
+}
+
+type migrationResult struct {
+	Copied  int
+	Skipped int
+	Failed  int
+}
+
+// migrateContent copies each key not yet recorded in the state from src to dst, recording each
+// successfully verified copy
+func migrateContent(src, dst contentstore.Store, keys []string, state *migrationState, concurrency int, logger *slog.Logger) migrationResult {
+	var result migrationResult
+	var mutex sync.Mutex
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+
+	for _, key := range keys {
+		if state.Done(key) {
+			result.Skipped++
+			continue
+		}
+		g.Go(func() error {
+			err := copyContent(src, dst, key)
+			if err == nil {
+				err = state.Record(key)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				result.Failed++
+				logger.Error("Unable to migrate content", "key", key, "error", err)
+			} else {
+				result.Copied++
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	return result
+}
+
+// copyContent copies a single object, then reads it back from the destination to verify that the
+// size and hash of the copy match the original
+func copyContent(src, dst contentstore.Store, key string) error {
+	reader, err := src.Read(key)
+	if err != nil {
+		return fmt.Errorf("unable to read source: %w", err)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	original := contentstore.NewHashingReader(reader)
+	if err := dst.UploadWithName(key, original); err != nil {
+		return fmt.Errorf("unable to write destination: %w", err)
+	}
+
+	reader, err = dst.Read(key)
+	if err != nil {
+		return fmt.Errorf("unable to read back destination: %w", err)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	copied := contentstore.NewHashingReader(reader)
+	if _, err := io.Copy(io.Discard, copied); err != nil {
+		return fmt.Errorf("unable to read back destination: %w", err)
+	}
+
+	if copied.Size() != original.Size() {
+		return fmt.Errorf("size mismatch: source has %v bytes, destination has %v bytes", original.Size(), copied.Size())
+	}
+	if copied.Sum() != original.Sum() {
+		return fmt.Errorf("hash mismatch: source is %v, destination is %v", original.Sum(), copied.Sum())
+	}
+	return nil
+}
+
+// reportOrphans logs every key in the store that is not referenced by any evidence
+func reportOrphans(store contentstore.Store, keys []string, logger *slog.Logger) error {
+	storedKeys, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	referenced := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		referenced[key] = true
+	}
+	orphans := 0
+	for _, key := range storedKeys {
+		if !referenced[key] {
+			orphans++
+			logger.Info("Orphaned content", "key", key)
+		}
+	}
+	logger.Info("Orphan report complete", "stored", len(storedKeys), "orphaned", orphans)
+	return nil
+}
+
+// migrationState tracks which keys have been migrated. Keys are appended to the state file, one per
+// line, as each copy is verified.
+type migrationState struct {
+	file  *os.File
+	done  map[string]bool
+	mutex sync.Mutex
+}
+
+func openMigrationState(path string) (*migrationState, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open migration state: %w", err)
+	}
+	state := &migrationState{file: file, done: map[string]bool{}}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			state.done[key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to read migration state: %w", err)
+	}
+	return state, nil
+}
+
+func (s *migrationState) Done(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.done[key]
+}
+
+func (s *migrationState) Count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.done)
+}
+
+func (s *migrationState) Record(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := fmt.Fprintln(s.file, key); err != nil {
+		return fmt.Errorf("unable to record migration state: %w", err)
+	}
+	s.done[key] = true
+	return nil
+}
+
+func (s *migrationState) Close() error {
+	return s.file.Close()
+}
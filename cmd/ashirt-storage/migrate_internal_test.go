@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateContent(t *testing.T) {
+	src, _ := contentstore.NewMemStore()
+	dst, _ := contentstore.NewMemStore()
+	logger := logging.NewNopLogger()
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, src.UploadWithName(key, bytes.NewReader([]byte("content "+key))))
+	}
+
+	statePath := filepath.Join(t.TempDir(), "state")
+	state, err := openMigrationState(statePath)
+	require.NoError(t, err)
+
+	// "missing" does not exist in the source, and should fail without stopping the migration
+	result := migrateContent(src, dst, []string{"a", "b", "missing"}, state, 2, logger)
+	require.Equal(t, migrationResult{Copied: 2, Failed: 1}, result)
+	require.NoError(t, state.Close())
+
+	reader, err := dst.Read("b")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	require.Equal(t, []byte("content b"), data)
+
+	// verify a resumed migration skips already migrated content
+	state, err = openMigrationState(statePath)
+	require.NoError(t, err)
+	defer state.Close()
+	require.Equal(t, 2, state.Count())
+
+	result = migrateContent(src, dst, []string{"a", "b", "c"}, state, 2, logger)
+	require.Equal(t, migrationResult{Copied: 1, Skipped: 2}, result)
+}
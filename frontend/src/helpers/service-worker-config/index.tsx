@@ -2,6 +2,7 @@ export type BasicServiceWorkerConfig = {
   type: string
   version: number //int only
   synchronous?: true
+  events?: Array<string> // the events the worker subscribes to. Defaults to evidence_created only
 }
 
 export type ServiceWorkerWeb = {
@@ -10,9 +11,29 @@ export type ServiceWorkerWeb = {
   url: string
   headers?: Record<string, string> // not allowing multiple values for a header for now
   synchronous?: true
+  events?: Array<string>
 }
 
-export type ServiceWorkerConfig = ServiceWorkerWeb
+export type ServiceWorkerExec = {
+  type: 'exec'
+  version: 1
+  path: string
+  args?: Array<string>
+  timeout?: string
+  events?: Array<string>
+}
+
+export type ServiceWorkerGRPC = {
+  type: 'grpc'
+  version: 1
+  address: string
+  tls?: boolean
+  headers?: Record<string, string>
+  timeout?: string
+  events?: Array<string>
+}
+
+export type ServiceWorkerConfig = ServiceWorkerWeb | ServiceWorkerExec | ServiceWorkerGRPC
 
 type JSONPrimitive = string | boolean | number | null
 
@@ -20,7 +41,10 @@ export const parseServiceConfig = (config: string): ServiceWorkerConfig | null =
   // restrict JSON.parse type into something reasonable. You might be able to trick this, but...
   const obj = JSON.parse(config) as Record<string, unknown> | Array<unknown> | JSONPrimitive
 
-  if (typeof obj === 'object' && obj !== null && !Array.isArray(obj) && isWebConfig(obj)) {
+  if (!isRecord(obj) || !hasOptionalStringArray(obj, 'events')) {
+    return null
+  }
+  if (isWebConfig(obj) || isExecConfig(obj) || isGRPCConfig(obj)) {
     return obj
   }
   return null
@@ -41,6 +65,31 @@ const isWebConfig = (json: Record<string, unknown>): json is ServiceWorkerWeb =>
   )
 }
 
+const isExecConfig = (json: Record<string, unknown>): json is ServiceWorkerExec => {
+  return (
+    hasValue(json, 'type', 'exec') &&
+    hasValue(json, 'version', 1) &&
+    hasValueType(json, 'path', 'string') &&
+    (!('args' in json) || Array.isArray(json.args)) &&
+    hasOptionalType(json, 'timeout', 'string')
+  )
+}
+
+const isGRPCConfig = (json: Record<string, unknown>): json is ServiceWorkerGRPC => {
+  return (
+    hasValue(json, 'type', 'grpc') &&
+    hasValue(json, 'version', 1) &&
+    hasValueType(json, 'address', 'string') &&
+    hasOptionalType(json, 'tls', 'boolean') &&
+    hasOptionalRecord(json, 'headers') &&
+    hasOptionalType(json, 'timeout', 'string')
+  )
+}
+
+const hasOptionalType = (object: Record<string, unknown>, field: string, valueType: string) => {
+  return !(field in object) || hasValueType(object, field, valueType)
+}
+
 const hasValue = (object: Record<string, unknown>, field: string, value: unknown) => {
   return object[field] == value
 }
@@ -56,6 +105,14 @@ const hasOptionalRecord = (object: Record<string, unknown>, field: string) => {
   return true
 }
 
+const hasOptionalStringArray = (object: Record<string, unknown>, field: string) => {
+  if (field in object) {
+    const value = object[field]
+    return Array.isArray(value) && value.every((v) => typeof v === 'string')
+  }
+  return true
+}
+
 const isRecord = (o: unknown): o is Record<string, unknown> => {
   return typeof o === 'object' && o !== null && !Array.isArray(o)
 }
@@ -46,6 +46,19 @@ export const SearchHelpModal = (props: { onRequestClose: () => void }) => {
           <CodeSnippet>"Jar Jar Binks"</CodeSnippet> would narrow the results to just{' '}
           <em>Star Wars</em> episodes 1-3.
         </p>
+        <p>
+          Filters can also be combined with <CodeSnippet>AND</CodeSnippet>,{' '}
+          <CodeSnippet>OR</CodeSnippet> and <CodeSnippet>NOT</CodeSnippet> (written in capitals),
+          and grouped with parentheses:
+          <CodeExample>(tag:web OR tag:api) AND NOT operator:bob</CodeExample>
+          Filters next to each other are joined with <CodeSnippet>AND</CodeSnippet>, which is
+          applied before <CodeSnippet>OR</CodeSnippet>. As with other searches, repeating a filter
+          next to itself matches any of its values (e.g. <CodeSnippet>tag:web tag:api</CodeSnippet>),
+          unless <CodeSnippet>AND</CodeSnippet> is written between them. A group or filter can also be negated with a
+          leading <CodeSnippet>!</CodeSnippet>, for example <CodeSnippet>!(tag:web OR tag:api)</CodeSnippet>{' '}
+          or <CodeSnippet>!tag:web</CodeSnippet>. To search for any of these words or symbols as
+          text, place them in quotes.
+        </p>
         <p>
           The below table lists all of the currently available filters, and value limitations, if
           any:
@@ -133,10 +146,35 @@ const HelpText: Array<FilterDetail> = [
         <p>
           Only one <CodeSnippet>range</CodeSnippet> field can be specified.
         </p>
+        <p>
+          Either date can also be given relative to now: <CodeSnippet>now</CodeSnippet>,{' '}
+          <CodeSnippet>today</CodeSnippet>, <CodeSnippet>last-week</CodeSnippet> (7 days ago), or an
+          offset such as <CodeSnippet>-30m</CodeSnippet>, <CodeSnippet>-24h</CodeSnippet>,{' '}
+          <CodeSnippet>-7d</CodeSnippet> or <CodeSnippet>-2w</CodeSnippet>. For example:{' '}
+          <CodeSnippet>range:-24h,now</CodeSnippet>.
+        </p>
         <p>Click on the calendar next to the Timeline Filter to help specify the date.</p>
       </>
     ),
   },
+  {
+    field: 'after / before',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that the evidence occurred after (or before) a particular
+          time. In the findings timeline, this requires that some evidence for the finding occurred
+          after (or before) the time. Accepts the same dates and relative times as{' '}
+          <CodeSnippet>range</CodeSnippet>, for example <CodeSnippet>after:last-week</CodeSnippet>{' '}
+          or <CodeSnippet>before:2020-01-31</CodeSnippet>.
+        </p>
+        <p>
+          Each of <CodeSnippet>after</CodeSnippet> and <CodeSnippet>before</CodeSnippet> can be
+          specified once.
+        </p>
+      </>
+    ),
+  },
   {
     field: 'sort',
     description: (
@@ -179,6 +217,34 @@ const HelpText: Array<FilterDetail> = [
       </>
     ),
   },
+  {
+    field: 'finding',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that the evidence is linked to a finding with the given
+          UUID or title (e.g. <CodeSnippet>finding:"SQL Injection"</CodeSnippet>). In the findings
+          timeline, this matches the finding itself.
+        </p>
+        <p>
+          Multiple values can be specified. When multiples are specified, any one finding must
+          match.
+        </p>
+      </>
+    ),
+  },
+  {
+    field: 'adjusted',
+    description: (
+      <>
+        <p>
+          Filters the result by finding evidence whose time has (or has not) been adjusted. In the
+          findings timeline, this requires that some evidence for the finding has been adjusted.
+        </p>
+        <p>Possible values: {valuesAsCodeSnippets(['true', 'false'])}</p>
+      </>
+    ),
+  },
   {
     field: 'with-evidence',
     description: (
@@ -247,4 +313,95 @@ const HelpText: Array<FilterDetail> = [
       </>
     ),
   },
+  {
+    field: 'has-metadata',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that the evidence has metadata from the named source (e.g.{' '}
+          <CodeSnippet>has-metadata:ocr</CodeSnippet>). In the findings timeline, this requires
+          that some evidence for the finding has the metadata.
+        </p>
+        <p>
+          Multiple values can be specified. When multiples are specified, any one source must match.
+        </p>
+      </>
+    ),
+  },
+  {
+    field: 'metadata-status',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that the evidence has metadata from an enhancement worker
+          with the given status. In the findings timeline, this requires that some evidence for the
+          finding has such metadata.
+        </p>
+        <p>
+          Possible values: {valuesAsCodeSnippets(['queued', 'processing', 'completed', 'error'])}
+        </p>
+      </>
+    ),
+  },
+  {
+    field: 'created-by-worker',
+    description: (
+      <>
+        <p>
+          Filters the result by finding evidence that has (or has not) had metadata created by an
+          enhancement worker. In the findings timeline, this requires that some evidence for the
+          finding has such metadata.
+        </p>
+        <p>Possible values: {valuesAsCodeSnippets(['true', 'false'])}</p>
+      </>
+    ),
+  },
+  {
+    field: 'host',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that an HTTP request/response cycle contains a request to
+          the specified host, for example <CodeSnippet>host:api.example.com</CodeSnippet>.{' '}
+          <em>This will only have an effect in the Evidence Timeline.</em>
+        </p>
+        <p>
+          Multiple values can be specified. When multiples are specified, any one host must match.
+        </p>
+      </>
+    ),
+  },
+  {
+    field: 'status',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that an HTTP request/response cycle contains a response
+          with the specified status. Either an exact status code (e.g.{' '}
+          <CodeSnippet>404</CodeSnippet>) or a class of status codes (e.g.{' '}
+          <CodeSnippet>5xx</CodeSnippet>) can be provided.{' '}
+          <em>This will only have an effect in the Evidence Timeline.</em>
+        </p>
+        <p>
+          Multiple values can be specified. When multiples are specified, any one status must match.
+        </p>
+      </>
+    ),
+  },
+  {
+    field: 'url',
+    description: (
+      <>
+        <p>
+          Filters the result by requiring that an HTTP request/response cycle contains a request
+          whose URL contains the provided value, for example{' '}
+          <CodeSnippet>url:/api/login</CodeSnippet>.{' '}
+          <em>This will only have an effect in the Evidence Timeline.</em>
+        </p>
+        <p>
+          Multiple values can be specified. When multiples are specified, any one value must match.
+        </p>
+      </>
+    ),
+  },
 ]
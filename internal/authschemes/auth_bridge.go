@@ -340,6 +340,18 @@ func (ah AShirtAuthBridge) OneTimeVerification(ctx context.Context, username str
 	return userID, nil
 }
 
+// SyncUserGroups updates the user's membership in the given managed user groups (and, optionally,
+// their admin status) to match what the auth scheme's identity provider reports. This should be
+// called prior to LoginUser, so that the new session reflects the updated admin status.
+func (ah AShirtAuthBridge) SyncUserGroups(r *http.Request, userID int64, managedGroupSlugs, groupSlugs []string, admin *bool) error {
+	return services.SyncUserGroups(r.Context(), ah.db, services.SyncUserGroupsInput{
+		UserID:            userID,
+		ManagedGroupSlugs: managedGroupSlugs,
+		GroupSlugs:        groupSlugs,
+		Admin:             admin,
+	})
+}
+
 // GetDatabase provides raw access to the database. In general, this should not be used by authschemes,
 // but is provided in situations where unique-access to the database is required.
 func (ah AShirtAuthBridge) GetDatabase() *database.Connection {
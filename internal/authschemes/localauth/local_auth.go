@@ -13,6 +13,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
 	"github.com/ashirt-ops/ashirt-server/internal/server/remux"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -99,7 +100,8 @@ func (p LocalAuthScheme) BindRoutes(r chi.Router, bridge authschemes.AShirtAuthB
 			return nil, err
 		}
 
-		return nil, registerNewUser(r.Context(), bridge, info)
+		_, err := registerNewUser(r.Context(), bridge, info)
+		return nil, err
 	}))
 
 	remux.Route(r, "POST", "/admin/register", remux.JSONHandler(func(r *http.Request) (interface{}, error) {
@@ -133,9 +135,11 @@ func (p LocalAuthScheme) BindRoutes(r chi.Router, bridge authschemes.AShirtAuthB
 			return nil, err
 		}
 
-		if err := registerNewUser(r.Context(), bridge, info); err != nil {
+		userSlug, err := registerNewUser(r.Context(), bridge, info)
+		if err != nil {
 			return nil, err
 		}
+		services.RecordAuditEvent(r.Context(), bridge.GetDatabase(), services.AuditActionRegisterUser, services.AuditTargetUser, userSlug)
 
 		return dtos.NewUserCreatedByAdmin{
 			TemporaryPassword: info.Password,
@@ -288,7 +292,11 @@ func (p LocalAuthScheme) BindRoutes(r chi.Router, bridge authschemes.AShirtAuthB
 		userAuth.EncryptedPassword = encryptedPassword
 		userAuth.NeedsPasswordReset = true
 
-		return nil, bridge.UpdateAuthForUser(userAuth)
+		if err := bridge.UpdateAuthForUser(userAuth); err != nil {
+			return nil, err
+		}
+		services.RecordAuditEvent(r.Context(), bridge.GetDatabase(), services.AuditActionResetUserPassword, services.AuditTargetUser, userSlug)
+		return nil, nil
 	}))
 
 	remux.Route(r, "POST", "/link", remux.JSONHandler(func(r *http.Request) (interface{}, error) {
@@ -63,10 +63,10 @@ func deleteUserTotp(ctx context.Context, bridge authschemes.AShirtAuthBridge, us
 	return bridge.UpdateAuthForUser(authData)
 }
 
-func registerNewUser(ctx context.Context, bridge authschemes.AShirtAuthBridge, info RegistrationInfo) error {
+func registerNewUser(ctx context.Context, bridge authschemes.AShirtAuthBridge, info RegistrationInfo) (string, error) {
 	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(info.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return errorwrap.WrapError("Unable to generate encrypted password", err)
+		return "", errorwrap.WrapError("Unable to generate encrypted password", err)
 	}
 
 	userResult, err := bridge.CreateNewUser(authschemes.UserProfile{
@@ -76,9 +76,9 @@ func registerNewUser(ctx context.Context, bridge authschemes.AShirtAuthBridge, i
 		Email:     info.Email,
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
-	return bridge.CreateNewAuthForUser(authschemes.UserAuthData{
+	return userResult.RealSlug, bridge.CreateNewAuthForUser(authschemes.UserAuthData{
 		UserID:             userResult.UserID,
 		Username:           info.Username,
 		EncryptedPassword:  encryptedPassword,
@@ -12,6 +12,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/authschemes"
 	"github.com/ashirt-ops/ashirt-server/internal/config"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
 	"github.com/ashirt-ops/ashirt-server/internal/server/remux"
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -29,6 +30,9 @@ type OIDCAuth struct {
 	profileFirstNameField         string
 	profileLastNameField          string
 	profileEmailField             string
+	profileGroupsField            string
+	groupMappings                 map[string]string
+	adminGroups                   []string
 	registrationEnabled           bool
 	authSuccessRedirectPath       string
 	authFailureRedirectPathPrefix string
@@ -81,6 +85,9 @@ func New(cfg config.AuthInstanceConfig, webConfig *config.WebConfig) (OIDCAuth,
 		profileFirstNameField:         cfg.ProfileFirstNameField,
 		profileLastNameField:          cfg.ProfileLastNameField,
 		profileEmailField:             cfg.ProfileEmailField,
+		profileGroupsField:            cfg.ProfileGroupsField,
+		groupMappings:                 cfg.GroupMappings,
+		adminGroups:                   cfg.AdminGroups,
 		registrationEnabled:           cfg.RegistrationEnabled,
 		authSuccessRedirectPath:       successRedirectURL,
 		authFailureRedirectPathPrefix: failureRedirectURLPrefix,
@@ -210,6 +217,19 @@ func (o OIDCAuth) handleCallback(w http.ResponseWriter, r *http.Request, bridge
 		return o.authSuccess(w, r, linkingAccount)
 	}
 
+	if o.profileGroupsField != "" {
+		// some providers only include groups in the id token, rather than the userinfo profile
+		idTokenClaims := make(map[string]interface{})
+		if err = idToken.Claims(&idTokenClaims); err != nil {
+			return o.authFailure(w, r, errorwrap.BadAuthErr(errors.New(authName+" unable to parse id token claims")), "/autherror/noaccess")
+		}
+		managedGroups, groups, admin := o.mapGroups(groupClaimValues(o.profileGroupsField, profileClaims, idTokenClaims))
+		err = bridge.SyncUserGroups(r, authData.UserID, managedGroups, groups, admin)
+		if err != nil {
+			return o.authFailure(w, r, errorwrap.WrapError("Unable to sync groups for "+authName+" user ["+authData.Username+"]", err), "/autherror/incomplete")
+		}
+	}
+
 	err = bridge.LoginUser(w, r, authData.UserID, &authSession{
 		IdToken:     rawIDToken,
 		AccessToken: idToken.AccessTokenHash,
@@ -254,6 +274,50 @@ func (o OIDCAuth) makeUserProfile(claims map[string]interface{}) (*authschemes.U
 	return &userProfile, nil
 }
 
+// groupClaimValues retrieves the values of the named groups claim from the first set of claims
+// that contains it. The claim may be either a list of strings, or a single string. A missing claim
+// is treated as the user belonging to no groups.
+func groupClaimValues(field string, claimSets ...map[string]interface{}) []string {
+	for _, claims := range claimSets {
+		switch value := claims[field].(type) {
+		case string:
+			return []string{value}
+		case []interface{}:
+			values := make([]string, 0, len(value))
+			for _, v := range value {
+				if s, ok := v.(string); ok {
+					values = append(values, s)
+				}
+			}
+			return values
+		}
+	}
+	return []string{}
+}
+
+// mapGroups converts the user's group claim values into the user groups managed by this auth
+// scheme, the subset of those the user should belong to, and whether the user should be an admin
+// (nil if admin status is not managed)
+func (o OIDCAuth) mapGroups(claimValues []string) (managedGroupSlugs, groupSlugs []string, admin *bool) {
+	for claimValue, groupSlug := range o.groupMappings {
+		if !helpers.ContainsMatch(managedGroupSlugs, groupSlug) {
+			managedGroupSlugs = append(managedGroupSlugs, groupSlug)
+		}
+		if helpers.ContainsMatch(claimValues, claimValue) && !helpers.ContainsMatch(groupSlugs, groupSlug) {
+			groupSlugs = append(groupSlugs, groupSlug)
+		}
+	}
+
+	if len(o.adminGroups) > 0 {
+		isAdmin := false
+		for _, adminGroup := range o.adminGroups {
+			isAdmin = isAdmin || helpers.ContainsMatch(claimValues, adminGroup)
+		}
+		admin = &isAdmin
+	}
+	return managedGroupSlugs, groupSlugs, admin
+}
+
 func all(fields ...bool) bool {
 	isTrue := true
 	for _, v := range fields {
@@ -0,0 +1,47 @@
+package oidcauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupClaimValues(t *testing.T) {
+	profileClaims := map[string]interface{}{
+		"groups": []interface{}{"aurors", 42, "order"},
+		"role":   "headmaster",
+	}
+	idTokenClaims := map[string]interface{}{
+		"groups": []interface{}{"ignored"},
+		"teams":  []interface{}{"quidditch"},
+	}
+
+	require.Equal(t, []string{"aurors", "order"}, groupClaimValues("groups", profileClaims, idTokenClaims))
+	require.Equal(t, []string{"headmaster"}, groupClaimValues("role", profileClaims, idTokenClaims))
+	require.Equal(t, []string{"quidditch"}, groupClaimValues("teams", profileClaims, idTokenClaims))
+	require.Equal(t, []string{}, groupClaimValues("houses", profileClaims, idTokenClaims))
+}
+
+func TestMapGroups(t *testing.T) {
+	o := OIDCAuth{
+		groupMappings: map[string]string{
+			"aurors":       "ministry",
+			"unspeakables": "ministry",
+			"order":        "order-of-the-phoenix",
+		},
+	}
+
+	managed, groups, admin := o.mapGroups([]string{"aurors", "unspeakables", "death-eaters"})
+	require.ElementsMatch(t, []string{"ministry", "order-of-the-phoenix"}, managed)
+	require.Equal(t, []string{"ministry"}, groups)
+	require.Nil(t, admin)
+
+	o.adminGroups = []string{"headmasters", "order"}
+	_, groups, admin = o.mapGroups([]string{"order"})
+	require.Equal(t, []string{"order-of-the-phoenix"}, groups)
+	require.True(t, *admin)
+
+	_, groups, admin = o.mapGroups([]string{})
+	require.Empty(t, groups)
+	require.False(t, *admin)
+}
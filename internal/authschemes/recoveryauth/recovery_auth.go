@@ -12,6 +12,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
 	"github.com/ashirt-ops/ashirt-server/internal/server/remux"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -89,6 +90,7 @@ func (p RecoveryAuthScheme) BindRoutes(r chi.Router, bridge authschemes.AShirtAu
 		if err != nil {
 			return nil, errorwrap.WrapError("Unable to create new user", err)
 		}
+		services.RecordAuditEvent(r.Context(), bridge.GetDatabase(), services.AuditActionRegisterUser, services.AuditTargetUser, userResult.RealSlug)
 
 		return generateRecoveryCodeForUser(r.Context(), bridge, userResult.RealSlug)
 	}))
@@ -12,6 +12,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
 
 	sq "github.com/Masterminds/squirrel"
 )
@@ -29,6 +30,7 @@ func DeleteExpiredRecoveryCodes(ctx context.Context, db *database.Connection, ex
 	if err != nil {
 		return errorwrap.WrapError("Unable to remove recovery code", errorwrap.DatabaseErr(err))
 	}
+	services.RecordAuditEvent(ctx, db, services.AuditActionDeleteExpiredRecoveryCodes, services.AuditTargetAuthScheme, recoveryConsts.Code)
 
 	return nil
 }
@@ -49,6 +51,7 @@ func generateRecoveryCodeForUser(ctx context.Context, bridge authschemes.AShirtA
 	if err != nil {
 		return nil, errorwrap.WrapError("Could not generate recovery code for user", err)
 	}
+	services.RecordAuditEvent(ctx, bridge.GetDatabase(), services.AuditActionCreateUserRecoveryCode, services.AuditTargetUser, userSlug)
 
 	response := struct {
 		Code string `json:"code"`
@@ -40,6 +40,14 @@ type OIDCConfig struct {
 	BackendURL               string `split_words:"true"`
 	SuccessRedirectURL       string `split_words:"true"`
 	FailureRedirectURLPrefix string `split_words:"true"`
+	// ProfileGroupsField names the claim listing the user's groups. When set, the user's membership
+	// in the mapped user groups (and optionally their admin status) is updated on every login.
+	ProfileGroupsField string `split_words:"true"`
+	// GroupMappings maps group claim values to user group slugs
+	GroupMappings map[string]string `split_words:"true"`
+	// AdminGroups lists the group claim values that grant admin status. If empty, admin status
+	// is not managed by the identity provider.
+	AdminGroups []string `split_words:"true"`
 }
 
 type WebauthnConfig struct {
@@ -1,10 +1,14 @@
 package confighelpers
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
 
 	"github.com/ashirt-ops/ashirt-server/internal/config"
 	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 )
 
@@ -14,6 +18,12 @@ import (
 // If an unknown type is provided, then an error is raised.
 func ChooseContentStoreType(cfg config.ContentStoreConfig) (contentstore.Store, error) {
 	if cfg.Type == "local" {
+		if cfg.Root == "" {
+			return nil, errors.New("a local content store requires a root directory (STORE_ROOT)")
+		}
+		return contentstore.NewFileStore(cfg.Root, cfg.FileMode)
+	}
+	if cfg.Type == "dev" {
 		return contentstore.NewDevStore()
 	}
 	if cfg.Type == "memory" {
@@ -35,6 +45,45 @@ func ChooseContentStoreType(cfg config.ContentStoreConfig) (contentstore.Store,
 	return nil, fmt.Errorf("unknown storage type: %v", cfg.Type)
 }
 
+// WrapContentStore applies the optional layers configured for the content store: encryption (when an
+// encryption key ID is provided) and deduplication. Deduplication is applied outermost, so that
+// content is identified by its unencrypted hash. Reads of unencrypted content are logged to logger.
+func WrapContentStore(store contentstore.Store, cfg config.ContentStoreConfig, db *database.Connection, logger *slog.Logger) (contentstore.Store, error) {
+	if cfg.EncryptionKeyID != "" {
+		encryptedStore, err := EncryptedContentStore(store, cfg)
+		if err != nil {
+			return nil, err
+		}
+		encryptedStore.Logger = logger
+		store = encryptedStore
+	} else if cfg.EncryptionRequired {
+		return nil, errors.New("encryption is required, but no active encryption key was provided (STORE_ENCRYPTION_KEY_ID)")
+	}
+	if cfg.Deduplicate {
+		store = contentstore.NewDedupStore(store, db)
+	}
+	return store, nil
+}
+
+// EncryptedContentStore wraps the provided store with encryption, using the keys provided in the
+// configuration. Unencrypted content is rejected if the configuration requires encryption.
+func EncryptedContentStore(store contentstore.Store, cfg config.ContentStoreConfig) (*contentstore.EncryptedStore, error) {
+	keys := make(map[string][]byte, len(cfg.EncryptionKeys))
+	for keyID, encodedKey := range cfg.EncryptionKeys {
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %v is not valid base64: %w", keyID, err)
+		}
+		keys[keyID] = key
+	}
+	encryptedStore, err := contentstore.NewEncryptedStore(store, keys, cfg.EncryptionKeyID)
+	if err != nil {
+		return nil, err
+	}
+	encryptedStore.RequireEncryption = cfg.EncryptionRequired
+	return encryptedStore, nil
+}
+
 // DefaultS3Store creates a content store that points to S3. Notably, this has a fallback to
 // deprecated environment variables, to help aid adoption of the more modern configuration
 func DefaultS3Store() (contentstore.Store, error) {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"strconv"
 	"time"
 
@@ -9,33 +10,63 @@ import (
 
 // WebConfig is a namespaced app-specific configuration.
 type WebConfig struct {
-	ImgstoreBucketName       string        `split_words:"true"`
-	ImgstoreRegion           string        `split_words:"true"`
-	SessionStoreKey          string        `split_words:"true"`
-	RecoveryExpiry           time.Duration `split_words:"true" default:"24h"`
-	DisableLocalRegistration bool          `split_words:"true"`
-	FrontendIndexURL         string        `split_words:"true"`
-	BackendURL               string        `split_words:"true"`
-	SuccessRedirectURL       string        `split_words:"true"`
-	FailureRedirectURLPrefix string        `split_words:"true"`
-	EnableEvidenceExport     bool          `split_words:"true"`
-	Flags                    string
-	Port                     int
-	SeedDatabase             bool   `split_words:"true"`
-	UseSecureCookies         bool   `split_words:"true" default:"true"`
-	MigrationsPath           string `split_words:"true" default:"/migrations"`
+	ImgstoreBucketName          string        `split_words:"true"`
+	ImgstoreRegion              string        `split_words:"true"`
+	SessionStoreKey             string        `split_words:"true"`
+	RecoveryExpiry              time.Duration `split_words:"true" default:"24h"`
+	DisableLocalRegistration    bool          `split_words:"true"`
+	FrontendIndexURL            string        `split_words:"true"`
+	BackendURL                  string        `split_words:"true"`
+	SuccessRedirectURL          string        `split_words:"true"`
+	FailureRedirectURLPrefix    string        `split_words:"true"`
+	EnableEvidenceExport        bool          `split_words:"true"`
+	Flags                       string
+	Port                        int
+	SeedDatabase                bool             `split_words:"true"`
+	UseSecureCookies            bool             `split_words:"true" default:"true"`
+	MigrationsPath              string           `split_words:"true" default:"/migrations"`
+	ContentReconcileInterval    time.Duration    `split_words:"true"`
+	ContentOrphanGracePeriod    time.Duration    `split_words:"true" default:"24h"`
+	ServiceWorkerConcurrency    int              `split_words:"true" default:"4"`
+	ServiceWorkerMaxAttempts    int64            `split_words:"true" default:"5"`
+	ServiceWorkerBackoff        time.Duration    `split_words:"true" default:"30s"`
+	ServiceWorkerStaleAfter     time.Duration    `split_words:"true" default:"15m"`
+	ServiceWorkerCallbackURL    string           `split_words:"true"`
+	ServiceWorkerCallbackExpiry time.Duration    `split_words:"true" default:"24h"`
+	ExecWorkerPaths             []string         `split_words:"true"`
+	WebhookMaxAttempts          int64            `split_words:"true" default:"5"`
+	WebhookDisableAfter         int64            `split_words:"true" default:"15"`
+	APIRequireNonce             bool             `split_words:"true" default:"false"`
+	RateLimit                   float64          `split_words:"true" default:"600"`
+	RateLimitBurst              int              `split_words:"true" default:"60"`
+	MaxEvidenceSize             int64            `split_words:"true" default:"104857600"`
+	MaxEvidenceSizes            map[string]int64 `split_words:"true"`
+	MaxImportSize               int64            `split_words:"true" default:"1073741824"`
+	SCIMToken                   string           `split_words:"true"`
+	SCIMAuthScheme              string           `split_words:"true"`
 }
 
+// requestSizeOverhead is the room left in a request, beyond the largest evidence size, for the other
+// fields of an evidence upload
+const requestSizeOverhead = 1024 * 1024
+
 // DBConfig provides configuration details on connecting to the backend database
 type DBConfig struct {
 	URI string `required:"true"`
 }
 
 type ContentStoreConfig struct {
-	Type           string `split_words:"true"`
-	Bucket         string `split_words:"true"`
-	Region         string `split_words:"true"`
-	S3UsePathStyle bool   `split_words:"true"`
+	Type           string      `split_words:"true"`
+	Bucket         string      `split_words:"true"`
+	Region         string      `split_words:"true"`
+	S3UsePathStyle bool        `split_words:"true"`
+	Root           string      `split_words:"true"`
+	FileMode       os.FileMode `split_words:"true" default:"0640"`
+	Deduplicate    bool        `split_words:"true"`
+	// EncryptionKeys maps key IDs to base64 encoded, 32 byte keys (e.g. "2024:abc...=,2025:def...=")
+	EncryptionKeys     map[string]string `split_words:"true"`
+	EncryptionKeyID    string            `split_words:"true"`
+	EncryptionRequired bool              `split_words:"true"`
 }
 
 var (
@@ -44,6 +75,8 @@ var (
 	auth  AuthConfig
 	email EmailConfig
 	store ContentStoreConfig
+
+	sourceStore ContentStoreConfig
 )
 
 // LoadConfig loads all of the environment configuration specified in environment variables
@@ -82,6 +115,17 @@ func LoadAPIConfig() error {
 	})
 }
 
+// LoadStorageToolConfig loads only the database and content store configuration from environment
+// variables, along with an optional source content store configuration (SOURCE_STORE_* variables).
+// This version exists primarily for offline storage maintenance tools.
+func LoadStorageToolConfig() error {
+	return loadConfig([]func() error{
+		loadDBConfig,
+		loadStoreConfig,
+		loadSourceStoreConfig,
+	})
+}
+
 func loadAppConfig() error {
 	config := WebConfig{}
 	err := envconfig.Process("app", &config)
@@ -106,6 +150,14 @@ func loadStoreConfig() error {
 	return err
 }
 
+func loadSourceStoreConfig() error {
+	config := ContentStoreConfig{}
+	err := envconfig.Process("source_store", &config)
+	sourceStore = config
+
+	return err
+}
+
 // DBUri retrieves the environment variable DB_URI
 func DBUri() string {
 	return db.URI
@@ -136,6 +188,138 @@ func RecoveryExpiry() time.Duration {
 	return app.RecoveryExpiry
 }
 
+// ContentReconcileInterval retrieves the APP_CONTENT_RECONCILE_INTERVAL value from the environment.
+// A zero value disables content reconciliation.
+func ContentReconcileInterval() time.Duration {
+	return app.ContentReconcileInterval
+}
+
+// ContentOrphanGracePeriod retrieves the APP_CONTENT_ORPHAN_GRACE_PERIOD value from the environment
+func ContentOrphanGracePeriod() time.Duration {
+	return app.ContentOrphanGracePeriod
+}
+
+// ServiceWorkerConcurrency retrieves the APP_SERVICE_WORKER_CONCURRENCY value from the environment.
+// This caps the number of jobs that can run at once for each service worker.
+func ServiceWorkerConcurrency() int {
+	return app.ServiceWorkerConcurrency
+}
+
+// ServiceWorkerMaxAttempts retrieves the APP_SERVICE_WORKER_MAX_ATTEMPTS value from the environment
+func ServiceWorkerMaxAttempts() int64 {
+	return app.ServiceWorkerMaxAttempts
+}
+
+// ServiceWorkerBackoff retrieves the APP_SERVICE_WORKER_BACKOFF value from the environment. This is
+// the delay before a failed service worker job is first retried.
+func ServiceWorkerBackoff() time.Duration {
+	return app.ServiceWorkerBackoff
+}
+
+// ServiceWorkerStaleAfter retrieves the APP_SERVICE_WORKER_STALE_AFTER value from the environment
+func ServiceWorkerStaleAfter() time.Duration {
+	return app.ServiceWorkerStaleAfter
+}
+
+// ServiceWorkerCallbackURL retrieves the APP_SERVICE_WORKER_CALLBACK_URL value from the environment.
+// This is the externally reachable API URL that service workers use to deliver deferred results.
+// Callbacks are not offered when this is empty.
+func ServiceWorkerCallbackURL() string {
+	return app.ServiceWorkerCallbackURL
+}
+
+// ServiceWorkerCallbackExpiry retrieves the APP_SERVICE_WORKER_CALLBACK_EXPIRY value from the environment
+func ServiceWorkerCallbackExpiry() time.Duration {
+	return app.ServiceWorkerCallbackExpiry
+}
+
+// ExecWorkerPaths retrieves the APP_EXEC_WORKER_PATHS value from the environment. These are the
+// absolute paths of the executables that exec service workers may run. Exec workers are disabled
+// when this is empty.
+func ExecWorkerPaths() []string {
+	return app.ExecWorkerPaths
+}
+
+// WebhookMaxAttempts retrieves the APP_WEBHOOK_MAX_ATTEMPTS value from the environment
+func WebhookMaxAttempts() int64 {
+	return app.WebhookMaxAttempts
+}
+
+// WebhookDisableAfter retrieves the APP_WEBHOOK_DISABLE_AFTER value from the environment. This is
+// the number of consecutive failed deliveries after which a webhook is disabled.
+func WebhookDisableAfter() int64 {
+	return app.WebhookDisableAfter
+}
+
+// APIRequireNonce retrieves the APP_API_REQUIRE_NONCE value from the environment. When true, signed
+// API requests without a nonce are rejected.
+func APIRequireNonce() bool {
+	return app.APIRequireNonce
+}
+
+// RateLimit retrieves the APP_RATE_LIMIT value from the environment. This is the number of requests
+// per minute allowed for each API key or user. Zero disables rate limiting.
+func RateLimit() float64 {
+	return app.RateLimit
+}
+
+// RateLimitBurst retrieves the APP_RATE_LIMIT_BURST value from the environment. This is the number of
+// requests that may be made at once, before being limited to the RateLimit.
+func RateLimitBurst() int {
+	return app.RateLimitBurst
+}
+
+// MaxEvidenceSize retrieves the largest allowed size, in bytes, of evidence with the given content type.
+// This is the content type's APP_MAX_EVIDENCE_SIZES value if present, otherwise the APP_MAX_EVIDENCE_SIZE
+// value. Zero allows evidence of any size.
+func MaxEvidenceSize(contentType string) int64 {
+	if size, ok := app.MaxEvidenceSizes[contentType]; ok {
+		return size
+	}
+	return app.MaxEvidenceSize
+}
+
+// MaxImportSize retrieves the APP_MAX_IMPORT_SIZE value from the environment. This is the largest
+// allowed size, in bytes, of an operation archive, both as uploaded and once uncompressed. Zero allows
+// archives of any size.
+func MaxImportSize() int64 {
+	return app.MaxImportSize
+}
+
+// MaxRequestSize returns the largest allowed size, in bytes, of a request body. This is large enough
+// to upload the largest allowed evidence. Zero allows requests of any size.
+func MaxRequestSize() int64 {
+	largest := app.MaxEvidenceSize
+	for _, size := range app.MaxEvidenceSizes {
+		if largest == 0 || size == 0 {
+			return 0
+		}
+		largest = max(largest, size)
+	}
+	if largest == 0 {
+		return 0
+	}
+	return largest + requestSizeOverhead
+}
+
+// SCIMToken retrieves the APP_SCIM_TOKEN value from the environment. This is the bearer token
+// identity providers use to authenticate with the SCIM provisioning endpoint.
+func SCIMToken() string {
+	return app.SCIMToken
+}
+
+// SCIMAuthScheme retrieves the APP_SCIM_AUTH_SCHEME value from the environment. Users provisioned
+// through SCIM log in with this authentication scheme, and their SCIM userName is their username
+// within the scheme.
+func SCIMAuthScheme() string {
+	return app.SCIMAuthScheme
+}
+
+// SCIMEnabled returns true when both APP_SCIM_TOKEN and APP_SCIM_AUTH_SCHEME are set
+func SCIMEnabled() bool {
+	return app.SCIMToken != "" && app.SCIMAuthScheme != ""
+}
+
 // FrontendIndexURL retrieves the APP_FRONTEND_INDEX_URL value from the environment
 func FrontendIndexURL() string {
 	return app.FrontendIndexURL
@@ -149,6 +333,11 @@ func AllStoreConfig() ContentStoreConfig {
 	return store
 }
 
+// AllSourceStoreConfig retrieves the SOURCE_STORE_* configuration. Only used by storage tools.
+func AllSourceStoreConfig() ContentStoreConfig {
+	return sourceStore
+}
+
 func StoreType() string {
 	return store.Type
 }
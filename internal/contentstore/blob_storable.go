@@ -29,3 +29,36 @@ func (blob blobStorable) ProcessPreviewAndUpload(s Store) (ContentKeys, error) {
 
 	return contentKeys, nil
 }
+
+type blobWithPreviewStorable struct {
+	data    io.Reader
+	preview io.Reader
+}
+
+// NewBlobWithPreview returns a Storable for binary/non-binary content where the preview has already
+// been generated by the caller (e.g. a text summary of a larger document)
+func NewBlobWithPreview(data, preview io.Reader) Storable {
+	return blobWithPreviewStorable{
+		data:    data,
+		preview: preview,
+	}
+}
+
+// ProcessPreviewAndUpload uploads the blob as the full/master content, and the provided preview as
+// the Thumbnail/preview/proxy version
+func (blob blobWithPreviewStorable) ProcessPreviewAndUpload(s Store) (ContentKeys, error) {
+	contentKeys := ContentKeys{}
+
+	var err error
+	contentKeys.Full, err = s.Upload(blob.data)
+	if err != nil {
+		return contentKeys, err
+	}
+	contentKeys.Thumbnail, err = s.Upload(blob.preview)
+	if err != nil {
+		s.Delete(contentKeys.Full)
+		return ContentKeys{}, err
+	}
+
+	return contentKeys, nil
+}
@@ -0,0 +1,132 @@
+package contentstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// dedupKeyPrefix marks keys generated by a DedupStore, and distinguishes them from keys generated
+// by the underlying store
+const dedupKeyPrefix = "sha256-"
+
+// DedupStore is a Store decorator that keys content by its hash, so that identical content is only
+// stored once. Each upload of a piece of content adds a reference to it, and each delete removes one.
+// The underlying content is only deleted once no references remain. References are tracked in the
+// content_references table.
+//
+// Content stored prior to enabling deduplication (i.e. without a reference) is passed through to the
+// underlying store unchanged.
+type DedupStore struct {
+	store Store
+	db    *database.Connection
+}
+
+// NewDedupStore wraps the provided store, tracking content references in the provided database
+func NewDedupStore(store Store, db *database.Connection) *DedupStore {
+	return &DedupStore{store: store, db: db}
+}
+
+// Upload stores the provided data, if it has not already been stored, and adds a reference to it.
+// The returned key is derived from the content itself.
+func (d *DedupStore) Upload(data io.Reader) (string, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", errorwrap.WrapError("Unable to read content to upload", err)
+	}
+	sum := sha256.Sum256(content)
+	key := dedupKeyPrefix + hex.EncodeToString(sum[:])
+
+	// The reference is added before the content is uploaded, and outside of any transaction, so that
+	// no locks are held during the (remote) upload. Adding the reference first ensures a concurrent
+	// delete of the last existing reference cannot remove the content out from under this upload:
+	// either the delete completes first (and this upload restores the content), or it sees this
+	// reference and leaves the content in place. Uploading is idempotent, since keys are derived from
+	// the content itself.
+	_, err = d.db.Insert("content_references", map[string]interface{}{
+		"content_key": key,
+		"ref_count":   1,
+	}, "ON DUPLICATE KEY UPDATE ref_count = ref_count + 1")
+	if err != nil {
+		return "", errorwrap.WrapError("Unable to add deduplicated content reference", errorwrap.DatabaseErr(err))
+	}
+
+	if err := d.store.UploadWithName(key, bytes.NewReader(content)); err != nil {
+		// release the reference added above, so that it does not keep orphaned content alive
+		d.Delete(key)
+		return "", errorwrap.WrapError("Unable to upload deduplicated content", err)
+	}
+
+	return key, nil
+}
+
+// UploadWithName passes the content directly to the underlying store, without tracking a reference.
+// As with other stores, this is only intended for development and testing.
+func (d *DedupStore) UploadWithName(key string, data io.Reader) error {
+	return d.store.UploadWithName(key, data)
+}
+
+// Read retrieves content from the underlying store
+func (d *DedupStore) Read(key string) (io.Reader, error) {
+	return d.store.Read(key)
+}
+
+// List retrieves the keys of all content in the underlying store
+func (d *DedupStore) List() ([]string, error) {
+	return d.store.List()
+}
+
+// Delete removes a reference to the content. The content is removed from the underlying store only
+// when the last reference is removed. Content without any references is deleted immediately.
+func (d *DedupStore) Delete(key string) error {
+	err := d.db.WithTx(context.Background(), func(tx *database.Transactable) {
+		refs := lockContentReference(tx, key)
+		if len(refs) > 0 && refs[0].RefCount > 1 {
+			tx.Update(sq.Update("content_references").
+				Set("ref_count", sq.Expr("ref_count - 1")).
+				Where(sq.Eq{"id": refs[0].ID}))
+			return
+		}
+		if len(refs) > 0 {
+			tx.Delete(sq.Delete("content_references").Where(sq.Eq{"id": refs[0].ID}))
+		}
+		if tx.Error() != nil {
+			return
+		}
+		if err := d.store.Delete(key); err != nil {
+			tx.FailTransaction(err)
+		}
+	})
+	if err != nil {
+		return errorwrap.WrapError("Unable to delete deduplicated content", err)
+	}
+	return nil
+}
+
+// Name returns the name of the underlying store
+func (d *DedupStore) Name() string {
+	return d.store.Name()
+}
+
+// Unwrap provides access to the underlying store. Keys are shared between the two stores, so
+// content can be read directly from the underlying store when needed.
+func (d *DedupStore) Unwrap() Store {
+	return d.store
+}
+
+func lockContentReference(tx *database.Transactable, key string) []models.ContentReference {
+	var refs []models.ContentReference
+	tx.Select(&refs, sq.Select("*").
+		From("content_references").
+		Where(sq.Eq{"content_key": key}).
+		Suffix("FOR UPDATE"))
+	return refs
+}
@@ -0,0 +1,100 @@
+package contentstore_test
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/stretchr/testify/require"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func TestDedupStore(t *testing.T) {
+	db := database.NewTestConnection(t, "contentstore-test-db")
+	memStore, _ := contentstore.NewMemStore()
+	store := contentstore.NewDedupStore(memStore, db)
+
+	content := []byte("the same screenshot")
+	keyA, err := store.Upload(bytes.NewReader(content))
+	require.NoError(t, err)
+	keyB, err := store.Upload(bytes.NewReader(content))
+	require.NoError(t, err)
+	require.Equal(t, keyA, keyB, "identical content should share a key")
+
+	keyC, err := store.Upload(bytes.NewReader([]byte("different content")))
+	require.NoError(t, err)
+	require.NotEqual(t, keyA, keyC)
+
+	// verify content survives until the last reference is removed
+	require.NoError(t, store.Delete(keyA))
+	reader, err := store.Read(keyA)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	require.Equal(t, content, data)
+
+	require.NoError(t, store.Delete(keyB))
+	_, err = memStore.Read(keyA)
+	require.Error(t, err, "content should be removed with the last reference")
+
+	// verify re-uploading removed content stores it again
+	keyD, err := store.Upload(bytes.NewReader(content))
+	require.NoError(t, err)
+	require.Equal(t, keyA, keyD)
+	_, err = memStore.Read(keyD)
+	require.NoError(t, err)
+
+	// verify content stored without a reference is deleted directly
+	require.NoError(t, memStore.UploadWithName("legacy", bytes.NewReader([]byte("old"))))
+	require.NoError(t, store.Delete("legacy"))
+	_, err = memStore.Read("legacy")
+	require.Error(t, err)
+}
+
+func TestDedupStoreConcurrentUpload(t *testing.T) {
+	db := database.NewTestConnection(t, "contentstore-test-db")
+	memStore, _ := contentstore.NewMemStore()
+	store := contentstore.NewDedupStore(memStore, db)
+
+	content := []byte("a brand new screenshot")
+	keys := make([]string, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			keys[i], errs[i] = store.Upload(bytes.NewReader(content))
+		}(i)
+	}
+	wg.Wait()
+
+	// verify both uploads succeed, and share a single reference row
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.Equal(t, keys[0], keys[1])
+	var refs []models.ContentReference
+	require.NoError(t, db.Select(&refs, sq.Select("*").From("content_references").Where(sq.Eq{"content_key": keys[0]})))
+	require.Len(t, refs, 1)
+	require.Equal(t, int64(2), refs[0].RefCount)
+
+	reader, err := memStore.Read(keys[0])
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	require.Equal(t, content, data)
+}
+
+func TestAsS3Store(t *testing.T) {
+	memStore, _ := contentstore.NewMemStore()
+	_, ok := contentstore.AsS3Store(memStore)
+	require.False(t, ok)
+
+	s3Store := &contentstore.S3Store{}
+	found, ok := contentstore.AsS3Store(contentstore.NewDedupStore(s3Store, nil))
+	require.True(t, ok)
+	require.Equal(t, s3Store, found)
+}
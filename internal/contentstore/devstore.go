@@ -55,6 +55,21 @@ func (d *DevStore) Read(key string) (io.Reader, error) {
 	return reader, err
 }
 
+// List retrieves the keys of all files in the DevStore directory
+func (d *DevStore) List() ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to list DevStore files", err)
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys, nil
+}
+
 // Delete removes files in in your OS's temp directory
 func (d *DevStore) Delete(key string) error {
 	err := os.Remove(path.Join(d.dir, path.Clean(key)))
@@ -65,5 +80,5 @@ func (d *DevStore) Delete(key string) error {
 }
 
 func (d *DevStore) Name() string {
-	return "local"
+	return "dev"
 }
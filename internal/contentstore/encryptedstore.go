@@ -0,0 +1,280 @@
+package contentstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+)
+
+// encryptedBlobMagic prefixes every blob written by an EncryptedStore. Blobs without this prefix
+// were stored prior to enabling encryption, and are read as-is unless encryption is required.
+const encryptedBlobMagic = "ASHIRTE1"
+
+// EncryptionKeySize is the required size of each encryption key (AES-256)
+const EncryptionKeySize = 32
+
+// EncryptedStore is a Store decorator that encrypts content at rest using AES-GCM envelope encryption.
+// Each blob is encrypted with a freshly generated data key, which is in turn encrypted ("wrapped")
+// with a long-lived key encryption key. The ID of the key encryption key is recorded in the blob
+// header, so that keys can be rotated: new content is always encrypted with the active key, while
+// existing content remains readable so long as its key is still provided.
+//
+// Blob layout:
+//
+//	magic | key id length (1 byte) | key id | wrapped key length (2 bytes) | wrapped key | nonce | ciphertext
+//
+// The header (everything before the nonce) is authenticated along with the content.
+type EncryptedStore struct {
+	store       Store
+	keys        map[string]cipher.AEAD
+	activeKeyID string
+
+	// RequireEncryption causes reads of content stored without encryption to fail, rather than
+	// returning the content as-is. Enable this once all existing content has been re-encrypted.
+	// Reencrypt is unaffected, so that unencrypted content can still be encrypted.
+	RequireEncryption bool
+	// Logger, if set, records every read of content stored without encryption
+	Logger *slog.Logger
+}
+
+// NewEncryptedStore wraps the provided store. keys maps key IDs to 32 byte keys, and activeKeyID
+// names the key used to encrypt new content.
+func NewEncryptedStore(store Store, keys map[string][]byte, activeKeyID string) (*EncryptedStore, error) {
+	s := &EncryptedStore{
+		store:       store,
+		keys:        make(map[string]cipher.AEAD, len(keys)),
+		activeKeyID: activeKeyID,
+	}
+	for keyID, key := range keys {
+		if keyID == "" || len(keyID) > 255 {
+			return nil, fmt.Errorf("encryption key IDs must be between 1 and 255 characters")
+		}
+		if len(key) != EncryptionKeySize {
+			return nil, fmt.Errorf("encryption key %v must be %v bytes", keyID, EncryptionKeySize)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to establish an EncryptedStore", err)
+		}
+		s.keys[keyID] = aead
+	}
+	if _, ok := s.keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("no encryption key provided for active key ID: %v", activeKeyID)
+	}
+	return s, nil
+}
+
+// Upload encrypts the provided data with the active key, and stores it in the underlying store
+func (s *EncryptedStore) Upload(data io.Reader) (string, error) {
+	blob, err := s.encryptReader(data)
+	if err != nil {
+		return "", err
+	}
+	return s.store.Upload(bytes.NewReader(blob))
+}
+
+// UploadWithName encrypts the provided data with the active key, and stores it in the underlying
+// store with the given key
+func (s *EncryptedStore) UploadWithName(key string, data io.Reader) error {
+	blob, err := s.encryptReader(data)
+	if err != nil {
+		return err
+	}
+	return s.store.UploadWithName(key, bytes.NewReader(blob))
+}
+
+// Read retrieves and decrypts the indicated content. Content stored without encryption is returned
+// unchanged, unless RequireEncryption is set.
+func (s *EncryptedStore) Read(key string) (io.Reader, error) {
+	blob, err := s.readRaw(key)
+	if err != nil {
+		return nil, err
+	}
+	content, keyID, err := s.decrypt(blob)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to decrypt content", err)
+	}
+	if keyID == "" {
+		if s.RequireEncryption {
+			return nil, fmt.Errorf("content %v is not encrypted", key)
+		}
+		if s.Logger != nil {
+			s.Logger.Warn("Read unencrypted content", "key", key)
+		}
+	}
+	return bytes.NewReader(content), nil
+}
+
+// List retrieves the keys of all content in the underlying store
+func (s *EncryptedStore) List() ([]string, error) {
+	return s.store.List()
+}
+
+// Delete removes the indicated content from the underlying store
+func (s *EncryptedStore) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+// Name returns the name of the underlying store
+func (s *EncryptedStore) Name() string {
+	return s.store.Name()
+}
+
+// Reencrypt re-encrypts the indicated content with the active key, replacing the stored content.
+// Content that is stored without encryption is encrypted. Returns false (with no error) if the
+// content was already encrypted with the active key.
+//
+// Note that this requires the underlying store to support UploadWithName.
+func (s *EncryptedStore) Reencrypt(key string) (bool, error) {
+	blob, err := s.readRaw(key)
+	if err != nil {
+		return false, err
+	}
+	content, keyID, err := s.decrypt(blob)
+	if err != nil {
+		return false, errorwrap.WrapError("Unable to decrypt content", err)
+	}
+	if keyID == s.activeKeyID {
+		return false, nil
+	}
+
+	blob, err = s.encrypt(content)
+	if err != nil {
+		return false, err
+	}
+	if err := s.store.UploadWithName(key, bytes.NewReader(blob)); err != nil {
+		return false, errorwrap.WrapError("Unable to store re-encrypted content", err)
+	}
+	return true, nil
+}
+
+func (s *EncryptedStore) readRaw(key string) ([]byte, error) {
+	reader, err := s.store.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	blob, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to read encrypted content", err)
+	}
+	return blob, nil
+}
+
+func (s *EncryptedStore) encryptReader(data io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to read content to encrypt", err)
+	}
+	return s.encrypt(content)
+}
+
+func (s *EncryptedStore) encrypt(content []byte) ([]byte, error) {
+	dataKey := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, errorwrap.WrapError("Unable to generate data key", err)
+	}
+	dataAEAD, err := newGCM(dataKey)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to encrypt content", err)
+	}
+
+	// wrap the data key, binding it to the key ID
+	keyAEAD := s.keys[s.activeKeyID]
+	wrappedKey, err := seal(keyAEAD, dataKey, []byte(s.activeKeyID))
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to wrap data key", err)
+	}
+
+	header := make([]byte, 0, len(encryptedBlobMagic)+1+len(s.activeKeyID)+2+len(wrappedKey))
+	header = append(header, encryptedBlobMagic...)
+	header = append(header, byte(len(s.activeKeyID)))
+	header = append(header, s.activeKeyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrappedKey)))
+	header = append(header, wrappedKey...)
+
+	sealed, err := seal(dataAEAD, content, header)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to encrypt content", err)
+	}
+	return append(header, sealed...), nil
+}
+
+// decrypt returns the plaintext content of the blob, along with the ID of the key used to encrypt
+// it. Blobs that are not encrypted are returned as-is, with an empty key ID.
+func (s *EncryptedStore) decrypt(blob []byte) ([]byte, string, error) {
+	if !bytes.HasPrefix(blob, []byte(encryptedBlobMagic)) {
+		return blob, "", nil
+	}
+	errMalformed := fmt.Errorf("malformed encrypted content")
+
+	rest := blob[len(encryptedBlobMagic):]
+	if len(rest) < 1 {
+		return nil, "", errMalformed
+	}
+	keyIDLen := int(rest[0])
+	rest = rest[1:]
+	if len(rest) < keyIDLen+2 {
+		return nil, "", errMalformed
+	}
+	keyID := string(rest[:keyIDLen])
+	rest = rest[keyIDLen:]
+	wrappedKeyLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < wrappedKeyLen {
+		return nil, "", errMalformed
+	}
+	wrappedKey := rest[:wrappedKeyLen]
+	header := blob[:len(blob)-len(rest)+wrappedKeyLen]
+	rest = rest[wrappedKeyLen:]
+
+	keyAEAD, ok := s.keys[keyID]
+	if !ok {
+		return nil, keyID, fmt.Errorf("no encryption key provided for key ID: %v", keyID)
+	}
+	dataKey, err := open(keyAEAD, wrappedKey, []byte(keyID))
+	if err != nil {
+		return nil, keyID, err
+	}
+	dataAEAD, err := newGCM(dataKey)
+	if err != nil {
+		return nil, keyID, err
+	}
+	content, err := open(dataAEAD, rest, header)
+	return content, keyID, err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext, returning the nonce followed by the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted content")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
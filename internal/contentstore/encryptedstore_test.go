@@ -0,0 +1,96 @@
+package contentstore_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	memStore, _ := contentstore.NewMemStore()
+	oldKey := bytes.Repeat([]byte{1}, contentstore.EncryptionKeySize)
+	newKey := bytes.Repeat([]byte{2}, contentstore.EncryptionKeySize)
+
+	store, err := contentstore.NewEncryptedStore(memStore, map[string][]byte{"old": oldKey}, "old")
+	require.NoError(t, err)
+
+	content := []byte("Very secret stuff")
+	key, err := store.Upload(bytes.NewReader(content))
+	require.NoError(t, err)
+
+	// verify content is encrypted at rest
+	raw, err := memStore.Read(key)
+	require.NoError(t, err)
+	rawBytes, _ := io.ReadAll(raw)
+	require.NotContains(t, string(rawBytes), string(content))
+
+	reader, err := store.Read(key)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	require.Equal(t, content, data)
+
+	// verify unencrypted content remains readable, and its reads are logged
+	var logs bytes.Buffer
+	store.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	require.NoError(t, memStore.UploadWithName("legacy", bytes.NewReader([]byte("plain"))))
+	reader, err = store.Read("legacy")
+	require.NoError(t, err)
+	data, _ = io.ReadAll(reader)
+	require.Equal(t, []byte("plain"), data)
+	require.Contains(t, logs.String(), "key=legacy")
+
+	// verify unencrypted content is rejected when encryption is required
+	store.RequireEncryption = true
+	_, err = store.Read("legacy")
+	require.Error(t, err)
+	_, err = store.Read(key)
+	require.NoError(t, err)
+	store.RequireEncryption = false
+
+	// verify tampered content is rejected
+	tampered := append([]byte{}, rawBytes...)
+	tampered[len(tampered)-1] ^= 0xff
+	require.NoError(t, memStore.UploadWithName("tampered", bytes.NewReader(tampered)))
+	_, err = store.Read("tampered")
+	require.Error(t, err)
+	require.NoError(t, memStore.Delete("tampered"))
+
+	// verify rotation
+	rotated, err := contentstore.NewEncryptedStore(memStore, map[string][]byte{"old": oldKey, "new": newKey}, "new")
+	require.NoError(t, err)
+	for _, k := range []string{key, "legacy"} {
+		changed, err := rotated.Reencrypt(k)
+		require.NoError(t, err)
+		require.True(t, changed)
+		changed, err = rotated.Reencrypt(k)
+		require.NoError(t, err)
+		require.False(t, changed, "content already using the active key should be skipped")
+	}
+
+	newOnly, err := contentstore.NewEncryptedStore(memStore, map[string][]byte{"new": newKey}, "new")
+	require.NoError(t, err)
+	reader, err = newOnly.Read(key)
+	require.NoError(t, err)
+	data, _ = io.ReadAll(reader)
+	require.Equal(t, content, data)
+
+	// verify the old key alone can no longer read rotated content
+	_, err = store.Read(key)
+	require.Error(t, err)
+}
+
+func TestNewEncryptedStoreValidatesKeys(t *testing.T) {
+	memStore, _ := contentstore.NewMemStore()
+	key := bytes.Repeat([]byte{1}, contentstore.EncryptionKeySize)
+
+	_, err := contentstore.NewEncryptedStore(memStore, map[string][]byte{"a": key}, "b")
+	require.Error(t, err)
+	_, err = contentstore.NewEncryptedStore(memStore, map[string][]byte{"a": key[:16]}, "a")
+	require.Error(t, err)
+	_, err = contentstore.NewEncryptedStore(memStore, map[string][]byte{"": key}, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package contentstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/google/uuid"
+)
+
+// DefaultFileStoreFileMode is the permission mode applied to files written by a FileStore when no
+// other mode is provided
+const DefaultFileStoreFileMode os.FileMode = 0640
+
+// FileStore is a durable content store backed by a local (or mounted) filesystem. Files are spread
+// across sharded subdirectories to avoid large flat directories, and every write is performed
+// atomically: content is written to a temporary file, synced to disk, and then renamed into place.
+type FileStore struct {
+	root     string
+	fileMode os.FileMode
+	dirMode  os.FileMode
+}
+
+// NewFileStore constructs a FileStore rooted at the provided directory, creating it if necessary.
+// Files are written with the provided permission mode. Directories receive the same permissions,
+// plus the execute bit wherever read access is granted.
+func NewFileStore(root string, fileMode os.FileMode) (*FileStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("unable to establish a FileStore: no root directory provided")
+	}
+	if fileMode == 0 {
+		fileMode = DefaultFileStoreFileMode
+	}
+	fileMode = fileMode.Perm() | 0600 // the server must always be able to read and replace its own files
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to establish a FileStore", err)
+	}
+
+	s := &FileStore{
+		root:     root,
+		fileMode: fileMode,
+		dirMode:  fileMode | (fileMode&0444)>>2,
+	}
+	if err := os.MkdirAll(root, s.dirMode); err != nil {
+		return nil, errorwrap.WrapError("Unable to establish a FileStore", err)
+	}
+	return s, nil
+}
+
+// Upload stores the provided data under a newly generated key
+func (s *FileStore) Upload(data io.Reader) (string, error) {
+	key := uuid.New().String()
+
+	err := s.UploadWithName(key, data)
+
+	return key, err
+}
+
+// UploadWithName stores the provided data under the given key, replacing any existing content.
+// Readers will observe either the previous content or the new content, never a partial write.
+func (s *FileStore) UploadWithName(key string, data io.Reader) error {
+	dst, err := s.keyPath(key)
+	if err != nil {
+		return errorwrap.WrapError("Unable to upload to FileStore", err)
+	}
+	dir := filepath.Dir(dst)
+	if err := os.MkdirAll(dir, s.dirMode); err != nil {
+		return errorwrap.WrapError("Unable to create FileStore directory", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+key+".tmp-*")
+	if err != nil {
+		return errorwrap.WrapError("Unable to upload to FileStore", err)
+	}
+	tmpName := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		return errorwrap.WrapError("Unable to upload to FileStore", err)
+	}
+	if err := tmp.Chmod(s.fileMode); err != nil {
+		return errorwrap.WrapError("Unable to set FileStore file permissions", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return errorwrap.WrapError("Unable to sync FileStore file", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errorwrap.WrapError("Unable to upload to FileStore", err)
+	}
+	if err := os.Rename(tmpName, dst); err != nil {
+		return errorwrap.WrapError("Unable to upload to FileStore", err)
+	}
+	committed = true
+
+	// sync the directory so the rename itself survives a crash
+	if err := syncDir(dir); err != nil {
+		return errorwrap.WrapError("Unable to sync FileStore directory", err)
+	}
+	return nil
+}
+
+// Read retrieves the content stored under the given key. The returned reader is an *os.File, and
+// should be closed by the caller.
+func (s *FileStore) Read(key string) (io.Reader, error) {
+	p, err := s.keyPath(key)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to read file from FileStore", err)
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to read file from FileStore", err)
+	}
+	return file, nil
+}
+
+// List retrieves the keys of all content in the FileStore. Temporary files from in-progress (or
+// interrupted) uploads are not included.
+func (s *FileStore) List() ([]string, error) {
+	keys := []string{}
+	err := filepath.WalkDir(s.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			keys = append(keys, entry.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to list FileStore files", err)
+	}
+	return keys, nil
+}
+
+// Delete removes the content stored under the given key
+func (s *FileStore) Delete(key string) error {
+	p, err := s.keyPath(key)
+	if err != nil {
+		return errorwrap.WrapError("Unable to delete file from FileStore", err)
+	}
+	if err := os.Remove(p); err != nil {
+		return errorwrap.WrapError("Unable to delete file from FileStore", err)
+	}
+	if err := syncDir(filepath.Dir(p)); err != nil {
+		return errorwrap.WrapError("Unable to sync FileStore directory", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Name() string {
+	return "file"
+}
+
+// keyPath determines where the content for a key is stored. Keys are sharded into two levels of
+// subdirectories, based on a hash of the key, so that arbitrary keys (not just uuids) spread evenly.
+func (s *FileStore) keyPath(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) || strings.ContainsRune(key, 0) {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	shard := hex.EncodeToString(sum[:2])
+	return filepath.Join(s.root, shard[:2], shard[2:], key), nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,111 @@
+package contentstore_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllAndClose(t *testing.T, reader io.Reader) []byte {
+	b, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	if closer, ok := reader.(io.Closer); ok {
+		require.NoError(t, closer.Close())
+	}
+	return b
+}
+
+func TestFileStore(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "content")
+	store, err := contentstore.NewFileStore(root, 0640)
+	require.NoError(t, err)
+
+	content := []byte("Very innocent stuff")
+	key, err := store.Upload(bytes.NewReader(content))
+	require.NoError(t, err)
+	require.NotEqual(t, "", key, "Key should be populated in response")
+
+	reader, err := store.Read(key)
+	require.NoError(t, err)
+	require.Equal(t, content, readAllAndClose(t, reader), "retrieved content should match uploaded content")
+
+	// verify the file is sharded, has the expected permissions, and no temp files remain
+	var files []string
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, p)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	rel, _ := filepath.Rel(root, files[0])
+	require.Len(t, strings.Split(rel, string(filepath.Separator)), 3, "files should be stored in shard directories")
+	require.Equal(t, key, filepath.Base(rel))
+
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	dirInfo, err := os.Stat(filepath.Dir(files[0]))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0750), dirInfo.Mode().Perm())
+
+	keys, err := store.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{key}, keys)
+
+	// verify content can be replaced by name
+	require.NoError(t, store.UploadWithName(key, bytes.NewReader([]byte("replaced"))))
+	reader, err = store.Read(key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("replaced"), readAllAndClose(t, reader))
+
+	require.NoError(t, store.Delete(key))
+	_, err = store.Read(key)
+	require.Error(t, err)
+	require.Error(t, store.Delete(key))
+}
+
+func TestFileStoreRejectsUnsafeKeys(t *testing.T) {
+	store, err := contentstore.NewFileStore(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "..", "../escape", "a/b", `a\b`, ".hidden"} {
+		require.Error(t, store.UploadWithName(key, bytes.NewReader([]byte("x"))), "key: %q", key)
+		_, err := store.Read(key)
+		require.Error(t, err, "key: %q", key)
+		require.Error(t, store.Delete(key), "key: %q", key)
+	}
+}
+
+func TestFileStoreFailedUploadLeavesNoContent(t *testing.T) {
+	root := t.TempDir()
+	store, err := contentstore.NewFileStore(root, 0600)
+	require.NoError(t, err)
+
+	err = store.UploadWithName("broken", io.MultiReader(bytes.NewReader([]byte("partial")), failingReader{}))
+	require.Error(t, err)
+
+	_, err = store.Read("broken")
+	require.Error(t, err)
+
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("unexpected file left behind: %v", p)
+		}
+		return err
+	})
+	require.NoError(t, err)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
@@ -7,6 +7,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
 )
 
 type GCPStore struct {
@@ -67,6 +68,23 @@ func (s *GCPStore) Read(key string) (io.Reader, error) {
 	return res, nil
 }
 
+// List retrieves the keys of all files in the Google Cloud bucket
+func (s *GCPStore) List() ([]string, error) {
+	keys := []string{}
+	it := s.bucketAccess.Objects(context.Background(), nil)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to list gcp objects", err)
+		}
+		keys = append(keys, attrs.Name)
+	}
+	return keys, nil
+}
+
 // Delete removes the indicated file from GCP
 func (s *GCPStore) Delete(key string) error {
 	ctx := context.Background()
@@ -0,0 +1,50 @@
+package contentstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// HashingReader wraps a reader, computing a SHA-256 digest of all of the data read through it. This
+// allows content to be hashed while it is being uploaded, without buffering it a second time.
+type HashingReader struct {
+	reader io.Reader
+	digest hash.Hash
+	size   int64
+}
+
+// NewHashingReader returns a HashingReader that reads from the provided reader
+func NewHashingReader(data io.Reader) *HashingReader {
+	digest := sha256.New()
+	return &HashingReader{
+		reader: io.TeeReader(data, digest),
+		digest: digest,
+	}
+}
+
+func (h *HashingReader) Read(p []byte) (int, error) {
+	n, err := h.reader.Read(p)
+	h.size += int64(n)
+	return n, err
+}
+
+// Size returns the number of bytes read so far
+func (h *HashingReader) Size() int64 {
+	return h.size
+}
+
+// Sum returns the hex-encoded SHA-256 digest of the data read so far
+func (h *HashingReader) Sum() string {
+	return hex.EncodeToString(h.digest.Sum(nil))
+}
+
+// HashContent reads the provided content to completion, returning the hex-encoded SHA-256 digest
+func HashContent(data io.Reader) (string, error) {
+	digest := sha256.New()
+	if _, err := io.Copy(digest, data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
@@ -0,0 +1,25 @@
+package contentstore_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashingReader(t *testing.T) {
+	content := []byte("Very innocent stuff")
+	expected := "d43cf40cdbbaf2baea769441657cd924df23e3442eec31808b28c91e26846654"
+
+	hashed, err := contentstore.HashContent(bytes.NewReader(content))
+	require.NoError(t, err)
+	require.Equal(t, expected, hashed)
+
+	reader := contentstore.NewHashingReader(bytes.NewReader(content))
+	b, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, content, b, "data should pass through unaltered")
+	require.Equal(t, hashed, reader.Sum())
+}
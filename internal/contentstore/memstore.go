@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
@@ -65,14 +66,26 @@ func (d *MemStore) Read(key string) (io.Reader, error) {
 	return bytes.NewReader(data), nil
 }
 
+// List retrieves all of the keys currently in memory, in sorted order
+func (d *MemStore) List() ([]string, error) {
+	d.mutex.Lock()
+	keys := make([]string, 0, len(d.content))
+	for key := range d.content {
+		keys = append(keys, key)
+	}
+	d.mutex.Unlock()
+	sort.Strings(keys)
+	return keys, nil
+}
+
 // Delete removes files in in your OS's temp directory
 func (d *MemStore) Delete(key string) error {
 	d.mutex.Lock()
+	defer d.mutex.Unlock()
 	if _, ok := d.content[key]; !ok { // artificial behavior to match other stores
 		return errorwrap.WrapError("Unable to delete from MemStore", fmt.Errorf("No such key"))
 	}
 	delete(d.content, key)
-	d.mutex.Unlock()
 	return nil
 }
 
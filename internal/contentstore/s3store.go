@@ -65,6 +65,24 @@ func (s *S3Store) UploadWithName(key string, data io.Reader) error {
 	return nil
 }
 
+// List retrieves the keys of all files in the Amazon S3 bucket
+func (s *S3Store) List() ([]string, error) {
+	keys := []string{}
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to list s3 objects", err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
 // Read retrieves the indicated file from Amazon S3
 func (s *S3Store) Read(key string) (io.Reader, error) {
 	res, err := s.s3Client.GetObject(context.Background(), &s3.GetObjectInput{
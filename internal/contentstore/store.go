@@ -12,11 +12,13 @@ import (
 // Note that UploadWithName is only intended for development and testing. This should not be used
 // directly.
 //
-// Read retrieves the raw bytes from the storage service, given a key obtained by Upload
+// Read retrieves the raw bytes from the storage service, given a key obtained by Upload. List
+// retrieves the keys of all content in the storage service.
 type Store interface {
 	Upload(data io.Reader) (string, error)
 	UploadWithName(key string, data io.Reader) error
 	Read(key string) (io.Reader, error)
+	List() ([]string, error)
 	Delete(key string) error
 	Name() string
 }
@@ -26,3 +28,18 @@ type ContentKeys struct {
 	Full      string
 	Thumbnail string
 }
+
+// AsS3Store retrieves the S3Store backing the provided store, if any. Decorators that store content
+// unchanged in an underlying store (i.e. that provide an Unwrap method) are looked through.
+func AsS3Store(s Store) (*S3Store, bool) {
+	for {
+		switch store := s.(type) {
+		case *S3Store:
+			return store, true
+		case interface{ Unwrap() Store }:
+			s = store.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
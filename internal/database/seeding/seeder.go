@@ -7,9 +7,11 @@ import (
 
 	localConsts "github.com/ashirt-ops/ashirt-server/internal/authschemes/localauth/constants"
 	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -225,6 +227,8 @@ func (seed Seeder) ApplyTo(db *database.Connection) error {
 				"updated_at":      seed.Findings[i].UpdatedAt,
 			}
 		})
+		search.IndexEvidence(tx, helpers.Map(seed.Evidences, func(e models.Evidence) int64 { return e.ID }))
+		search.IndexFindings(tx, helpers.Map(seed.Findings, func(f models.Finding) int64 { return f.ID }))
 		tx.BatchInsert("evidence_finding_map", len(seed.EviFindingsMap), func(i int) map[string]interface{} {
 			return map[string]interface{}{
 				"evidence_id": seed.EviFindingsMap[i].EvidenceID,
@@ -84,7 +84,9 @@ func ClearDB(db *database.Connection) error {
 		tx.Delete(sq.Delete("user_operation_permissions"))
 		tx.Delete(sq.Delete("user_group_operation_permissions"))
 		tx.Delete(sq.Delete("user_operation_preferences"))
+		tx.Delete(sq.Delete("api_key_operations"))
 		tx.Delete(sq.Delete("api_keys"))
+		tx.Delete(sq.Delete("api_nonces"))
 		tx.Delete(sq.Delete("auth_scheme_data"))
 		tx.Delete(sq.Delete("email_queue"))
 		tx.Delete(sq.Delete("tag_evidence_map"))
@@ -92,6 +94,8 @@ func ClearDB(db *database.Connection) error {
 		tx.Delete(sq.Delete("default_tags"))
 		tx.Delete(sq.Delete("evidence_finding_map"))
 		tx.Delete(sq.Delete("evidence_metadata"))
+		tx.Delete(sq.Delete("har_entries"))
+		tx.Delete(sq.Delete("search_index"))
 		tx.Delete(sq.Delete("evidence"))
 		tx.Delete(sq.Delete("findings"))
 		tx.Delete(sq.Delete("finding_categories"))
@@ -101,9 +105,18 @@ func ClearDB(db *database.Connection) error {
 		tx.Delete(sq.Delete("queries"))
 		tx.Delete(sq.Delete("var_operation_map"))
 		tx.Delete(sq.Delete("operations"))
+		tx.Delete(sq.Delete("service_worker_callbacks"))
+		tx.Delete(sq.Delete("service_worker_jobs"))
 		tx.Delete(sq.Delete("service_workers"))
 		tx.Delete(sq.Delete("global_vars"))
 		tx.Delete(sq.Delete("operation_vars"))
+		tx.Delete(sq.Delete("audit_events"))
+		tx.Delete(sq.Delete("content_references"))
+		tx.Delete(sq.Delete("content_issues"))
+		tx.Delete(sq.Delete("webhook_deliveries"))
+		tx.Delete(sq.Delete("webhooks"))
+		tx.Delete(sq.Delete("query_subscriptions"))
+		tx.Delete(sq.Delete("query_digest_schedules"))
 	})
 	return err
 }
@@ -1,16 +1,21 @@
 package dtos
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
 )
 
+// APIKey describes an API key. OperationSlugs is nil for keys that may access every operation.
 type APIKey struct {
-	AccessKey string     `json:"accessKey"`
-	SecretKey []byte     `json:"secretKey"`
-	LastAuth  *time.Time `json:"lastAuth"`
+	AccessKey      string     `json:"accessKey"`
+	SecretKey      []byte     `json:"secretKey"`
+	LastAuth       *time.Time `json:"lastAuth"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	OperationSlugs []string   `json:"operationSlugs"`
+	Scope          string     `json:"scope"`
 }
 
 type Evidence struct {
@@ -73,6 +78,13 @@ type Operation struct {
 	UserCanExportData bool          `json:"userCanExportData,omitempty"`
 }
 
+// OperationStorage describes the storage used by an operation's evidence content, in bytes. A nil
+// Quota means the operation's storage is unlimited.
+type OperationStorage struct {
+	Used  int64  `json:"used"`
+	Quota *int64 `json:"quota"`
+}
+
 type Query struct {
 	ID    int64  `json:"id"`
 	Name  string `json:"name"`
@@ -244,3 +256,155 @@ type OperationVar struct {
 	Name          string `json:"name"`
 	Value         string `json:"value"`
 }
+
+type AuditEvent struct {
+	ID            int64           `json:"id"`
+	Actor         *User           `json:"actor"`
+	Action        string          `json:"action"`
+	TargetType    string          `json:"targetType"`
+	Target        string          `json:"target"`
+	OperationSlug *string         `json:"operationSlug"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	RequestID     *string         `json:"requestId"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+type EvidenceVerification struct {
+	UUID         string  `json:"uuid"`
+	Status       string  `json:"status"`
+	ExpectedHash *string `json:"expectedHash"`
+	ActualHash   *string `json:"actualHash"`
+}
+
+type OperationVerificationReport struct {
+	OperationSlug string                 `json:"operationSlug"`
+	VerifiedAt    time.Time              `json:"verifiedAt"`
+	TotalCount    int64                  `json:"totalCount"`
+	VerifiedCount int64                  `json:"verifiedCount"`
+	UnhashedCount int64                  `json:"unhashedCount"`
+	Problems      []EvidenceVerification `json:"problems"`
+}
+
+type ContentIssue struct {
+	ContentKey     string    `json:"contentKey"`
+	EvidenceUUID   *string   `json:"evidenceUuid"`
+	OperationSlug  *string   `json:"operationSlug"`
+	FirstSeenAt    time.Time `json:"firstSeenAt"`
+	LastSeenAt     time.Time `json:"lastSeenAt"`
+	DeleteAttempts int64     `json:"deleteAttempts"`
+	LastError      *string   `json:"lastError"`
+}
+
+type ContentIssueReport struct {
+	Orphans  []ContentIssue `json:"orphans"`
+	Dangling []ContentIssue `json:"dangling"`
+}
+
+type Webhook struct {
+	ID                  int64      `json:"id"`
+	OperationSlug       *string    `json:"operationSlug"`
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	AccessKey           string     `json:"accessKey"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int64      `json:"consecutiveFailures"`
+	DisabledAt          *time.Time `json:"disabledAt"`
+	CreatedAt           time.Time  `json:"createdAt"`
+}
+
+// CreatedWebhook includes the webhook's secret key, which is only available when the webhook is created
+type CreatedWebhook struct {
+	Webhook
+	SecretKey []byte `json:"secretKey"`
+}
+
+type WebhookDelivery struct {
+	UUID        string     `json:"uuid"`
+	EventType   string     `json:"eventType"`
+	Status      string     `json:"status"`
+	Attempts    int64      `json:"attempts"`
+	StatusCode  *int64     `json:"statusCode"`
+	LastError   *string    `json:"lastError"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DeliveredAt *time.Time `json:"deliveredAt"`
+}
+
+// SearchResult is a single document (evidence or finding) matched by a search, along with excerpts
+// of the matching fields. For evidence, Title is the evidence description and OccurredAt is when the
+// evidence occurred; for findings, OccurredAt is when the finding was created.
+type SearchResult struct {
+	Type        string          `json:"type"`
+	UUID        string          `json:"uuid"`
+	Title       string          `json:"title"`
+	ContentType string          `json:"contentType"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	Score       float64         `json:"score"`
+	Snippets    []SearchSnippet `json:"snippets"`
+}
+
+// OperationSearchResults groups the results of a search across operations. NumEvidence and NumFindings
+// count every match within the operation, including those not on the current page.
+type OperationSearchResults struct {
+	OperationSlug string          `json:"operationSlug"`
+	OperationName string          `json:"operationName"`
+	NumEvidence   int64           `json:"numEvidence"`
+	NumFindings   int64           `json:"numFindings"`
+	Evidence      []*SearchResult `json:"evidence"`
+	Findings      []*SearchResult `json:"findings"`
+}
+
+type SearchSnippet struct {
+	Field     string           `json:"field"`
+	Fragments []SearchFragment `json:"fragments"`
+}
+
+type SearchFragment struct {
+	Text      string `json:"text"`
+	Highlight bool   `json:"highlight"`
+}
+
+type QuerySubscription struct {
+	QueryID       int64     `json:"queryId"`
+	QueryName     string    `json:"queryName"`
+	Query         string    `json:"query"`
+	Type          string    `json:"type"`
+	OperationSlug string    `json:"operationSlug"`
+	OperationName string    `json:"operationName"`
+	LastRunAt     time.Time `json:"lastRunAt"`
+}
+
+// QueryDigestSchedule describes when a user's saved query digest is sent. SendHour is the hour of the
+// day (in UTC), and SendWeekday the day of the week (0 is Sunday) used by weekly digests.
+type QueryDigestSchedule struct {
+	Frequency   string     `json:"frequency"`
+	SendHour    int64      `json:"sendHour"`
+	SendWeekday int64      `json:"sendWeekday"`
+	LastSentAt  *time.Time `json:"lastSentAt"`
+	NextSendAt  time.Time  `json:"nextSendAt"`
+}
+
+// QueryDigest is the content of a saved query digest email, stored alongside the queued email
+type QueryDigest struct {
+	Sections []QueryDigestSection `json:"sections"`
+}
+
+// QueryDigestSection lists the new matches for one subscribed query. Total counts every new match,
+// which may be more than the number of Items included in the email.
+type QueryDigestSection struct {
+	OperationSlug string            `json:"operationSlug"`
+	OperationName string            `json:"operationName"`
+	QueryName     string            `json:"queryName"`
+	Query         string            `json:"query"`
+	Type          string            `json:"type"`
+	Since         time.Time         `json:"since"`
+	Total         int64             `json:"total"`
+	Items         []QueryDigestItem `json:"items"`
+}
+
+type QueryDigestItem struct {
+	UUID       string    `json:"uuid"`
+	Title      string    `json:"title"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
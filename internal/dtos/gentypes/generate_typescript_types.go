@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -18,6 +19,7 @@ func main() {
 	gen(dtos.TopContrib{})
 	gen(dtos.EvidenceCount{})
 	gen(dtos.Operation{})
+	gen(dtos.OperationStorage{})
 	gen(dtos.Query{})
 	gen(dtos.Tag{})
 	gen(dtos.DefaultTag{})
@@ -45,6 +47,20 @@ func main() {
 	gen(dtos.UserGroupOperationRole{})
 	gen(dtos.GlobalVar{})
 	gen(dtos.OperationVar{})
+	gen(dtos.AuditEvent{})
+	gen(dtos.EvidenceVerification{})
+	gen(dtos.OperationVerificationReport{})
+	gen(dtos.ContentIssue{})
+	gen(dtos.ContentIssueReport{})
+	gen(dtos.Webhook{})
+	gen(dtos.CreatedWebhook{})
+	gen(dtos.WebhookDelivery{})
+	gen(dtos.SearchResult{})
+	gen(dtos.OperationSearchResults{})
+	gen(dtos.SearchSnippet{})
+	gen(dtos.SearchFragment{})
+	gen(dtos.QuerySubscription{})
+	gen(dtos.QueryDigestSchedule{})
 
 	// Since this file only contains typescript types, webpack doesn't pick up the
 	// changes unless there is some actual executable javascript referenced from
@@ -92,6 +108,9 @@ func toTypescriptType(t reflect.Type) string {
 	case reflect.String:
 		return "string"
 	case reflect.Slice:
+		if t == reflect.TypeOf(json.RawMessage{}) {
+			return "unknown"
+		}
 		if t.Elem().Kind() == reflect.Uint8 {
 			// []byte is serialized as base64 encoded string
 			return "string /* base64 encoded */"
@@ -2,12 +2,16 @@ package emailtemplates
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"net/url"
 	"text/template"
+	"time"
 
 	recoveryHelpers "github.com/ashirt-ops/ashirt-server/internal/authschemes/recoveryauth/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/config"
 	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/jaytaylor/html2text"
 )
@@ -22,11 +26,22 @@ const (
 	// EmailRecoveryDeniedTemplate contains a message indicating that a user CANNOT recover their
 	// account because it's disabled
 	EmailRecoveryDeniedTemplate EmailTemplate = "self-service-recovery-denied-email"
+
+	// EmailQueryDigestTemplate contains the new evidence and findings matching a user's subscribed
+	// saved queries. The queued email's template data holds a dtos.QueryDigest
+	EmailQueryDigestTemplate EmailTemplate = "query-digest-email"
 )
 
 type EmailTemplateData struct {
 	UserRecord *models.User
 	DB         *database.Connection
+	// Payload holds the template data stored alongside the queued email, if any
+	Payload []byte
+}
+
+type queryDigestTemplateData struct {
+	EmailTemplateData
+	Digest dtos.QueryDigest
 }
 
 var templateFuncs = template.New("base").Funcs(template.FuncMap{
@@ -42,6 +57,17 @@ var templateFuncs = template.New("base").Funcs(template.FuncMap{
 		}
 		return ""
 	},
+	"QueryURL": func(section dtos.QueryDigestSection) string {
+		return config.FrontendIndexURL() + "/operations/" + url.PathEscape(section.OperationSlug) + "/" + section.Type +
+			"?q=" + url.QueryEscape(section.Query) + "&name=" + url.QueryEscape(section.QueryName)
+	},
+	"ItemURL": func(section dtos.QueryDigestSection, item dtos.QueryDigestItem) string {
+		return config.FrontendIndexURL() + "/operations/" + url.PathEscape(section.OperationSlug) + "/" + section.Type +
+			"/" + url.PathEscape(item.UUID)
+	},
+	"FormatTime": func(t time.Time) string {
+		return t.UTC().Format("Jan 2, 2006 15:04 MST")
+	},
 })
 
 type EmailContent struct {
@@ -64,6 +90,15 @@ func BuildEmailContent(emailTemplate EmailTemplate, templateData EmailTemplateDa
 	case EmailRecoveryDeniedTemplate:
 		err = recoveryDeniedDisabledEmail.Execute(w, templateData)
 		rtn.Subject = "Recover your AShirt account"
+	case EmailQueryDigestTemplate:
+		digestData := queryDigestTemplateData{EmailTemplateData: templateData}
+		if len(templateData.Payload) > 0 {
+			err = json.Unmarshal(templateData.Payload, &digestData.Digest)
+		}
+		if err == nil {
+			err = queryDigestEmail.Execute(w, digestData)
+		}
+		rtn.Subject = "New results for your saved AShirt queries"
 	default:
 		err = errors.New("unsupported email template")
 	}
@@ -1,10 +1,13 @@
 package emailtemplates_test
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/database/seeding"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/emailtemplates"
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +18,7 @@ func TestBuildEmailContent(t *testing.T) {
 	allTemplates := []emailtemplates.EmailTemplate{
 		emailtemplates.EmailRecoveryTemplate,
 		emailtemplates.EmailRecoveryDeniedTemplate,
+		emailtemplates.EmailQueryDigestTemplate,
 	}
 
 	for _, tmpl := range allTemplates {
@@ -29,6 +33,38 @@ func TestBuildEmailContent(t *testing.T) {
 	}
 }
 
+func TestBuildQueryDigestEmail(t *testing.T) {
+	payload, err := json.Marshal(dtos.QueryDigest{Sections: []dtos.QueryDigestSection{{
+		OperationSlug: "chamberofsecrets",
+		OperationName: "Chamber of Secrets",
+		QueryName:     "Spiders & Snakes",
+		Query:         "spider OR snake",
+		Type:          "evidence",
+		Since:         time.Date(2024, 3, 14, 8, 0, 0, 0, time.UTC),
+		Total:         1,
+		Items: []dtos.QueryDigestItem{
+			{UUID: "seen-a-spider", Title: "<b>Aragog</b>", OccurredAt: time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC)},
+		},
+	}}})
+	require.NoError(t, err)
+
+	emailContent, err := emailtemplates.BuildEmailContent(emailtemplates.EmailQueryDigestTemplate, emailtemplates.EmailTemplateData{
+		UserRecord: &seeding.UserHarry,
+		Payload:    payload,
+	})
+	require.NoError(t, err)
+	require.Contains(t, emailContent.HTMLContent, "Chamber of Secrets: Spiders &amp; Snakes")
+	require.Contains(t, emailContent.HTMLContent, "&lt;b&gt;Aragog&lt;/b&gt;")
+	require.Contains(t, emailContent.HTMLContent, "/operations/chamberofsecrets/evidence/seen-a-spider")
+	require.Contains(t, emailContent.HTMLContent, "/operations/chamberofsecrets/evidence?q=spider+OR+snake&amp;name=Spiders+%26+Snakes")
+
+	_, err = emailtemplates.BuildEmailContent(emailtemplates.EmailQueryDigestTemplate, emailtemplates.EmailTemplateData{
+		UserRecord: &seeding.UserHarry,
+		Payload:    []byte("not json"),
+	})
+	require.Error(t, err)
+}
+
 func setupDb(t *testing.T) *database.Connection {
 	db := seeding.InitTestWithName(t, "emailtemplates-test-db")
 	seeding.ApplySeeding(t, seeding.HarryPotterSeedData, db)
@@ -0,0 +1,13 @@
+package emailtemplates
+
+import (
+	_ "embed"
+	"text/template"
+)
+
+//go:embed query_digest.html
+var queryDigestTemplate string
+
+var queryDigestEmail = template.Must(templateFuncs.New("queryDigestEmail").Parse(
+	queryDigestTemplate,
+))
@@ -0,0 +1,146 @@
+package enhancementservices
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	callbackAccessKeyLength = 18
+	callbackSecretKeyLength = 64
+)
+
+// ErrCallbackUnavailable is returned when a callback does not exist, has expired, or has already
+// been used
+var ErrCallbackUnavailable = errors.New("callback is not available")
+
+// CallbackConfig determines whether, and how, deferred evidence workers are offered a callback to
+// deliver their results
+type CallbackConfig struct {
+	// BaseURL is the externally reachable URL of the API (e.g. https://ashirt.example.com/api). No
+	// callbacks are offered when this is empty
+	BaseURL string
+	// Expiry is how long a callback remains usable after it is issued
+	Expiry time.Duration
+}
+
+// WorkerCallback provides the details a worker needs to deliver a deferred result. Requests to the
+// URL must be signed with the access and secret keys, in the same manner as API requests.
+type WorkerCallback struct {
+	URL       string    `json:"url"`
+	AccessKey string    `json:"accessKey"`
+	SecretKey string    `json:"secretKey"` // base64 encoded
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CallbackPath returns the API path (relative to the API root) that receives results for the
+// callback with the given access key
+func CallbackPath(accessKey string) string {
+	return "/service-workers/callbacks/" + accessKey
+}
+
+// createWorkerCallback issues a new callback for the given evidence and worker. Any earlier callbacks
+// for the same evidence and worker that have not been used are revoked, so that only the result of
+// the most recent run can be recorded.
+func createWorkerCallback(db *database.Connection, cfg CallbackConfig, evidenceID int64, workerName string) (*WorkerCallback, error) {
+	accessKeyBytes := make([]byte, callbackAccessKeyLength)
+	if _, err := rand.Read(accessKeyBytes); err != nil {
+		return nil, err
+	}
+	secretKey := make([]byte, callbackSecretKeyLength)
+	if _, err := rand.Read(secretKey); err != nil {
+		return nil, err
+	}
+	accessKey := "SW-" + base64.URLEncoding.EncodeToString(accessKeyBytes)
+	expiresAt := time.Now().Add(cfg.Expiry).Truncate(time.Second)
+
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		tx.Delete(sq.Delete("service_worker_callbacks").Where(sq.Eq{
+			"evidence_id": evidenceID,
+			"worker_name": workerName,
+			"used_at":     nil,
+		}))
+		tx.Insert("service_worker_callbacks", map[string]interface{}{
+			"access_key":  accessKey,
+			"secret_key":  secretKey,
+			"evidence_id": evidenceID,
+			"worker_name": workerName,
+			"expires_at":  expiresAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkerCallback{
+		URL:       strings.TrimSuffix(cfg.BaseURL, "/") + CallbackPath(accessKey),
+		AccessKey: accessKey,
+		SecretKey: base64.StdEncoding.EncodeToString(secretKey),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// LookupCallback retrieves the callback with the given access key. ErrCallbackUnavailable is returned
+// if the callback has expired or has already been used. Note that the returned callback is always
+// usable for computing a signature (even on error), so that callers can avoid revealing which access
+// keys exist.
+func LookupCallback(db *database.Connection, accessKey string) (models.ServiceWorkerCallback, error) {
+	var callback models.ServiceWorkerCallback
+	err := db.Get(&callback, sq.Select("*").
+		From("service_worker_callbacks").
+		Where(sq.Eq{"access_key": accessKey}))
+	if err != nil {
+		if database.IsEmptyResultSetError(err) {
+			return callback, ErrCallbackUnavailable
+		}
+		return callback, err
+	}
+	if callback.UsedAt != nil || time.Now().After(callback.ExpiresAt) {
+		return callback, ErrCallbackUnavailable
+	}
+	return callback, nil
+}
+
+// CompleteCallback records a result delivered via a callback. The result is interpreted in the same
+// way as a direct response from the worker. Deferring again leaves the callback usable; any other
+// result uses up the callback.
+func CompleteCallback(db *database.Connection, callback models.ServiceWorkerCallback, result ProcessResponse) (*models.EvidenceMetadata, error) {
+	model := models.EvidenceMetadata{
+		Source:     callback.WorkerName,
+		EvidenceID: callback.EvidenceID,
+	}
+	handleProcessResponse(&model, 200, result)
+
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		// lock the callback, so that concurrent deliveries cannot both be recorded
+		var unused []int64
+		tx.Select(&unused, sq.Select("id").
+			From("service_worker_callbacks").
+			Where(sq.Eq{"id": callback.ID, "used_at": nil}).
+			Suffix("FOR UPDATE"))
+		if len(unused) == 0 {
+			tx.FailTransaction(ErrCallbackUnavailable)
+			return
+		}
+		if model.Status == nil || *model.Status != evidencemetadata.StatusQueued {
+			tx.Update(sq.Update("service_worker_callbacks").
+				Set("used_at", time.Now()).
+				Where(sq.Eq{"id": callback.ID}))
+		}
+		upsertWorkerCompleteData(tx, model)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
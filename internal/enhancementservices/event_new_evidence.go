@@ -12,6 +12,8 @@ import (
 	sq "github.com/Masterminds/squirrel"
 )
 
+// NewEvidencePayload describes a piece of evidence. It is delivered for evidence_created,
+// evidence_updated and evidence_deleted events.
 type NewEvidencePayload struct {
 	Type            string              `json:"type" db:"type"`
 	EvidenceUUID    string              `json:"evidenceUuid"  db:"uuid"`
@@ -19,6 +21,8 @@ type NewEvidencePayload struct {
 	ContentType     string              `json:"contentType"   db:"content_type"`
 	GlobalVariables []dtos.GlobalVar    `json:"globalVariables"`
 	OperationVars   []dtos.OperationVar `json:"operationVariables"`
+	// Callback allows the worker to deliver its result later, if it chooses to defer the work
+	Callback *WorkerCallback `json:"callback,omitempty" db:"-"`
 }
 
 type ExpandedNewEvidencePayload struct {
@@ -26,10 +30,6 @@ type ExpandedNewEvidencePayload struct {
 	EvidenceID int64 `db:"id"`
 }
 
-func getExpandedPayloadID(e ExpandedNewEvidencePayload) int64 {
-	return e.EvidenceID
-}
-
 // BatchBuildNewEvidencePayload creates a set of payloads for the given operation and evidence uuids.
 // This function provides convenience over the alternatives: BatchBuildNewEvidencePayloadFromUUIDs and
 // BatchBuildNewEvidencePayloadForAllEvidence. Note that if no evidenceUUIDs are provided,
@@ -66,38 +66,24 @@ func batchBuildNewEvidencePayloadSpecial(ctx context.Context, db database.Connec
 	err := db.WithTx(ctx, func(tx *database.Transactable) {
 		evidence, _ := fetch(tx)
 		ids := helpers.Map(evidence, database.EvidenceToID)
-		payloads, _ = batchBuildNewEvidencePayloadFromIDs(tx, ids)
+		payloads, _ = batchBuildEvidencePayloadFromIDs(tx, EventEvidenceCreated, ids)
 	})
 
 	return payloads, err
 }
 
-// batchBuildNewEvidencePayloadFromIDs builds a payload by getting all of the necessary details in bulk.
+// batchBuildEvidencePayloadFromIDs builds a payload, of the given event type, by getting all of the
+// necessary details in bulk.
 // Note: this relies on the ordering of evidenceIDs. No particular order is required as input,
 // but the result is ordered by evidenceID, in ASC order.
-func batchBuildNewEvidencePayloadFromIDs(db database.ConnectionProxy, evidenceIDs []int64) ([]ExpandedNewEvidencePayload, error) {
+func batchBuildEvidencePayloadFromIDs(db database.ConnectionProxy, eventType string, evidenceIDs []int64) ([]ExpandedNewEvidencePayload, error) {
 	var payloads []ExpandedNewEvidencePayload
 
-	var globalVariables []models.GlobalVar
-
-	err := db.Select(&globalVariables, sq.Select("name", "value").From("global_vars"))
-	if err != nil {
-		return nil, fmt.Errorf("unable to gather global variables for worker")
-	}
-	var globalVariablesDTO []dtos.GlobalVar
-	for _, v := range globalVariables {
-		globalVariablesDTO = append(globalVariablesDTO, dtos.GlobalVar{
-			Name:  v.Name,
-			Value: v.Value,
-		})
-	}
-
-	err = db.Select(&payloads, sq.Select(
+	err := db.Select(&payloads, sq.Select(
 		"e.id AS id",
 		"e.uuid AS uuid",
 		"e.content_type",
 		"slug AS operation_slug",
-		"'evidence_created' AS type", // hardcode in the type so we don't have to edit each entry manually
 	).
 		From("evidence e").
 		LeftJoin("operations o ON e.operation_id = o.id").
@@ -109,6 +95,25 @@ func batchBuildNewEvidencePayloadFromIDs(db database.ConnectionProxy, evidenceID
 		return nil, fmt.Errorf("unable to gather evidence data for worker")
 	}
 
+	return completeEvidencePayloads(db, eventType, payloads)
+}
+
+// completeEvidencePayloads sets the event type, and the global and operation variables, of each payload
+func completeEvidencePayloads(db database.ConnectionProxy, eventType string, payloads []ExpandedNewEvidencePayload) ([]ExpandedNewEvidencePayload, error) {
+	var globalVariables []models.GlobalVar
+
+	err := db.Select(&globalVariables, sq.Select("name", "value").From("global_vars"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to gather global variables for worker")
+	}
+	var globalVariablesDTO []dtos.GlobalVar
+	for _, v := range globalVariables {
+		globalVariablesDTO = append(globalVariablesDTO, dtos.GlobalVar{
+			Name:  v.Name,
+			Value: v.Value,
+		})
+	}
+
 	for i := range payloads {
 		var operationVariables []models.OperationVar
 
@@ -132,6 +137,7 @@ func batchBuildNewEvidencePayloadFromIDs(db database.ConnectionProxy, evidenceID
 			})
 		}
 
+		payloads[i].Type = eventType
 		payloads[i].OperationVars = operationVariablesDTO
 		payloads[i].GlobalVariables = globalVariablesDTO
 	}
@@ -0,0 +1,238 @@
+package enhancementservices
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// The events that service workers may subscribe to. Workers that produce evidence metadata should
+// subscribe to EventEvidenceCreated, which is the only event whose result is recorded.
+const (
+	EventEvidenceCreated      = "evidence_created"
+	EventEvidenceUpdated      = "evidence_updated"
+	EventEvidenceDeleted      = "evidence_deleted"
+	EventEvidenceTagged       = "evidence_tagged"
+	EventFindingCreated       = "finding_created"
+	EventFindingUpdated       = "finding_updated"
+	EventFindingReadyToReport = "finding_ready_to_report"
+	EventOperationCreated     = "operation_created"
+)
+
+var allEvents = []string{
+	EventEvidenceCreated,
+	EventEvidenceUpdated,
+	EventEvidenceDeleted,
+	EventEvidenceTagged,
+	EventFindingCreated,
+	EventFindingUpdated,
+	EventFindingReadyToReport,
+	EventOperationCreated,
+}
+
+// defaultEvents are the events delivered to workers that do not configure a subscription
+var defaultEvents = []string{EventEvidenceCreated}
+
+// FindingPayload is delivered for finding_created, finding_updated and finding_ready_to_report events
+type FindingPayload struct {
+	Type          string   `json:"type"`
+	FindingUUID   string   `json:"findingUuid"   db:"finding_uuid"`
+	OperationSlug string   `json:"operationSlug" db:"operation_slug"`
+	Title         string   `json:"title"         db:"title"`
+	Category      string   `json:"category"      db:"category"`
+	ReadyToReport bool     `json:"readyToReport" db:"ready_to_report"`
+	TicketLink    *string  `json:"ticketLink"    db:"ticket_link"`
+	EvidenceUUIDs []string `json:"evidenceUuids"`
+}
+
+// EvidenceTaggedPayload is delivered for evidence_tagged events. Tags contains only the newly added tags.
+type EvidenceTaggedPayload struct {
+	Type          string   `json:"type"`
+	EvidenceUUID  string   `json:"evidenceUuid"  db:"evidence_uuid"`
+	OperationSlug string   `json:"operationSlug" db:"operation_slug"`
+	Tags          []string `json:"tags"`
+}
+
+// OperationPayload is delivered for operation_created events
+type OperationPayload struct {
+	Type          string `json:"type"`
+	OperationSlug string `json:"operationSlug"`
+	Name          string `json:"name"`
+}
+
+// SubscribedEvents returns the events the worker should receive
+func (c BasicServiceWorkerConfig) SubscribedEvents() []string {
+	if len(c.Events) == 0 {
+		return defaultEvents
+	}
+	return c.Events
+}
+
+// validateEvents verifies that the worker only subscribes to known events
+func (c BasicServiceWorkerConfig) validateEvents() error {
+	for _, event := range c.Events {
+		if !helpers.ContainsMatch(allEvents, event) {
+			return fmt.Errorf("unsupported service worker event %q", event)
+		}
+	}
+	return nil
+}
+
+// subscribedWorkers returns the workers that subscribe to the given event. Workers with an
+// unparsable configuration are skipped.
+func subscribedWorkers(workers []models.ServiceWorker, eventType string) []models.ServiceWorker {
+	return helpers.Filter(workers, func(w models.ServiceWorker) bool {
+		var config BasicServiceWorkerConfig
+		if err := json.Unmarshal([]byte(w.Config), &config); err != nil {
+			return false
+		}
+		return helpers.ContainsMatch(config.SubscribedEvents(), eventType)
+	})
+}
+
+// BuildEvidencePayloads returns a builder for SendServiceWorkerEvent that produces a
+// NewEvidencePayload, of the given event type, for each of the indicated evidence
+func BuildEvidencePayloads(eventType string, evidenceIDs []int64) func(db database.ConnectionProxy) ([]interface{}, error) {
+	return func(db database.ConnectionProxy) ([]interface{}, error) {
+		payloads, err := batchBuildEvidencePayloadFromIDs(db, eventType, evidenceIDs)
+		if err != nil {
+			return nil, err
+		}
+		return helpers.Map(payloads, func(p ExpandedNewEvidencePayload) interface{} {
+			return p.NewEvidencePayload
+		}), nil
+	}
+}
+
+// BuildDeletedEvidencePayloads returns a builder for SendServiceWorkerEvent that produces an
+// evidence_deleted NewEvidencePayload for each of the given evidence. The payloads are built from the
+// provided records, rather than read from the database, as the evidence has already been removed.
+func BuildDeletedEvidencePayloads(operationSlug string, evidence []models.Evidence) func(db database.ConnectionProxy) ([]interface{}, error) {
+	return func(db database.ConnectionProxy) ([]interface{}, error) {
+		payloads, err := completeEvidencePayloads(db, EventEvidenceDeleted, helpers.Map(evidence, func(e models.Evidence) ExpandedNewEvidencePayload {
+			return ExpandedNewEvidencePayload{
+				NewEvidencePayload: NewEvidencePayload{
+					EvidenceUUID:  e.UUID,
+					OperationSlug: operationSlug,
+					ContentType:   e.ContentType,
+				},
+				EvidenceID: e.ID,
+			}
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return helpers.Map(payloads, func(p ExpandedNewEvidencePayload) interface{} {
+			return p.NewEvidencePayload
+		}), nil
+	}
+}
+
+// BuildFindingPayloads returns a builder for SendServiceWorkerEvent that produces a FindingPayload,
+// of the given event type, for each of the indicated findings
+func BuildFindingPayloads(eventType string, findingIDs []int64) func(db database.ConnectionProxy) ([]interface{}, error) {
+	return func(db database.ConnectionProxy) ([]interface{}, error) {
+		var findings []struct {
+			FindingPayload
+			ID int64 `db:"id"`
+		}
+		err := db.Select(&findings, sq.Select(
+			"f.id",
+			"f.uuid AS finding_uuid",
+			"o.slug AS operation_slug",
+			"f.title",
+			"COALESCE(fc.category, '') AS category",
+			"f.ready_to_report",
+			"f.ticket_link",
+		).
+			From("findings f").
+			Join("operations o ON o.id = f.operation_id").
+			LeftJoin("finding_categories fc ON fc.id = f.category_id").
+			Where(sq.Eq{"f.id": findingIDs}).
+			OrderBy("f.id"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to gather finding data for worker")
+		}
+
+		var evidence []struct {
+			FindingID    int64  `db:"finding_id"`
+			EvidenceUUID string `db:"uuid"`
+		}
+		err = db.Select(&evidence, sq.Select("efm.finding_id", "e.uuid").
+			From("evidence_finding_map efm").
+			Join("evidence e ON e.id = efm.evidence_id").
+			Where(sq.Eq{"efm.finding_id": findingIDs}).
+			OrderBy("e.occurred_at"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to gather finding evidence for worker")
+		}
+
+		payloads := make([]interface{}, len(findings))
+		for i, finding := range findings {
+			payload := finding.FindingPayload
+			payload.Type = eventType
+			payload.EvidenceUUIDs = []string{}
+			for _, e := range evidence {
+				if e.FindingID == finding.ID {
+					payload.EvidenceUUIDs = append(payload.EvidenceUUIDs, e.EvidenceUUID)
+				}
+			}
+			payloads[i] = payload
+		}
+		return payloads, nil
+	}
+}
+
+// BuildEvidenceTaggedPayloads returns a builder for SendServiceWorkerEvent that produces an
+// EvidenceTaggedPayload for the indicated evidence, listing the indicated (newly added) tags
+func BuildEvidenceTaggedPayloads(evidenceID int64, tagIDs []int64) func(db database.ConnectionProxy) ([]interface{}, error) {
+	return func(db database.ConnectionProxy) ([]interface{}, error) {
+		var payload EvidenceTaggedPayload
+		err := db.Get(&payload, sq.Select("e.uuid AS evidence_uuid", "o.slug AS operation_slug").
+			From("evidence e").
+			Join("operations o ON o.id = e.operation_id").
+			Where(sq.Eq{"e.id": evidenceID}))
+		if err != nil {
+			return nil, fmt.Errorf("unable to gather evidence data for worker")
+		}
+
+		err = db.Select(&payload.Tags, sq.Select("name").
+			From("tags").
+			Where(sq.Eq{"id": tagIDs}).
+			OrderBy("name"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to gather tags for worker")
+		}
+		payload.Type = EventEvidenceTagged
+
+		return []interface{}{payload}, nil
+	}
+}
+
+// BuildOperationPayloads returns a builder for SendServiceWorkerEvent that produces an
+// OperationPayload, of the given event type, for each of the indicated operations
+func BuildOperationPayloads(eventType string, operationIDs []int64) func(db database.ConnectionProxy) ([]interface{}, error) {
+	return func(db database.ConnectionProxy) ([]interface{}, error) {
+		var operations []models.Operation
+		err := db.Select(&operations, sq.Select("*").
+			From("operations").
+			Where(sq.Eq{"id": operationIDs}).
+			OrderBy("id"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to gather operation data for worker")
+		}
+
+		return helpers.Map(operations, func(op models.Operation) interface{} {
+			return OperationPayload{
+				Type:          eventType,
+				OperationSlug: op.Slug,
+				Name:          op.Name,
+			}
+		}), nil
+	}
+}
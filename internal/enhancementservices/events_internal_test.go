@@ -0,0 +1,34 @@
+package enhancementservices
+
+import (
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribedWorkers(t *testing.T) {
+	workers := []models.ServiceWorker{
+		{Name: "default", Config: `{"type": "web", "version": 1, "url": "http://default"}`},
+		{Name: "tickets", Config: `{"type": "web", "version": 1, "url": "http://tickets", "events": ["finding_created", "finding_updated"]}`},
+		{Name: "everything", Config: `{"type": "exec", "version": 1, "path": "/bin/true", "events": ["evidence_created", "finding_created"]}`},
+		{Name: "broken", Config: `{"type": "web",`},
+	}
+	subscribed := func(eventType string) []string {
+		return helpers.Map(subscribedWorkers(workers, eventType), getServiceWorkerName)
+	}
+
+	require.Equal(t, []string{"default", "everything"}, subscribed(EventEvidenceCreated))
+	require.Equal(t, []string{"tickets", "everything"}, subscribed(EventFindingCreated))
+	require.Equal(t, []string{"tickets"}, subscribed(EventFindingUpdated))
+	require.Empty(t, subscribed(EventOperationCreated))
+}
+
+func TestBuildWorkerValidatesEvents(t *testing.T) {
+	_, err := buildWorker("w", []byte(`{"type": "web", "version": 1, "url": "http://w", "events": ["finding_ready_to_report"]}`))
+	require.NoError(t, err)
+
+	_, err = buildWorker("w", []byte(`{"type": "web", "version": 1, "url": "http://w", "events": ["finding_deleted"]}`))
+	require.ErrorContains(t, err, "finding_deleted")
+}
@@ -0,0 +1,264 @@
+package enhancementservices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// JobStatus reflects the possible states of a service_worker_jobs row. Jobs that complete successfully
+// are removed from the table.
+type JobStatus = string
+
+const (
+	// JobQueued reflects jobs that are waiting to be run (possibly after a retry delay)
+	JobQueued JobStatus = "queued"
+	// JobRunning reflects jobs that have been claimed by a runner
+	JobRunning JobStatus = "running"
+	// JobFailed reflects jobs that have exhausted their retries
+	JobFailed JobStatus = "failed"
+)
+
+// jobsEnqueued is signalled whenever new jobs are added, so that an idle runner can start on them
+// without waiting for its next poll
+var jobsEnqueued = make(chan struct{}, 1)
+
+// JobsEnqueued returns a channel that receives a value whenever new jobs have been queued
+func JobsEnqueued() <-chan struct{} {
+	return jobsEnqueued
+}
+
+func notifyJobsEnqueued() {
+	select {
+	case jobsEnqueued <- struct{}{}:
+	default: // a notification is already pending
+	}
+}
+
+// enqueueEvidenceJobs adds a job for each (evidence, worker) pair
+func enqueueEvidenceJobs(db database.ConnectionProxy, evidenceIDs []int64, workerNames []string) error {
+	numEvidenceIDs := len(evidenceIDs)
+	return db.BatchInsert("service_worker_jobs", numEvidenceIDs*len(workerNames), func(row int) map[string]interface{} {
+		return map[string]interface{}{
+			"worker_name": workerNames[row/numEvidenceIDs],
+			"event_type":  EventEvidenceCreated,
+			"evidence_id": evidenceIDs[row%numEvidenceIDs],
+			"status":      JobQueued,
+		}
+	})
+}
+
+// enqueueEventJobs adds a job for each (payload, worker) pair
+func enqueueEventJobs(db database.ConnectionProxy, eventType string, payloads []string, workerNames []string) error {
+	numPayloads := len(payloads)
+	return db.BatchInsert("service_worker_jobs", numPayloads*len(workerNames), func(row int) map[string]interface{} {
+		return map[string]interface{}{
+			"worker_name": workerNames[row/numPayloads],
+			"event_type":  eventType,
+			"payload":     payloads[row%numPayloads],
+			"status":      JobQueued,
+		}
+	})
+}
+
+// ClaimJobs marks ready jobs as running, and returns them. Jobs are claimed separately for each worker
+// with ready jobs, oldest first, up to the number of jobs returned by capacity for that worker (e.g.
+// the worker's remaining concurrency). This keeps a worker with a large backlog from holding up
+// jobs for the other workers.
+func ClaimJobs(db *database.Connection, capacity func(workerName string) int) ([]models.ServiceWorkerJob, error) {
+	var claimed []models.ServiceWorkerJob
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		readyJobs := sq.And{
+			sq.Eq{"status": JobQueued},
+			sq.LtOrEq{"run_after": time.Now()},
+		}
+		var workerNames []string
+		tx.Select(&workerNames, sq.Select("DISTINCT worker_name").
+			From("service_worker_jobs").
+			Where(readyJobs))
+
+		var candidates []models.ServiceWorkerJob
+		for _, workerName := range workerNames {
+			limit := capacity(workerName)
+			if limit <= 0 {
+				continue
+			}
+			var workerJobs []models.ServiceWorkerJob
+			tx.Select(&workerJobs, sq.Select("*").
+				From("service_worker_jobs").
+				Where(readyJobs).
+				Where(sq.Eq{"worker_name": workerName}).
+				OrderBy("run_after", "id").
+				Limit(uint64(limit)).
+				Suffix("FOR UPDATE SKIP LOCKED"))
+			candidates = append(candidates, workerJobs...)
+		}
+
+		now := time.Now()
+		for _, job := range candidates {
+			job.Status = JobRunning
+			job.Attempts++
+			job.StartedAt = &now
+			claimed = append(claimed, job)
+		}
+		if len(claimed) > 0 {
+			tx.Update(sq.Update("service_worker_jobs").
+				SetMap(map[string]interface{}{
+					"status":     JobRunning,
+					"attempts":   sq.Expr("attempts + 1"),
+					"started_at": now,
+				}).
+				Where(sq.Eq{"id": helpers.Map(claimed, jobID)}))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// RunJob executes a claimed job. For evidence jobs, the resulting evidence metadata is recorded; an
+// error is returned if the worker could not be reached or reported an error, so that the job can
+// be retried. Jobs whose worker or evidence no longer exists are considered complete. Evidence jobs
+// are offered a callback, per the provided CallbackConfig, and workers that receive evidence content
+// directly read it from the contentStore.
+func RunJob(db *database.Connection, contentStore contentstore.Store, job models.ServiceWorkerJob, callbackCfg CallbackConfig) error {
+	workers, err := filterWorkers(db, []string{job.WorkerName})
+	if err != nil {
+		return err
+	}
+	if len(workers) == 0 {
+		return nil // worker has been deleted
+	}
+	worker := workers[0]
+
+	if job.EventType != EventEvidenceCreated {
+		var payload json.RawMessage
+		if job.Payload != nil {
+			payload = json.RawMessage(*job.Payload)
+		}
+		return runProcessEvent(db, worker, payload)
+	}
+
+	if job.EvidenceID == nil {
+		return errors.New("evidence job is missing an evidence id")
+	}
+	payloads, err := batchBuildEvidencePayloadFromIDs(db, EventEvidenceCreated, []int64{*job.EvidenceID})
+	if err != nil {
+		return err
+	}
+	if len(payloads) == 0 {
+		return nil // evidence has been deleted
+	}
+	if err := markWorkStarting(db, []int64{*job.EvidenceID}, []string{worker.Name}); err != nil {
+		return err
+	}
+	payload := payloads[0].NewEvidencePayload
+	if callbackCfg.BaseURL != "" {
+		if payload.Callback, err = createWorkerCallback(db, callbackCfg, *job.EvidenceID, worker.Name); err != nil {
+			return err
+		}
+	}
+	return runProcessMetadata(db, worker, *job.EvidenceID, &payload, evidenceContentLoader(db, contentStore, *job.EvidenceID))
+}
+
+// evidenceContentLoader provides the full content of the indicated evidence. Evidence without content
+// (e.g. events) provides empty content.
+func evidenceContentLoader(db *database.Connection, contentStore contentstore.Store, evidenceID int64) ContentLoader {
+	return func() (io.Reader, error) {
+		var evidence models.Evidence
+		err := db.Get(&evidence, sq.Select("full_image_key").
+			From("evidence").
+			Where(sq.Eq{"id": evidenceID}))
+		if err != nil {
+			return nil, err
+		}
+		if evidence.FullImageKey == "" {
+			return bytes.NewReader(nil), nil
+		}
+		return contentStore.Read(evidence.FullImageKey)
+	}
+}
+
+// FinishJob records the outcome of running a job. Successful jobs are removed. Failed jobs are
+// rescheduled after the given backoff, or marked as failed once maxAttempts has been reached.
+func FinishJob(db *database.Connection, job models.ServiceWorkerJob, runErr error, maxAttempts int64, backoff time.Duration) error {
+	if runErr == nil {
+		return db.Delete(sq.Delete("service_worker_jobs").Where(sq.Eq{"id": job.ID}))
+	}
+
+	ub := sq.Update("service_worker_jobs").
+		Set("last_error", runErr.Error()).
+		Where(sq.Eq{"id": job.ID})
+	if job.Attempts >= maxAttempts {
+		ub = ub.Set("status", JobFailed)
+	} else {
+		ub = ub.SetMap(map[string]interface{}{
+			"status":    JobQueued,
+			"run_after": time.Now().Add(backoff),
+		})
+	}
+	return db.Update(ub)
+}
+
+// RequeueStaleJobs recovers work that was interrupted (e.g. by a server restart). Running jobs that
+// started before staleBefore are returned to the queue, and evidence metadata that has been processing
+// since before staleBefore, without any outstanding job, has a new job queued. Returns the number of
+// jobs that were requeued or created.
+func RequeueStaleJobs(db *database.Connection, staleBefore time.Time) (int, error) {
+	var numRequeued int
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		var staleJobIDs []int64
+		tx.Select(&staleJobIDs, sq.Select("id").
+			From("service_worker_jobs").
+			Where(sq.Eq{"status": JobRunning}).
+			Where(sq.Lt{"started_at": staleBefore}))
+		if len(staleJobIDs) > 0 {
+			tx.Update(sq.Update("service_worker_jobs").
+				Set("status", JobQueued).
+				Set("run_after", time.Now()).
+				Where(sq.Eq{"id": staleJobIDs}))
+		}
+
+		var stuckMetadata []models.EvidenceMetadata
+		tx.Select(&stuckMetadata, sq.Select("evidence_id", "source").
+			From("evidence_metadata em").
+			Where(sq.Eq{"em.status": evidencemetadata.StatusProcessing}).
+			Where(sq.Lt{"em.work_started_at": staleBefore}).
+			Where("NOT EXISTS (SELECT 1 FROM service_worker_jobs j"+
+				" WHERE j.evidence_id = em.evidence_id AND j.worker_name = em.source"+
+				" AND j.status IN ('"+JobQueued+"', '"+JobRunning+"'))"))
+		tx.BatchInsert("service_worker_jobs", len(stuckMetadata), func(row int) map[string]interface{} {
+			return map[string]interface{}{
+				"worker_name": stuckMetadata[row].Source,
+				"event_type":  EventEvidenceCreated,
+				"evidence_id": stuckMetadata[row].EvidenceID,
+				"status":      JobQueued,
+			}
+		})
+		numRequeued = len(staleJobIDs) + len(stuckMetadata)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to requeue stale jobs: %w", err)
+	}
+	if numRequeued > 0 {
+		notifyJobsEnqueued()
+	}
+	return numRequeued, nil
+}
+
+func jobID(job models.ServiceWorkerJob) int64 {
+	return job.ID
+}
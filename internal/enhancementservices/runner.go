@@ -2,15 +2,17 @@ package enhancementservices
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"sync"
 	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
 	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
 
 	sq "github.com/Masterminds/squirrel"
@@ -33,101 +35,65 @@ type SendServiceWorkerEventInput struct {
 	EventType   string
 }
 
+// SendServiceWorkerEvent queues a job for each payload produced by the builder, for each of the
+// specified workers that subscribe to the event. The jobs are run by the service worker runner.
+// Note that the builder is not invoked if no worker subscribes to the event.
 func SendServiceWorkerEvent(db *database.Connection, input SendServiceWorkerEventInput) {
-	var workersToRun []models.ServiceWorker
-	var payloads []interface{}
-	workerContext := context.Background()
-
-	go func() {
-		err := db.WithTx(workerContext, func(tx *database.Transactable) {
-			workersToRun, _ = filterWorkers(tx, input.WorkerNames)
-			payloads, _ = input.Builder(tx)
-		})
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		workersToRun, _ := filterWorkers(tx, input.WorkerNames)
+		workersToRun = subscribedWorkers(workersToRun, input.EventType)
+		if len(workersToRun) == 0 {
+			return
+		}
+		payloads, err := input.Builder(tx)
 		if err != nil {
-			input.Logger.Error("Unable to execute service workers", "error", err.Error())
+			tx.FailTransaction(err)
 			return
 		}
 
-		var wg sync.WaitGroup
-		for _, payload := range payloads {
-			// set these aside so that they are preserved in the closure
-			payloadCopy := payload
-			for _, worker := range workersToRun {
-				wg.Add(1)
-				workerCopy := worker
-				go func() {
-					defer wg.Done()
-					err := runProcessEvent(db, workerCopy, &payloadCopy)
-					logger := input.Logger.With(
-						"worker", workerCopy.Name,
-						"eventType", input.EventType,
-					)
-
-					if err != nil {
-						logger.Error("Unable to run worker", "error", err)
-					} else {
-						logger.Info("Worker completed")
-					}
-				}()
+		encodedPayloads := make([]string, len(payloads))
+		for i, payload := range payloads {
+			encoded, err := json.Marshal(payload)
+			if err != nil {
+				tx.FailTransaction(err)
+				return
 			}
+			encodedPayloads[i] = string(encoded)
 		}
-		wg.Wait()
-		if notifyWorkersRunForTest != nil {
-			notifyWorkersRunForTest <- true
-		}
-	}()
+		enqueueEventJobs(tx, input.EventType, encodedPayloads, helpers.Map(workersToRun, getServiceWorkerName))
+	})
+	if err != nil {
+		input.Logger.Error("Unable to queue service workers", "eventType", input.EventType, "error", err.Error())
+		return
+	}
+	notifyJobsEnqueued()
 }
 
-// SendEvidenceCreatedEvent starts a specified set of workers for a specified set of evidenceUUIDs
-// Note that this process kicks off a number of goroutines.
+// SendEvidenceCreatedEvent queues a job for each of the specified workers that subscribe to
+// evidence_created, for each of the specified evidenceUUIDs. The jobs are run by the service worker
+// runner. Note that if no evidenceUUIDs are provided, then all evidence in the operation is chosen.
 func SendEvidenceCreatedEvent(db *database.Connection, reqLogger *slog.Logger, operationID int64, evidenceUUIDs []string, workerNames []string) error {
-	var workersToRun []models.ServiceWorker
-	var expandedPayloads []ExpandedNewEvidencePayload
-	workerContext := context.Background()
-
-	go func() {
-		err := db.WithTx(workerContext, func(tx *database.Transactable) {
-			workersToRun, _ = filterWorkers(tx, workerNames)
-			expandedPayloads, _ = BatchBuildNewEvidencePayload(workerContext, tx, operationID, evidenceUUIDs)
-
-			markWorkStarting(tx,
-				helpers.Map(expandedPayloads, getExpandedPayloadID),
-				helpers.Map(workersToRun, getServiceWorkerName))
-		})
-		if err != nil {
-			reqLogger.Error("Unable to execute service workers", "error", err.Error())
-			return
-		}
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		workersToRun, _ := filterWorkers(tx, workerNames)
+		workersToRun = subscribedWorkers(workersToRun, EventEvidenceCreated)
 
-		var wg sync.WaitGroup
-		for _, ePayload := range expandedPayloads {
-			// set these aside so that they are preserved in the closure
-			evidenceID := ePayload.EvidenceID
-			payload := ePayload.NewEvidencePayload
-			for _, worker := range workersToRun {
-				wg.Add(1)
-				workerCopy := worker
-				go func() {
-					defer wg.Done()
-					err := runProcessMetadata(db, workerCopy, evidenceID, &payload)
-					logger := reqLogger.With(
-						"worker", workerCopy.Name,
-						"evidenceID", evidenceID,
-					)
-
-					if err != nil {
-						logger.Error("Unable to run worker", "error", err)
-					} else {
-						logger.Info("Worker completed")
-					}
-				}()
-			}
-		}
-		wg.Wait()
-		if notifyWorkersRunForTest != nil {
-			notifyWorkersRunForTest <- true
+		var evidence []models.Evidence
+		if len(evidenceUUIDs) == 0 {
+			evidence, _ = database.GetAllEvidenceForOperation(tx, operationID)
+		} else {
+			evidence, _ = database.GetEvidenceFromUUIDs(tx, operationID, evidenceUUIDs)
 		}
-	}()
+		evidenceIDs := helpers.Map(evidence, database.EvidenceToID)
+		names := helpers.Map(workersToRun, getServiceWorkerName)
+
+		markWorkStarting(tx, evidenceIDs, names)
+		enqueueEvidenceJobs(tx, evidenceIDs, names)
+	})
+	if err != nil {
+		reqLogger.Error("Unable to queue service workers", "error", err.Error())
+		return errorwrap.WrapError("Unable to queue service workers", errorwrap.DatabaseErr(err))
+	}
+	notifyJobsEnqueued()
 
 	return nil
 }
@@ -141,18 +107,34 @@ func runProcessEvent(db *database.Connection, worker models.ServiceWorker, paylo
 	return handler.ProcessEvent(payload)
 }
 
-func runProcessMetadata(db *database.Connection, worker models.ServiceWorker, evidenceID int64, payload *NewEvidencePayload) error {
+// runProcessMetadata runs the worker against the evidence, and records the result. An error is
+// returned if the worker could not be run, or if the worker reported an error.
+func runProcessMetadata(db *database.Connection, worker models.ServiceWorker, evidenceID int64, payload *NewEvidencePayload, content ContentLoader) error {
 	handler, err := buildWorker(worker.Name, []byte(worker.Config))
 	if err != nil {
 		return err
 	}
 
-	if pendingUpdate, err := handler.ProcessMetadata(evidenceID, payload); err != nil {
+	pendingUpdate, err := handler.ProcessMetadata(evidenceID, payload, content)
+	if err != nil {
+		// record the failure, so that the evidence does not appear to be processing indefinitely
+		failure := models.EvidenceMetadata{Source: worker.Name, EvidenceID: evidenceID}
+		recordError(&failure, helpers.Ptr(err.Error()))
+		upsertWorkerCompleteData(db, failure)
 		return err
-	} else if pendingUpdate != nil { // should always be not-nil
-		_, err := upsertWorkerCompleteData(db, *pendingUpdate)
+	}
+	if pendingUpdate == nil { // should always be not-nil
+		return nil
+	}
+	if _, err := upsertWorkerCompleteData(db, *pendingUpdate); err != nil {
 		return err
 	}
+	if pendingUpdate.Status != nil && *pendingUpdate.Status == evidencemetadata.StatusError {
+		if pendingUpdate.LastRunMessage != nil {
+			return fmt.Errorf("worker reported an error: %v", *pendingUpdate.LastRunMessage)
+		}
+		return errors.New("worker reported an error")
+	}
 
 	return nil
 }
@@ -209,20 +191,27 @@ func getServiceWorkerName(w models.ServiceWorker) string {
 	return w.Name
 }
 
-func upsertWorkerCompleteData(db *database.Connection, data models.EvidenceMetadata) (int64, error) {
-	return db.Insert("evidence_metadata", map[string]interface{}{
-		"evidence_id":      data.EvidenceID,
-		"source":           data.Source,
-		"body":             data.Body,
-		"status":           data.Status,
-		"last_run_message": data.LastRunMessage,
-		"can_process":      data.CanProcess,
-	}, "ON DUPLICATE KEY UPDATE "+
-		"body=VALUES(body),"+
-		"status=VALUES(status),"+
-		"last_run_message=VALUES(last_run_message),"+
-		"can_process=VALUES(can_process)",
-	)
+// upsertWorkerCompleteData records the result of a worker run, and reindexes the evidence so that the
+// new metadata is searchable
+func upsertWorkerCompleteData(db database.ConnectionProxy, data models.EvidenceMetadata) (int64, error) {
+	var id int64
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		id, _ = tx.Insert("evidence_metadata", map[string]interface{}{
+			"evidence_id":      data.EvidenceID,
+			"source":           data.Source,
+			"body":             data.Body,
+			"status":           data.Status,
+			"last_run_message": data.LastRunMessage,
+			"can_process":      data.CanProcess,
+		}, "ON DUPLICATE KEY UPDATE "+
+			"body=VALUES(body),"+
+			"status=VALUES(status),"+
+			"last_run_message=VALUES(last_run_message),"+
+			"can_process=VALUES(can_process)",
+		)
+		search.IndexEvidence(tx, []int64{data.EvidenceID})
+	})
+	return id, err
 }
 
 // alignWorkers matches the names of the provided services with the currently active services.
@@ -290,12 +279,3 @@ func getAllEvidenceForOperation(db database.ConnectionProxy, operationID int64)
 
 	return evidence, err
 }
-
-var notifyWorkersRunForTest chan<- bool = nil
-
-func SetNotifyWorkersRunForTest(notifier chan<- bool) {
-	if cap(notifier) == 0 {
-		fmt.Println("Capacity for notifier channel is 0. This could easily cause deadlocks")
-	}
-	notifyWorkersRunForTest = notifier
-}
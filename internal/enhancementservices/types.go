@@ -17,6 +17,8 @@ func AllWorkers() []string {
 type BasicServiceWorkerConfig struct {
 	Type    string `json:"type"`
 	Version int64  `json:"version"`
+	// Events lists the events the worker subscribes to. If empty, only evidence_created is delivered.
+	Events []string `json:"events,omitempty"`
 }
 
 // ServiceTestResult provides a view of a Worker test
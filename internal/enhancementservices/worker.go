@@ -0,0 +1,68 @@
+package enhancementservices
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+)
+
+// defaultWorkerTimeout limits how long exec and grpc workers may take, when no timeout is configured
+const defaultWorkerTimeout = time.Minute
+
+// ContentLoader provides the content of a piece of evidence, for transports that deliver evidence
+// content directly to the worker
+type ContentLoader func() (io.Reader, error)
+
+// worker is implemented by each of the supported service worker transports
+type worker interface {
+	// Test contacts the worker to verify that it's running
+	Test() ServiceTestResult
+	// ProcessMetadata runs the worker against the indicated evidence, and returns the resulting
+	// metadata. content may be nil if the evidence content is not available.
+	ProcessMetadata(evidenceID int64, payload *NewEvidencePayload, content ContentLoader) (*models.EvidenceMetadata, error)
+	// ProcessEvent notifies the worker of any other event
+	ProcessEvent(payload interface{}) error
+}
+
+// buildWorker parses a stored service worker config and returns a ready-to-use worker for the
+// configured transport. Any unsupported type/version (e.g. a legacy "aws" Lambda config) is rejected
+// with a clear error.
+func buildWorker(workerName string, workerConfig []byte) (worker, error) {
+	var basicConfig BasicServiceWorkerConfig
+	if err := json.Unmarshal(workerConfig, &basicConfig); err != nil {
+		return nil, errorwrap.WrapError("worker configuration is unparsable", err)
+	}
+	if err := basicConfig.validateEvents(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case basicConfig.Type == "web" && basicConfig.Version == 1:
+		return buildWebWorker(workerName, workerConfig)
+	case basicConfig.Type == "exec" && basicConfig.Version == 1:
+		return buildExecWorker(workerName, workerConfig)
+	case basicConfig.Type == "grpc" && basicConfig.Version == 1:
+		return buildGRPCWorker(workerName, workerConfig)
+	}
+	return nil, fmt.Errorf("unsupported service worker type %q (version %d)", basicConfig.Type, basicConfig.Version)
+}
+
+// parseWorkerTimeout parses a configured timeout (e.g. "30s"), falling back to defaultWorkerTimeout
+// if none was configured
+func parseWorkerTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return defaultWorkerTimeout, nil
+	}
+	parsed, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, errorwrap.WrapError("worker timeout is unparsable", err)
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("worker timeout must be positive (got %v)", timeout)
+	}
+	return parsed, nil
+}
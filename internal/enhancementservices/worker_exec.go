@@ -0,0 +1,233 @@
+package enhancementservices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+)
+
+// maxExecErrorLength limits how much of an executable's stderr is included in error messages
+const maxExecErrorLength = 500
+
+// maxExecOutputSize limits how much output is read from an executable. Workers that write more than
+// this fail, rather than having their response silently truncated.
+const maxExecOutputSize = 16 * 1024 * 1024
+
+// execWorkerPaths holds the executables that exec workers are allowed to run. Exec workers are
+// disabled while this is empty.
+var execWorkerPaths = map[string]bool{}
+
+// SetExecWorkerPaths sets the absolute paths of the executables that exec workers may run, replacing
+// any previously allowed paths. Exec workers are disabled if no paths are given. This is expected to
+// be set once, at startup.
+func SetExecWorkerPaths(paths []string) {
+	allowed := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path != "" {
+			allowed[filepath.Clean(path)] = true
+		}
+	}
+	execWorkerPaths = allowed
+}
+
+// ValidateWorkerConfig checks that a service worker configuration may be saved. Configurations for
+// local executables are rejected unless the executable has been allowed by the server operator.
+func ValidateWorkerConfig(workerName string, workerConfig string) error {
+	var basicConfig BasicServiceWorkerConfig
+	if err := json.Unmarshal([]byte(workerConfig), &basicConfig); err != nil {
+		return errorwrap.WrapError("worker configuration is unparsable", err)
+	}
+	if basicConfig.Type != "exec" {
+		return nil
+	}
+	_, err := buildExecWorker(workerName, []byte(workerConfig))
+	return err
+}
+
+type execConfigV1Worker struct {
+	Config     ExecConfigV1
+	WorkerName string
+	timeout    time.Duration
+}
+
+// ExecConfigV1 configures a worker that runs a local executable. Each message is written to the
+// executable's stdin as a single line of JSON. For evidence, the line is followed by the evidence
+// content. The response is read from stdout.
+type ExecConfigV1 struct {
+	BasicServiceWorkerConfig
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	// Timeout limits how long the executable may run for each message (e.g. "30s")
+	Timeout string `json:"timeout"`
+}
+
+// buildExecWorker builds a worker for the local executable ("exec", version 1) transport
+func buildExecWorker(workerName string, workerConfig []byte) (worker, error) {
+	var execConfig ExecConfigV1
+	if err := json.Unmarshal(workerConfig, &execConfig); err != nil {
+		return nil, errorwrap.WrapError("worker configuration is unparsable", err)
+	}
+	if execConfig.Path == "" {
+		return nil, errors.New("exec worker configuration is missing a path")
+	}
+	if len(execWorkerPaths) == 0 {
+		return nil, errors.New("exec workers are disabled on this server")
+	}
+	if !filepath.IsAbs(execConfig.Path) || !execWorkerPaths[filepath.Clean(execConfig.Path)] {
+		return nil, fmt.Errorf("exec worker path %q is not allowed on this server", execConfig.Path)
+	}
+	timeout, err := parseWorkerTimeout(execConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &execConfigV1Worker{
+		WorkerName: workerName,
+		Config:     execConfig,
+		timeout:    timeout,
+	}, nil
+}
+
+func (w *execConfigV1Worker) Test() ServiceTestResult {
+	output, err := w.run(strings.NewReader(`{"type": "test"}` + "\n"))
+	if err != nil {
+		return errorTestResultWithMessage(err, "Unable to verify worker status")
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return testResultSuccess("Service is functional")
+	}
+
+	var parsedData TestResp
+	if err := json.Unmarshal(output, &parsedData); err != nil {
+		return errorTestResultWithMessage(err, "Unable to parse response")
+	}
+	if parsedData.Status == "ok" {
+		return testResultSuccess("Service is functional")
+	}
+	if parsedData.Status == "error" {
+		if parsedData.Message != nil {
+			return errorTestResultWithMessage(nil, *parsedData.Message)
+		}
+		return errorTestResultWithMessage(nil, "Service reported an error")
+	}
+
+	return errorTestResultWithMessage(nil, "Service did not reply with a supported status")
+}
+
+func (w *execConfigV1Worker) ProcessMetadata(evidenceID int64, payload *NewEvidencePayload, content ContentLoader) (*models.EvidenceMetadata, error) {
+	body, err := json.Marshal(*payload)
+	if err != nil {
+		return nil, errorwrap.WrapError("unable to construct body", err)
+	}
+	stdin := []io.Reader{bytes.NewReader(body), strings.NewReader("\n")}
+	if content != nil {
+		evidenceContent, err := content()
+		if err != nil {
+			return nil, errorwrap.WrapError("unable to load evidence content", err)
+		}
+		if closer, ok := evidenceContent.(io.Closer); ok {
+			defer closer.Close()
+		}
+		stdin = append(stdin, evidenceContent)
+	}
+
+	output, err := w.run(io.MultiReader(stdin...))
+	if err != nil {
+		return nil, err
+	}
+
+	model := models.EvidenceMetadata{
+		Source:     w.WorkerName,
+		EvidenceID: evidenceID,
+	}
+	var parsedData ProcessResponse
+	if err := json.Unmarshal(output, &parsedData); err != nil {
+		recordError(&model, helpers.Ptr("Unable to parse response"))
+		return &model, nil
+	}
+	// the executable's response has the same meaning as a 200/OK response from a web worker
+	handleProcessResponse(&model, http.StatusOK, parsedData)
+
+	return &model, nil
+}
+
+func (w *execConfigV1Worker) ProcessEvent(payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errorwrap.WrapError("unable to construct body", err)
+	}
+
+	_, err = w.run(io.MultiReader(bytes.NewReader(body), strings.NewReader("\n")))
+	return err
+}
+
+// run executes the configured executable with the given stdin, returning its stdout. An error is
+// returned if the executable exits unsuccessfully, or does not complete within the timeout.
+func (w *execConfigV1Worker) run(stdin io.Reader) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	stdout := limitedBuffer{limit: maxExecOutputSize}
+	stderr := limitedBuffer{limit: maxExecErrorLength + 1}
+	cmd := exec.CommandContext(ctx, w.Config.Path, w.Config.Args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// don't wait indefinitely on any processes the executable may have left running
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("worker did not complete within %v", w.timeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			if len(message) > maxExecErrorLength {
+				message = message[:maxExecErrorLength] + "..."
+			}
+			return nil, fmt.Errorf("worker failed: %w: %v", err, message)
+		}
+		return nil, fmt.Errorf("worker failed: %w", err)
+	}
+	if stdout.exceeded {
+		return nil, fmt.Errorf("worker output exceeded %d bytes", maxExecOutputSize)
+	}
+	return stdout.Bytes(), nil
+}
+
+// limitedBuffer keeps at most limit bytes written to it, discarding the rest. Writes always succeed,
+// so that the executable is not interrupted by a closed pipe.
+type limitedBuffer struct {
+	buffer   bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buffer.Len(); len(p) > remaining {
+		b.exceeded = true
+		b.buffer.Write(p[:max(remaining, 0)])
+		return len(p), nil
+	}
+	return b.buffer.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buffer.Bytes()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buffer.String()
+}
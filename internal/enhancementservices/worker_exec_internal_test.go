@@ -0,0 +1,134 @@
+package enhancementservices
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/stretchr/testify/require"
+)
+
+func allowExecWorkerPaths(t *testing.T, paths ...string) {
+	SetExecWorkerPaths(paths)
+	t.Cleanup(func() { SetExecWorkerPaths(nil) })
+}
+
+func makeExecWorkerConfig(t *testing.T, script string, timeout string) string {
+	config, err := json.Marshal(ExecConfigV1{
+		BasicServiceWorkerConfig: BasicServiceWorkerConfig{Type: "exec", Version: 1},
+		Path:                     "/bin/sh",
+		Args:                     []string{"-c", script},
+		Timeout:                  timeout,
+	})
+	require.NoError(t, err)
+	return string(config)
+}
+
+func TestExecWorkerPaths(t *testing.T) {
+	config := makeExecWorkerConfig(t, "true", "")
+
+	// verify exec workers are disabled by default
+	_, err := buildWorker("exec", []byte(config))
+	require.ErrorContains(t, err, "disabled")
+	require.Error(t, ValidateWorkerConfig("exec", config))
+
+	// verify only allowed paths are accepted
+	allowExecWorkerPaths(t, "/usr/local/bin/ocr")
+	_, err = buildWorker("exec", []byte(config))
+	require.ErrorContains(t, err, "not allowed")
+	require.Error(t, ValidateWorkerConfig("exec", config))
+
+	allowExecWorkerPaths(t, "/usr/local/bin/ocr", "/bin/sh")
+	_, err = buildWorker("exec", []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ValidateWorkerConfig("exec", config))
+
+	// verify relative paths are rejected, even if they resolve to an allowed path
+	_, err = buildWorker("exec", []byte(`{"type": "exec", "version": 1, "path": "../../bin/sh"}`))
+	require.Error(t, err)
+
+	// verify other worker types are unaffected
+	require.NoError(t, ValidateWorkerConfig("web", `{"type": "web", "version": 1, "url": "http://test:1234"}`))
+}
+
+func TestExecWorkerTest(t *testing.T) {
+	allowExecWorkerPaths(t, "/bin/sh")
+	runTest := func(script, timeout string) ServiceTestResult {
+		return TestServiceWorker(models.ServiceWorker{Name: "exec", Config: makeExecWorkerConfig(t, script, timeout)})
+	}
+
+	// verify the test message is delivered
+	result := runTest(`read -r msg; [ "$msg" = '{"type": "test"}' ] || exit 1`, "")
+	require.True(t, result.Live)
+	require.NoError(t, result.Error)
+
+	result = runTest(`echo '{"status": "ok"}'`, "")
+	require.True(t, result.Live)
+
+	result = runTest(`echo '{"status": "error", "message": "no tesseract"}'`, "")
+	require.False(t, result.Live)
+	require.Equal(t, "no tesseract", result.Message)
+
+	result = runTest(`echo "missing model" >&2; exit 3`, "")
+	require.False(t, result.Live)
+	require.ErrorContains(t, result.Error, "missing model")
+
+	result = runTest(`sleep 5`, "50ms")
+	require.False(t, result.Live)
+	require.ErrorContains(t, result.Error, "did not complete")
+
+	// verify invalid configurations
+	result = TestServiceWorker(models.ServiceWorker{Name: "exec", Config: `{"type": "exec", "version": 1}`})
+	require.False(t, result.Live)
+	require.Error(t, result.Error)
+}
+
+func TestExecWorkerProcessMetadata(t *testing.T) {
+	allowExecWorkerPaths(t, "/bin/sh")
+	payload := NewEvidencePayload{
+		Type:          "evidence_created",
+		EvidenceUUID:  "abc123",
+		OperationSlug: "whatsit",
+		ContentType:   "image",
+	}
+	content := func() (io.Reader, error) { return strings.NewReader("pixels"), nil }
+
+	process := func(script string) (*models.EvidenceMetadata, error) {
+		w, err := buildWorker("exec", []byte(makeExecWorkerConfig(t, script, "")))
+		require.NoError(t, err)
+		return w.ProcessMetadata(12, &payload, content)
+	}
+
+	// verify the payload and content are delivered, and the result is recorded
+	result, err := process(`read -r msg; content=$(cat);` +
+		`case "$msg" in *'"evidenceUuid":"abc123"'*) ;; *) exit 1;; esac;` +
+		`printf '{"action": "processed", "content": "%s"}' "$content"`)
+	require.NoError(t, err)
+	require.Equal(t, "exec", result.Source)
+	require.Equal(t, int64(12), result.EvidenceID)
+	require.Equal(t, evidencemetadata.StatusCompleted, *result.Status)
+	require.Equal(t, "pixels", result.Body)
+
+	result, err = process(`echo '{"action": "rejected", "content": "not an image"}'`)
+	require.NoError(t, err)
+	require.Equal(t, evidencemetadata.StatusCompleted, *result.Status)
+	require.False(t, *result.CanProcess)
+
+	result, err = process(`echo '{"action": "deferred"}'`)
+	require.NoError(t, err)
+	require.Equal(t, evidencemetadata.StatusQueued, *result.Status)
+
+	result, err = process(`echo 'not json'`)
+	require.NoError(t, err)
+	require.Equal(t, evidencemetadata.StatusError, *result.Status)
+
+	_, err = process(`exit 1`)
+	require.Error(t, err)
+
+	// verify oversized output is rejected, rather than truncated
+	_, err = process(`head -c 17000000 /dev/zero`)
+	require.ErrorContains(t, err, "output exceeded")
+}
@@ -0,0 +1,156 @@
+package enhancementservices
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices/workerpb"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+type grpcConfigV1Worker struct {
+	Config     GRPCConfigV1
+	WorkerName string
+	timeout    time.Duration
+}
+
+// GRPCConfigV1 configures a worker that implements the Worker service defined in
+// workerpb/worker.proto
+type GRPCConfigV1 struct {
+	BasicServiceWorkerConfig
+	// Address is the target of the worker (e.g. "ocr-worker:50051")
+	Address string `json:"address"`
+	// TLS indicates that the worker should be contacted over TLS. Otherwise, the connection is unencrypted
+	TLS bool `json:"tls"`
+	// Headers are sent as metadata with each request
+	Headers map[string]string `json:"headers"`
+	// Timeout limits how long each request may take (e.g. "30s")
+	Timeout string `json:"timeout"`
+}
+
+// buildGRPCWorker builds a worker for the gRPC ("grpc", version 1) transport
+func buildGRPCWorker(workerName string, workerConfig []byte) (worker, error) {
+	var grpcConfig GRPCConfigV1
+	if err := json.Unmarshal(workerConfig, &grpcConfig); err != nil {
+		return nil, errorwrap.WrapError("worker configuration is unparsable", err)
+	}
+	if grpcConfig.Address == "" {
+		return nil, errors.New("grpc worker configuration is missing an address")
+	}
+	timeout, err := parseWorkerTimeout(grpcConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcConfigV1Worker{
+		WorkerName: workerName,
+		Config:     grpcConfig,
+		timeout:    timeout,
+	}, nil
+}
+
+func (w *grpcConfigV1Worker) Test() ServiceTestResult {
+	var resp *workerpb.TestResponse
+	err := w.call(func(ctx context.Context, client workerpb.WorkerClient) (err error) {
+		resp, err = client.Test(ctx, &workerpb.TestRequest{})
+		return err
+	})
+	if err != nil {
+		return errorTestResultWithMessage(err, "Unable to verify worker status")
+	}
+
+	if resp.Status == "ok" {
+		return testResultSuccess("Service is functional")
+	}
+	if resp.Status == "error" {
+		if resp.Message != nil {
+			return errorTestResultWithMessage(nil, *resp.Message)
+		}
+		return errorTestResultWithMessage(nil, "Service reported an error")
+	}
+
+	return errorTestResultWithMessage(nil, "Service did not reply with a supported status")
+}
+
+func (w *grpcConfigV1Worker) ProcessMetadata(evidenceID int64, payload *NewEvidencePayload, content ContentLoader) (*models.EvidenceMetadata, error) {
+	body, err := json.Marshal(*payload)
+	if err != nil {
+		return nil, errorwrap.WrapError("unable to construct body", err)
+	}
+	req := &workerpb.ProcessEvidenceRequest{Payload: body}
+	if content != nil {
+		evidenceContent, err := content()
+		if err != nil {
+			return nil, errorwrap.WrapError("unable to load evidence content", err)
+		}
+		if closer, ok := evidenceContent.(io.Closer); ok {
+			defer closer.Close()
+		}
+		if req.Content, err = io.ReadAll(evidenceContent); err != nil {
+			return nil, errorwrap.WrapError("unable to load evidence content", err)
+		}
+	}
+
+	var resp *workerpb.ProcessEvidenceResponse
+	err = w.call(func(ctx context.Context, client workerpb.WorkerClient) (err error) {
+		resp, err = client.ProcessEvidence(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	model := models.EvidenceMetadata{
+		Source:     w.WorkerName,
+		EvidenceID: evidenceID,
+	}
+	// the worker's response has the same meaning as a 200/OK response from a web worker
+	handleProcessResponse(&model, http.StatusOK, ProcessResponse{
+		Action:  resp.Action,
+		Content: resp.Content,
+	})
+
+	return &model, nil
+}
+
+func (w *grpcConfigV1Worker) ProcessEvent(payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errorwrap.WrapError("unable to construct body", err)
+	}
+
+	return w.call(func(ctx context.Context, client workerpb.WorkerClient) error {
+		_, err := client.ProcessEvent(ctx, &workerpb.ProcessEventRequest{Payload: body})
+		return err
+	})
+}
+
+// call connects to the worker, and invokes fn with a client for the connection. The provided context
+// carries the configured headers, and expires after the configured timeout.
+func (w *grpcConfigV1Worker) call(fn func(context.Context, workerpb.WorkerClient) error) error {
+	creds := insecure.NewCredentials()
+	if w.Config.TLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(w.Config.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return errorwrap.WrapError("unable to connect to worker", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(w.Config.Headers))
+
+	return fn(ctx, workerpb.NewWorkerClient(conn))
+}
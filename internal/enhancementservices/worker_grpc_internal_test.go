@@ -0,0 +1,129 @@
+package enhancementservices
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices/workerpb"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type mockGRPCWorker struct {
+	workerpb.UnimplementedWorkerServer
+	test            func(context.Context) (*workerpb.TestResponse, error)
+	processEvidence func(context.Context, *workerpb.ProcessEvidenceRequest) (*workerpb.ProcessEvidenceResponse, error)
+}
+
+func (m *mockGRPCWorker) Test(ctx context.Context, _ *workerpb.TestRequest) (*workerpb.TestResponse, error) {
+	return m.test(ctx)
+}
+
+func (m *mockGRPCWorker) ProcessEvidence(ctx context.Context, req *workerpb.ProcessEvidenceRequest) (*workerpb.ProcessEvidenceResponse, error) {
+	return m.processEvidence(ctx, req)
+}
+
+// startGRPCWorker starts a gRPC server for the mock worker, and returns the config to reach it
+func startGRPCWorker(t *testing.T, mock *mockGRPCWorker) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	workerpb.RegisterWorkerServer(server, mock)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	config, err := json.Marshal(GRPCConfigV1{
+		BasicServiceWorkerConfig: BasicServiceWorkerConfig{Type: "grpc", Version: 1},
+		Address:                  listener.Addr().String(),
+		Headers:                  map[string]string{"Authorization": "Bearer hunter2"},
+	})
+	require.NoError(t, err)
+	return string(config)
+}
+
+func TestGRPCWorkerTest(t *testing.T) {
+	mock := &mockGRPCWorker{}
+	config := startGRPCWorker(t, mock)
+	runTest := func() ServiceTestResult {
+		return TestServiceWorker(models.ServiceWorker{Name: "grpc", Config: config})
+	}
+
+	// verify headers are delivered
+	mock.test = func(ctx context.Context) (*workerpb.TestResponse, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if strings.Join(md.Get("authorization"), "") != "Bearer hunter2" {
+			return nil, status.Error(codes.Unauthenticated, "bad token")
+		}
+		return &workerpb.TestResponse{Status: "ok"}, nil
+	}
+	result := runTest()
+	require.True(t, result.Live)
+	require.NoError(t, result.Error)
+
+	mock.test = func(context.Context) (*workerpb.TestResponse, error) {
+		return &workerpb.TestResponse{Status: "error", Message: helpers.Ptr("no tesseract")}, nil
+	}
+	result = runTest()
+	require.False(t, result.Live)
+	require.Equal(t, "no tesseract", result.Message)
+
+	mock.test = func(context.Context) (*workerpb.TestResponse, error) {
+		return nil, status.Error(codes.Unavailable, "warming up")
+	}
+	result = runTest()
+	require.False(t, result.Live)
+	require.ErrorContains(t, result.Error, "warming up")
+}
+
+func TestGRPCWorkerProcessMetadata(t *testing.T) {
+	mock := &mockGRPCWorker{}
+	w, err := buildWorker("grpc", []byte(startGRPCWorker(t, mock)))
+	require.NoError(t, err)
+
+	payload := NewEvidencePayload{
+		Type:          "evidence_created",
+		EvidenceUUID:  "abc123",
+		OperationSlug: "whatsit",
+		ContentType:   "image",
+	}
+	content := func() (io.Reader, error) { return strings.NewReader("pixels"), nil }
+
+	// verify the payload and content are delivered, and the result is recorded
+	mock.processEvidence = func(_ context.Context, req *workerpb.ProcessEvidenceRequest) (*workerpb.ProcessEvidenceResponse, error) {
+		var received NewEvidencePayload
+		if err := json.Unmarshal(req.Payload, &received); err != nil || received.EvidenceUUID != payload.EvidenceUUID {
+			return nil, status.Error(codes.InvalidArgument, "bad payload")
+		}
+		return &workerpb.ProcessEvidenceResponse{Action: "processed", Content: helpers.Ptr(string(req.Content))}, nil
+	}
+	result, err := w.ProcessMetadata(12, &payload, content)
+	require.NoError(t, err)
+	require.Equal(t, "grpc", result.Source)
+	require.Equal(t, int64(12), result.EvidenceID)
+	require.Equal(t, evidencemetadata.StatusCompleted, *result.Status)
+	require.Equal(t, "pixels", result.Body)
+
+	mock.processEvidence = func(context.Context, *workerpb.ProcessEvidenceRequest) (*workerpb.ProcessEvidenceResponse, error) {
+		return &workerpb.ProcessEvidenceResponse{Action: "error", Content: helpers.Ptr("unreadable")}, nil
+	}
+	result, err = w.ProcessMetadata(12, &payload, content)
+	require.NoError(t, err)
+	require.Equal(t, evidencemetadata.StatusError, *result.Status)
+	require.Equal(t, "unreadable", *result.LastRunMessage)
+
+	mock.processEvidence = func(context.Context, *workerpb.ProcessEvidenceRequest) (*workerpb.ProcessEvidenceResponse, error) {
+		return nil, status.Error(codes.Internal, "crashed")
+	}
+	_, err = w.ProcessMetadata(12, &payload, content)
+	require.Error(t, err)
+}
@@ -28,18 +28,8 @@ type WebConfigV1 struct {
 
 var workerRequestFnMap map[string]*RequestFn = map[string]*RequestFn{}
 
-// buildWorker parses a stored service worker config and returns a ready-to-use worker.
-// Only the HTTP ("web", version 1) transport is supported; any other type/version (e.g. a
-// legacy "aws" Lambda config) is rejected with a clear error.
-func buildWorker(workerName string, workerConfig []byte) (*webConfigV1Worker, error) {
-	var basicConfig BasicServiceWorkerConfig
-	if err := json.Unmarshal(workerConfig, &basicConfig); err != nil {
-		return nil, errorwrap.WrapError("worker configuration is unparsable", err)
-	}
-	if basicConfig.Type != "web" || basicConfig.Version != 1 {
-		return nil, fmt.Errorf("unsupported service worker type %q (version %d)", basicConfig.Type, basicConfig.Version)
-	}
-
+// buildWebWorker builds a worker for the HTTP ("web", version 1) transport
+func buildWebWorker(workerName string, workerConfig []byte) (worker, error) {
 	var webConfig WebConfigV1
 	if err := json.Unmarshal(workerConfig, &webConfig); err != nil {
 		return nil, errorwrap.WrapError("worker configuration is unparsable", err)
@@ -90,7 +80,9 @@ func (w *webConfigV1Worker) Test() ServiceTestResult {
 	return errorTestResultWithMessage(nil, "Service did not reply with a supported status")
 }
 
-func (w *webConfigV1Worker) ProcessMetadata(evidenceID int64, payload *NewEvidencePayload) (*models.EvidenceMetadata, error) {
+// ProcessMetadata sends the payload to the worker. Web workers retrieve evidence content via the API,
+// so the content is not used.
+func (w *webConfigV1Worker) ProcessMetadata(evidenceID int64, payload *NewEvidencePayload, _ ContentLoader) (*models.EvidenceMetadata, error) {
 	body, err := json.Marshal(*payload)
 	if err != nil {
 		return nil, errorwrap.WrapError("unable to construct body", err)
@@ -120,12 +112,20 @@ func (w *webConfigV1Worker) ProcessEvent(payload interface{}) error {
 		return errorwrap.WrapError("unable to construct body", err)
 	}
 
-	_, err = w.makeJSONRequest("POST", w.Config.URL, bytes.NewReader(body), func(req *http.Request) error {
+	resp, err := w.makeJSONRequest("POST", w.Config.URL, bytes.NewReader(body), func(req *http.Request) error {
 		helpers.AddHeaders(req, w.Config.Headers)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	return err
+	// any 2xx response acknowledges the event; anything else is retried
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("worker responded with status %v", resp.StatusCode)
+	}
+	return nil
 }
 
 func handleWebResponse(dbModel *models.EvidenceMetadata, resp *http.Response) {
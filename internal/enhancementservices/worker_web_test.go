@@ -76,7 +76,7 @@ func TestWebProcessMetadata(t *testing.T) {
 
 	// verify success
 	setClient(processSuccessReponse)
-	result, err := worker.ProcessMetadata(eviID, &payload)
+	result, err := worker.ProcessMetadata(eviID, &payload, nil)
 	require.NoError(t, err)
 	require.Equal(t, eviID, result.EvidenceID)
 	require.True(t, *result.CanProcess)
@@ -93,19 +93,19 @@ func TestWebProcessMetadata(t *testing.T) {
 
 		// no-content failure
 		setClient(processErrorResponse_NoContent)
-		result, err = worker.ProcessMetadata(eviID, &payload)
+		result, err = worker.ProcessMetadata(eviID, &payload, nil)
 		verifyErrorScenario(result, err)
 		require.NotNil(t, result.LastRunMessage)
 
 		// with message
 		setClient(processErrorResponse_WithMessage)
-		result, err = worker.ProcessMetadata(eviID, &payload)
+		result, err = worker.ProcessMetadata(eviID, &payload, nil)
 		verifyErrorScenario(result, err)
 		require.Equal(t, content, *result.LastRunMessage)
 
 		// without message
 		setClient(processErrorResponse_StatusCode)
-		result, err = worker.ProcessMetadata(eviID, &payload)
+		result, err = worker.ProcessMetadata(eviID, &payload, nil)
 		verifyErrorScenario(result, err)
 		require.Nil(t, result.LastRunMessage)
 	}
@@ -120,11 +120,11 @@ func TestWebProcessMetadata(t *testing.T) {
 		}
 		// status code version
 		setClient(processDeferalResponse_StatusCode)
-		verifyDefferalResult(worker.ProcessMetadata(eviID, &payload))
+		verifyDefferalResult(worker.ProcessMetadata(eviID, &payload, nil))
 
 		// action version
 		setClient(processDeferalReponse_Action)
-		verifyDefferalResult(worker.ProcessMetadata(eviID, &payload))
+		verifyDefferalResult(worker.ProcessMetadata(eviID, &payload, nil))
 	}
 
 	// verify Rejections
@@ -136,11 +136,11 @@ func TestWebProcessMetadata(t *testing.T) {
 		}
 		// status code version
 		setClient(processRejectedResponse_StatusCode)
-		verifyRejectionResult(worker.ProcessMetadata(eviID, &payload))
+		verifyRejectionResult(worker.ProcessMetadata(eviID, &payload, nil))
 
 		// action version
 		setClient(processRejectedReponse_Action)
-		result, err := worker.ProcessMetadata(eviID, &payload)
+		result, err := worker.ProcessMetadata(eviID, &payload, nil)
 		verifyRejectionResult(result, err)
 		require.Equal(t, content, *result.LastRunMessage)
 	}
@@ -0,0 +1,394 @@
+// Protocol for enhancement service workers that use the "grpc" transport. Workers implement the
+// Worker service; AShirt acts as the client.
+//
+// To regenerate worker.pb.go, run `make generate-proto`
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: internal/enhancementservices/workerpb/worker.proto
+
+package workerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestRequest) Reset() {
+	*x = TestRequest{}
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestRequest) ProtoMessage() {}
+
+func (x *TestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestRequest.ProtoReflect.Descriptor instead.
+func (*TestRequest) Descriptor() ([]byte, []int) {
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP(), []int{0}
+}
+
+type TestResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Either "ok" or "error"
+	Status string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	// An optional message to show to the user
+	Message       *string `protobuf:"bytes,2,opt,name=message,proto3,oneof" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestResponse) Reset() {
+	*x = TestResponse{}
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestResponse) ProtoMessage() {}
+
+func (x *TestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestResponse.ProtoReflect.Descriptor instead.
+func (*TestResponse) Descriptor() ([]byte, []int) {
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TestResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *TestResponse) GetMessage() string {
+	if x != nil && x.Message != nil {
+		return *x.Message
+	}
+	return ""
+}
+
+type ProcessEvidenceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The JSON encoded evidence created message (see pipeline_readme.md)
+	Payload []byte `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	// The evidence content. Empty for evidence without content
+	Content       []byte `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessEvidenceRequest) Reset() {
+	*x = ProcessEvidenceRequest{}
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessEvidenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessEvidenceRequest) ProtoMessage() {}
+
+func (x *ProcessEvidenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessEvidenceRequest.ProtoReflect.Descriptor instead.
+func (*ProcessEvidenceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ProcessEvidenceRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ProcessEvidenceRequest) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type ProcessEvidenceResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of "processed", "rejected", "error", or "deferred"
+	Action string `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	// For processed evidence, the result. For rejected or errored evidence, an optional reason
+	Content       *string `protobuf:"bytes,2,opt,name=content,proto3,oneof" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessEvidenceResponse) Reset() {
+	*x = ProcessEvidenceResponse{}
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessEvidenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessEvidenceResponse) ProtoMessage() {}
+
+func (x *ProcessEvidenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessEvidenceResponse.ProtoReflect.Descriptor instead.
+func (*ProcessEvidenceResponse) Descriptor() ([]byte, []int) {
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ProcessEvidenceResponse) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ProcessEvidenceResponse) GetContent() string {
+	if x != nil && x.Content != nil {
+		return *x.Content
+	}
+	return ""
+}
+
+type ProcessEventRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The JSON encoded event
+	Payload       []byte `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessEventRequest) Reset() {
+	*x = ProcessEventRequest{}
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessEventRequest) ProtoMessage() {}
+
+func (x *ProcessEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessEventRequest.ProtoReflect.Descriptor instead.
+func (*ProcessEventRequest) Descriptor() ([]byte, []int) {
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ProcessEventRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type ProcessEventResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessEventResponse) Reset() {
+	*x = ProcessEventResponse{}
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessEventResponse) ProtoMessage() {}
+
+func (x *ProcessEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_enhancementservices_workerpb_worker_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessEventResponse.ProtoReflect.Descriptor instead.
+func (*ProcessEventResponse) Descriptor() ([]byte, []int) {
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP(), []int{5}
+}
+
+var File_internal_enhancementservices_workerpb_worker_proto protoreflect.FileDescriptor
+
+const file_internal_enhancementservices_workerpb_worker_proto_rawDesc = "" +
+	"\n" +
+	"2internal/enhancementservices/workerpb/worker.proto\x12\x10ashirt.worker.v1\"\r\n" +
+	"\vTestRequest\"Q\n" +
+	"\fTestResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1d\n" +
+	"\amessage\x18\x02 \x01(\tH\x00R\amessage\x88\x01\x01B\n" +
+	"\n" +
+	"\b_message\"L\n" +
+	"\x16ProcessEvidenceRequest\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\"\\\n" +
+	"\x17ProcessEvidenceResponse\x12\x16\n" +
+	"\x06action\x18\x01 \x01(\tR\x06action\x12\x1d\n" +
+	"\acontent\x18\x02 \x01(\tH\x00R\acontent\x88\x01\x01B\n" +
+	"\n" +
+	"\b_content\"/\n" +
+	"\x13ProcessEventRequest\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\"\x16\n" +
+	"\x14ProcessEventResponse2\x96\x02\n" +
+	"\x06Worker\x12E\n" +
+	"\x04Test\x12\x1d.ashirt.worker.v1.TestRequest\x1a\x1e.ashirt.worker.v1.TestResponse\x12f\n" +
+	"\x0fProcessEvidence\x12(.ashirt.worker.v1.ProcessEvidenceRequest\x1a).ashirt.worker.v1.ProcessEvidenceResponse\x12]\n" +
+	"\fProcessEvent\x12%.ashirt.worker.v1.ProcessEventRequest\x1a&.ashirt.worker.v1.ProcessEventResponseBKZIgithub.com/ashirt-ops/ashirt-server/internal/enhancementservices/workerpbb\x06proto3"
+
+var (
+	file_internal_enhancementservices_workerpb_worker_proto_rawDescOnce sync.Once
+	file_internal_enhancementservices_workerpb_worker_proto_rawDescData []byte
+)
+
+func file_internal_enhancementservices_workerpb_worker_proto_rawDescGZIP() []byte {
+	file_internal_enhancementservices_workerpb_worker_proto_rawDescOnce.Do(func() {
+		file_internal_enhancementservices_workerpb_worker_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_enhancementservices_workerpb_worker_proto_rawDesc), len(file_internal_enhancementservices_workerpb_worker_proto_rawDesc)))
+	})
+	return file_internal_enhancementservices_workerpb_worker_proto_rawDescData
+}
+
+var file_internal_enhancementservices_workerpb_worker_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_internal_enhancementservices_workerpb_worker_proto_goTypes = []any{
+	(*TestRequest)(nil),             // 0: ashirt.worker.v1.TestRequest
+	(*TestResponse)(nil),            // 1: ashirt.worker.v1.TestResponse
+	(*ProcessEvidenceRequest)(nil),  // 2: ashirt.worker.v1.ProcessEvidenceRequest
+	(*ProcessEvidenceResponse)(nil), // 3: ashirt.worker.v1.ProcessEvidenceResponse
+	(*ProcessEventRequest)(nil),     // 4: ashirt.worker.v1.ProcessEventRequest
+	(*ProcessEventResponse)(nil),    // 5: ashirt.worker.v1.ProcessEventResponse
+}
+var file_internal_enhancementservices_workerpb_worker_proto_depIdxs = []int32{
+	0, // 0: ashirt.worker.v1.Worker.Test:input_type -> ashirt.worker.v1.TestRequest
+	2, // 1: ashirt.worker.v1.Worker.ProcessEvidence:input_type -> ashirt.worker.v1.ProcessEvidenceRequest
+	4, // 2: ashirt.worker.v1.Worker.ProcessEvent:input_type -> ashirt.worker.v1.ProcessEventRequest
+	1, // 3: ashirt.worker.v1.Worker.Test:output_type -> ashirt.worker.v1.TestResponse
+	3, // 4: ashirt.worker.v1.Worker.ProcessEvidence:output_type -> ashirt.worker.v1.ProcessEvidenceResponse
+	5, // 5: ashirt.worker.v1.Worker.ProcessEvent:output_type -> ashirt.worker.v1.ProcessEventResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_internal_enhancementservices_workerpb_worker_proto_init() }
+func file_internal_enhancementservices_workerpb_worker_proto_init() {
+	if File_internal_enhancementservices_workerpb_worker_proto != nil {
+		return
+	}
+	file_internal_enhancementservices_workerpb_worker_proto_msgTypes[1].OneofWrappers = []any{}
+	file_internal_enhancementservices_workerpb_worker_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_enhancementservices_workerpb_worker_proto_rawDesc), len(file_internal_enhancementservices_workerpb_worker_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_internal_enhancementservices_workerpb_worker_proto_goTypes,
+		DependencyIndexes: file_internal_enhancementservices_workerpb_worker_proto_depIdxs,
+		MessageInfos:      file_internal_enhancementservices_workerpb_worker_proto_msgTypes,
+	}.Build()
+	File_internal_enhancementservices_workerpb_worker_proto = out.File
+	file_internal_enhancementservices_workerpb_worker_proto_goTypes = nil
+	file_internal_enhancementservices_workerpb_worker_proto_depIdxs = nil
+}
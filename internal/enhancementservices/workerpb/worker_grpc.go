@@ -0,0 +1,144 @@
+package workerpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC bindings for the Worker service. These mirror the bindings that protoc-gen-go-grpc would
+// produce for worker.proto, and must be kept in sync with it.
+
+const (
+	workerTestMethod            = "/ashirt.worker.v1.Worker/Test"
+	workerProcessEvidenceMethod = "/ashirt.worker.v1.Worker/ProcessEvidence"
+	workerProcessEventMethod    = "/ashirt.worker.v1.Worker/ProcessEvent"
+)
+
+// WorkerClient is the client API for the Worker service
+type WorkerClient interface {
+	Test(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error)
+	ProcessEvidence(ctx context.Context, in *ProcessEvidenceRequest, opts ...grpc.CallOption) (*ProcessEvidenceResponse, error)
+	ProcessEvent(ctx context.Context, in *ProcessEventRequest, opts ...grpc.CallOption) (*ProcessEventResponse, error)
+}
+
+type workerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewWorkerClient constructs a WorkerClient that communicates over the given connection
+func NewWorkerClient(cc grpc.ClientConnInterface) WorkerClient {
+	return &workerClient{cc}
+}
+
+func (c *workerClient) Test(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error) {
+	out := new(TestResponse)
+	if err := c.cc.Invoke(ctx, workerTestMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerClient) ProcessEvidence(ctx context.Context, in *ProcessEvidenceRequest, opts ...grpc.CallOption) (*ProcessEvidenceResponse, error) {
+	out := new(ProcessEvidenceResponse)
+	if err := c.cc.Invoke(ctx, workerProcessEvidenceMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerClient) ProcessEvent(ctx context.Context, in *ProcessEventRequest, opts ...grpc.CallOption) (*ProcessEventResponse, error) {
+	out := new(ProcessEventResponse)
+	if err := c.cc.Invoke(ctx, workerProcessEventMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WorkerServer is the server API for the Worker service. Implementations should embed
+// UnimplementedWorkerServer, so that methods added in the future are rejected rather than breaking
+// the build.
+type WorkerServer interface {
+	Test(context.Context, *TestRequest) (*TestResponse, error)
+	ProcessEvidence(context.Context, *ProcessEvidenceRequest) (*ProcessEvidenceResponse, error)
+	ProcessEvent(context.Context, *ProcessEventRequest) (*ProcessEventResponse, error)
+}
+
+// UnimplementedWorkerServer rejects all calls
+type UnimplementedWorkerServer struct{}
+
+func (UnimplementedWorkerServer) Test(context.Context, *TestRequest) (*TestResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Test not implemented")
+}
+
+func (UnimplementedWorkerServer) ProcessEvidence(context.Context, *ProcessEvidenceRequest) (*ProcessEvidenceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ProcessEvidence not implemented")
+}
+
+func (UnimplementedWorkerServer) ProcessEvent(context.Context, *ProcessEventRequest) (*ProcessEventResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ProcessEvent not implemented")
+}
+
+// RegisterWorkerServer registers the Worker service implementation with a gRPC server
+func RegisterWorkerServer(s grpc.ServiceRegistrar, srv WorkerServer) {
+	s.RegisterService(&Worker_ServiceDesc, srv)
+}
+
+// Worker_ServiceDesc is the grpc.ServiceDesc for the Worker service
+var Worker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ashirt.worker.v1.Worker",
+	HandlerType: (*WorkerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Test",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(TestRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return handleUnary(ctx, srv, in, workerTestMethod, interceptor, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(WorkerServer).Test(ctx, req.(*TestRequest))
+				})
+			},
+		},
+		{
+			MethodName: "ProcessEvidence",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(ProcessEvidenceRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return handleUnary(ctx, srv, in, workerProcessEvidenceMethod, interceptor, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(WorkerServer).ProcessEvidence(ctx, req.(*ProcessEvidenceRequest))
+				})
+			},
+		},
+		{
+			MethodName: "ProcessEvent",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(ProcessEventRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return handleUnary(ctx, srv, in, workerProcessEventMethod, interceptor, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(WorkerServer).ProcessEvent(ctx, req.(*ProcessEventRequest))
+				})
+			},
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal/enhancementservices/workerpb/worker.proto",
+}
+
+func handleUnary(ctx context.Context, srv interface{}, in interface{}, method string, interceptor grpc.UnaryServerInterceptor, handler grpc.UnaryHandler) (interface{}, error) {
+	if interceptor == nil {
+		return handler(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: method,
+	}
+	return interceptor(ctx, in, info, handler)
+}
@@ -100,6 +100,21 @@ func DeleteErr(err error) error {
 // NotFoundErr provides an error for situations when a user requests data that does not exist.
 func NotFoundErr(err error) error { return HTTPErr(http.StatusNotFound, "Not Found", err) }
 
+// ConflictErr provides an error for requests that would create a duplicate of an existing resource
+func ConflictErr(err error, reason string) error {
+	return HTTPErr(http.StatusConflict, reason, err)
+}
+
+// TooLargeErr provides an error for requests, or uploaded content, that exceed a size limit. Wraps a Request Entity Too Large error
+func TooLargeErr(err error, reason string) error {
+	return HTTPErr(http.StatusRequestEntityTooLarge, reason, err)
+}
+
+// TooManyRequestsErr provides an error for users that have made too many requests in a short time
+func TooManyRequestsErr(err error) error {
+	return HTTPErr(http.StatusTooManyRequests, "Too many requests. Please wait a moment and try again.", err)
+}
+
 // UnauthorizedReadErr provides an error for sitatutions where a user is unable to read whatever data is/may be found
 func UnauthorizedReadErr(err error) error {
 	return HTTPErr(http.StatusNotFound, "Not Found", err)
@@ -177,6 +192,24 @@ func DisabledUserError() error {
 	return errors.New("This account has been disabled. Please contact an adminstrator if you think this is an error.")
 }
 
+// ReplayedRequest returns an error indicating that a signed API request reused a nonce, and so may
+// have been intercepted and replayed.
+func ReplayedRequest() error {
+	return HTTPErr(http.StatusUnauthorized, "This request has already been received. Sign each request with a new nonce.",
+		errors.New("Request nonce has already been used"))
+}
+
+// IsErrorReplayedRequest checks if the provided error is the same as a "Replayed Request" error.
+// See ReplayedRequest() in this package.
+func IsErrorReplayedRequest(err error) bool {
+	switch err := err.(type) {
+	case *HTTPError:
+		model := ReplayedRequest().(*HTTPError)
+		return model.HTTPStatus == err.HTTPStatus && model.PublicReason == err.PublicReason
+	}
+	return false
+}
+
 // PanicedError represents any error the occurs
 func PanicedError() error {
 	return HTTPErr(http.StatusInternalServerError, "An unknown error occurred", errors.New("pancied during processing"))
@@ -0,0 +1,87 @@
+// Package har provides a minimal parser for HTTP Archive (HAR) files, as produced by browser
+// developer tools and intercepting proxies. Only the fields needed to summarize and search the
+// request/response cycles are parsed.
+package har
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxPreviewEntries limits the number of entries rendered in a text preview
+const maxPreviewEntries = 50
+
+// Entry summarizes a single request/response cycle
+type Entry struct {
+	Method string
+	URL    string
+	Host   string
+	Status int
+}
+
+// Summary is the list of request/response cycles in a HAR file, in the order they were recorded
+type Summary struct {
+	Entries []Entry
+}
+
+type harFile struct {
+	Log *struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status int `json:"status"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// Parse reads the HAR content, summarizing each entry. Hosts are lowercased, and exclude any port.
+func Parse(data []byte) (*Summary, error) {
+	var parsed harFile
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse HAR: %w", err)
+	}
+	if parsed.Log == nil {
+		return nil, errors.New("unable to parse HAR: missing log")
+	}
+
+	summary := Summary{Entries: make([]Entry, len(parsed.Log.Entries))}
+	for i, entry := range parsed.Log.Entries {
+		host := ""
+		if u, err := url.Parse(entry.Request.URL); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+		summary.Entries[i] = Entry{
+			Method: strings.ToUpper(entry.Request.Method),
+			URL:    entry.Request.URL,
+			Host:   host,
+			Status: entry.Response.Status,
+		}
+	}
+	return &summary, nil
+}
+
+// Preview renders a plain text overview of the entries, one line per request, e.g.:
+//
+//	GET 200 https://example.com/
+//	POST 500 https://example.com/api/login
+func (s *Summary) Preview() string {
+	var sb strings.Builder
+	for i, entry := range s.Entries {
+		if i == maxPreviewEntries {
+			fmt.Fprintf(&sb, "... and %d more requests\n", len(s.Entries)-maxPreviewEntries)
+			break
+		}
+		fmt.Fprintf(&sb, "%s %d %s\n", entry.Method, entry.Status, entry.URL)
+	}
+	if len(s.Entries) == 0 {
+		sb.WriteString("No requests recorded\n")
+	}
+	return sb.String()
+}
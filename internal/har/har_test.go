@@ -0,0 +1,55 @@
+package har_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/har"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleHAR = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {"request": {"method": "get", "url": "https://API.client.com:8443/v1/users?id=1"}, "response": {"status": 200}},
+      {"request": {"method": "POST", "url": "https://api.client.com/v1/login"}, "response": {"status": 500}}
+    ]
+  }
+}`
+
+func TestParse(t *testing.T) {
+	summary, err := har.Parse([]byte(sampleHAR))
+	require.NoError(t, err)
+	require.Equal(t, []har.Entry{
+		{Method: "GET", URL: "https://API.client.com:8443/v1/users?id=1", Host: "api.client.com", Status: 200},
+		{Method: "POST", URL: "https://api.client.com/v1/login", Host: "api.client.com", Status: 500},
+	}, summary.Entries)
+
+	require.Equal(t,
+		"GET 200 https://API.client.com:8443/v1/users?id=1\nPOST 500 https://api.client.com/v1/login\n",
+		summary.Preview(),
+	)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := har.Parse([]byte("not a har"))
+	require.Error(t, err)
+
+	_, err = har.Parse([]byte(`{"something": "else"}`))
+	require.Error(t, err)
+}
+
+func TestPreviewTruncates(t *testing.T) {
+	entries := make([]string, 60)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"request": {"method": "GET", "url": "https://example.com/%d"}, "response": {"status": 200}}`, i)
+	}
+	summary, err := har.Parse([]byte(`{"log": {"entries": [` + strings.Join(entries, ",") + `]}}`))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(summary.Preview()), "\n")
+	require.Len(t, lines, 51)
+	require.Equal(t, "... and 10 more requests", lines[50])
+}
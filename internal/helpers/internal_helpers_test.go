@@ -3,6 +3,7 @@ package helpers
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/helpers/filter"
 	"github.com/stretchr/testify/require"
@@ -73,3 +74,95 @@ func TestTokenizeTimelineQuery(t *testing.T) {
 	require.Equal(t, filter.Value{Value: tokenValue, Modifier: filter.Not}, result["not"][0])
 	require.Equal(t, filter.Value{Value: tokenValueTwo, Modifier: filter.Not}, result["not"][1])
 }
+
+func TestParseQueryExpression(t *testing.T) {
+	parse := func(query string) (*QueryExpr, error) {
+		tokens, err := lexTimelineQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		return parseQueryExpression(tokens, len([]rune(query)))
+	}
+	term := func(key, value string, position int) *QueryExpr {
+		return &QueryExpr{Operator: QueryTerm, Key: key, Value: filter.Val(value), Position: position}
+	}
+
+	expr, err := parse(`(tag:web OR tag:api) AND !operator:bob`)
+	require.NoError(t, err)
+	require.Equal(t, &QueryExpr{Operator: QueryAnd, Position: 2, Children: []*QueryExpr{
+		{Operator: QueryOr, Position: 2, Children: []*QueryExpr{term("tag", "web", 2), term("tag", "api", 13)}},
+		{Operator: QueryNot, Position: 26, Children: []*QueryExpr{term("operator", "bob", 27)}},
+	}}, expr)
+
+	// AND binds tighter than OR, and is implied between adjacent terms
+	expr, err = parse(`a b OR "c d" NOT e`)
+	require.NoError(t, err)
+	require.Equal(t, &QueryExpr{Operator: QueryOr, Position: 1, Children: []*QueryExpr{
+		{Operator: QueryAnd, Position: 1, Children: []*QueryExpr{term("", "a", 1), term("", "b", 3)}},
+		{Operator: QueryAnd, Position: 8, Children: []*QueryExpr{
+			term("", "c d", 8),
+			{Operator: QueryNot, Position: 14, Children: []*QueryExpr{term("", "e", 18)}},
+		}},
+	}}, expr)
+
+	// quoted operators and parentheses are plain text; a leading ! on plain text is literal
+	expr, err = parse(`"OR" tag:"(web)" !plain url:!/static/`)
+	require.NoError(t, err)
+	require.Equal(t, []*QueryExpr{
+		term("", "OR", 1),
+		term("tag", "(web)", 6),
+		term("", "!plain", 18),
+		{Operator: QueryTerm, Key: "url", Value: filter.NotVal("/static/"), Position: 25},
+	}, expr.Terms())
+
+	expr, err = parse("   ")
+	require.NoError(t, err)
+	require.Nil(t, expr)
+
+	errorCases := map[string]*QuerySyntaxError{
+		`tag:web OR`:            {Position: 11, Message: "Expected a filter after 'OR'"},
+		`(tag:web OR tag:api`:   {Position: 1, Message: "Missing ')' to close this '('"},
+		`tag:web)`:              {Position: 8, Message: "Unexpected ')'"},
+		`AND tag:web`:           {Position: 1, Message: "Unexpected 'AND'"},
+		`tag:web () `:           {Position: 10, Message: "Parentheses must contain at least one filter"},
+		`tag:"unfinished quote`: {Position: 5, Message: "Missing closing quote"},
+		`NOT`:                   {Position: 4, Message: "Expected a filter after 'NOT'"},
+	}
+	for query, expected := range errorCases {
+		_, err := parse(query)
+		require.Equal(t, expected, err, "query: %v", query)
+	}
+}
+
+func TestParseRelativeTimes(t *testing.T) {
+	now := time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	midnight := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	endOfDay := time.Date(2024, 3, 14, 23, 59, 59, 0, time.UTC)
+
+	filters, err := ParseTimelineQuery(`range:-24h,now`)
+	require.NoError(t, err)
+	require.Equal(t, filter.DateValues{filter.DateVal(filter.DateRange{From: now.Add(-24 * time.Hour), To: now})}, filters.DateRanges)
+
+	filters, err = ParseTimelineQuery(`range:today,today`)
+	require.NoError(t, err)
+	require.Equal(t, filter.DateValues{filter.DateVal(filter.DateRange{From: midnight, To: endOfDay})}, filters.DateRanges)
+
+	filters, err = ParseTimelineQuery(`after:last-week before:-2d`)
+	require.NoError(t, err)
+	require.Equal(t, now.AddDate(0, 0, -7), *filters.After)
+	require.Equal(t, now.AddDate(0, 0, -2), *filters.Before)
+
+	filters, err = ParseTimelineQuery(`after:TODAY OR before:-1w`)
+	require.NoError(t, err)
+	terms := filters.Expression.Terms()
+	require.Equal(t, midnight, terms[0].DateRange.From)
+	require.Equal(t, now.AddDate(0, 0, -7), terms[1].DateRange.To)
+
+	for _, invalid := range []string{"-24", "24h", "-1y", "yesterday", "-15251w", "+9223372036854775807s", "-99999999999999999999s"} {
+		_, err = ParseTimelineQuery(`after:` + invalid)
+		require.Error(t, err, "value: %v", invalid)
+	}
+}
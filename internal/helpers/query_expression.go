@@ -0,0 +1,386 @@
+package helpers
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/ashirt-ops/ashirt-server/internal/helpers/filter"
+)
+
+// QueryOperator identifies the kind of a QueryExpr node
+type QueryOperator int
+
+const (
+	// QueryTerm nodes are single filters (e.g. tag:web), or plain text
+	QueryTerm QueryOperator = iota
+	// QueryAnd nodes match when all of their children match
+	QueryAnd
+	// QueryOr nodes match when any of their children match
+	QueryOr
+	// QueryNot nodes match when their (only) child does not
+	QueryNot
+)
+
+// QueryExpr is a node in a timeline query that uses boolean logic. For example,
+// `(tag:web OR tag:api) AND !operator:bob` is parsed as:
+//
+//	And(
+//	  Or(Term(tag:web), Term(tag:api)),
+//	  Not(Term(operator:bob)),
+//	)
+type QueryExpr struct {
+	Operator QueryOperator
+	// Children holds the operands of And, Or and Not nodes
+	Children []*QueryExpr
+
+	// Key is the filter key of a Term node ("" for plain text)
+	Key string
+	// Value is the filter value of a Term node. Values negated with the key:!value syntax carry the
+	// filter.Not modifier.
+	Value filter.Value
+	// DateRange is the parsed value of range terms
+	DateRange *filter.DateRange
+
+	// Position is the (1-based) character position of the node within the query
+	Position int
+}
+
+// QuerySyntaxError describes a malformed query, and where the problem was found
+type QuerySyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("%v (at position %v)", e.Message, e.Position)
+}
+
+func syntaxErr(position int, format string, args ...interface{}) *QuerySyntaxError {
+	return &QuerySyntaxError{Position: position, Message: fmt.Sprintf(format, args...)}
+}
+
+type queryTokenKind int
+
+const (
+	tokenTerm queryTokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpenParen
+	tokenCloseParen
+)
+
+type queryToken struct {
+	kind     queryTokenKind
+	position int
+	key      string
+	value    filter.Value
+	text     string // the token as written, for error messages
+}
+
+// lexTimelineQuery splits a query into terms, operators and parentheses. Terms follow the same rules as
+// tokenizeTimelineQuery. In addition:
+//   - AND, OR and NOT (in capitals, and unquoted) are operators
+//   - ( and ) group terms, unless quoted
+//   - ! negates a following group or filter (e.g. !(...) or !tag:web). Plain text is negated with NOT,
+//     as a leading ! on plain text has always been searched for literally.
+func lexTimelineQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	tokens := []queryToken{}
+
+	for pos := 0; pos < len(runes); {
+		char := runes[pos]
+		switch {
+		case unicode.IsSpace(char):
+			pos++
+		case char == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpenParen, position: pos + 1, text: "("})
+			pos++
+		case char == ')':
+			tokens = append(tokens, queryToken{kind: tokenCloseParen, position: pos + 1, text: ")"})
+			pos++
+		case char == '!' && pos+1 < len(runes) && runes[pos+1] == '(':
+			tokens = append(tokens, queryToken{kind: tokenNot, position: pos + 1, text: "!"})
+			pos++
+		case char == '!':
+			term, next, err := lexTerm(runes, pos+1)
+			if err != nil {
+				return nil, err
+			}
+			if term.key != "" {
+				tokens = append(tokens, queryToken{kind: tokenNot, position: pos + 1, text: "!"}, term)
+			} else {
+				term.position = pos + 1
+				term.value.Value = "!" + term.value.Value
+				tokens = append(tokens, term)
+			}
+			pos = next
+		default:
+			term, next, err := lexTerm(runes, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, term)
+			pos = next
+		}
+	}
+	return tokens, nil
+}
+
+// lexTerm reads a single term (or operator) starting at start, returning it along with the position
+// immediately after it
+func lexTerm(runes []rune, start int) (queryToken, int, error) {
+	token := queryToken{kind: tokenTerm, position: start + 1}
+	value := []rune{}
+	hasKey, quoted, inQuote := false, false, false
+	quoteStart := 0
+
+	pos := start
+	for ; pos < len(runes); pos++ {
+		char := runes[pos]
+		if !inQuote && (unicode.IsSpace(char) || char == '(' || char == ')') {
+			break
+		}
+		switch {
+		case char == '"':
+			inQuote = !inQuote
+			quoted = true
+			quoteStart = pos + 1
+			continue
+		case char == ':' && !inQuote && !hasKey:
+			hasKey = true
+			token.key = string(value)
+			value = value[:0]
+			continue
+		case char == '!' && !inQuote && hasKey && len(value) == 0 && token.value.Modifier == filter.Normal:
+			token.value.Modifier = filter.Not
+			continue
+		}
+		value = append(value, char)
+	}
+	if inQuote {
+		return token, pos, syntaxErr(quoteStart, "Missing closing quote")
+	}
+
+	token.value.Value = string(value)
+	token.text = string(runes[start:pos])
+	token.key, token.value = expandBareFlag(token.key, token.value, quoted)
+	if !hasKey && !quoted {
+		switch token.value.Value {
+		case "AND":
+			token.kind = tokenAnd
+		case "OR":
+			token.kind = tokenOr
+		case "NOT":
+			token.kind = tokenNot
+		}
+	}
+	return token, pos, nil
+}
+
+// isPlainQuery returns true if the tokens contain only terms (i.e. the query does not use any
+// boolean logic or grouping)
+func isPlainQuery(tokens []queryToken) bool {
+	for _, token := range tokens {
+		if token.kind != tokenTerm {
+			return false
+		}
+	}
+	return true
+}
+
+// queryParser is a recursive descent parser for the grammar:
+//
+//	expression := and ( "OR" and )*
+//	and        := unary ( ["AND"] unary )*
+//	unary      := ( "NOT" | "!" ) unary | primary
+//	primary    := "(" expression ")" | term
+type queryParser struct {
+	tokens []queryToken
+	next   int
+	// end is the position just past the end of the query, for errors about missing tokens
+	end int
+}
+
+// parseQueryExpression builds an expression tree from the lexed query. Returns nil for an empty query.
+func parseQueryExpression(tokens []queryToken, queryLength int) (*QueryExpr, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &queryParser{tokens: tokens, end: queryLength + 1}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := p.peek(); ok {
+		return nil, syntaxErr(token.position, "Unexpected '%v'", token.text)
+	}
+	return expr, nil
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.next >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.next], true
+}
+
+func (p *queryParser) parseOr() (*QueryExpr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*QueryExpr{first}
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind != tokenOr {
+			break
+		}
+		p.next++
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	return combine(QueryOr, operands), nil
+}
+
+// anyOfKeys are the filters that match if any of their values match, when the values are written side
+// by side (e.g. `tag:web tag:api`), as in plain queries. An explicit AND requires both (e.g.
+// `tag:web AND tag:api`). Other filters, such as plain text, must always all match.
+var anyOfKeys = map[string]bool{
+	"tag":             true,
+	"operator":        true,
+	"uuid":            true,
+	"with-evidence":   true,
+	"type":            true,
+	"host":            true,
+	"status":          true,
+	"url":             true,
+	"finding":         true,
+	"has-metadata":    true,
+	"metadata-status": true,
+	"range":           true,
+}
+
+// parseAnd parses terms joined by AND, whether written or implied. Within each run of implicitly
+// joined terms, (non-negated) anyOfKeys filters that share a key are combined with OR instead.
+func (p *queryParser) parseAnd() (*QueryExpr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	operands := make([]*QueryExpr, 0, 1)
+	// anyOf maps each key within the current run to the index of the operand holding its values
+	anyOf := map[string]int{}
+	addOperand := func(operand *QueryExpr) {
+		if operand.Operator != QueryTerm || operand.Value.Modifier != filter.Normal || !anyOfKeys[operand.Key] {
+			operands = append(operands, operand)
+			return
+		}
+		index, ok := anyOf[operand.Key]
+		if !ok {
+			anyOf[operand.Key] = len(operands)
+			operands = append(operands, operand)
+			return
+		}
+		if existing := operands[index]; existing.Operator == QueryTerm {
+			operands[index] = &QueryExpr{Operator: QueryOr, Children: []*QueryExpr{existing}, Position: existing.Position}
+		}
+		operands[index].Children = append(operands[index].Children, operand)
+	}
+	addOperand(first)
+
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind == tokenOr || token.kind == tokenCloseParen {
+			break
+		}
+		if token.kind == tokenAnd {
+			p.next++
+			anyOf = map[string]int{}
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		addOperand(operand)
+	}
+	return combine(QueryAnd, operands), nil
+}
+
+func (p *queryParser) parseUnary() (*QueryExpr, error) {
+	token, ok := p.peek()
+	if ok && token.kind == tokenNot {
+		p.next++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &QueryExpr{Operator: QueryNot, Children: []*QueryExpr{operand}, Position: token.position}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (*QueryExpr, error) {
+	token, ok := p.peek()
+	if !ok {
+		previous := p.tokens[p.next-1]
+		return nil, syntaxErr(p.end, "Expected a filter after '%v'", previous.text)
+	}
+	p.next++
+
+	switch token.kind {
+	case tokenTerm:
+		return &QueryExpr{Operator: QueryTerm, Key: token.key, Value: token.value, Position: token.position}, nil
+
+	case tokenOpenParen:
+		if next, ok := p.peek(); ok && next.kind == tokenCloseParen {
+			return nil, syntaxErr(next.position, "Parentheses must contain at least one filter")
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenCloseParen {
+			return nil, syntaxErr(token.position, "Missing ')' to close this '('")
+		}
+		p.next++
+		return expr, nil
+
+	default:
+		return nil, syntaxErr(token.position, "Unexpected '%v'", token.text)
+	}
+}
+
+// combine joins the operands with the operator, merging directly nested uses of the same operator
+func combine(operator QueryOperator, operands []*QueryExpr) *QueryExpr {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	expr := &QueryExpr{Operator: operator, Position: operands[0].Position}
+	for _, operand := range operands {
+		if operand.Operator == operator {
+			expr.Children = append(expr.Children, operand.Children...)
+		} else {
+			expr.Children = append(expr.Children, operand)
+		}
+	}
+	return expr
+}
+
+// Terms returns each Term node within the expression, in the order they were written
+func (e *QueryExpr) Terms() []*QueryExpr {
+	if e == nil {
+		return nil
+	}
+	if e.Operator == QueryTerm {
+		return []*QueryExpr{e}
+	}
+	terms := []*QueryExpr{}
+	for _, child := range e.Children {
+		terms = append(terms, child.Terms()...)
+	}
+	return terms
+}
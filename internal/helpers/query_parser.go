@@ -3,6 +3,8 @@ package helpers
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -19,18 +21,90 @@ type TimelineFilters struct {
 	Tags             filter.Values
 	Type             filter.Values
 	Operator         filter.Values
+	Host             filter.Values
+	Status           filter.Values
+	URL              filter.Values
 	DateRanges       filter.DateValues
 	WithEvidenceUUID filter.Values
+	Finding          filter.Values
+	HasMetadata      filter.Values
+	MetadataStatus   filter.Values
+	After            *time.Time
+	Before           *time.Time
 	Linked           *bool
+	Adjusted         *bool
+	CreatedByWorker  *bool
 	SortAsc          bool
+	// Expression holds the filters of queries that use boolean logic or grouping (e.g.
+	// `(tag:web OR tag:api) AND !operator:bob`). When set, the individual filter fields above are left
+	// empty, apart from SortAsc.
+	Expression *QueryExpr
 }
 
+// httpStatusRegex matches HTTP status codes (e.g. 404) and status classes (e.g. 4xx)
+var httpStatusRegex = regexp.MustCompile(`^[1-5]([0-9]{2}|[xX]{2})$`)
+
+// relativeTimeRegex matches times relative to now, e.g. -24h or -2w
+var relativeTimeRegex = regexp.MustCompile(`^([+-][0-9]+)([smhdw])$`)
+
+var relativeTimeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// bareFlags are the filters that may be written without a value (e.g. created-by-worker), which is
+// equivalent to key:true. Quoted text is still searched for literally.
+var bareFlags = map[string]bool{
+	"created-by-worker": true,
+}
+
+// expandBareFlag converts an unquoted bare flag into its key and value. Other terms are returned as-is.
+func expandBareFlag(key string, value filter.Value, quoted bool) (string, filter.Value) {
+	if key != "" || quoted || !bareFlags[value.Value] {
+		return key, value
+	}
+	return value.Value, filter.Value{Value: "true", Modifier: value.Modifier}
+}
+
+// metadataStatuses maps the (lowercase) statuses that evidence metadata can have to their stored form.
+// These mirror evidencemetadata.Status, which cannot be imported here.
+var metadataStatuses = map[string]string{
+	"queued":     "Queued",
+	"processing": "Processing",
+	"completed":  "Completed",
+	"error":      "Error",
+}
+
+// timeNow is used to resolve relative times. Replaced in tests.
+var timeNow = time.Now
+
 // ParseTimelineQuery parses a query a user may type into the search box on the timeline page
 // into a TimelineFilters struct that the events/evidence services expect
 func ParseTimelineQuery(query string) (TimelineFilters, error) {
+	tokens, err := lexTimelineQuery(query)
+	if err != nil {
+		return TimelineFilters{}, errorwrap.BadInputErr(err, err.Error())
+	}
+	if isPlainQuery(tokens) {
+		return parseTimelineFilters(tokenizeTimelineQuery(query))
+	}
+
+	expr, err := parseQueryExpression(tokens, len([]rune(query)))
+	if err != nil {
+		return TimelineFilters{}, errorwrap.BadInputErr(err, err.Error())
+	}
+	return parseTimelineExpression(expr)
+}
+
+// parseTimelineFilters interprets a plain (i.e. not boolean) query, where every term must match,
+// except that multiple values for the same key match if any of them match
+func parseTimelineFilters(tokens map[string]filter.Values) (TimelineFilters, error) {
 	timelineFilters := TimelineFilters{}
 
-	for k, v := range tokenizeTimelineQuery(query) {
+	for k, v := range tokens {
 		switch k {
 		case "":
 			timelineFilters.Text = v.Values()
@@ -54,6 +128,55 @@ func ParseTimelineQuery(query string) (TimelineFilters, error) {
 			timelineFilters.UUID = v
 		case "with-evidence":
 			timelineFilters.WithEvidenceUUID = v
+		case "finding":
+			timelineFilters.Finding = v
+		case "has-metadata":
+			timelineFilters.HasMetadata = v
+		case "metadata-status":
+			for i, status := range v {
+				normalized, err := parseMetadataStatus(status.Value)
+				if err != nil {
+					return timelineFilters, err
+				}
+				v[i].Value = normalized
+			}
+			timelineFilters.MetadataStatus = v
+		case "after", "before":
+			if len(v) != 1 {
+				errReason := fmt.Sprintf("'%s' can only be specified once", k)
+				return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
+			}
+			if v[0].Modifier == filter.Not {
+				errReason := fmt.Sprintf("'%s' cannot be negated. Use '%s' instead", k, oppositeDateKey(k))
+				return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
+			}
+			t, err := parseTime(v.Value(0), k == "before")
+			if err != nil {
+				return timelineFilters, err
+			}
+			if k == "after" {
+				timelineFilters.After = &t
+			} else {
+				timelineFilters.Before = &t
+			}
+		case "adjusted", "created-by-worker":
+			if len(v) != 1 {
+				errReason := fmt.Sprintf("'%s' can only be specified once", k)
+				return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
+			}
+			val, err := strconv.ParseBool(v.Value(0))
+			if err != nil {
+				errReason := fmt.Sprintf("'%s' value must be True or False", k)
+				return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
+			}
+			if v[0].Modifier == filter.Not {
+				val = !val
+			}
+			if k == "adjusted" {
+				timelineFilters.Adjusted = &val
+			} else {
+				timelineFilters.CreatedByWorker = &val
+			}
 		case "linked":
 			if len(v) != 1 {
 				errReason := "Linked can only be specified once"
@@ -73,12 +196,21 @@ func ParseTimelineQuery(query string) (TimelineFilters, error) {
 				errReason := "Only one sorting flag can be specified"
 				return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
 			}
-			direction := strings.ToLower(v.Value(0))
-			if direction == "asc" || direction == "chronological" || direction == "ascending" {
-				timelineFilters.SortAsc = true
-			}
+			timelineFilters.SortAsc = isAscendingSort(v.Value(0))
 		case "type":
 			timelineFilters.Type = v
+		case "host":
+			timelineFilters.Host = v
+		case "status":
+			for _, status := range v {
+				if !httpStatusRegex.MatchString(status.Value) {
+					errReason := fmt.Sprintf("Status must be a status code (e.g. 404) or class (e.g. 4xx). (Got '%s')", status.Value)
+					return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
+				}
+			}
+			timelineFilters.Status = v
+		case "url":
+			timelineFilters.URL = v
 		default:
 			errReason := fmt.Sprintf("Unknown filter key '%s'", k)
 			return timelineFilters, errorwrap.BadInputErr(errors.New(errReason), errReason)
@@ -88,6 +220,124 @@ func ParseTimelineQuery(query string) (TimelineFilters, error) {
 	return timelineFilters, nil
 }
 
+// parseTimelineExpression validates each term of a boolean query. Sorting is not a filter, and so may
+// only be specified alongside the rest of the query (i.e. not within an OR or NOT).
+func parseTimelineExpression(expr *QueryExpr) (TimelineFilters, error) {
+	timelineFilters := TimelineFilters{}
+
+	if expr.Operator == QueryAnd {
+		var sortTerm *QueryExpr
+		filters := make([]*QueryExpr, 0, len(expr.Children))
+		for _, child := range expr.Children {
+			if child.Operator != QueryTerm || child.Key != "sort" {
+				filters = append(filters, child)
+				continue
+			}
+			if sortTerm != nil {
+				return timelineFilters, querySyntaxErr(child, "Only one sorting flag can be specified")
+			}
+			sortTerm = child
+		}
+		if sortTerm != nil {
+			timelineFilters.SortAsc = isAscendingSort(sortTerm.Value.Value)
+			expr = combine(QueryAnd, filters)
+		}
+	}
+
+	for _, term := range expr.Terms() {
+		if err := validateTimelineTerm(term); err != nil {
+			return timelineFilters, err
+		}
+	}
+	timelineFilters.Expression = expr
+	return timelineFilters, nil
+}
+
+// validateTimelineTerm checks the term's key and value, parsing the value where needed
+func validateTimelineTerm(term *QueryExpr) error {
+	switch term.Key {
+	case "", "meta", "tag", "operator", "uuid", "with-evidence", "type", "host", "url", "finding", "has-metadata":
+		if term.Value.Value == "" {
+			return querySyntaxErr(term, "Missing a value for '%v'", term.Key)
+		}
+	case "range":
+		dateRange, err := parseDateRangeString(term.Value.Value)
+		if err != nil {
+			return err
+		}
+		term.DateRange = dateRange
+	case "after", "before":
+		// after uses the start of the given time, and before the end (e.g. the whole of a day)
+		from, err := parseTime(term.Value.Value, false)
+		if err != nil {
+			return err
+		}
+		to, err := parseTime(term.Value.Value, true)
+		if err != nil {
+			return err
+		}
+		term.DateRange = &filter.DateRange{From: from, To: to}
+	case "metadata-status":
+		status, err := parseMetadataStatus(term.Value.Value)
+		if err != nil {
+			return err
+		}
+		term.Value.Value = status
+	case "adjusted", "created-by-worker":
+		val, err := strconv.ParseBool(term.Value.Value)
+		if err != nil {
+			return querySyntaxErr(term, "'%v' value must be True or False", term.Key)
+		}
+		term.Value.Value = strconv.FormatBool(val)
+	case "linked":
+		if strings.ToLower(term.Value.Value) == "all" {
+			term.Value.Value = "all"
+			return nil
+		}
+		val, err := strconv.ParseBool(term.Value.Value)
+		if err != nil {
+			return querySyntaxErr(term, "Linked value must be True or False")
+		}
+		term.Value.Value = strconv.FormatBool(val)
+	case "status":
+		if !httpStatusRegex.MatchString(term.Value.Value) {
+			return querySyntaxErr(term, "Status must be a status code (e.g. 404) or class (e.g. 4xx). (Got '%s')", term.Value.Value)
+		}
+	case "sort":
+		return querySyntaxErr(term, "Sorting cannot be combined with OR or NOT")
+	default:
+		return querySyntaxErr(term, "Unknown filter key '%s'", term.Key)
+	}
+	return nil
+}
+
+func querySyntaxErr(term *QueryExpr, format string, args ...interface{}) error {
+	err := syntaxErr(term.Position, format, args...)
+	return errorwrap.BadInputErr(err, err.Error())
+}
+
+// parseMetadataStatus returns the properly cased version of a metadata status (e.g. error => Error)
+func parseMetadataStatus(status string) (string, error) {
+	normalized, ok := metadataStatuses[strings.ToLower(status)]
+	if !ok {
+		errReason := fmt.Sprintf("Metadata status must be one of Queued, Processing, Completed or Error. (Got '%s')", status)
+		return "", errorwrap.BadInputErr(errors.New(errReason), errReason)
+	}
+	return normalized, nil
+}
+
+func oppositeDateKey(key string) string {
+	if key == "after" {
+		return "before"
+	}
+	return "after"
+}
+
+func isAscendingSort(direction string) bool {
+	direction = strings.ToLower(direction)
+	return direction == "asc" || direction == "chronological" || direction == "ascending"
+}
+
 // Parses the raw query string into a map.
 //
 // Examples:
@@ -112,7 +362,7 @@ func tokenizeTimelineQuery(query string) map[string]filter.Values {
 	parsed := map[string]filter.Values{}
 	modifier := filter.Normal
 	currentToken := ""
-	inQuote := false
+	inQuote, quoted := false, false
 	currentKey := ""
 
 	for _, char := range query {
@@ -120,16 +370,18 @@ func tokenizeTimelineQuery(query string) map[string]filter.Values {
 		case ' ':
 			if !inQuote {
 				if len(currentToken) > 0 {
-					filterValue := filter.Value{Value: currentToken, Modifier: modifier}
-					parsed[currentKey] = append(parsed[currentKey], filterValue)
+					key, filterValue := expandBareFlag(currentKey, filter.Value{Value: currentToken, Modifier: modifier}, quoted)
+					parsed[key] = append(parsed[key], filterValue)
 				}
 				currentToken = ""
 				currentKey = ""
 				modifier = filter.Normal
+				quoted = false
 				continue
 			}
 		case '"':
 			inQuote = !inQuote
+			quoted = true
 			continue
 		case ':':
 			if currentKey == "" {
@@ -148,8 +400,8 @@ func tokenizeTimelineQuery(query string) map[string]filter.Values {
 		currentToken += string(char)
 	}
 	if len(currentToken) > 0 {
-		filterValue := filter.Value{Value: currentToken, Modifier: modifier}
-		parsed[currentKey] = append(parsed[currentKey], filterValue)
+		key, filterValue := expandBareFlag(currentKey, filter.Value{Value: currentToken, Modifier: modifier}, quoted)
+		parsed[key] = append(parsed[key], filterValue)
 	}
 	return parsed
 }
@@ -183,7 +435,20 @@ func parseDateRangeString(dateRange string) (*filter.DateRange, error) {
 	return &filter.DateRange{From: from, To: to}, nil
 }
 
+// parseTime parses absolute times (RFC3339 or YYYY-MM-DD) and times relative to now (see
+// parseRelativeTime)
 func parseTime(str string, useEndOfDayIfTimeIsMissing bool) (time.Time, error) {
+	start, end, ok, err := parseRelativeTime(str, timeNow().UTC())
+	if err != nil {
+		return time.Now(), err
+	}
+	if ok {
+		if useEndOfDayIfTimeIsMissing {
+			return end, nil
+		}
+		return start, nil
+	}
+
 	t, rfc3339Err := time.Parse(time.RFC3339, str)
 	if rfc3339Err == nil {
 		return t, nil
@@ -199,6 +464,42 @@ func parseTime(str string, useEndOfDayIfTimeIsMissing bool) (time.Time, error) {
 
 	return time.Now(), errorwrap.BadInputErr(
 		fmt.Errorf("Failed to parse time. (RFC3339: %v) (ISO8601: %v)", rfc3339Err.Error(), iso8601Err.Error()),
-		fmt.Sprintf("Query ranges must be in ISO8601 or RFC3339 format. (Got '%s')", str),
+		fmt.Sprintf("Query times must be in ISO8601 or RFC3339 format, or relative to now (e.g. -24h, now, today or last-week). (Got '%s')", str),
 	)
 }
+
+// parseRelativeTime interprets times relative to now. Like dates, some of these cover a span of time,
+// so both the start and end of the span are returned. Supported values:
+//   - now
+//   - today: from midnight (UTC) until the end of the day
+//   - last-week: 7 days ago
+//   - an offset from now, in seconds, minutes, hours, days or weeks (e.g. -30m, -24h, -2w)
+//
+// Returns false if the value is not a relative time, and an error for offsets too large to represent.
+func parseRelativeTime(str string, now time.Time) (time.Time, time.Time, bool, error) {
+	switch strings.ToLower(str) {
+	case "now":
+		return now, now, true, nil
+	case "today":
+		midnight := now.Truncate(24 * time.Hour)
+		return midnight, midnight.Add(23*time.Hour + 59*time.Minute + 59*time.Second), true, nil
+	case "last-week":
+		t := now.AddDate(0, 0, -7)
+		return t, t, true, nil
+	}
+
+	match := relativeTimeRegex.FindStringSubmatch(str)
+	if match == nil {
+		return now, now, false, nil
+	}
+	unit := relativeTimeUnits[match[2]]
+	amount, parseErr := strconv.ParseInt(match[1], 10, 64)
+	if parseErr != nil || amount > math.MaxInt64/int64(unit) || amount < math.MinInt64/int64(unit) {
+		return now, now, false, errorwrap.BadInputErr(
+			fmt.Errorf("Relative time out of range: %v", str),
+			fmt.Sprintf("Relative times must be within roughly 290 years of now. (Got '%s')", str),
+		)
+	}
+	t := now.Add(time.Duration(amount) * unit)
+	return t, t, true, nil
+}
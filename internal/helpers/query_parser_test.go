@@ -123,6 +123,16 @@ func TestParseTimelineQuery(t *testing.T) {
 		Type: filter.Values{filter.Val("image"), filter.Val("codeblock")},
 	})
 
+	testTimelineQueryCase(t, `host:api.client.com host:!cdn.client.com`, helpers.TimelineFilters{
+		Host: filter.Values{filter.Val("api.client.com"), filter.NotVal("cdn.client.com")},
+	})
+	testTimelineQueryCase(t, `status:404 status:5xx`, helpers.TimelineFilters{
+		Status: filter.Values{filter.Val("404"), filter.Val("5xx")},
+	})
+	testTimelineQueryCase(t, `url:/api/login url:!"/static/"`, helpers.TimelineFilters{
+		URL: filter.Values{filter.Val("/api/login"), filter.NotVal("/static/")},
+	})
+
 	True := true
 	False := false
 	testTimelineQueryCase(t, `linked:true`, helpers.TimelineFilters{
@@ -151,10 +161,112 @@ func TestParseTimelineQuery(t *testing.T) {
 		SortAsc: false,
 	})
 
+	testTimelineQueryCase(t, `finding:"SQL Injection" finding:!find-uuid`, helpers.TimelineFilters{
+		Finding: filter.Values{filter.Val("SQL Injection"), filter.NotVal("find-uuid")},
+	})
+	testTimelineQueryCase(t, `has-metadata:ocr metadata-status:error`, helpers.TimelineFilters{
+		HasMetadata:    filter.Values{filter.Val("ocr")},
+		MetadataStatus: filter.Values{filter.Val("Error")},
+	})
+	testTimelineQueryCase(t, `adjusted:true created-by-worker:!true`, helpers.TimelineFilters{
+		Adjusted:        helpers.PTrue(),
+		CreatedByWorker: helpers.PFalse(),
+	})
+	testTimelineQueryCase(t, `created-by-worker spiders`, helpers.TimelineFilters{
+		Text:            []string{"spiders"},
+		CreatedByWorker: helpers.PTrue(),
+	})
+	testTimelineQueryCase(t, `"created-by-worker"`, helpers.TimelineFilters{
+		Text: []string{"created-by-worker"},
+	})
+	after := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2019, 8, 5, 23, 59, 59, 0, time.UTC)
+	testTimelineQueryCase(t, `after:2019-05-01 before:2019-08-05`, helpers.TimelineFilters{
+		After:  &after,
+		Before: &before,
+	})
+
 	testTimelineQueryExpectErr(t, `invalid keys cause error invalid:value`)
 	testTimelineQueryExpectErr(t, `multiple linked          cause error linked:all linked:true`)
 	testTimelineQueryExpectErr(t, `multiple sort_directions cause error sort:desc sort:asc`)
 	testTimelineQueryExpectErr(t, `unparsable bool/not all  cause error linked:maybe`)
+	testTimelineQueryExpectErr(t, `invalid status causes error status:ok`)
+	testTimelineQueryExpectErr(t, `invalid status class causes error status:6xx`)
 	testTimelineQueryExpectErr(t, `unparsable date cause error range:2021-01-01,2021-02-31`)
 	testTimelineQueryExpectErr(t, `unparsable date cause error (alt) range:2021-01-01`)
+	testTimelineQueryExpectErr(t, `unparsable relative date cause error range:-24x,now`)
+	testTimelineQueryExpectErr(t, `multiple afters cause error after:today after:-1h`)
+	testTimelineQueryExpectErr(t, `negated after causes error after:!today`)
+	testTimelineQueryExpectErr(t, `invalid metadata status causes error metadata-status:broken`)
+	testTimelineQueryExpectErr(t, `unparsable bool causes error adjusted:maybe`)
+}
+
+func TestParseTimelineQueryExpression(t *testing.T) {
+	filters, err := helpers.ParseTimelineQuery(`(tag:web OR tag:api) AND !operator:bob sort:asc`)
+	require.NoError(t, err)
+	require.True(t, filters.SortAsc)
+	require.Nil(t, filters.Tags, "boolean queries only populate the expression")
+	require.Equal(t, helpers.QueryAnd, filters.Expression.Operator)
+	require.Len(t, filters.Expression.Children, 2, "sorting is removed from the expression")
+
+	filters, err = helpers.ParseTimelineQuery(`range:2019-05-01,2019-08-05 OR linked:TRUE`)
+	require.NoError(t, err)
+	terms := filters.Expression.Terms()
+	before := time.Date(2019, 8, 5, 23, 59, 59, 0, time.UTC)
+	require.Equal(t, &filter.DateRange{
+		From: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+		To:   before,
+	}, terms[0].DateRange)
+	require.Equal(t, "true", terms[1].Value.Value)
+
+	filters, err = helpers.ParseTimelineQuery(`metadata-status:COMPLETED OR (has-metadata:ocr AND !adjusted:yes)`)
+	require.ErrorContains(t, err, "'adjusted' value must be True or False")
+	filters, err = helpers.ParseTimelineQuery(`metadata-status:COMPLETED OR (has-metadata:ocr AND !adjusted:t) OR before:2019-08-05`)
+	require.NoError(t, err)
+	terms = filters.Expression.Terms()
+	require.Equal(t, "Completed", terms[0].Value.Value)
+	require.Equal(t, "true", terms[2].Value.Value)
+	require.Equal(t, before, terms[3].DateRange.To)
+
+	filters, err = helpers.ParseTimelineQuery(`tag:web OR !created-by-worker`)
+	require.NoError(t, err)
+	require.Equal(t, helpers.QueryNot, filters.Expression.Children[1].Operator)
+	worker := filters.Expression.Children[1].Children[0]
+	require.Equal(t, "created-by-worker", worker.Key)
+	require.Equal(t, "true", worker.Value.Value)
+
+	// repeated filters next to each other match any of their values, as they do in plain queries
+	filters, err = helpers.ParseTimelineQuery(`tag:web operator:bob tag:api OR type:image`)
+	require.NoError(t, err)
+	require.Equal(t, helpers.QueryOr, filters.Expression.Operator)
+	and := filters.Expression.Children[0]
+	require.Equal(t, helpers.QueryAnd, and.Operator)
+	require.Len(t, and.Children, 2)
+	require.Equal(t, helpers.QueryOr, and.Children[0].Operator)
+	require.Equal(t, []string{"web", "api"}, []string{and.Children[0].Children[0].Value.Value, and.Children[0].Children[1].Value.Value})
+	require.Equal(t, "operator", and.Children[1].Key)
+
+	filters, err = helpers.ParseTimelineQuery(`(tag:web tag:api) AND tag:db`)
+	require.NoError(t, err)
+	require.Equal(t, helpers.QueryAnd, filters.Expression.Operator)
+	require.Equal(t, helpers.QueryOr, filters.Expression.Children[0].Operator)
+	require.Equal(t, "db", filters.Expression.Children[1].Value.Value)
+
+	// but explicit ANDs, negated filters and text must all match
+	for _, query := range []string{`tag:web AND tag:api`, `(tag:!web tag:!api)`, `(web api)`, `(meta:web meta:api)`} {
+		filters, err = helpers.ParseTimelineQuery(query)
+		require.NoError(t, err)
+		require.Equal(t, helpers.QueryAnd, filters.Expression.Operator, query)
+		require.Len(t, filters.Expression.Children, 2, query)
+	}
+
+	// errors report where the problem is
+	_, err = helpers.ParseTimelineQuery(`tag:web OR bogus:value`)
+	require.EqualError(t, err, "Unknown filter key 'bogus' (at position 12)")
+	_, err = helpers.ParseTimelineQuery(`tag:web OR (status:6xx)`)
+	require.ErrorContains(t, err, "(at position 13)")
+	_, err = helpers.ParseTimelineQuery(`tag:web OR sort:asc`)
+	require.ErrorContains(t, err, "Sorting cannot be combined with OR or NOT")
+	_, err = helpers.ParseTimelineQuery(`(tag:web OR tag:api`)
+	require.EqualError(t, err, "Missing ')' to close this '(' (at position 1)")
 }
@@ -239,6 +239,7 @@ func (b *RequestBuilder) Do() *ResponseTester {
 	}
 
 	if b.apiKey != nil {
+		require.NoError(b.t, signer.AddNonce(b.req))
 		authorization, err := signer.BuildClientRequestAuthorization(b.req, b.apiKey.AccessKey, b.apiKey.SecretKey)
 		require.NoError(b.t, err)
 		b.req.Header.Set("Authorization", authorization)
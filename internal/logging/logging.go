@@ -10,6 +10,7 @@ import (
 )
 
 var requestLoggerKey = &struct{ name string }{"requestLogger"}
+var requestIDKey = &struct{ name string }{"requestID"}
 
 var systemLogger *slog.Logger
 
@@ -52,9 +53,18 @@ func ReqLogger(ctx context.Context) *slog.Logger {
 func AddRequestLogger(ctx context.Context, baseLogger *slog.Logger) (context.Context, *slog.Logger) {
 	requestUUID, _ := uuid.NewRandom()
 	reqLogger := baseLogger.With("ctx", requestUUID.String())
+	ctx = context.WithValue(ctx, requestIDKey, requestUUID.String())
 	return context.WithValue(ctx, requestLoggerKey, reqLogger), reqLogger
 }
 
+// RequestID retrieves the unique identifier assigned to this request by AddRequestLogger. This
+// matches the "ctx" field written by the request logger. Returns an empty string if no identifier
+// has been assigned.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
 // Fatal is an effective copy of go's log.Fatal, but using the logger provided, rather than
 // using go's native logging. After writing the message, the code will exit with code 1
 func Fatal(logger *slog.Logger, msg string, keyvals ...interface{}) {
@@ -15,8 +15,20 @@ type APIKey struct {
 	AccessKey string     `db:"access_key"`
 	SecretKey []byte     `db:"secret_key"`
 	LastAuth  *time.Time `db:"last_auth"`
-	CreatedAt time.Time  `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	// RestrictOperations is true if the key may only access the operations listed in api_key_operations
+	RestrictOperations bool       `db:"restrict_operations"`
+	Scope              string     `db:"scope"`
+	CreatedAt          time.Time  `db:"created_at"`
+	UpdatedAt          *time.Time `db:"updated_at"`
+}
+
+// APIKeyOperation reflects the structure of the database table 'api_key_operations'
+type APIKeyOperation struct {
+	APIKeyID    int64      `db:"api_key_id"`
+	OperationID int64      `db:"operation_id"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
 }
 
 // Finding reflects the structure of the database table 'findings'
@@ -43,6 +55,8 @@ type Evidence struct {
 	ContentType   string     `db:"content_type"`
 	FullImageKey  string     `db:"full_image_key"`
 	ThumbImageKey string     `db:"thumb_image_key"`
+	ContentHash   *string    `db:"content_hash"`
+	ContentSize   *int64     `db:"content_size"`
 	OccurredAt    time.Time  `db:"occurred_at"`
 	CreatedAt     time.Time  `db:"created_at"`
 	AdjustedAt    *time.Time `db:"adjusted_at"`
@@ -81,11 +95,12 @@ type TagEvidenceMap struct {
 
 // Operation reflects the structure of the database table 'operations'
 type Operation struct {
-	ID        int64      `db:"id"`
-	Slug      string     `db:"slug"`
-	Name      string     `db:"name"`
-	CreatedAt time.Time  `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"`
+	ID           int64      `db:"id"`
+	Slug         string     `db:"slug"`
+	Name         string     `db:"name"`
+	StorageQuota *int64     `db:"storage_quota"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
 }
 
 // Tag reflects the structure of the database table 'tags'
@@ -200,15 +215,16 @@ type Session struct {
 
 // QueuedEmail reflects the structure of the database table 'email_queue'
 type QueuedEmail struct {
-	ID          int64      `db:"id"`
-	ToEmail     string     `db:"to_email"`
-	UserID      int64      `db:"user_id"`
-	Template    string     `db:"template"`
-	EmailStatus string     `db:"email_status"`
-	ErrorCount  int64      `db:"error_count"`
-	ErrorText   *string    `db:"error_text"`
-	CreatedAt   time.Time  `db:"created_at"`
-	UpdatedAt   *time.Time `db:"updated_at"`
+	ID           int64      `db:"id"`
+	ToEmail      string     `db:"to_email"`
+	UserID       int64      `db:"user_id"`
+	Template     string     `db:"template"`
+	TemplateData *string    `db:"template_data"`
+	EmailStatus  string     `db:"email_status"`
+	ErrorCount   int64      `db:"error_count"`
+	ErrorText    *string    `db:"error_text"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
 }
 
 type FindingCategory struct {
@@ -264,3 +280,148 @@ type VarOperationMap struct {
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   *time.Time `db:"updated_at"`
 }
+
+// AuditEvent reflects the structure of the database table 'audit_events'
+type AuditEvent struct {
+	ID          int64     `db:"id"`
+	ActorID     *int64    `db:"actor_id"`
+	Action      string    `db:"action"`
+	TargetType  string    `db:"target_type"`
+	Target      string    `db:"target"`
+	OperationID *int64    `db:"operation_id"`
+	BeforeState *string   `db:"before_state"`
+	AfterState  *string   `db:"after_state"`
+	RequestID   *string   `db:"request_id"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// ContentReference reflects the structure of the database table 'content_references'
+type ContentReference struct {
+	ID         int64      `db:"id"`
+	ContentKey string     `db:"content_key"`
+	RefCount   int64      `db:"ref_count"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at"`
+}
+
+// ContentIssue reflects the structure of the database table 'content_issues'
+type ContentIssue struct {
+	ID             int64      `db:"id"`
+	IssueType      string     `db:"issue_type"`
+	ContentKey     string     `db:"content_key"`
+	EvidenceID     *int64     `db:"evidence_id"`
+	FirstSeenAt    time.Time  `db:"first_seen_at"`
+	LastSeenAt     time.Time  `db:"last_seen_at"`
+	DeleteAttempts int64      `db:"delete_attempts"`
+	LastError      *string    `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      *time.Time `db:"updated_at"`
+}
+
+// HarEntry reflects the structure of the database table 'har_entries'
+type HarEntry struct {
+	ID         int64     `db:"id"`
+	EvidenceID int64     `db:"evidence_id"`
+	EntryIndex int64     `db:"entry_index"`
+	Method     string    `db:"method"`
+	URL        string    `db:"url"`
+	Host       string    `db:"host"`
+	Status     int64     `db:"status"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// ServiceWorkerJob reflects the structure of the database table 'service_worker_jobs'
+type ServiceWorkerJob struct {
+	ID         int64      `db:"id"`
+	WorkerName string     `db:"worker_name"`
+	EventType  string     `db:"event_type"`
+	EvidenceID *int64     `db:"evidence_id"`
+	Payload    *string    `db:"payload"`
+	Status     string     `db:"status"`
+	Attempts   int64      `db:"attempts"`
+	RunAfter   time.Time  `db:"run_after"`
+	StartedAt  *time.Time `db:"started_at"`
+	LastError  *string    `db:"last_error"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at"`
+}
+
+// ServiceWorkerCallback reflects the structure of the database table 'service_worker_callbacks'
+type ServiceWorkerCallback struct {
+	ID         int64      `db:"id"`
+	AccessKey  string     `db:"access_key"`
+	SecretKey  []byte     `db:"secret_key"`
+	EvidenceID int64      `db:"evidence_id"`
+	WorkerName string     `db:"worker_name"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at"`
+}
+
+// Webhook reflects the structure of the database table 'webhooks'
+type Webhook struct {
+	ID                  int64      `db:"id"`
+	OperationID         *int64     `db:"operation_id"`
+	Name                string     `db:"name"`
+	URL                 string     `db:"url"`
+	Events              string     `db:"events"`
+	AccessKey           string     `db:"access_key"`
+	SecretKey           []byte     `db:"secret_key"`
+	ConsecutiveFailures int64      `db:"consecutive_failures"`
+	DisabledAt          *time.Time `db:"disabled_at"`
+	CreatedAt           time.Time  `db:"created_at"`
+	UpdatedAt           *time.Time `db:"updated_at"`
+}
+
+// WebhookDelivery reflects the structure of the database table 'webhook_deliveries'
+type WebhookDelivery struct {
+	ID          int64      `db:"id"`
+	UUID        string     `db:"uuid"`
+	WebhookID   int64      `db:"webhook_id"`
+	EventType   string     `db:"event_type"`
+	Payload     string     `db:"payload"`
+	Status      string     `db:"status"`
+	Attempts    int64      `db:"attempts"`
+	StatusCode  *int64     `db:"status_code"`
+	LastError   *string    `db:"last_error"`
+	RunAfter    time.Time  `db:"run_after"`
+	StartedAt   *time.Time `db:"started_at"`
+	DeliveredAt *time.Time `db:"delivered_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+}
+
+// SearchIndexEntry reflects the structure of the database table 'search_index'
+type SearchIndexEntry struct {
+	ID           int64      `db:"id"`
+	OperationID  int64      `db:"operation_id"`
+	DocumentType string     `db:"document_type"`
+	DocumentID   int64      `db:"document_id"`
+	Field        string     `db:"field"`
+	Content      string     `db:"content"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
+}
+
+// QuerySubscription reflects the structure of the database table 'query_subscriptions'
+type QuerySubscription struct {
+	ID        int64      `db:"id"`
+	QueryID   int64      `db:"query_id"`
+	UserID    int64      `db:"user_id"`
+	LastRunAt time.Time  `db:"last_run_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// QueryDigestSchedule reflects the structure of the database table 'query_digest_schedules'
+type QueryDigestSchedule struct {
+	ID          int64      `db:"id"`
+	UserID      int64      `db:"user_id"`
+	Frequency   string     `db:"frequency"`
+	SendHour    int64      `db:"send_hour"`
+	SendWeekday int64      `db:"send_weekday"`
+	LastSentAt  *time.Time `db:"last_sent_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+}
@@ -61,6 +61,9 @@ func (o *Operation) Check(permission Permission) bool {
 		return o.hasRole(p.OperationID, OperationRoleAdmin)
 	case CanDeleteOpVars:
 		return o.hasRole(p.OperationID, OperationRoleAdmin)
+
+	case CanModifyWebhooksOfOperation:
+		return o.hasRole(p.OperationID, OperationRoleAdmin)
 	}
 
 	return false
@@ -48,3 +48,5 @@ type CanCreateOpVars struct{ OperationID int64 }
 type CanViewOpVars struct{ OperationID int64 }
 type CanModifyOpVars struct{ OperationID int64 }
 type CanDeleteOpVars struct{ OperationID int64 }
+
+type CanModifyWebhooksOfOperation struct{ OperationID int64 }
@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+)
+
+// APIKeyScope limits the actions that may be performed with an API key
+type APIKeyScope = string
+
+const (
+	// APIKeyScopeFull allows every action the key's user may perform
+	APIKeyScopeFull APIKeyScope = "full"
+	// APIKeyScopeEvidenceWrite allows reading operations, and creating/editing evidence and tags
+	APIKeyScopeEvidenceWrite APIKeyScope = "evidence-write"
+	// APIKeyScopeReadOnly allows reading operations only
+	APIKeyScopeReadOnly APIKeyScope = "read-only"
+)
+
+// APIKeyScopes lists every supported APIKeyScope
+var APIKeyScopes = []APIKeyScope{APIKeyScopeFull, APIKeyScopeEvidenceWrite, APIKeyScopeReadOnly}
+
+// Restricted Policy
+// Grants a permission only if the underlying policy grants it, and the permission falls within the
+// allowed operations and scope. A nil OperationIDs allows every operation.
+//
+// Permissions that do not refer to an operation are limited to reading user details, so a restricted
+// policy can never be used to create operations, or to create (unrestricted) API keys.
+type Restricted struct {
+	P            Policy
+	OperationIDs []int64
+	Scope        APIKeyScope
+}
+
+func (r *Restricted) String() string {
+	return fmt.Sprintf("RestrictedPolicy(scope:%s, operations:%v, %s)", r.Scope, r.OperationIDs, r.P.String())
+}
+
+// Check performs the underlying policy check, then verifies the permission is within the restriction
+func (r *Restricted) Check(permission Permission) bool {
+	if !r.P.Check(permission) {
+		return false
+	}
+
+	operationID, ok := operationOf(permission)
+	if !ok {
+		switch permission.(type) {
+		case CanReadUser, CanReadDetailedUser, CanListAPIKeys:
+			return true
+		}
+		return false
+	}
+	if r.OperationIDs != nil && !slices.Contains(r.OperationIDs, operationID) {
+		return false
+	}
+
+	switch permission.(type) {
+	case CanReadOperation, CanListUsersOfOperation, CanListUserGroupsOfOperation, CanExportOperationData, CanViewOpVars:
+		return true
+	case CanModifyEvidenceOfOperation, CanModifyTagsOfOperation:
+		return r.Scope == APIKeyScopeEvidenceWrite || r.Scope == APIKeyScopeFull
+	}
+	return r.Scope == APIKeyScopeFull
+}
+
+// operationOf returns the operation a permission refers to, if any
+func operationOf(permission Permission) (int64, bool) {
+	switch p := permission.(type) {
+	case CanListUsersOfOperation:
+		return p.OperationID, true
+	case CanModifyFindingsOfOperation:
+		return p.OperationID, true
+	case CanModifyEvidenceOfOperation:
+		return p.OperationID, true
+	case CanModifyOperation:
+		return p.OperationID, true
+	case CanModifyQueriesOfOperation:
+		return p.OperationID, true
+	case CanModifyTagsOfOperation:
+		return p.OperationID, true
+	case CanReadOperation:
+		return p.OperationID, true
+	case CanDeleteOperation:
+		return p.OperationID, true
+	case CanModifyUserOfOperation:
+		return p.OperationID, true
+	case CanListUserGroupsOfOperation:
+		return p.OperationID, true
+	case CanExportOperationData:
+		return p.OperationID, true
+	case CanModifyUserGroupOfOperation:
+		return p.OperationID, true
+	case CanCreateOpVars:
+		return p.OperationID, true
+	case CanViewOpVars:
+		return p.OperationID, true
+	case CanModifyOpVars:
+		return p.OperationID, true
+	case CanDeleteOpVars:
+		return p.OperationID, true
+	case CanModifyWebhooksOfOperation:
+		return p.OperationID, true
+	}
+	return 0, false
+}
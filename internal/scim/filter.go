@@ -0,0 +1,281 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Resource is implemented by resources that can be filtered
+type Resource interface {
+	// Values returns the (string formatted) values of the named attribute. Attribute names are
+	// case-insensitive, and sub-attributes are named with a dot (e.g. name.givenName)
+	Values(attribute string) []string
+}
+
+// Filter is a parsed SCIM filter expression (RFC 7644, section 3.4.2.2)
+type Filter interface {
+	Matches(r Resource) bool
+}
+
+// ParseFilter parses a SCIM filter expression. Attribute comparisons (eq, ne, co, sw, ew, gt, ge, lt,
+// le and pr), the logical operators and, or and not, and grouping with parentheses are supported.
+// Comparisons are case-insensitive. An empty expression produces a filter that matches everything.
+func ParseFilter(expression string) (Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return matchAll{}, nil
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+type matchAll struct{}
+
+func (matchAll) Matches(Resource) bool { return true }
+
+type logicalFilter struct {
+	and         bool
+	left, right Filter
+}
+
+func (f logicalFilter) Matches(r Resource) bool {
+	if f.and {
+		return f.left.Matches(r) && f.right.Matches(r)
+	}
+	return f.left.Matches(r) || f.right.Matches(r)
+}
+
+type notFilter struct {
+	f Filter
+}
+
+func (f notFilter) Matches(r Resource) bool { return !f.f.Matches(r) }
+
+type comparisonFilter struct {
+	attribute string
+	operator  string
+	value     string
+}
+
+func (f comparisonFilter) Matches(r Resource) bool {
+	values := r.Values(f.attribute)
+	if f.operator == "pr" {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if f.operator == "ne" {
+		return !(comparisonFilter{f.attribute, "eq", f.value}).Matches(r)
+	}
+
+	for _, v := range values {
+		v = strings.ToLower(v)
+		var matched bool
+		switch f.operator {
+		case "eq":
+			matched = v == f.value
+		case "co":
+			matched = strings.Contains(v, f.value)
+		case "sw":
+			matched = strings.HasPrefix(v, f.value)
+		case "ew":
+			matched = strings.HasSuffix(v, f.value)
+		case "gt":
+			matched = v > f.value
+		case "ge":
+			matched = v >= f.value
+		case "lt":
+			matched = v < f.value
+		case "le":
+			matched = v <= f.value
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+var comparisonOperators = []string{"eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le"}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func (t filterToken) is(word string) bool {
+	return !t.quoted && strings.EqualFold(t.text, word)
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) next() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, true
+}
+
+func (p *filterParser) peekIs(word string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].is(word)
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekIs("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekIs("and") {
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseTerm() (Filter, error) {
+	t, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	switch {
+	case t.is("not"):
+		if !p.peekIs("(") {
+			return nil, fmt.Errorf("expected ( after not")
+		}
+		f, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{f}, nil
+
+	case t.is("("):
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.next(); !ok || !closing.is(")") {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		return f, nil
+
+	case t.quoted:
+		return nil, fmt.Errorf("expected an attribute name, but found %q", t.text)
+	}
+
+	attribute := attributeName(t.text)
+	op, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("missing operator after %q", t.text)
+	}
+	if op.is("pr") {
+		return comparisonFilter{attribute: attribute, operator: "pr"}, nil
+	}
+	operator := strings.ToLower(op.text)
+	if op.quoted || !containsString(comparisonOperators, operator) {
+		return nil, fmt.Errorf("unsupported filter operator %q", op.text)
+	}
+	value, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("missing value after %q %q", t.text, op.text)
+	}
+	if !value.quoted && value.is("null") {
+		value.text = ""
+	}
+	return comparisonFilter{attribute: attribute, operator: operator, value: strings.ToLower(value.text)}, nil
+}
+
+// attributeName strips any schema URN prefix from an attribute path
+// (e.g. urn:ietf:params:scim:schemas:core:2.0:User:userName becomes userName)
+func attributeName(path string) string {
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		return path[strings.LastIndex(path, ":")+1:]
+	}
+	return path
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(' || c == ')':
+			tokens = append(tokens, filterToken{text: string(c)})
+			i++
+
+		case c == '"':
+			end := i + 1
+			for ; end < len(expression) && expression[end] != '"'; end++ {
+				if expression[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(expression[i:end+1]), &value); err != nil {
+				return nil, fmt.Errorf("invalid string in filter: %w", err)
+			}
+			tokens = append(tokens, filterToken{text: value, quoted: true})
+			i = end + 1
+
+		default:
+			end := i
+			for ; end < len(expression) && !strings.ContainsRune(" \t\n\r()\"", rune(expression[end])); end++ {
+			}
+			tokens = append(tokens, filterToken{text: expression[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
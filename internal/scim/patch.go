@@ -0,0 +1,321 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PatchRequest is the body of a PATCH request (RFC 7644, section 3.5.2)
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is a single modification within a PatchRequest
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Path is a parsed PATCH attribute path, such as members[value eq "abc"] or emails[type eq "work"].value
+type Path struct {
+	// Attribute is the lowercased attribute name, without any schema URN (e.g. name.givenname)
+	Attribute string
+	// Filter selects values of a multi-valued attribute. Nil when no filter is provided.
+	Filter Filter
+	// SubAttribute is the lowercased sub-attribute following a filter, if any
+	SubAttribute string
+}
+
+// ParsePath parses a PATCH attribute path
+func ParsePath(path string) (Path, error) {
+	head, rest, hasFilter := strings.Cut(path, "[")
+	p := Path{Attribute: strings.ToLower(attributeName(strings.TrimSpace(head)))}
+	if p.Attribute == "" {
+		return Path{}, fmt.Errorf("invalid path %q", path)
+	}
+	if !hasFilter {
+		return p, nil
+	}
+
+	end := strings.LastIndex(rest, "]")
+	if end == -1 {
+		return Path{}, fmt.Errorf("missing ] in path %q", path)
+	}
+	filter, err := ParseFilter(rest[:end])
+	if err != nil {
+		return Path{}, fmt.Errorf("invalid filter in path %q: %w", path, err)
+	}
+	p.Filter = filter
+
+	switch after := rest[end+1:]; {
+	case after == "":
+	case strings.HasPrefix(after, ".") && len(after) > 1:
+		p.SubAttribute = strings.ToLower(after[1:])
+	default:
+		return Path{}, fmt.Errorf("invalid path %q", path)
+	}
+	return p, nil
+}
+
+// Values returns the values of the named sub-attribute, for use with Filter.Matches
+func (v MultiValue) Values(attribute string) []string {
+	switch strings.ToLower(attribute) {
+	case "value":
+		return []string{v.Value}
+	case "display":
+		return []string{v.Display}
+	case "type":
+		return []string{v.Type}
+	case "primary":
+		return []string{strconv.FormatBool(v.Primary)}
+	}
+	return nil
+}
+
+// ApplyPatch applies the PATCH operations to the user. Attributes not represented by User (such as
+// externalId, or extension attributes) are ignored.
+func (u *User) ApplyPatch(operations []PatchOperation) error {
+	return applyOperations(operations, u.applyOperation)
+}
+
+func (u *User) applyOperation(op string, path Path, value json.RawMessage) error {
+	remove := op == "remove"
+	var err error
+
+	switch path.Attribute {
+	case "active":
+		if remove {
+			u.Active = nil
+			return nil
+		}
+		var active bool
+		active, err = decodeBool(value)
+		u.Active = &active
+	case "username":
+		if remove {
+			return fmt.Errorf("userName cannot be removed")
+		}
+		u.UserName, err = decodeString(value)
+	case "displayname":
+		u.DisplayName, err = decodeStringUnlessRemoved(remove, value)
+	case "name":
+		if remove {
+			u.Name = Name{}
+			return nil
+		}
+		err = json.Unmarshal(value, &u.Name)
+	case "name.givenname":
+		u.Name.GivenName, err = decodeStringUnlessRemoved(remove, value)
+	case "name.familyname":
+		u.Name.FamilyName, err = decodeStringUnlessRemoved(remove, value)
+	case "name.formatted":
+		u.Name.Formatted, err = decodeStringUnlessRemoved(remove, value)
+	case "emails":
+		u.Emails, err = patchMultiValue(u.Emails, op, path, value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", path.Attribute, err)
+	}
+	return nil
+}
+
+// ApplyPatch applies the PATCH operations to the group. Attributes not represented by Group (such as
+// externalId) are ignored.
+func (g *Group) ApplyPatch(operations []PatchOperation) error {
+	return applyOperations(operations, g.applyOperation)
+}
+
+func (g *Group) applyOperation(op string, path Path, value json.RawMessage) error {
+	var err error
+	switch path.Attribute {
+	case "displayname":
+		if op == "remove" {
+			return fmt.Errorf("displayName cannot be removed")
+		}
+		g.DisplayName, err = decodeString(value)
+	case "members":
+		g.Members, err = patchMultiValue(g.Members, op, path, value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", path.Attribute, err)
+	}
+	return nil
+}
+
+// applyOperations validates each operation, and passes it to apply with a lowercased operation name.
+// Operations without a path carry an object of attribute paths to values, each of which is applied
+// separately.
+func applyOperations(operations []PatchOperation, apply func(op string, path Path, value json.RawMessage) error) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "remove" && op != "replace" {
+			return fmt.Errorf("unsupported operation %q", operation.Op)
+		}
+
+		if operation.Path != "" {
+			path, err := ParsePath(operation.Path)
+			if err != nil {
+				return err
+			}
+			if err := apply(op, path, operation.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op == "remove" {
+			return fmt.Errorf("remove operations require a path")
+		}
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return fmt.Errorf("operations without a path require an object value: %w", err)
+		}
+		keys := make([]string, 0, len(attributes))
+		for key := range attributes {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			path, err := ParsePath(key)
+			if err != nil {
+				return err
+			}
+			if err := apply(op, path, attributes[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// patchMultiValue applies an operation to a multi-valued attribute, returning the updated values
+func patchMultiValue(current []MultiValue, op string, path Path, value json.RawMessage) ([]MultiValue, error) {
+	if op == "remove" {
+		switch {
+		case path.Filter != nil:
+			return slices.DeleteFunc(current, func(v MultiValue) bool { return path.Filter.Matches(v) }), nil
+		case len(value) > 0 && string(value) != "null":
+			// Some providers remove specific values by listing them, rather than with a filter
+			removed, err := decodeMultiValues(value)
+			if err != nil {
+				return nil, err
+			}
+			return slices.DeleteFunc(current, func(v MultiValue) bool { return containsValue(removed, v.Value) }), nil
+		}
+		return nil, nil
+	}
+
+	if path.Filter == nil {
+		values, err := decodeMultiValues(value)
+		if err != nil {
+			return nil, err
+		}
+		if op == "replace" {
+			return values, nil
+		}
+		for _, v := range values {
+			if !containsValue(current, v.Value) {
+				current = append(current, v)
+			}
+		}
+		return current, nil
+	}
+
+	updated := false
+	for i := range current {
+		if !path.Filter.Matches(current[i]) {
+			continue
+		}
+		if err := setMultiValue(&current[i], path.SubAttribute, value); err != nil {
+			return nil, err
+		}
+		updated = true
+	}
+	if !updated {
+		// Nothing matched the filter, so add a value that would have. Only the type can be inferred.
+		newValue := MultiValue{}
+		if f, ok := path.Filter.(comparisonFilter); ok && f.operator == "eq" && f.attribute == "type" {
+			newValue.Type = f.value
+		}
+		if err := setMultiValue(&newValue, path.SubAttribute, value); err != nil {
+			return nil, err
+		}
+		current = append(current, newValue)
+	}
+	return current, nil
+}
+
+// setMultiValue sets the named sub-attribute of v, or replaces v entirely if no sub-attribute is named
+func setMultiValue(v *MultiValue, subAttribute string, value json.RawMessage) error {
+	var err error
+	switch subAttribute {
+	case "":
+		err = json.Unmarshal(value, v)
+	case "value":
+		v.Value, err = decodeString(value)
+	case "display":
+		v.Display, err = decodeString(value)
+	case "type":
+		v.Type, err = decodeString(value)
+	case "primary":
+		v.Primary, err = decodeBool(value)
+	default:
+		err = fmt.Errorf("unsupported sub-attribute %q", subAttribute)
+	}
+	return err
+}
+
+// decodeMultiValues decodes either a list of values, or a single value
+func decodeMultiValues(value json.RawMessage) ([]MultiValue, error) {
+	var values []MultiValue
+	if err := json.Unmarshal(value, &values); err == nil {
+		return values, nil
+	}
+	var single MultiValue
+	if err := json.Unmarshal(value, &single); err != nil {
+		return nil, err
+	}
+	return []MultiValue{single}, nil
+}
+
+func containsValue(values []MultiValue, value string) bool {
+	return slices.ContainsFunc(values, func(v MultiValue) bool { return v.Value == value })
+}
+
+// decodeBool decodes a boolean, which some providers send as a string (e.g. "False")
+func decodeBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, fmt.Errorf("expected a boolean")
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+func decodeString(value json.RawMessage) (string, error) {
+	var s *string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", fmt.Errorf("expected a string")
+	}
+	if s == nil {
+		return "", nil
+	}
+	return *s, nil
+}
+
+func decodeStringUnlessRemoved(remove bool, value json.RawMessage) (string, error) {
+	if remove {
+		return "", nil
+	}
+	return decodeString(value)
+}
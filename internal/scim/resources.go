@@ -0,0 +1,220 @@
+// Package scim provides the resource types, filter parsing and PATCH operation parsing needed to
+// serve a SCIM 2.0 (RFC 7643 / RFC 7644) provisioning endpoint. Only the subset of the protocol used
+// by common identity providers to provision users and groups is supported.
+package scim
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The schema URNs used by supported resources and messages
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Meta holds the common resource metadata
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      time.Time  `json:"created"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+}
+
+// Name is the components of a user's name
+type Name struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+// MultiValue is an entry in a multi-valued attribute, such as a user's emails, or a group's members
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// User is a SCIM User resource
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        Name         `json:"name"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	Groups      []MultiValue `json:"groups,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// PrimaryEmail returns the email marked as primary, or the first email if none are marked
+func (u User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// Values returns the values of the named attribute, for use with Filter.Matches
+func (u User) Values(attribute string) []string {
+	switch strings.ToLower(attribute) {
+	case "id":
+		return []string{u.ID}
+	case "username":
+		return []string{u.UserName}
+	case "displayname":
+		return []string{u.DisplayName}
+	case "name.givenname":
+		return []string{u.Name.GivenName}
+	case "name.familyname":
+		return []string{u.Name.FamilyName}
+	case "emails", "emails.value":
+		return values(u.Emails)
+	case "groups", "groups.value":
+		return values(u.Groups)
+	case "active":
+		return []string{strconv.FormatBool(u.Active == nil || *u.Active)}
+	}
+	return nil
+}
+
+// Group is a SCIM Group resource
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// Values returns the values of the named attribute, for use with Filter.Matches
+func (g Group) Values(attribute string) []string {
+	switch strings.ToLower(attribute) {
+	case "id":
+		return []string{g.ID}
+	case "displayname":
+		return []string{g.DisplayName}
+	case "members", "members.value":
+		return values(g.Members)
+	}
+	return nil
+}
+
+// MemberValues returns the values (i.e. resource ids) of the group's members
+func (g Group) MemberValues() []string {
+	return values(g.Members)
+}
+
+func values(multiValues []MultiValue) []string {
+	rtn := make([]string, len(multiValues))
+	for i, v := range multiValues {
+		rtn[i] = v.Value
+	}
+	return rtn
+}
+
+// ListResponse is the response to a query for resources
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// NewListResponse builds a ListResponse for a single page of results, where startIndex is the
+// (1-based) index of the first item in the page
+func NewListResponse[T any](page []T, totalResults, startIndex int) ListResponse {
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+// Paginate returns the page of items starting at the 1-based startIndex, with at most count items.
+// Following RFC 7644, a startIndex less than 1 is treated as 1, and a negative count as 0.
+func Paginate[T any](items []T, startIndex, count int) []T {
+	startIndex = max(startIndex, 1)
+	count = max(count, 0)
+	if startIndex > len(items) {
+		return []T{}
+	}
+	end := min(startIndex-1+count, len(items))
+	return items[startIndex-1 : end]
+}
+
+// Error is a SCIM error response
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// NewError builds an Error for the given http status
+func NewError(status int, detail string) Error {
+	return Error{
+		Schemas: []string{SchemaError},
+		Status:  strconv.Itoa(status),
+		Detail:  detail,
+	}
+}
+
+// ServiceProviderConfig describes the supported features of the SCIM endpoint
+type ServiceProviderConfig struct {
+	Schemas               []string        `json:"schemas"`
+	Patch                 supported       `json:"patch"`
+	Bulk                  json.RawMessage `json:"bulk"`
+	Filter                json.RawMessage `json:"filter"`
+	ChangePassword        supported       `json:"changePassword"`
+	Sort                  supported       `json:"sort"`
+	ETag                  supported       `json:"etag"`
+	AuthenticationSchemes []authScheme    `json:"authenticationSchemes"`
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type authScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// NewServiceProviderConfig describes the features supported by this package
+func NewServiceProviderConfig(maxResults int) ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          supported{true},
+		Bulk:           json.RawMessage(`{"supported":false,"maxOperations":0,"maxPayloadSize":0}`),
+		Filter:         json.RawMessage(`{"supported":true,"maxResults":` + strconv.Itoa(maxResults) + `}`),
+		ChangePassword: supported{false},
+		Sort:           supported{false},
+		ETag:           supported{false},
+		AuthenticationSchemes: []authScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with a static bearer token",
+		}},
+	}
+}
@@ -0,0 +1,149 @@
+package scim_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/scim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	user := scim.User{
+		ID:       "harry.potter",
+		UserName: "Harry.Potter@hogwarts.edu",
+		Name:     scim.Name{GivenName: "Harry", FamilyName: "Potter"},
+		Emails:   []scim.MultiValue{{Value: "harry@hogwarts.edu", Type: "work"}},
+		Active:   helpers.Ptr(false),
+	}
+
+	matches := map[string]bool{
+		``: true,
+		`userName eq "harry.potter@hogwarts.edu"`:                 true,
+		`USERNAME Eq "harry.potter@hogwarts.edu"`:                 true,
+		`userName eq "ron.weasley@hogwarts.edu"`:                  false,
+		`userName ne "ron.weasley@hogwarts.edu"`:                  true,
+		`name.familyName sw "pot"`:                                true,
+		`emails co "hogwarts"`:                                    true,
+		`emails.value ew ".com"`:                                  false,
+		`displayName pr`:                                          false,
+		`active eq false`:                                         true,
+		`name.givenName eq "Harry" and active eq true`:            false,
+		`name.givenName eq "Ron" or id eq "harry.potter"`:         true,
+		`not (id eq "harry.potter")`:                              false,
+		`id eq "x" or (id eq "harry.potter" and active eq false)`: true,
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "Harry.Potter@hogwarts.edu"`: true,
+	}
+	for expression, expected := range matches {
+		filter, err := scim.ParseFilter(expression)
+		require.NoError(t, err, expression)
+		require.Equal(t, expected, filter.Matches(user), expression)
+	}
+
+	for _, expression := range []string{
+		`userName`,
+		`userName eq`,
+		`userName like "harry"`,
+		`userName eq "harry`,
+		`(userName eq "harry"`,
+		`userName eq "harry" and`,
+		`"userName" eq "harry"`,
+	} {
+		_, err := scim.ParseFilter(expression)
+		require.Error(t, err, expression)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	path, err := scim.ParsePath("name.givenName")
+	require.NoError(t, err)
+	require.Equal(t, scim.Path{Attribute: "name.givenname"}, path)
+
+	path, err = scim.ParsePath(`emails[type eq "work"].value`)
+	require.NoError(t, err)
+	require.Equal(t, "emails", path.Attribute)
+	require.Equal(t, "value", path.SubAttribute)
+	require.True(t, path.Filter.Matches(scim.MultiValue{Type: "Work"}))
+
+	_, err = scim.ParsePath(`members[value eq "x"`)
+	require.Error(t, err)
+	_, err = scim.ParsePath(`members[value eq "x"]value`)
+	require.Error(t, err)
+}
+
+func TestUserApplyPatch(t *testing.T) {
+	user := scim.User{
+		UserName: "harry.potter@hogwarts.edu",
+		Name:     scim.Name{GivenName: "Harry", FamilyName: "Potter"},
+		Emails:   []scim.MultiValue{{Value: "harry@hogwarts.edu", Type: "work"}},
+	}
+
+	err := user.ApplyPatch(decodeOperations(t, `[
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "hpotter@hogwarts.edu"},
+		{"op": "add", "path": "emails[type eq \"home\"].value", "value": "harry@privet.drive"},
+		{"op": "replace", "value": {"name.familyName": "Potter-Weasley", "externalId": "abc"}}
+	]`))
+	require.NoError(t, err)
+	require.Equal(t, false, *user.Active)
+	require.Equal(t, "Potter-Weasley", user.Name.FamilyName)
+	require.Equal(t, []scim.MultiValue{
+		{Value: "hpotter@hogwarts.edu", Type: "work"},
+		{Value: "harry@privet.drive", Type: "home"},
+	}, user.Emails)
+
+	err = user.ApplyPatch(decodeOperations(t, `[{"op": "replace", "value": {"active": true}}]`))
+	require.NoError(t, err)
+	require.Equal(t, true, *user.Active)
+
+	require.Error(t, user.ApplyPatch(decodeOperations(t, `[{"op": "move", "path": "active"}]`)))
+	require.Error(t, user.ApplyPatch(decodeOperations(t, `[{"op": "remove"}]`)))
+	require.Error(t, user.ApplyPatch(decodeOperations(t, `[{"op": "replace", "path": "active", "value": "maybe"}]`)))
+}
+
+func TestGroupApplyPatch(t *testing.T) {
+	group := scim.Group{
+		DisplayName: "Gryffindor",
+		Members:     []scim.MultiValue{{Value: "harry.potter"}, {Value: "ron.weasley"}},
+	}
+
+	err := group.ApplyPatch(decodeOperations(t, `[
+		{"op": "add", "path": "members", "value": [{"value": "hermione.granger"}, {"value": "harry.potter"}]},
+		{"op": "remove", "path": "members[value eq \"ron.weasley\"]"}
+	]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"harry.potter", "hermione.granger"}, group.MemberValues())
+
+	// Azure style removal, listing the removed members
+	err = group.ApplyPatch(decodeOperations(t, `[
+		{"op": "Remove", "path": "members", "value": [{"value": "harry.potter"}]},
+		{"op": "Replace", "path": "displayName", "value": "Dumbledore's Army"}
+	]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"hermione.granger"}, group.MemberValues())
+	require.Equal(t, "Dumbledore's Army", group.DisplayName)
+
+	err = group.ApplyPatch(decodeOperations(t, `[{"op": "replace", "path": "members", "value": [{"value": "neville.longbottom"}]}]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"neville.longbottom"}, group.MemberValues())
+
+	err = group.ApplyPatch(decodeOperations(t, `[{"op": "remove", "path": "members"}]`))
+	require.NoError(t, err)
+	require.Empty(t, group.MemberValues())
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	require.Equal(t, []int{1, 2}, scim.Paginate(items, 1, 2))
+	require.Equal(t, []int{4, 5}, scim.Paginate(items, 4, 10))
+	require.Equal(t, []int{1, 2, 3}, scim.Paginate(items, 0, 3))
+	require.Equal(t, []int{}, scim.Paginate(items, 6, 3))
+	require.Equal(t, []int{}, scim.Paginate(items, 1, -1))
+}
+
+func decodeOperations(t *testing.T, operations string) []scim.PatchOperation {
+	var rtn []scim.PatchOperation
+	require.NoError(t, json.Unmarshal([]byte(operations), &rtn))
+	return rtn
+}
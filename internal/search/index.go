@@ -0,0 +1,193 @@
+// Package search maintains a full-text index of operation content (evidence descriptions, codeblock
+// content and metadata, along with finding titles and descriptions), and answers relevance-ranked
+// queries against that index, with highlighted snippets.
+//
+// The index is stored in the search_index table, which holds one row per indexed field of each
+// document, and relies on a MySQL FULLTEXT index for matching and ranking. Callers are responsible
+// for keeping the index in sync when documents are created, updated or deleted.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// The types of documents stored in the index
+const (
+	DocumentEvidence = "evidence"
+	DocumentFinding  = "finding"
+)
+
+// The fields indexed for each document. Evidence metadata is indexed per source, via MetadataField.
+const (
+	FieldDescription = "description"
+	FieldTitle       = "title"
+	FieldContent     = "content"
+
+	metadataFieldPrefix = "metadata:"
+)
+
+// maxIndexedLength caps how much of a single field is indexed (in bytes), so that very large OCR
+// output or codeblocks do not bloat the index
+const maxIndexedLength = 1 << 20
+
+// MetadataField returns the name of the field used to index metadata from the given source
+func MetadataField(source string) string {
+	return metadataFieldPrefix + source
+}
+
+type indexEntry struct {
+	operationID  int64
+	documentType string
+	documentID   int64
+	field        string
+	content      string
+}
+
+// IndexEvidence (re)indexes the description and metadata of the indicated evidence. Codeblock
+// content lives in the content store, and so is indexed separately via IndexEvidenceContent. Any
+// existing entries for the evidence are moved to the evidence's current operation.
+func IndexEvidence(db database.ConnectionProxy, evidenceIDs []int64) error {
+	if len(evidenceIDs) == 0 {
+		return nil
+	}
+	return db.WithTx(context.Background(), func(tx *database.Transactable) {
+		var evidence []models.Evidence
+		tx.Select(&evidence, sq.Select("id", "operation_id", "description").
+			From("evidence").
+			Where(sq.Eq{"id": evidenceIDs}))
+
+		var metadata []models.EvidenceMetadata
+		tx.Select(&metadata, sq.Select("evidence_id", "source", "body").
+			From("evidence_metadata").
+			Where(sq.Eq{"evidence_id": evidenceIDs}))
+
+		entries := make([]indexEntry, 0, len(evidence)+len(metadata))
+		operationIDs := make(map[int64]int64, len(evidence))
+		for _, evi := range evidence {
+			operationIDs[evi.ID] = evi.OperationID
+			entries = append(entries, indexEntry{evi.OperationID, DocumentEvidence, evi.ID, FieldDescription, evi.Description})
+		}
+		for _, meta := range metadata {
+			if operationID, ok := operationIDs[meta.EvidenceID]; ok {
+				entries = append(entries, indexEntry{operationID, DocumentEvidence, meta.EvidenceID, MetadataField(meta.Source), meta.Body})
+			}
+		}
+
+		tx.Delete(sq.Delete("search_index").
+			Where(sq.Eq{"document_type": DocumentEvidence, "document_id": evidenceIDs}).
+			Where(sq.NotEq{"field": FieldContent}))
+		for _, evi := range evidence {
+			tx.Update(sq.Update("search_index").
+				Set("operation_id", evi.OperationID).
+				Where(sq.Eq{"document_type": DocumentEvidence, "document_id": evi.ID}).
+				Where(sq.NotEq{"operation_id": evi.OperationID}))
+		}
+		insertEntries(tx, entries)
+	})
+}
+
+// IndexEvidenceContent indexes the text content of the indicated evidence, replacing any content
+// previously indexed for it. Only codeblock content is indexed; other content types are ignored.
+func IndexEvidenceContent(db database.ConnectionProxy, evidence models.Evidence, content []byte) error {
+	if evidence.ContentType != "codeblock" {
+		return nil
+	}
+	return db.WithTx(context.Background(), func(tx *database.Transactable) {
+		tx.Delete(sq.Delete("search_index").
+			Where(sq.Eq{"document_type": DocumentEvidence, "document_id": evidence.ID, "field": FieldContent}))
+		insertEntries(tx, []indexEntry{
+			{evidence.OperationID, DocumentEvidence, evidence.ID, FieldContent, CodeblockText(content)},
+		})
+	})
+}
+
+// IndexFindings (re)indexes the title and description of the indicated findings
+func IndexFindings(db database.ConnectionProxy, findingIDs []int64) error {
+	if len(findingIDs) == 0 {
+		return nil
+	}
+	return db.WithTx(context.Background(), func(tx *database.Transactable) {
+		var findings []models.Finding
+		tx.Select(&findings, sq.Select("id", "operation_id", "title", "description").
+			From("findings").
+			Where(sq.Eq{"id": findingIDs}))
+
+		entries := make([]indexEntry, 0, 2*len(findings))
+		for _, finding := range findings {
+			entries = append(entries,
+				indexEntry{finding.OperationID, DocumentFinding, finding.ID, FieldTitle, finding.Title},
+				indexEntry{finding.OperationID, DocumentFinding, finding.ID, FieldDescription, finding.Description},
+			)
+		}
+
+		tx.Delete(sq.Delete("search_index").
+			Where(sq.Eq{"document_type": DocumentFinding, "document_id": findingIDs}))
+		insertEntries(tx, entries)
+	})
+}
+
+// RemoveDocuments removes all index entries for the indicated documents
+func RemoveDocuments(db database.ConnectionProxy, documentType string, documentIDs []int64) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	return db.Delete(sq.Delete("search_index").
+		Where(sq.Eq{"document_type": documentType, "document_id": documentIDs}))
+}
+
+// CodeblockText extracts the source code from codeblock evidence content. Content that cannot be
+// parsed as a codeblock is indexed as-is.
+func CodeblockText(content []byte) string {
+	var codeblock struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(content, &codeblock); err != nil {
+		return string(content)
+	}
+	return codeblock.Content
+}
+
+// insertEntries adds the entries to the index. Empty fields are skipped, and overly long fields are
+// truncated.
+func insertEntries(tx *database.Transactable, entries []indexEntry) {
+	nonEmpty := make([]indexEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.content = truncate(entry.content, maxIndexedLength)
+		if strings.TrimSpace(entry.content) != "" {
+			nonEmpty = append(nonEmpty, entry)
+		}
+	}
+	tx.BatchInsert("search_index", len(nonEmpty), func(idx int) map[string]interface{} {
+		entry := nonEmpty[idx]
+		return map[string]interface{}{
+			"operation_id":  entry.operationID,
+			"document_type": entry.documentType,
+			"document_id":   entry.documentID,
+			"field":         entry.field,
+			"content":       entry.content,
+		}
+	})
+}
+
+// truncate shortens s to at most maxBytes bytes, without splitting a multi-byte character
+func truncate(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,202 @@
+package search
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ErrEmptyQuery is returned when a query contains nothing that can be searched for (e.g. it is
+// blank, or consists only of punctuation and very short words)
+var ErrEmptyQuery = errors.New("search query has no searchable terms")
+
+// minTermLength mirrors InnoDB's default innodb_ft_min_token_size. Shorter words are never indexed,
+// so requiring them would cause every search to fail.
+const minTermLength = 3
+
+// maxSnippetsPerResult limits how many of a document's fields are excerpted in its result
+const maxSnippetsPerResult = 3
+
+// Query describes a search over the index
+type Query struct {
+	// Text is the user's search text. Words are matched by prefix, and double-quoted text is matched
+	// as a phrase. All words and phrases must be present for a document to match.
+	Text string
+	// OperationIDs restricts the search to documents within the listed operations. At least one
+	// operation is required.
+	OperationIDs []int64
+	Limit        uint64
+	Offset       uint64
+}
+
+// Result is a single matched document, along with excerpts of the fields that matched
+type Result struct {
+	DocumentType string  `db:"document_type"`
+	DocumentID   int64   `db:"document_id"`
+	OperationID  int64   `db:"operation_id"`
+	Score        float64 `db:"score"`
+	Snippets     []Snippet
+}
+
+// parsedQuery is the user's search text, converted into a MySQL boolean mode query, along with the
+// terms to highlight in matched content
+type parsedQuery struct {
+	booleanQuery string
+	terms        []string
+}
+
+// Search finds the documents matching the query, ordered from most to least relevant. The total
+// number of matching documents is also returned, to support pagination.
+func Search(db database.ConnectionProxy, q Query) ([]Result, int64, error) {
+	parsed, err := parseQuery(q.Text)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(q.OperationIDs) == 0 {
+		return []Result{}, 0, nil
+	}
+
+	matches := sq.Expr("MATCH(content) AGAINST(? IN BOOLEAN MODE)", parsed.booleanQuery)
+	inOperations := sq.Eq{"operation_id": q.OperationIDs}
+
+	var total int64
+	err = db.Get(&total, sq.Select("COUNT(DISTINCT document_type, document_id)").
+		From("search_index").
+		Where(inOperations).
+		Where(matches))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var results []Result
+	pageQuery := sq.Select("document_type", "document_id", "operation_id").
+		Column("SUM(MATCH(content) AGAINST(? IN BOOLEAN MODE)) AS score", parsed.booleanQuery).
+		From("search_index").
+		Where(inOperations).
+		Where(matches).
+		GroupBy("document_type", "document_id", "operation_id").
+		OrderBy("score DESC", "document_id DESC").
+		Offset(q.Offset)
+	if q.Limit > 0 {
+		pageQuery = pageQuery.Limit(q.Limit)
+	}
+	if err := db.Select(&results, pageQuery); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return []Result{}, total, nil
+	}
+
+	if err := addSnippets(db, results, parsed); err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+// addSnippets excerpts the best matching fields of each result
+func addSnippets(db database.ConnectionProxy, results []Result, parsed parsedQuery) error {
+	idsByType := map[string][]int64{}
+	for _, result := range results {
+		idsByType[result.DocumentType] = append(idsByType[result.DocumentType], result.DocumentID)
+	}
+	documentsMatch := sq.Or{}
+	for documentType, ids := range idsByType {
+		documentsMatch = append(documentsMatch, sq.Eq{"document_type": documentType, "document_id": ids})
+	}
+
+	var fields []struct {
+		DocumentType string  `db:"document_type"`
+		DocumentID   int64   `db:"document_id"`
+		Field        string  `db:"field"`
+		Content      string  `db:"content"`
+		Score        float64 `db:"score"`
+	}
+	err := db.Select(&fields, sq.Select("document_type", "document_id", "field", "content").
+		Column("MATCH(content) AGAINST(? IN BOOLEAN MODE) AS score", parsed.booleanQuery).
+		From("search_index").
+		Where(documentsMatch).
+		OrderBy("score DESC", "field"))
+	if err != nil {
+		return err
+	}
+
+	type documentKey struct {
+		documentType string
+		documentID   int64
+	}
+	snippets := map[documentKey][]Snippet{}
+	for _, field := range fields {
+		key := documentKey{field.DocumentType, field.DocumentID}
+		if len(snippets[key]) >= maxSnippetsPerResult {
+			continue
+		}
+		if snippet, ok := makeSnippet(field.Field, field.Content, parsed.terms); ok {
+			snippets[key] = append(snippets[key], snippet)
+		}
+	}
+	for idx, result := range results {
+		results[idx].Snippets = snippets[documentKey{result.DocumentType, result.DocumentID}]
+		if results[idx].Snippets == nil {
+			results[idx].Snippets = []Snippet{}
+		}
+	}
+	return nil
+}
+
+// parseQuery converts user search text into a boolean mode query, in which every word (matched by
+// prefix) and every quoted phrase is required. Characters that carry meaning in boolean mode are
+// never passed through, so users cannot produce malformed queries.
+func parseQuery(text string) (parsedQuery, error) {
+	var clauses, terms []string
+	addWords := func(s string) {
+		for _, word := range splitWords(s) {
+			if len([]rune(word)) < minTermLength {
+				continue
+			}
+			clauses = append(clauses, "+"+word+"*")
+			terms = append(terms, word)
+		}
+	}
+
+	for idx, part := range strings.Split(text, `"`) {
+		if idx%2 == 0 { // outside of quotes (an unterminated quote is treated as a phrase anyway)
+			addWords(part)
+			continue
+		}
+		words := splitWords(part)
+		switch len(words) {
+		case 0:
+		case 1:
+			addWords(words[0])
+		default:
+			phrase := strings.Join(words, " ")
+			clauses = append(clauses, `+"`+phrase+`"`)
+			terms = append(terms, phrase)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return parsedQuery{}, ErrEmptyQuery
+	}
+	// longer terms are highlighted first, so that they win over any shorter terms they contain
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	return parsedQuery{
+		booleanQuery: strings.Join(clauses, " "),
+		terms:        terms,
+	}, nil
+}
+
+// splitWords lowercases s and splits it into words the way the full-text parser would: on any
+// character that is not a letter, digit or underscore
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !isWordRune(r) })
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	parsed, err := parseQuery(`Admin  PASSWORD "reset link sent" 10.0.0.1`)
+	require.NoError(t, err)
+	require.Equal(t, `+admin* +password* +"reset link sent"`, parsed.booleanQuery, "short words are dropped")
+	require.Equal(t, []string{"reset link sent", "password", "admin"}, parsed.terms)
+
+	// boolean mode operators are never passed through
+	parsed, err = parseQuery(`-secret +(token) ~weird> <case@ "single"`)
+	require.NoError(t, err)
+	require.Equal(t, `+secret* +token* +weird* +case* +single*`, parsed.booleanQuery)
+
+	// unterminated quotes are treated as a phrase
+	parsed, err = parseQuery(`"open phrase here`)
+	require.NoError(t, err)
+	require.Equal(t, `+"open phrase here"`, parsed.booleanQuery)
+
+	for _, empty := range []string{"", "   ", `""`, "a an to", "*-+"} {
+		_, err = parseQuery(empty)
+		require.ErrorIs(t, err, ErrEmptyQuery, "query %q", empty)
+	}
+}
+
+func TestMakeSnippet(t *testing.T) {
+	snippet, ok := makeSnippet(FieldDescription, "Found the\n\tadmin  password in a Passwords.txt file", []string{"password"})
+	require.True(t, ok)
+	require.Equal(t, FieldDescription, snippet.Field)
+	require.Equal(t, []Fragment{
+		{Text: "Found the admin "},
+		{Text: "password", Highlight: true},
+		{Text: " in a "},
+		{Text: "Password", Highlight: true}, // the matched prefix is highlighted, in its original case
+		{Text: "s.txt file"},
+	}, snippet.Fragments)
+
+	_, ok = makeSnippet(FieldDescription, "nothing to see here", []string{"password"})
+	require.False(t, ok)
+
+	// terms only match at the start of a word
+	_, ok = makeSnippet(FieldDescription, "a superadmin account", []string{"admin"})
+	require.False(t, ok)
+
+	// long content is cut around the first match
+	long := strings.Repeat("lorem ipsum ", 50) + "the secret is here " + strings.Repeat("dolor sit amet ", 50)
+	snippet, ok = makeSnippet(FieldContent, long, []string{"secret"})
+	require.True(t, ok)
+	require.Equal(t, ellipsis, snippet.Fragments[0].Text)
+	require.Equal(t, ellipsis, snippet.Fragments[len(snippet.Fragments)-1].Text)
+	require.Equal(t, Fragment{Text: "secret", Highlight: true}, snippet.Fragments[2])
+	firstWord := strings.Fields(snippet.Fragments[1].Text)[0]
+	require.Contains(t, []string{"lorem", "ipsum", "the"}, firstWord, "excerpts start at a word boundary")
+	excerptLength := 0
+	for _, fragment := range snippet.Fragments {
+		excerptLength += len([]rune(fragment.Text))
+	}
+	require.LessOrEqual(t, excerptLength, snippetLength+2)
+}
+
+func TestCodeblockText(t *testing.T) {
+	require.Equal(t, "print('hi')", CodeblockText([]byte(`{"contentSubtype":"python","content":"print('hi')"}`)))
+	require.Equal(t, "not json", CodeblockText([]byte("not json")))
+}
+
+func TestTruncate(t *testing.T) {
+	require.Equal(t, "abc", truncate("abc", 5))
+	require.Equal(t, "ab", truncate("abc", 2))
+	require.Equal(t, "a", truncate("aé", 2), "multi-byte characters are not split")
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// snippetLength is the approximate length (in characters) of each excerpt
+const snippetLength = 160
+
+// snippetLeadIn is how much text (in characters) to show before the first highlighted term
+const snippetLeadIn = 40
+
+// ellipsis marks where an excerpt has been cut from its surrounding text
+const ellipsis = "…"
+
+// Snippet is an excerpt of a matched field. The excerpt is split into fragments, each of which is
+// either highlighted (i.e. it matched a search term) or not, which lets clients render highlights
+// without needing to trust or escape server-generated markup.
+type Snippet struct {
+	Field     string
+	Fragments []Fragment
+}
+
+// Fragment is a piece of a Snippet
+type Fragment struct {
+	Text      string
+	Highlight bool
+}
+
+type span struct {
+	start, end int
+}
+
+// makeSnippet excerpts content around the first occurrence of any of the (lowercase) terms, and
+// highlights every occurrence within the excerpt. Terms match at the start of words, mirroring how
+// the query matches words by prefix. Returns false if no term occurs in the content.
+func makeSnippet(field, content string, terms []string) (Snippet, bool) {
+	text := []rune(strings.Join(strings.Fields(content), " "))
+	matches := findTerms(text, terms)
+	if len(matches) == 0 {
+		return Snippet{}, false
+	}
+
+	start, end := snippetWindow(text, matches[0].start)
+	fragments := []Fragment{}
+	if start > 0 {
+		fragments = append(fragments, Fragment{Text: ellipsis})
+	}
+	pos := start
+	for _, match := range matches {
+		if match.end <= start || match.start >= end {
+			continue
+		}
+		matchStart, matchEnd := max(match.start, start), min(match.end, end)
+		if matchStart > pos {
+			fragments = append(fragments, Fragment{Text: string(text[pos:matchStart])})
+		}
+		fragments = append(fragments, Fragment{Text: string(text[matchStart:matchEnd]), Highlight: true})
+		pos = matchEnd
+	}
+	if pos < end {
+		fragments = append(fragments, Fragment{Text: string(text[pos:end])})
+	}
+	if end < len(text) {
+		fragments = append(fragments, Fragment{Text: ellipsis})
+	}
+	return Snippet{Field: field, Fragments: fragments}, true
+}
+
+// findTerms locates each occurrence of the terms within text, in order and without overlaps
+func findTerms(text []rune, terms []string) []span {
+	lower := make([]rune, len(text))
+	for idx, r := range text {
+		lower[idx] = unicode.ToLower(r)
+	}
+
+	var matches []span
+	for pos := 0; pos < len(lower); pos++ {
+		if pos > 0 && isWordRune(lower[pos-1]) {
+			continue
+		}
+		for _, term := range terms {
+			termRunes := []rune(term)
+			if hasRunePrefix(lower[pos:], termRunes) {
+				matches = append(matches, span{pos, pos + len(termRunes)})
+				pos += len(termRunes) - 1
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// snippetWindow picks the excerpt bounds for text, so that the first match is shown near the start
+// of the excerpt. Bounds are moved to word boundaries where possible.
+func snippetWindow(text []rune, firstMatch int) (int, int) {
+	start := max(firstMatch-snippetLeadIn, 0)
+	end := min(start+snippetLength, len(text))
+	if end == len(text) {
+		start = max(end-snippetLength, 0)
+	}
+
+	if start > 0 {
+		if space := indexRune(text[start:firstMatch], ' '); space >= 0 {
+			start += space + 1
+		}
+	}
+	if end < len(text) {
+		if space := lastIndexRune(text[firstMatch:end], ' '); space > 0 {
+			end = firstMatch + space
+		}
+	}
+	return start, end
+}
+
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for idx, r := range prefix {
+		if s[idx] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func indexRune(s []rune, r rune) int {
+	for idx, c := range s {
+		if c == r {
+			return idx
+		}
+	}
+	return -1
+}
+
+func lastIndexRune(s []rune, r rune) int {
+	for idx := len(s) - 1; idx >= 0; idx-- {
+		if s[idx] == r {
+			return idx
+		}
+	}
+	return -1
+}
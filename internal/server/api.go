@@ -1,18 +1,22 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/ashirt-ops/ashirt-server/internal/config"
 	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
 	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/signer"
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -20,11 +24,54 @@ import (
 func API(r chi.Router, db *database.Connection, contentStore contentstore.Store, logger *slog.Logger) {
 	r.Handle("/metrics", promhttp.Handler())
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.AuthenticateAppAndInjectCtx(db))
+		r.Use(middleware.LimitRequestSize(config.MaxRequestSize()))
+		r.Use(middleware.AuthenticateAppAndInjectCtx(db, config.APIRequireNonce()))
+		r.Use(middleware.LimitRequestRate(middleware.NewRateLimiter(config.RateLimit(), config.RateLimitBurst()), middleware.RateLimitByAccessKey))
 		r.Use(middleware.LogRequests(logger))
 		bindSharedRoutes(r, db, contentStore)
 		bindAPIRoutes(r, db, contentStore)
 	})
+	r.Group(func(r chi.Router) {
+		// service worker callbacks are signed with per-callback keys, rather than api keys
+		r.Use(middleware.LimitRequestSize(maxServiceWorkerCallbackSize))
+		r.Use(middleware.LogRequests(logger))
+		bindServiceWorkerCallbackRoutes(r, db)
+	})
+}
+
+// maxServiceWorkerCallbackSize limits the size of service worker callbacks. These are read in full
+// before their signature can be checked, so are kept much smaller than evidence uploads.
+const maxServiceWorkerCallbackSize = 1024 * 1024
+
+func bindServiceWorkerCallbackRoutes(r chi.Router, db *database.Connection) {
+	route(r, "POST", enhancementservices.CallbackPath("{access_key}"), jsonHandler(func(r *http.Request) (interface{}, error) {
+		body, err := io.ReadAll(r.Body)
+		if middleware.IsRequestTooLarge(err) {
+			return nil, errorwrap.TooLargeErr(err, "The callback is too large")
+		}
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to read callback body", err)
+		}
+		signedAccessKey, signature, err := middleware.ParseSignedRequest(r)
+		if err != nil {
+			return nil, errorwrap.UnauthorizedWriteErr(err)
+		}
+
+		dr := dissectNoBodyRequest(r)
+		i := services.RecordServiceWorkerCallbackInput{
+			AccessKey:       dr.FromURL("access_key").Required().AsString(),
+			SignedAccessKey: signedAccessKey,
+			Signature:       signature,
+			BuildSignature: func(secretKey []byte) []byte {
+				return signer.BuildRequestHMAC(r, bytes.NewReader(body), secretKey)
+			},
+			Body: body,
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return nil, services.RecordServiceWorkerCallback(r.Context(), db, i)
+	}))
 }
 
 func bindAPIRoutes(r chi.Router, db *database.Connection, contentStore contentstore.Store) {
@@ -160,6 +160,18 @@ func (c *Coercable) AsInt64() int64 {
 	return value
 }
 
+// AsInt64Ptr converts the Coercable into a *int64 type.
+// If the underlying value is nil, then this will return nil.
+// if the underlying value is not nil, but also not an int64,
+// then the zero value will be returned
+func (c *Coercable) AsInt64Ptr() *int64 {
+	if c.rawValue == nil {
+		return nil
+	}
+	v := c.AsInt64()
+	return &v
+}
+
 // AsInt64Slice converts the Coercable into an []int64 type.
 // If this is impossible, then the zero value will be returned
 //
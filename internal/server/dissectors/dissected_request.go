@@ -2,6 +2,7 @@ package dissectors
 
 import (
 	"encoding/json"
+	"errors"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -56,7 +57,7 @@ func DissectJSONRequest(r *http.Request, urlParameters map[string]string) Dissec
 
 	if r.Body != http.NoBody {
 		if err := json.NewDecoder(r.Body).Decode(&rtn.bodyValues); err != nil {
-			rtn.Error = errorwrap.BadInputErr(err, "Invalid JSON body")
+			rtn.Error = bodyError(err, "Invalid JSON body")
 		}
 	}
 
@@ -84,7 +85,7 @@ func DissectFormRequest(r *http.Request, urlParameters map[string]string) Dissec
 
 	if r.Body != http.NoBody {
 		if err := r.ParseMultipartForm(5 * 1024 * 1024); err != nil {
-			rtn.Error = errorwrap.BadInputErr(err, "Unable to parse Form body")
+			rtn.Error = bodyError(err, "Unable to parse Form body")
 		}
 		//need to unwrap the post form to coerce it into a consistent format
 		for key, value := range r.PostForm {
@@ -95,6 +96,17 @@ func DissectFormRequest(r *http.Request, urlParameters map[string]string) Dissec
 	return rtn
 }
 
+// bodyError converts an error encountered while reading the request body into an HTTPError. Bodies
+// cut short by a size limit (see http.MaxBytesReader) are reported as too large, rather than as
+// malformed.
+func bodyError(err error, reason string) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return errorwrap.TooLargeErr(err, "The request is too large")
+	}
+	return errorwrap.BadInputErr(err, reason)
+}
+
 // FromBody attempts to retrieve a field from the parsed body.
 // Returns a Coercable, which can then be transformed into the desired type.
 // If an error has already been encountered, the resulting action is a no-op
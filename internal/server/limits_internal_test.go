@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadsLimitRequestSize(t *testing.T) {
+	t.Setenv("DB_URI", "unused")
+	t.Setenv("APP_MAX_EVIDENCE_SIZE", "10")
+	t.Setenv("APP_MAX_EVIDENCE_SIZES", "")
+	require.NoError(t, config.LoadAPIConfig())
+	maxSize := config.MaxRequestSize()
+
+	apiRouter := chi.NewRouter()
+	API(apiRouter, nil, nil, logging.NewNopLogger())
+
+	webRouter := chi.NewRouter()
+	bindWebRoutes(webRouter, nil, nil, nil, &[]dtos.SupportedAuthScheme{}, maxSize)
+
+	upload := func(router http.Handler, method, path, field string, size int64) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		file, err := form.CreateFormFile(field, "evidence")
+		require.NoError(t, err)
+		_, err = file.Write(bytes.Repeat([]byte("a"), int(size)))
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(method, path, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := upload(apiRouter, "POST", "/operations/op/evidence", "file", maxSize)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = upload(webRouter, "POST", "/operations/op/evidence", "content", maxSize)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	rec = upload(webRouter, "PUT", "/operations/op/evidence/evi", "content", maxSize)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// small uploads pass the limit, and so go on to fail authentication instead
+	rec = upload(apiRouter, "POST", "/operations/op/evidence", "file", 5)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestServiceWorkerCallbacksLimitRequestSize(t *testing.T) {
+	apiRouter := chi.NewRouter()
+	API(apiRouter, nil, nil, logging.NewNopLogger())
+
+	callback := func(size int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", enhancementservices.CallbackPath("abc"), bytes.NewReader(bytes.Repeat([]byte("a"), size)))
+		rec := httptest.NewRecorder()
+		apiRouter.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := callback(maxServiceWorkerCallbackSize + 1)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// small callbacks pass the limit, and so go on to fail authentication instead
+	rec = callback(10)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
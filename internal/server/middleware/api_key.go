@@ -14,6 +14,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/signer"
 
 	sq "github.com/Masterminds/squirrel"
@@ -25,18 +26,26 @@ const maxDateDelta = time.Hour
 type UserData struct {
 	ID       int64
 	Headless bool
+	// Policy is the user's policy, narrowed to the API key's allowed operations and scope
+	Policy policy.Policy
 }
 
-func authenticateAPI(db *database.Connection, r *http.Request, requestBody io.Reader) (UserData, error) {
+// authenticateAPI verifies a request signed with an API key. Nonces sent with the request are recorded
+// in the provided store, and reused nonces are rejected with errorwrap.ReplayedRequest. Requests without
+// a nonce are only accepted when requireNonce is false.
+func authenticateAPI(db *database.Connection, r *http.Request, requestBody io.Reader, nonces *nonceStore, requireNonce bool) (UserData, error) {
 	emptyUserData := UserData{ID: -1, Headless: false}
-	if err := checkDateHeader(r.Header.Get("Date")); err != nil {
-		return emptyUserData, errorwrap.WrapError("Unable to parse date header (for api auth)", err)
+	accessKey, headerHMAC, err := ParseSignedRequest(r)
+	if err != nil {
+		return emptyUserData, err
 	}
 
-	// Check HMAC
-	accessKey, headerHMAC, err := parseAuthorizationHeader(r.Header.Get("Authorization"))
-	if err != nil {
-		return emptyUserData, errorwrap.WrapError("Unable to parse (api) authorization header", err)
+	nonce := r.Header.Get(signer.NonceHeader)
+	if nonce == "" && requireNonce {
+		return emptyUserData, errors.New("Missing required nonce header")
+	}
+	if len(nonce) > maxNonceLength {
+		return emptyUserData, fmt.Errorf("Nonce header is longer than %d characters", maxNonceLength)
 	}
 
 	var apiKey struct {
@@ -47,7 +56,7 @@ func authenticateAPI(db *database.Connection, r *http.Request, requestBody io.Re
 
 	// var apiKey models.APIKey
 	// Defer checking error here to avoid timing attacks to discover valid access keys
-	err = db.Get(&apiKey, sq.Select("secret_key", "user_id", "disabled", "headless").
+	err = db.Get(&apiKey, sq.Select("api_keys.id", "secret_key", "user_id", "expires_at", "restrict_operations", "scope", "disabled", "headless").
 		From("api_keys").
 		LeftJoin("users ON users.id = user_id").
 		Where(sq.Eq{"access_key": accessKey}))
@@ -61,13 +70,69 @@ func authenticateAPI(db *database.Connection, r *http.Request, requestBody io.Re
 	if apiKey.DisabledFlag {
 		return emptyUserData, errorwrap.DisabledUserError()
 	}
+	if apiKey.ExpiresAt != nil && !time.Now().Before(*apiKey.ExpiresAt) {
+		return emptyUserData, errors.New("API key has expired")
+	}
+	if nonce != "" {
+		fresh, err := nonces.use(accessKey, nonce, time.Now())
+		if err != nil {
+			return emptyUserData, errorwrap.WrapError("Unable to record request nonce", err)
+		}
+		if !fresh {
+			return emptyUserData, errorwrap.ReplayedRequest()
+		}
+	}
 
 	err = db.Update(sq.Update("api_keys").Set("last_auth", time.Now()).Where(sq.Eq{"access_key": accessKey}))
 	if err != nil {
 		logging.ReqLogger(r.Context()).Error("Failed to update last_auth", "access_key", accessKey, "error", err)
 	}
 
-	return UserData{ID: apiKey.UserID, Headless: apiKey.Headless}, nil
+	var operationIDs []int64
+	if apiKey.RestrictOperations {
+		err = db.Select(&operationIDs, sq.Select("operation_id").
+			From("api_key_operations").
+			Where(sq.Eq{"api_key_id": apiKey.ID}))
+		if err != nil {
+			return emptyUserData, errorwrap.WrapError("Unable to retrieve API key operations", err)
+		}
+	}
+
+	userPolicy := buildPolicyForUser(r.Context(), db, apiKey.UserID, false, apiKey.Headless)
+	return UserData{
+		ID:       apiKey.UserID,
+		Headless: apiKey.Headless,
+		Policy:   restrictPolicyForAPIKey(userPolicy, apiKey.APIKey, operationIDs),
+	}, nil
+}
+
+// restrictPolicyForAPIKey wraps the user's policy in a policy.Restricted when the API key is limited to
+// specific operations (those provided) or to a scope other than full access
+func restrictPolicyForAPIKey(userPolicy policy.Policy, apiKey models.APIKey, operationIDs []int64) policy.Policy {
+	if !apiKey.RestrictOperations && (apiKey.Scope == "" || apiKey.Scope == policy.APIKeyScopeFull) {
+		return userPolicy
+	}
+
+	restricted := &policy.Restricted{P: userPolicy, Scope: apiKey.Scope}
+	if apiKey.RestrictOperations {
+		restricted.OperationIDs = append([]int64{}, operationIDs...)
+	}
+	return restricted
+}
+
+// ParseSignedRequest checks the Date header of a signed request, and returns the access key and HMAC
+// from its Authorization header. Verifying the HMAC is left to the caller.
+func ParseSignedRequest(r *http.Request) (string, []byte, error) {
+	if err := checkDateHeader(r.Header.Get("Date")); err != nil {
+		return "", nil, errorwrap.WrapError("Unable to parse date header (for api auth)", err)
+	}
+
+	// Check HMAC
+	accessKey, headerHMAC, err := parseAuthorizationHeader(r.Header.Get("Authorization"))
+	if err != nil {
+		return "", nil, errorwrap.WrapError("Unable to parse (api) authorization header", err)
+	}
+	return accessKey, headerHMAC, nil
 }
 
 // parseAuthorizationHeader parses the authorization header and returns the access key and HMAC
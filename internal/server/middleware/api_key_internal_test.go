@@ -14,8 +14,10 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/signer"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/stretchr/testify/require"
 )
 
@@ -68,6 +70,7 @@ func TestAuthenticateAPI(t *testing.T) {
 	disabledUser := createDummyUser(t, db, models.User{Slug: "snail", FirstName: "fn", LastName: "ln", Email: "disabledUser@example.com", Disabled: true})
 	disabledUsernames := createAPIKey(t, db, disabledUser)
 
+	nonces := newNonceStore(db)
 	browser := testBrowser{}
 	newReq := func() (*http.Request, io.Reader) {
 		_, r := browser.newRequest()
@@ -76,28 +79,167 @@ func TestAuthenticateAPI(t *testing.T) {
 
 	// actual tests
 	req, reader := newReq()
-	_, badDateErr := authenticateAPI(db, req, reader)
+	_, badDateErr := authenticateAPI(db, req, reader, nonces, false)
 	require.Error(t, badDateErr)
 
 	req, reader = newReq()
 	req.Header.Add("Date", nowInGMT())
-	_, badAuth := authenticateAPI(db, req, reader)
+	_, badAuth := authenticateAPI(db, req, reader, nonces, false)
 	require.Error(t, badAuth)
 
 	req, reader = newReq()
 	addGoodHeaders(t, req, "badAccessKey", []byte("badSecretKey"))
-	_, badKeys := authenticateAPI(db, req, reader)
+	_, badKeys := authenticateAPI(db, req, reader, nonces, false)
 	require.Error(t, badKeys)
 
 	req, reader = newReq()
 	addGoodHeaders(t, req, disabledUsernames.AccessKey, disabledUsernames.SecretKey)
-	_, disabledUserError := authenticateAPI(db, req, reader)
+	_, disabledUserError := authenticateAPI(db, req, reader, nonces, false)
 	require.Equal(t, errorwrap.DisabledUserError(), disabledUserError)
 
 	req, reader = newReq()
 	addGoodHeaders(t, req, keyData.AccessKey, keyData.SecretKey)
-	_, shouldWorkErr := authenticateAPI(db, req, reader)
+	userData, shouldWorkErr := authenticateAPI(db, req, reader, nonces, false)
 	require.NoError(t, shouldWorkErr)
+	require.IsType(t, &policy.Union{}, userData.Policy)
+
+	expiredKey := createAPIKey(t, db, userID)
+	err := db.Update(sq.Update("api_keys").
+		Set("expires_at", time.Now().Add(-time.Minute)).
+		Where(sq.Eq{"access_key": expiredKey.AccessKey}))
+	require.NoError(t, err)
+	req, reader = newReq()
+	addGoodHeaders(t, req, expiredKey.AccessKey, expiredKey.SecretKey)
+	_, expiredErr := authenticateAPI(db, req, reader, nonces, false)
+	require.Error(t, expiredErr)
+
+	scopedKey := createAPIKey(t, db, userID)
+	err = db.Update(sq.Update("api_keys").
+		SetMap(map[string]interface{}{
+			"expires_at":          time.Now().Add(time.Hour),
+			"restrict_operations": true,
+			"scope":               policy.APIKeyScopeReadOnly,
+		}).
+		Where(sq.Eq{"access_key": scopedKey.AccessKey}))
+	require.NoError(t, err)
+	operationID, err := db.Insert("operations", map[string]interface{}{"slug": "op", "name": "Operation"})
+	require.NoError(t, err)
+	var scopedKeyID int64
+	require.NoError(t, db.Get(&scopedKeyID, sq.Select("id").From("api_keys").Where(sq.Eq{"access_key": scopedKey.AccessKey})))
+	_, err = db.Insert("api_key_operations", map[string]interface{}{"api_key_id": scopedKeyID, "operation_id": operationID})
+	require.NoError(t, err)
+	req, reader = newReq()
+	addGoodHeaders(t, req, scopedKey.AccessKey, scopedKey.SecretKey)
+	userData, scopedErr := authenticateAPI(db, req, reader, nonces, false)
+	require.NoError(t, scopedErr)
+	require.Equal(t, &policy.Restricted{
+		P:            userData.Policy.(*policy.Restricted).P,
+		OperationIDs: []int64{operationID},
+		Scope:        policy.APIKeyScopeReadOnly,
+	}, userData.Policy)
+
+	// deleting the key's operations does not grant the key access to other operations
+	require.NoError(t, db.Delete(sq.Delete("operations").Where(sq.Eq{"id": operationID})))
+	req, reader = newReq()
+	addGoodHeaders(t, req, scopedKey.AccessKey, scopedKey.SecretKey)
+	userData, scopedErr = authenticateAPI(db, req, reader, nonces, false)
+	require.NoError(t, scopedErr)
+	require.Equal(t, []int64{}, userData.Policy.(*policy.Restricted).OperationIDs)
+
+	req, reader = newReq()
+	addGoodHeaders(t, req, keyData.AccessKey, keyData.SecretKey)
+	_, missingNonceErr := authenticateAPI(db, req, reader, nonces, true)
+	require.Error(t, missingNonceErr)
+
+	req, reader = newReq()
+	require.NoError(t, signer.AddNonce(req))
+	addGoodHeaders(t, req, keyData.AccessKey, keyData.SecretKey)
+	_, nonceErr := authenticateAPI(db, req, reader, nonces, true)
+	require.NoError(t, nonceErr)
+	_, replayErr := authenticateAPI(db, req, strings.NewReader(""), nonces, true)
+	require.True(t, errorwrap.IsErrorReplayedRequest(replayErr))
+
+	// nonces are part of the signature, so cannot be swapped out for a fresh one
+	req.Header.Set(signer.NonceHeader, "a-different-nonce")
+	_, swappedNonceErr := authenticateAPI(db, req, strings.NewReader(""), nonces, true)
+	require.Error(t, swappedNonceErr)
+	require.False(t, errorwrap.IsErrorReplayedRequest(swappedNonceErr))
+}
+
+func TestNonceStore(t *testing.T) {
+	db := initTestDB(t)
+	nonces := newNonceStore(db)
+	now := time.Now().Truncate(time.Second)
+	use := func(accessKey, nonce string, at time.Time) bool {
+		fresh, err := nonces.use(accessKey, nonce, at)
+		require.NoError(t, err)
+		return fresh
+	}
+
+	require.True(t, use("key-a", "nonce", now))
+	require.False(t, use("key-a", "nonce", now.Add(time.Minute)))
+	// nonces are tracked per access key, and are case sensitive
+	require.True(t, use("key-b", "nonce", now.Add(time.Minute)))
+	require.True(t, use("key-a", "NONCE", now.Add(time.Minute)))
+
+	// nonces are shared between servers
+	otherServer := newNonceStore(db)
+	fresh, err := otherServer.use("key-a", "nonce", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, fresh)
+
+	// expired nonces may be reused, and are pruned
+	later := now.Add(nonceLifetime + time.Minute)
+	require.True(t, use("key-a", "other-nonce", later))
+	var remaining int64
+	require.NoError(t, db.Get(&remaining, sq.Select("COUNT(*)").From("api_nonces").Where(sq.Eq{"access_key": "key-b"})))
+	require.Equal(t, int64(0), remaining)
+	require.True(t, use("key-a", "nonce", later))
+}
+
+func TestRestrictPolicyForAPIKey(t *testing.T) {
+	userPolicy := &policy.Operation{
+		UserID: 1,
+		OperationRoleMap: map[int64]policy.OperationRole{
+			1: policy.OperationRoleAdmin,
+			2: policy.OperationRoleAdmin,
+		},
+	}
+	restrict := func(operationIDs []int64, scope string) policy.Policy {
+		return restrictPolicyForAPIKey(&policy.Union{P1: policy.NewAuthenticatedPolicy(1, false), P2: userPolicy}, models.APIKey{
+			RestrictOperations: operationIDs != nil,
+			Scope:              scope,
+		}, operationIDs)
+	}
+	opOne := []int64{1}
+
+	// unrestricted keys keep the user's policy
+	require.IsType(t, &policy.Union{}, restrict(nil, policy.APIKeyScopeFull))
+
+	evidenceWriter := restrict(opOne, policy.APIKeyScopeEvidenceWrite)
+	require.True(t, evidenceWriter.Check(policy.CanReadOperation{OperationID: 1}))
+	require.True(t, evidenceWriter.Check(policy.CanModifyEvidenceOfOperation{OperationID: 1}))
+	require.True(t, evidenceWriter.Check(policy.CanModifyTagsOfOperation{OperationID: 1}))
+	require.False(t, evidenceWriter.Check(policy.CanModifyFindingsOfOperation{OperationID: 1}))
+	require.False(t, evidenceWriter.Check(policy.CanDeleteOperation{OperationID: 1}))
+	require.False(t, evidenceWriter.Check(policy.CanReadOperation{OperationID: 2}))
+	require.False(t, evidenceWriter.Check(policy.CanModifyEvidenceOfOperation{OperationID: 2}))
+	require.False(t, evidenceWriter.Check(policy.CanCreateOperations{}))
+	require.False(t, evidenceWriter.Check(policy.CanModifyAPIKeys{UserID: 1}))
+	require.True(t, evidenceWriter.Check(policy.CanReadUser{UserID: 1}))
+
+	readOnly := restrict(nil, policy.APIKeyScopeReadOnly)
+	require.True(t, readOnly.Check(policy.CanReadOperation{OperationID: 2}))
+	require.False(t, readOnly.Check(policy.CanModifyEvidenceOfOperation{OperationID: 2}))
+
+	// restricting operations, but not actions, still prevents minting unrestricted keys
+	fullForOne := restrict(opOne, policy.APIKeyScopeFull)
+	require.True(t, fullForOne.Check(policy.CanDeleteOperation{OperationID: 1}))
+	require.False(t, fullForOne.Check(policy.CanDeleteOperation{OperationID: 2}))
+	require.False(t, fullForOne.Check(policy.CanModifyAPIKeys{UserID: 1}))
+
+	// a key whose operations have all been deleted cannot access any operation
+	require.False(t, restrict([]int64{}, policy.APIKeyScopeFull).Check(policy.CanReadOperation{OperationID: 1}))
 }
 
 func addGoodHeaders(t *testing.T, r *http.Request, accessKey string, secretKey []byte) {
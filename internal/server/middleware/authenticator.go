@@ -79,7 +79,8 @@ func Policy(ctx context.Context) policy.Policy {
 	return p
 }
 
-func AuthenticateAppAndInjectCtx(db *database.Connection) MiddlewareFunc {
+func AuthenticateAppAndInjectCtx(db *database.Connection, requireNonce bool) MiddlewareFunc {
+	nonces := newNonceStore(db)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			body, cleanup, err := cloneBody(r)
@@ -89,16 +90,23 @@ func AuthenticateAppAndInjectCtx(db *database.Connection) MiddlewareFunc {
 			}
 			defer cleanup()
 
-			userData, err := authenticateAPI(db, r, body)
+			userData, err := authenticateAPI(db, r, body, nonces, requireNonce)
 			if err != nil {
 				logging.LogWithoutAuth(
 					"Unable to build user policy",
 					"error", err.Error(),
 				)
-				respondWithError(w, r, errorwrap.UnauthorizedWriteErr(err))
+				if !errorwrap.IsErrorReplayedRequest(err) {
+					err = errorwrap.UnauthorizedWriteErr(err)
+				}
+				respondWithError(w, r, err)
 				return
 			}
-			ctx := buildContextForUser(r.Context(), db, userData.ID, false, userData.Headless)
+			ctx := InjectIntoContext(r.Context(), InjectIntoContextInput{
+				IsSuperAdmin: false,
+				UserID:       userData.ID,
+				UserPolicy:   userData.Policy,
+			})
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -203,6 +211,11 @@ func cloneBody(r *http.Request) (io.Reader, func(), error) {
 
 	_, err = io.Copy(bodyTmpFile, r.Body)
 	if err != nil {
+		bodyTmpFile.Close()
+		os.Remove(bodyTmpFile.Name())
+		if IsRequestTooLarge(err) {
+			return nil, func() {}, errorwrap.TooLargeErr(err, "The request is too large")
+		}
 		return nil, func() {}, err
 	}
 	bodyTmpFile.Close()
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"golang.org/x/time/rate"
+)
+
+// How often idle limiters are removed from a RateLimiter
+const rateLimiterPruneInterval = 10 * time.Minute
+
+// LimitRequestSize rejects requests with a body larger than maxSize bytes. Since bodies are streamed,
+// oversized requests are only detected once read, at which point reading the body returns an
+// *http.MaxBytesError (see IsRequestTooLarge). A maxSize of zero allows bodies of any size.
+func LimitRequestSize(maxSize int64) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxSize > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsRequestTooLarge checks if the provided error was caused by reading a body beyond the limit
+// set by LimitRequestSize
+func IsRequestTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// RateLimiter tracks how quickly each API key or user is making requests. Each is allowed a number of
+// requests per minute, and may briefly exceed that rate by up to the burst size.
+type RateLimiter struct {
+	mutex     sync.Mutex
+	limit     rate.Limit
+	burst     int
+	limiters  map[string]*rate.Limiter
+	lastPrune time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing perMinute requests per minute, with the given burst
+// size. A perMinute of zero (or less) disables rate limiting.
+func NewRateLimiter(perMinute float64, burst int) *RateLimiter {
+	limit := rate.Inf
+	if perMinute > 0 {
+		limit = rate.Limit(perMinute / 60)
+	}
+	return &RateLimiter{
+		limit:    limit,
+		burst:    max(burst, 1),
+		limiters: map[string]*rate.Limiter{},
+	}
+}
+
+// allow records a request for the given key. If the key has exceeded its rate, false is returned,
+// along with how long to wait before the next request will be allowed.
+func (l *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.limit == rate.Inf {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.lastPrune) >= rateLimiterPruneInterval {
+		l.prune(now)
+	}
+
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = limiter
+	}
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// prune removes limiters that have refilled completely, since they behave the same as a new limiter.
+// The caller must hold the mutex.
+func (l *RateLimiter) prune(now time.Time) {
+	for key, limiter := range l.limiters {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.limiters, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// LimitRequestRate rejects requests made faster than the limiter allows. Requests are grouped by the
+// key returned from requestKey (see RateLimitByAccessKey and RateLimitByUser). Requests with an empty
+// key are not limited.
+func LimitRequestRate(limiter *RateLimiter, requestKey func(*http.Request) string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := requestKey(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if ok, retryAfter := limiter.allow(key, time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				respondWithError(w, r, errorwrap.TooManyRequestsErr(fmt.Errorf("Rate limit exceeded for %s", key)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitByAccessKey groups requests by the API key they were signed with. This should only be used
+// after AuthenticateAppAndInjectCtx, so that the access key has been verified.
+func RateLimitByAccessKey(r *http.Request) string {
+	accessKey, _, err := parseAuthorizationHeader(r.Header.Get("Authorization"))
+	if err != nil || accessKey == "" {
+		return ""
+	}
+	return "apikey:" + accessKey
+}
+
+// RateLimitByUser groups requests by the logged in user. Requests without a logged in user are not limited.
+func RateLimitByUser(r *http.Request) string {
+	userID := UserID(r.Context())
+	if userID == 0 {
+		return ""
+	}
+	return "user:" + strconv.FormatInt(userID, 10)
+}
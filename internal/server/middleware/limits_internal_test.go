@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(60, 2)
+
+	ok, _ := limiter.allow("a", now)
+	require.True(t, ok)
+	ok, _ = limiter.allow("a", now)
+	require.True(t, ok)
+	ok, retryAfter := limiter.allow("a", now)
+	require.False(t, ok)
+	require.Equal(t, time.Second, retryAfter)
+
+	// keys are limited separately
+	ok, _ = limiter.allow("b", now)
+	require.True(t, ok)
+
+	// requests are allowed again once the rate allows
+	ok, _ = limiter.allow("a", now.Add(time.Second))
+	require.True(t, ok)
+
+	// refilled limiters are pruned
+	limiter.allow("c", now.Add(time.Hour))
+	require.Equal(t, []string{"c"}, keysOf(limiter.limiters))
+
+	unlimited := NewRateLimiter(0, 0)
+	for range 100 {
+		ok, _ = unlimited.allow("a", now)
+		require.True(t, ok)
+	}
+}
+
+func TestLimitRequestRate(t *testing.T) {
+	handler := LimitRequestRate(NewRateLimiter(1, 1), func(r *http.Request) string {
+		return r.Header.Get("X-Test-Key")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Test-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, serve("a").Code)
+	limited := serve("a")
+	require.Equal(t, http.StatusTooManyRequests, limited.Code)
+	require.Equal(t, "60", limited.Header().Get("Retry-After"))
+
+	// requests without a key are not limited
+	require.Equal(t, http.StatusOK, serve("").Code)
+	require.Equal(t, http.StatusOK, serve("").Code)
+}
+
+func TestLimitRequestSize(t *testing.T) {
+	var cloneErr error
+	handler := LimitRequestSize(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cleanup func()
+		_, cleanup, cloneErr = cloneBody(r)
+		cleanup()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("short")))
+	require.NoError(t, cloneErr)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("far too long")))
+	require.Error(t, cloneErr)
+	require.Equal(t, http.StatusRequestEntityTooLarge, cloneErr.(*errorwrap.HTTPError).HTTPStatus)
+}
+
+func keysOf[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Max allowed length of a request nonce
+const maxNonceLength = 128
+
+// Nonces must be remembered for as long as a request using them could be accepted. Since the Date
+// header may be up to maxDateDelta in the future, that is twice the allowed delta from first use.
+const nonceLifetime = 2 * maxDateDelta
+
+// How often expired nonces are removed from the database
+const noncePruneInterval = time.Minute
+
+// nonceStore remembers the nonces recently used with each access key, so that a signed request
+// cannot be replayed while its Date header is still accepted. Nonces are kept in the api_nonces
+// table, so a request used with one server is also rejected by any other server sharing the database.
+type nonceStore struct {
+	db        *database.Connection
+	mutex     sync.Mutex
+	lastPrune time.Time
+}
+
+func newNonceStore(db *database.Connection) *nonceStore {
+	return &nonceStore{db: db}
+}
+
+// use records the nonce as used with the given access key. Returns false if the nonce was already
+// used with that access key, and has not yet expired.
+func (s *nonceStore) use(accessKey, nonce string, now time.Time) (bool, error) {
+	s.pruneIfDue(now)
+
+	// an expired nonce may be reused, so it is removed first. Concurrent uses of the same nonce are
+	// resolved by the primary key: only one of the inserts can succeed.
+	err := s.db.Delete(sq.Delete("api_nonces").Where(sq.And{
+		sq.Eq{"access_key": accessKey, "nonce": nonce},
+		sq.LtOrEq{"expires_at": now},
+	}))
+	if err != nil {
+		return false, err
+	}
+	_, err = s.db.Insert("api_nonces", map[string]interface{}{
+		"access_key": accessKey,
+		"nonce":      nonce,
+		"expires_at": now.Add(nonceLifetime),
+	})
+	if database.IsAlreadyExistsError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pruneIfDue removes all expired nonces, at most once per noncePruneInterval
+func (s *nonceStore) pruneIfDue(now time.Time) {
+	s.mutex.Lock()
+	if now.Sub(s.lastPrune) < noncePruneInterval {
+		s.mutex.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mutex.Unlock()
+
+	// failures are retried on the next prune, and do not affect whether nonces are accepted
+	s.db.Delete(sq.Delete("api_nonces").Where(sq.LtOrEq{"expires_at": now}))
+}
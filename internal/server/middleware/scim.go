@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/server/remux"
+)
+
+// AuthenticateSCIMAndInjectCtx verifies that SCIM requests carry the configured bearer token. Since
+// the identity provider manages every user and group, authenticated requests act as an admin, with no
+// user of their own.
+func AuthenticateSCIMAndInjectCtx(token string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !validSCIMToken(r.Header.Get("Authorization"), token) {
+				logging.LogWithoutAuth("Unable to authenticate SCIM request", "url", r.URL)
+				remux.HandleSCIMError(w, r, errorwrap.UnauthorizedWriteErr(errors.New("Invalid SCIM bearer token")))
+				return
+			}
+			ctx := InjectIntoContext(r.Context(), InjectIntoContextInput{
+				IsSuperAdmin: true,
+				UserID:       0,
+				UserPolicy:   &policy.FullAccess{},
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func validSCIMToken(authorization string, token string) bool {
+	scheme, provided, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(provided)), []byte(token)) == 1
+}
@@ -3,10 +3,12 @@ package remux
 import (
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/scim"
 )
 
 // MediaHandler provides a generic handler for any content that _prefers_ a return value as raw data.
@@ -31,6 +33,48 @@ func MediaHandler(handler func(*http.Request) (io.Reader, error)) http.Handler {
 	})
 }
 
+// DownloadableFile represents content that should be delivered to the client as a file attachment,
+// rather than being rendered inline.
+type DownloadableFile struct {
+	Filename    string
+	ContentType string
+	Content     io.Reader
+}
+
+// DownloadHandler provides a generic handler for content that should be saved by the client as a file.
+// Success responses stream the content with a Content-Disposition header naming the file, and close
+// the content (if it is an io.Closer) once done. Failure cases return json, matching MediaHandler.
+func DownloadHandler(handler func(*http.Request) (*DownloadableFile, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var file *DownloadableFile
+		var err error
+		defer watcher(logging.ReqLogger(r.Context()), func(paniced bool) {
+			if paniced {
+				err = errorwrap.PanicedError()
+			}
+			if err != nil {
+				HandleError(w, r, err)
+				return
+			}
+
+			contentType := file.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+			// closing the content releases whatever produces it, even if the client disconnects early
+			if closer, ok := file.Content.(io.Closer); ok {
+				defer closer.Close()
+			}
+			if _, copyErr := io.Copy(w, file.Content); copyErr != nil {
+				logging.ReqLogger(r.Context()).Error("Unable to complete download", "filename", file.Filename, "error", copyErr)
+			}
+		})
+		file, err = handler(r)
+	})
+}
+
 // JSONHandler provides a generic handler for any request that prefers JSON responses. In all
 // success scenarios, and most error scenarios, json is returned. The exception here is when
 // this project cannot decode/Marshal a JSON message, in which case a plain 500 error with no content
@@ -64,6 +108,51 @@ func JSONHandler(handler func(*http.Request) (interface{}, error)) http.HandlerF
 // Note: In general, users should prefer to use JSONHandler or MediaHandler. This function should
 // only be used in instances where those handlers cannot be used (e.g. because of a redirect)
 func HandleError(w http.ResponseWriter, r *http.Request, rootErr error) {
+	status, publicReason := logError(r, rootErr)
+	writeJSONResponse(w, status, map[string]string{"error": publicReason})
+}
+
+// SCIMHandler provides a handler for SCIM endpoints. Responses use the SCIM media type, and errors
+// are returned as SCIM error messages. Successful POST requests respond with 201 Created, and
+// successful DELETE requests with 204 No Content.
+func SCIMHandler(handler func(*http.Request) (interface{}, error)) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data interface{}
+		var err error
+		defer watcher(logging.ReqLogger(r.Context()), func(paniced bool) {
+			if paniced {
+				err = errorwrap.PanicedError()
+			}
+			if err != nil {
+				HandleSCIMError(w, r, err)
+				return
+			}
+
+			switch r.Method {
+			case "POST":
+				writeSCIMResponse(w, http.StatusCreated, data)
+			case "DELETE":
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				writeSCIMResponse(w, http.StatusOK, data)
+			}
+		})
+		data, err = handler(r)
+	})
+}
+
+// HandleSCIMError is the SCIM equivalent of HandleError, responding with a SCIM error message
+func HandleSCIMError(w http.ResponseWriter, r *http.Request, rootErr error) {
+	status, publicReason := logError(r, rootErr)
+	scimErr := scim.NewError(status, publicReason)
+	if status == http.StatusConflict {
+		scimErr.ScimType = "uniqueness"
+	}
+	writeSCIMResponse(w, status, scimErr)
+}
+
+// logError logs the given error, returning the http status and public reason to respond with
+func logError(r *http.Request, rootErr error) (int, string) {
 	var status int
 	var publicReason string
 	var loggedReason error
@@ -88,8 +177,7 @@ func HandleError(w http.ResponseWriter, r *http.Request, rootErr error) {
 		"status", status,
 		"url", r.URL,
 	)
-
-	writeJSONResponse(w, status, map[string]string{"error": publicReason})
+	return status, publicReason
 }
 
 func writeJSONResponse(w http.ResponseWriter, status int, data interface{}) {
@@ -103,3 +191,15 @@ func writeJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.WriteHeader(status)
 	w.Write(bytes)
 }
+
+func writeSCIMResponse(w http.ResponseWriter, status int, data interface{}) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
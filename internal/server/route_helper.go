@@ -36,3 +36,11 @@ func mediaHandler(handler func(*http.Request) (io.Reader, error)) http.Handler {
 func jsonHandler(handler func(*http.Request) (interface{}, error)) http.Handler {
 	return remux.JSONHandler(handler)
 }
+
+func downloadHandler(handler func(*http.Request) (*remux.DownloadableFile, error)) http.Handler {
+	return remux.DownloadHandler(handler)
+}
+
+func scimHandler(handler func(*http.Request) (interface{}, error)) http.Handler {
+	return remux.SCIMHandler(handler)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/scim"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// SCIMConfig configures the SCIM provisioning endpoint
+type SCIMConfig struct {
+	// Token is the bearer token identity providers authenticate with
+	Token string
+	// AuthScheme is the authentication scheme provisioned users log in with
+	AuthScheme services.SCIMAuthScheme
+	Logger     *slog.Logger
+}
+
+// SCIM binds a SCIM 2.0 provisioning endpoint, allowing an identity provider to manage users and
+// user groups. Requests are authenticated with a bearer token rather than a session or API key.
+func SCIM(r chi.Router, db *database.Connection, cfg SCIMConfig) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthenticateSCIMAndInjectCtx(cfg.Token))
+		r.Use(middleware.LogRequests(cfg.Logger))
+		bindSCIMRoutes(r, db, cfg.AuthScheme)
+	})
+}
+
+func bindSCIMRoutes(r chi.Router, db *database.Connection, scheme services.SCIMAuthScheme) {
+	route(r, "GET", "/ServiceProviderConfig", scimHandler(func(r *http.Request) (interface{}, error) {
+		return scim.NewServiceProviderConfig(services.SCIMMaxResults), nil
+	}))
+
+	route(r, "GET", "/Users", scimHandler(func(r *http.Request) (interface{}, error) {
+		i, err := dissectSCIMListRequest(r)
+		if err != nil {
+			return nil, err
+		}
+		return services.ListSCIMUsers(r.Context(), db, scheme, i)
+	}))
+
+	route(r, "POST", "/Users", scimHandler(func(r *http.Request) (interface{}, error) {
+		var user scim.User
+		if err := decodeSCIMBody(r, &user); err != nil {
+			return nil, err
+		}
+		return services.CreateSCIMUser(r.Context(), db, scheme, user)
+	}))
+
+	route(r, "GET", "/Users/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		return services.ReadSCIMUser(r.Context(), db, scheme, scimResourceID(r))
+	}))
+
+	route(r, "PUT", "/Users/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		var user scim.User
+		if err := decodeSCIMBody(r, &user); err != nil {
+			return nil, err
+		}
+		return services.ReplaceSCIMUser(r.Context(), db, scheme, scimResourceID(r), user)
+	}))
+
+	route(r, "PATCH", "/Users/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		var patch scim.PatchRequest
+		if err := decodeSCIMBody(r, &patch); err != nil {
+			return nil, err
+		}
+		return services.PatchSCIMUser(r.Context(), db, scheme, scimResourceID(r), patch)
+	}))
+
+	route(r, "DELETE", "/Users/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		return nil, services.DeleteSCIMUser(r.Context(), db, scheme, scimResourceID(r))
+	}))
+
+	route(r, "GET", "/Groups", scimHandler(func(r *http.Request) (interface{}, error) {
+		i, err := dissectSCIMListRequest(r)
+		if err != nil {
+			return nil, err
+		}
+		return services.ListSCIMGroups(r.Context(), db, i)
+	}))
+
+	route(r, "POST", "/Groups", scimHandler(func(r *http.Request) (interface{}, error) {
+		var group scim.Group
+		if err := decodeSCIMBody(r, &group); err != nil {
+			return nil, err
+		}
+		return services.CreateSCIMGroup(r.Context(), db, group)
+	}))
+
+	route(r, "GET", "/Groups/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		return services.ReadSCIMGroup(r.Context(), db, scimResourceID(r))
+	}))
+
+	route(r, "PUT", "/Groups/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		var group scim.Group
+		if err := decodeSCIMBody(r, &group); err != nil {
+			return nil, err
+		}
+		return services.ReplaceSCIMGroup(r.Context(), db, scimResourceID(r), group)
+	}))
+
+	route(r, "PATCH", "/Groups/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		var patch scim.PatchRequest
+		if err := decodeSCIMBody(r, &patch); err != nil {
+			return nil, err
+		}
+		return services.PatchSCIMGroup(r.Context(), db, scimResourceID(r), patch)
+	}))
+
+	route(r, "DELETE", "/Groups/{id}", scimHandler(func(r *http.Request) (interface{}, error) {
+		return nil, services.DeleteSCIMGroup(r.Context(), db, scimResourceID(r))
+	}))
+}
+
+func dissectSCIMListRequest(r *http.Request) (services.ListSCIMResourcesInput, error) {
+	dr := dissectNoBodyRequest(r)
+	i := services.ListSCIMResourcesInput{
+		Filter:     dr.FromQuery("filter").OrDefault("").AsString(),
+		StartIndex: int(dr.FromQuery("startIndex").OrDefault(int64(1)).AsInt64()),
+		Count:      int(dr.FromQuery("count").OrDefault(int64(services.SCIMMaxResults)).AsInt64()),
+	}
+	return i, dr.Error
+}
+
+// scimResourceID retrieves the id (i.e. user or group slug) of the requested resource
+func scimResourceID(r *http.Request) string {
+	dr := dissectNoBodyRequest(r)
+	return dr.FromURL("id").AsString()
+}
+
+// decodeSCIMBody decodes SCIM request bodies. These are decoded directly, rather than with a
+// dissector, since SCIM resources are nested structures.
+func decodeSCIMBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errorwrap.BadInputErr(err, "Unable to parse request body")
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/server/remux"
 	"github.com/ashirt-ops/ashirt-server/internal/services"
 	"github.com/go-chi/chi/v5"
 )
@@ -68,4 +69,41 @@ func bindSharedRoutes(r chi.Router, db *database.Connection, contentStore conten
 		return services.ListEvidenceForOperation(r.Context(), db, contentStore, i)
 	}))
 
+	route(r, "GET", "/operations/{operation_slug}/evidence/{evidence_uuid}/verify", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectNoBodyRequest(r)
+		i := services.VerifyEvidenceInput{
+			OperationSlug: dr.FromURL("operation_slug").Required().AsString(),
+			EvidenceUUID:  dr.FromURL("evidence_uuid").Required().AsString(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return services.VerifyEvidence(r.Context(), db, contentStore, i)
+	}))
+
+	route(r, "GET", "/operations/{operation_slug}/verify", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectNoBodyRequest(r)
+		operationSlug := dr.FromURL("operation_slug").Required().AsString()
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return services.VerifyOperationEvidence(r.Context(), db, contentStore, operationSlug)
+	}))
+
+	route(r, "GET", "/operations/{operation_slug}/export", downloadHandler(func(r *http.Request) (*remux.DownloadableFile, error) {
+		dr := dissectNoBodyRequest(r)
+		operationSlug := dr.FromURL("operation_slug").Required().AsString()
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		out, err := services.ExportOperation(r.Context(), db, contentStore, operationSlug)
+		if err != nil {
+			return nil, err
+		}
+		return &remux.DownloadableFile{
+			Filename:    out.Filename,
+			ContentType: "application/zip",
+			Content:     out.Archive,
+		}, nil
+	}))
 }
@@ -34,6 +34,12 @@ type WebConfig struct {
 	SessionStoreKey  []byte
 	UseSecureCookies bool
 	Logger           *slog.Logger
+	// RateLimit is the number of requests per minute allowed for each logged in user. Zero disables rate limiting
+	RateLimit float64
+	// RateLimitBurst is the number of requests a user may make at once, before being limited to the RateLimit
+	RateLimitBurst int
+	// MaxRequestSize is the largest allowed size, in bytes, of an evidence upload. Zero allows uploads of any size
+	MaxRequestSize int64
 }
 
 func (c *WebConfig) validate() error {
@@ -70,6 +76,7 @@ func Web(r chi.Router, db *database.Connection, contentStore contentstore.Store,
 		r.Use(middleware.LogRequests(config.Logger))
 		r.Use(csrf.Handler)
 		r.Use(middleware.AuthenticateUserAndInjectCtx(db, sessionStore))
+		r.Use(middleware.LimitRequestRate(middleware.NewRateLimiter(config.RateLimit, config.RateLimitBurst), middleware.RateLimitByUser))
 
 		supportedAuthSchemes := make([]dtos.SupportedAuthScheme, len(config.AuthSchemes))
 		for i, scheme := range config.AuthSchemes {
@@ -94,11 +101,14 @@ func Web(r chi.Router, db *database.Connection, contentStore contentstore.Store,
 		}
 
 		bindSharedRoutes(r, db, contentStore)
-		bindWebRoutes(r, db, contentStore, sessionStore, &authsWithOutRecovery)
+		bindWebRoutes(r, db, contentStore, sessionStore, &authsWithOutRecovery, config.MaxRequestSize)
 	})
 }
 
-func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentstore.Store, sessionStore *session.Store, supportedAuthSchemes *[]dtos.SupportedAuthScheme) {
+func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentstore.Store, sessionStore *session.Store, supportedAuthSchemes *[]dtos.SupportedAuthScheme, maxRequestSize int64) {
+	// uploads are limited to the size of the largest allowed evidence (see config.MaxRequestSize)
+	uploads := r.With(middleware.LimitRequestSize(maxRequestSize))
+
 	route(r, "POST", "/logout", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		jsonHandler(func(r *http.Request) (interface{}, error) {
 			err := sessionStore.Delete(w, r)
@@ -157,6 +167,30 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return services.ListUsersForAdmin(r.Context(), db, i)
 	}))
 
+	route(r, "GET", "/admin/audit", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		i := services.ListAuditEventsInput{
+			Pagination:    services.ParseRequestQueryPagination(dr, 25),
+			UserSlug:      dr.FromQuery("user").OrDefault("").AsString(),
+			OperationSlug: dr.FromQuery("operation").OrDefault("").AsString(),
+			Action:        dr.FromQuery("action").OrDefault("").AsString(),
+			From:          dr.FromQuery("from").AsTimePtr(),
+			To:            dr.FromQuery("to").AsTimePtr(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return services.ListAuditEvents(r.Context(), db, i)
+	}))
+
+	route(r, "GET", "/admin/content/issues", jsonHandler(func(r *http.Request) (interface{}, error) {
+		return services.ListContentIssues(r.Context(), db)
+	}))
+
+	route(r, "POST", "/admin/search/reindex", jsonHandler(func(r *http.Request) (interface{}, error) {
+		return nil, services.RebuildSearchIndex(r.Context(), db, contentStore)
+	}))
+
 	route(r, "DELETE", "/admin/user/{userSlug}", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectJSONRequest(r)
 		i := dr.FromURL("userSlug").AsString()
@@ -273,6 +307,43 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return services.ListOperationsForAdmin(r.Context(), db)
 	}))
 
+	route(r.With(middleware.LimitRequestSize(config.MaxImportSize())), "POST", "/admin/operations/import", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectFormRequest(r)
+		archive := dr.FromFile("archive")
+		i := services.ImportOperationInput{
+			Slug: dr.FromBody("slug").OrDefault("").AsString(),
+			Name: dr.FromBody("name").OrDefault("").AsString(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		if archive == nil {
+			return nil, errorwrap.MissingValueErr("archive")
+		}
+		defer archive.Close()
+
+		size, err := archive.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, errorwrap.BadInputErr(err, "Unable to read archive")
+		}
+		i.Archive = archive
+		i.ArchiveSize = size
+
+		return services.ImportOperation(r.Context(), db, contentStore, i)
+	}))
+
+	route(r, "PUT", "/admin/operations/{operation_slug}/storage-quota", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		i := services.SetOperationStorageQuotaInput{
+			OperationSlug: dr.FromURL("operation_slug").Required().AsString(),
+			Quota:         dr.FromBody("quota").OrDefault(nil).AsInt64Ptr(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return nil, services.SetOperationStorageQuota(r.Context(), db, i)
+	}))
+
 	route(r, "DELETE", "/operations/{operation_slug}", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectJSONRequest(r)
 		operationSlug := dr.FromURL("operation_slug").Required().AsString()
@@ -305,6 +376,15 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return nil, services.UpdateOperation(r.Context(), db, i)
 	}))
 
+	route(r, "GET", "/operations/{operation_slug}/storage", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		operationSlug := dr.FromURL("operation_slug").Required().AsString()
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return services.ReadOperationStorage(r.Context(), db, operationSlug)
+	}))
+
 	route(r, "GET", "/operations/{operation_slug}/users", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectJSONRequest(r)
 		i := services.ListUsersForOperationInput{
@@ -455,6 +535,35 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return nil, services.DeleteFinding(r.Context(), db, i)
 	}))
 
+	route(r, "GET", "/operations/{operation_slug}/search", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		i := services.SearchOperationInput{
+			Pagination:    services.ParseRequestQueryPagination(dr, 25),
+			OperationSlug: dr.FromURL("operation_slug").Required().AsString(),
+			Query:         dr.FromQuery("query").Required().AsString(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return services.SearchOperation(r.Context(), db, i)
+	}))
+
+	route(r, "GET", "/search", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		timelineFilters, err := helpers.ParseTimelineQuery(dr.FromQuery("query").Required().AsString())
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to parse search query", err)
+		}
+		i := services.SearchOperationsInput{
+			Pagination: services.ParseRequestQueryPagination(dr, 25),
+			Filters:    timelineFilters,
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return services.SearchOperations(r.Context(), db, i)
+	}))
+
 	route(r, "GET", "/operations/{operation_slug}/evidence/creators", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectJSONRequest(r)
 
@@ -492,7 +601,7 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		if err != nil {
 			return nil, errorwrap.WrapError("Unable to read evidence", err)
 		}
-		if s3Store, ok := contentStore.(*contentstore.S3Store); ok && evidence.ContentType == "image" {
+		if s3Store, ok := contentstore.AsS3Store(contentStore); ok && evidence.ContentType == "image" {
 			urlData, err := services.SendURLData(r.Context(), db, s3Store, i)
 			if err != nil {
 				return nil, errorwrap.WrapError("Unable to get s3 URL", err)
@@ -572,7 +681,7 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return nil, services.RunServiceWorker(r.Context(), db, i)
 	}))
 
-	route(r, "POST", "/operations/{operation_slug}/evidence", jsonHandler(func(r *http.Request) (interface{}, error) {
+	route(uploads, "POST", "/operations/{operation_slug}/evidence", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectFormRequest(r)
 		i := services.CreateEvidenceInput{
 			Description:   dr.FromBody("description").Required().AsString(),
@@ -592,7 +701,7 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return services.CreateEvidence(r.Context(), db, contentStore, i)
 	}))
 
-	route(r, "PUT", "/operations/{operation_slug}/evidence/{evidence_uuid}", jsonHandler(func(r *http.Request) (interface{}, error) {
+	route(uploads, "PUT", "/operations/{operation_slug}/evidence/{evidence_uuid}", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectFormRequest(r)
 		i := services.UpdateEvidenceInput{
 			EvidenceUUID:  dr.FromURL("evidence_uuid").Required().AsString(),
@@ -723,6 +832,51 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 		return nil, services.DeleteQuery(r.Context(), db, i)
 	}))
 
+	route(r, "POST", "/operations/{operation_slug}/queries/{query_id}/subscription", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		i := services.QuerySubscriptionInput{
+			OperationSlug: dr.FromURL("operation_slug").Required().AsString(),
+			QueryID:       dr.FromURL("query_id").Required().AsInt64(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return nil, services.SubscribeToQuery(r.Context(), db, i)
+	}))
+
+	route(r, "DELETE", "/operations/{operation_slug}/queries/{query_id}/subscription", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		i := services.QuerySubscriptionInput{
+			OperationSlug: dr.FromURL("operation_slug").Required().AsString(),
+			QueryID:       dr.FromURL("query_id").Required().AsInt64(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return nil, services.UnsubscribeFromQuery(r.Context(), db, i)
+	}))
+
+	route(r, "GET", "/user/query-subscriptions", jsonHandler(func(r *http.Request) (interface{}, error) {
+		return services.ListQuerySubscriptions(r.Context(), db)
+	}))
+
+	route(r, "GET", "/user/query-digest", jsonHandler(func(r *http.Request) (interface{}, error) {
+		return services.ReadQueryDigestSchedule(r.Context(), db)
+	}))
+
+	route(r, "PUT", "/user/query-digest", jsonHandler(func(r *http.Request) (interface{}, error) {
+		dr := dissectJSONRequest(r)
+		i := services.SetQueryDigestScheduleInput{
+			Frequency:   dr.FromBody("frequency").Required().AsString(),
+			SendHour:    dr.FromBody("sendHour").Required().AsInt64(),
+			SendWeekday: dr.FromBody("sendWeekday").OrDefault(int64(1)).AsInt64(),
+		}
+		if dr.Error != nil {
+			return nil, dr.Error
+		}
+		return nil, services.SetQueryDigestSchedule(r.Context(), db, i)
+	}))
+
 	route(r, "PUT", "/operations/{operation_slug}/tags/{tag_id}", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectJSONRequest(r)
 		i := services.UpdateTagInput{
@@ -815,11 +969,16 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 
 	route(r, "POST", "/user/{userSlug}/apikeys", jsonHandler(func(r *http.Request) (interface{}, error) {
 		dr := dissectJSONRequest(r)
-		userSlug := dr.FromURL("userSlug").AsString()
+		i := services.CreateAPIKeyInput{
+			UserSlug:       dr.FromURL("userSlug").AsString(),
+			ExpiresAt:      dr.FromBody("expiresAt").AsTimePtr(),
+			OperationSlugs: dr.FromBody("operationSlugs").AsStringSlice(),
+			Scope:          dr.FromBody("scope").AsString(),
+		}
 		if dr.Error != nil {
 			return nil, dr.Error
 		}
-		return services.CreateAPIKey(r.Context(), db, userSlug)
+		return services.CreateAPIKey(r.Context(), db, i)
 	}))
 
 	route(r, "DELETE", "/user/{userSlug}/apikeys/{access_key}", jsonHandler(func(r *http.Request) (interface{}, error) {
@@ -904,6 +1063,79 @@ func bindWebRoutes(r chi.Router, db *database.Connection, contentStore contentst
 	}))
 
 	bindServiceWorkerRoutes(r, db)
+	bindWebhookRoutes(r, db)
+}
+
+// bindWebhookRoutes binds the same set of webhook routes for global (admin) webhooks and for
+// operation-scoped webhooks. Global webhook routes have no operation_slug, which services interpret
+// as the global scope.
+func bindWebhookRoutes(r chi.Router, db *database.Connection) {
+	for _, prefix := range []string{"/admin/webhooks", "/operations/{operation_slug}/webhooks"} {
+		route(r, "GET", prefix, jsonHandler(func(r *http.Request) (interface{}, error) {
+			dr := dissectJSONRequest(r)
+			i := services.WebhookScope{
+				OperationSlug: dr.FromURL("operation_slug").AsString(),
+			}
+			if dr.Error != nil {
+				return nil, dr.Error
+			}
+			return services.ListWebhooks(r.Context(), db, i)
+		}))
+
+		route(r, "POST", prefix, jsonHandler(func(r *http.Request) (interface{}, error) {
+			dr := dissectJSONRequest(r)
+			i := services.CreateWebhookInput{
+				WebhookScope: services.WebhookScope{OperationSlug: dr.FromURL("operation_slug").AsString()},
+				Name:         dr.FromBody("name").Required().AsString(),
+				URL:          dr.FromBody("url").Required().AsString(),
+				Events:       dr.FromBody("events").Required().AsStringSlice(),
+			}
+			if dr.Error != nil {
+				return nil, dr.Error
+			}
+			return services.CreateWebhook(r.Context(), db, i)
+		}))
+
+		route(r, "PUT", prefix+"/{webhook_id}", jsonHandler(func(r *http.Request) (interface{}, error) {
+			dr := dissectJSONRequest(r)
+			i := services.UpdateWebhookInput{
+				WebhookScope: services.WebhookScope{OperationSlug: dr.FromURL("operation_slug").AsString()},
+				ID:           dr.FromURL("webhook_id").Required().AsInt64(),
+				Name:         dr.FromBody("name").Required().AsString(),
+				URL:          dr.FromBody("url").Required().AsString(),
+				Events:       dr.FromBody("events").Required().AsStringSlice(),
+				Enabled:      dr.FromBody("enabled").OrDefault(true).AsBool(),
+			}
+			if dr.Error != nil {
+				return nil, dr.Error
+			}
+			return nil, services.UpdateWebhook(r.Context(), db, i)
+		}))
+
+		route(r, "DELETE", prefix+"/{webhook_id}", jsonHandler(func(r *http.Request) (interface{}, error) {
+			dr := dissectJSONRequest(r)
+			i := services.DeleteWebhookInput{
+				WebhookScope: services.WebhookScope{OperationSlug: dr.FromURL("operation_slug").AsString()},
+				ID:           dr.FromURL("webhook_id").Required().AsInt64(),
+			}
+			if dr.Error != nil {
+				return nil, dr.Error
+			}
+			return nil, services.DeleteWebhook(r.Context(), db, i)
+		}))
+
+		route(r, "GET", prefix+"/{webhook_id}/deliveries", jsonHandler(func(r *http.Request) (interface{}, error) {
+			dr := dissectJSONRequest(r)
+			i := services.ListWebhookDeliveriesInput{
+				WebhookScope: services.WebhookScope{OperationSlug: dr.FromURL("operation_slug").AsString()},
+				ID:           dr.FromURL("webhook_id").Required().AsInt64(),
+			}
+			if dr.Error != nil {
+				return nil, dr.Error
+			}
+			return services.ListWebhookDeliveries(r.Context(), db, i)
+		}))
+	}
 }
 
 func bindServiceWorkerRoutes(r chi.Router, db *database.Connection) {
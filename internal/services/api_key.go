@@ -5,10 +5,14 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
@@ -19,16 +23,25 @@ import (
 const accessKeyLength = 18
 const secretKeyLength = 64
 
+// CreateAPIKeyInput describes a new API key. Keys without an expiry never expire, keys without any
+// operation slugs may access every operation, and keys without a scope have full access.
+type CreateAPIKeyInput struct {
+	UserSlug       string
+	ExpiresAt      *time.Time
+	OperationSlugs []string
+	Scope          string
+}
+
 type DeleteAPIKeyInput struct {
 	AccessKey string
 	UserSlug  string
 }
 
-func CreateAPIKey(ctx context.Context, db *database.Connection, userSlug string) (*dtos.APIKey, error) {
+func CreateAPIKey(ctx context.Context, db *database.Connection, i CreateAPIKeyInput) (*dtos.APIKey, error) {
 	var userID int64
 	var err error
 
-	if userID, err = SelfOrSlugToUserID(ctx, db, userSlug); err != nil {
+	if userID, err = SelfOrSlugToUserID(ctx, db, i.UserSlug); err != nil {
 		return nil, errorwrap.WrapError("Unable to create api key", errorwrap.DatabaseErr(err))
 	}
 
@@ -36,6 +49,32 @@ func CreateAPIKey(ctx context.Context, db *database.Connection, userSlug string)
 		return nil, errorwrap.WrapError("Unable to create api key", errorwrap.UnauthorizedWriteErr(err))
 	}
 
+	if i.Scope == "" {
+		i.Scope = policy.APIKeyScopeFull
+	}
+	if !helpers.ContainsMatch(policy.APIKeyScopes, i.Scope) {
+		err := fmt.Errorf("Bad scope: %s", i.Scope)
+		return nil, errorwrap.BadInputErr(err, "Scope must be one of: "+strings.Join(policy.APIKeyScopes, ", "))
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(time.Now()) {
+		err := fmt.Errorf("Expiry in the past: %v", i.ExpiresAt)
+		return nil, errorwrap.BadInputErr(err, "API keys must expire in the future")
+	}
+
+	operationIDs := make([]int64, len(i.OperationSlugs))
+	for idx, slug := range i.OperationSlugs {
+		operation, err := lookupOperation(db, slug)
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to create api key", errorwrap.UnauthorizedReadErr(err))
+		}
+		if !middleware.IsAdmin(ctx) {
+			if err := policy.Require(middleware.Policy(ctx), policy.CanReadOperation{OperationID: operation.ID}); err != nil {
+				return nil, errorwrap.WrapError("Unable to create api key", errorwrap.UnauthorizedReadErr(err))
+			}
+		}
+		operationIDs[idx] = operation.ID
+	}
+
 	accessKey := make([]byte, accessKeyLength)
 	if _, err := rand.Read(accessKey); err != nil {
 		return nil, errorwrap.WrapError("Unable to generate api key", err)
@@ -49,18 +88,47 @@ func CreateAPIKey(ctx context.Context, db *database.Connection, userSlug string)
 
 	prefixedAccessKey := "AS-" + accessKeyStr
 
-	_, err = db.Insert("api_keys", map[string]interface{}{
-		"user_id":    userID,
-		"access_key": prefixedAccessKey,
-		"secret_key": secretKey,
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		apiKeyID, _ := tx.Insert("api_keys", map[string]interface{}{
+			"user_id":             userID,
+			"access_key":          prefixedAccessKey,
+			"secret_key":          secretKey,
+			"expires_at":          i.ExpiresAt,
+			"restrict_operations": len(operationIDs) > 0,
+			"scope":               i.Scope,
+		})
+		tx.BatchInsert("api_key_operations", len(operationIDs), func(idx int) map[string]interface{} {
+			return map[string]interface{}{
+				"api_key_id":   apiKeyID,
+				"operation_id": operationIDs[idx],
+			}
+		}, "ON DUPLICATE KEY UPDATE operation_id=operation_id")
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionCreateAPIKey,
+			TargetType: AuditTargetAPIKey,
+			Target:     prefixedAccessKey,
+			After: map[string]interface{}{
+				"userId":         userID,
+				"expiresAt":      i.ExpiresAt,
+				"operationSlugs": i.OperationSlugs,
+				"scope":          i.Scope,
+			},
+		})
 	})
 	if err != nil {
 		return nil, errorwrap.WrapError("Unable to record api and secret keys", errorwrap.DatabaseErr(err))
 	}
 
+	var operationSlugs []string
+	if len(i.OperationSlugs) > 0 {
+		operationSlugs = i.OperationSlugs
+	}
 	return &dtos.APIKey{
-		AccessKey: prefixedAccessKey,
-		SecretKey: secretKey,
+		AccessKey:      prefixedAccessKey,
+		SecretKey:      secretKey,
+		ExpiresAt:      i.ExpiresAt,
+		OperationSlugs: operationSlugs,
+		Scope:          i.Scope,
 	}, nil
 }
 
@@ -83,6 +151,12 @@ func DeleteAPIKey(ctx context.Context, db *database.Connection, i DeleteAPIKeyIn
 			From("api_keys").
 			Where(sq.Eq{"user_id": userID, "access_key": i.AccessKey}))
 		tx.Delete(sq.Delete("api_keys").Where(sq.Eq{"id": apiKeyID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionDeleteAPIKey,
+			TargetType: AuditTargetAPIKey,
+			Target:     i.AccessKey,
+			Before:     map[string]interface{}{"userId": userID},
+		})
 	})
 	if err != nil {
 		if database.IsEmptyResultSetError(err) {
@@ -107,7 +181,7 @@ func ListAPIKeys(ctx context.Context, db *database.Connection, userSlug string)
 	}
 
 	var keys []models.APIKey
-	err = db.Select(&keys, sq.Select("access_key", "last_auth").
+	err = db.Select(&keys, sq.Select("id", "access_key", "last_auth", "expires_at", "restrict_operations", "scope").
 		From("api_keys").
 		Where(sq.Eq{"user_id": userID}))
 
@@ -115,11 +189,35 @@ func ListAPIKeys(ctx context.Context, db *database.Connection, userSlug string)
 		return nil, errorwrap.WrapError("Cannot list api keys", errorwrap.DatabaseErr(err))
 	}
 
+	var keyOperations []struct {
+		APIKeyID int64  `db:"api_key_id"`
+		Slug     string `db:"slug"`
+	}
+	err = db.Select(&keyOperations, sq.Select("api_key_id", "slug").
+		From("api_key_operations").
+		Join("operations ON operations.id = api_key_operations.operation_id").
+		Join("api_keys ON api_keys.id = api_key_operations.api_key_id").
+		Where(sq.Eq{"api_keys.user_id": userID}).
+		OrderBy("slug"))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list api keys", errorwrap.DatabaseErr(err))
+	}
+	operationSlugs := make(map[int64][]string, len(keys))
+	for _, keyOperation := range keyOperations {
+		operationSlugs[keyOperation.APIKeyID] = append(operationSlugs[keyOperation.APIKeyID], keyOperation.Slug)
+	}
+
 	keysDTO := make([]*dtos.APIKey, len(keys))
 	for i, key := range keys {
 		keysDTO[i] = &dtos.APIKey{
 			AccessKey: key.AccessKey,
 			LastAuth:  key.LastAuth,
+			ExpiresAt: key.ExpiresAt,
+			Scope:     key.Scope,
+		}
+		if key.RestrictOperations {
+			// operations that have since been deleted are no longer listed
+			keysDTO[i].OperationSlugs = append([]string{}, operationSlugs[key.ID]...)
 		}
 	}
 	return keysDTO, nil
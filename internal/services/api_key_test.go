@@ -3,9 +3,11 @@ package services_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/internal/services"
 	"github.com/stretchr/testify/require"
 )
@@ -30,9 +32,67 @@ func TestCreateAPIKey(t *testing.T) {
 	})
 }
 
+func TestCreateScopedAPIKey(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, seed TestSeedData) {
+		ctx := contextForUser(UserRon, db)
+		expiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		input := services.CreateAPIKeyInput{
+			ExpiresAt:      &expiry,
+			OperationSlugs: []string{OpChamberOfSecrets.Slug},
+			Scope:          policy.APIKeyScopeEvidenceWrite,
+		}
+
+		// verify validation
+		badScope := input
+		badScope.Scope = "everything"
+		_, err := services.CreateAPIKey(ctx, db, badScope)
+		require.Error(t, err)
+		past := time.Now().Add(-time.Minute)
+		badExpiry := input
+		badExpiry.ExpiresAt = &past
+		_, err = services.CreateAPIKey(ctx, db, badExpiry)
+		require.Error(t, err)
+		unreadable := input
+		unreadable.OperationSlugs = []string{OpChamberOfSecrets.Slug, "no-such-operation"}
+		_, err = services.CreateAPIKey(ctx, db, unreadable)
+		require.Error(t, err)
+		_, err = services.CreateAPIKey(contextForUser(UserDraco, db), db, input) // Draco cannot read this operation
+		require.Error(t, err)
+
+		// verify create
+		apiKey, err := services.CreateAPIKey(ctx, db, input)
+		require.NoError(t, err)
+		require.Equal(t, policy.APIKeyScopeEvidenceWrite, apiKey.Scope)
+
+		keys, err := services.ListAPIKeys(ctx, db, "")
+		require.NoError(t, err)
+		for _, key := range keys {
+			if key.AccessKey != apiKey.AccessKey {
+				require.Equal(t, policy.APIKeyScopeFull, key.Scope)
+				require.Nil(t, key.OperationSlugs)
+				require.Nil(t, key.ExpiresAt)
+				continue
+			}
+			require.Equal(t, []string{OpChamberOfSecrets.Slug}, key.OperationSlugs)
+			require.Equal(t, policy.APIKeyScopeEvidenceWrite, key.Scope)
+			require.True(t, expiry.Equal(*key.ExpiresAt))
+		}
+
+		// verify deleted operations are removed from the key, which remains restricted
+		require.NoError(t, services.DeleteOperation(ctx, db, createPopulatedMemStore(seed), OpChamberOfSecrets.Slug))
+		keys, err = services.ListAPIKeys(ctx, db, "")
+		require.NoError(t, err)
+		for _, key := range keys {
+			if key.AccessKey == apiKey.AccessKey {
+				require.Equal(t, []string{}, key.OperationSlugs)
+			}
+		}
+	})
+}
+
 func verifyCreateAPIKey(t *testing.T, expectError bool, ctx context.Context, db *database.Connection, userID int64, userSlug string) {
 	originalKeys := getAPIKeysForUserID(t, db, userID)
-	apiKey, apiErr := services.CreateAPIKey(ctx, db, userSlug)
+	apiKey, apiErr := services.CreateAPIKey(ctx, db, services.CreateAPIKeyInput{UserSlug: userSlug})
 	if expectError {
 		require.Error(t, apiErr)
 		return
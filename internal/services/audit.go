@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Audit actions recorded in the audit_events table
+const (
+	AuditActionCreateAPIKey               = "api_key.create"
+	AuditActionDeleteAPIKey               = "api_key.delete"
+	AuditActionDeleteAuthSchemeUsers      = "auth_scheme.users.delete"
+	AuditActionDeleteExpiredRecoveryCodes = "auth_scheme.expired_recovery_codes.delete"
+	AuditActionDeleteEvidence             = "evidence.delete"
+	AuditActionMoveEvidence               = "evidence.move"
+	AuditActionDeleteFinding              = "finding.delete"
+	AuditActionCreateFindingCategory      = "finding_category.create"
+	AuditActionUpdateFindingCategory      = "finding_category.update"
+	AuditActionDeleteFindingCategory      = "finding_category.delete"
+	AuditActionRestoreFindingCategory     = "finding_category.restore"
+	AuditActionCreateGlobalVar            = "global_var.create"
+	AuditActionUpdateGlobalVar            = "global_var.update"
+	AuditActionDeleteGlobalVar            = "global_var.delete"
+	AuditActionCreateOperation            = "operation.create"
+	AuditActionDeleteOperation            = "operation.delete"
+	AuditActionUpdateOperation            = "operation.update"
+	AuditActionSetOperationStorageQuota   = "operation.storage_quota.set"
+	AuditActionExportOperation            = "operation.export"
+	AuditActionImportOperation            = "operation.import"
+	AuditActionSetUserOperationRole       = "operation.user_role.set"
+	AuditActionSetUserGroupOperationRole  = "operation.user_group_role.set"
+	AuditActionRebuildSearchIndex         = "search_index.rebuild"
+	AuditActionCreateServiceWorker        = "service_worker.create"
+	AuditActionUpdateServiceWorker        = "service_worker.update"
+	AuditActionDeleteServiceWorker        = "service_worker.delete"
+	AuditActionRestoreServiceWorker       = "service_worker.restore"
+	AuditActionDeleteTag                  = "tag.delete"
+	AuditActionCreateDefaultTag           = "default_tag.create"
+	AuditActionUpdateDefaultTag           = "default_tag.update"
+	AuditActionMergeDefaultTags           = "default_tags.merge"
+	AuditActionDeleteDefaultTag           = "default_tag.delete"
+	AuditActionCreateHeadlessUser         = "user.create_headless"
+	AuditActionRegisterUser               = "user.register"
+	AuditActionDeleteUser                 = "user.delete"
+	AuditActionProvisionUser              = "user.provision"
+	AuditActionUpdateProvisionedUser      = "user.provision.update"
+	AuditActionSetUserFlags               = "user.flags.set"
+	AuditActionSyncUserGroups             = "user.groups.sync"
+	AuditActionDeleteUserAuthScheme       = "user.auth_scheme.delete"
+	AuditActionResetUserPassword          = "user.password.reset"
+	AuditActionCreateUserRecoveryCode     = "user.recovery_code.create"
+	AuditActionDeleteUserSessions         = "user.sessions.delete"
+	AuditActionCreateUserGroup            = "user_group.create"
+	AuditActionUpdateUserGroup            = "user_group.update"
+	AuditActionDeleteUserGroup            = "user_group.delete"
+	AuditActionCreateWebhook              = "webhook.create"
+	AuditActionUpdateWebhook              = "webhook.update"
+	AuditActionDeleteWebhook              = "webhook.delete"
+)
+
+// Audit target types recorded in the audit_events table
+const (
+	AuditTargetAPIKey          = "api_key"
+	AuditTargetAuthScheme      = "auth_scheme"
+	AuditTargetDefaultTag      = "default_tag"
+	AuditTargetEvidence        = "evidence"
+	AuditTargetFinding         = "finding"
+	AuditTargetFindingCategory = "finding_category"
+	AuditTargetGlobalVar       = "global_var"
+	AuditTargetOperation       = "operation"
+	AuditTargetSearchIndex     = "search_index"
+	AuditTargetServiceWorker   = "service_worker"
+	AuditTargetTag             = "tag"
+	AuditTargetUser            = "user"
+	AuditTargetUserGroup       = "user_group"
+	AuditTargetWebhook         = "webhook"
+)
+
+// auditEvent describes a single mutating action. Before and After are optional, and are stored as
+// json to capture the state of the target prior to, and after, the change.
+type auditEvent struct {
+	Action      string
+	TargetType  string
+	Target      string
+	OperationID *int64
+	Before      interface{}
+	After       interface{}
+}
+
+type ListAuditEventsInput struct {
+	Pagination
+	UserSlug      string
+	OperationSlug string
+	Action        string
+	From          *time.Time
+	To            *time.Time
+}
+
+// recordAuditEvent stores the provided event, attributing it to the user in the context. When
+// provided a transaction, any failure will cause the transaction to fail, keeping the audit log
+// consistent with the change. Otherwise, failures are logged, but not returned.
+func recordAuditEvent(ctx context.Context, db database.ConnectionProxy, event auditEvent) {
+	log := logging.ReqLogger(ctx)
+
+	before, err := auditStateToJSON(event.Before)
+	if err != nil {
+		log.Error("Unable to encode audit event state", "action", event.Action, "error", err.Error())
+	}
+	after, err := auditStateToJSON(event.After)
+	if err != nil {
+		log.Error("Unable to encode audit event state", "action", event.Action, "error", err.Error())
+	}
+
+	var actorID *int64
+	if userID := middleware.UserID(ctx); userID != 0 {
+		actorID = &userID
+	}
+	var requestID *string
+	if reqID := logging.RequestID(ctx); reqID != "" {
+		requestID = &reqID
+	}
+
+	_, err = db.Insert("audit_events", map[string]interface{}{
+		"actor_id":     actorID,
+		"action":       event.Action,
+		"target_type":  event.TargetType,
+		"target":       event.Target,
+		"operation_id": event.OperationID,
+		"before_state": before,
+		"after_state":  after,
+		"request_id":   requestID,
+	})
+	if err != nil {
+		log.Error("Unable to record audit event", "action", event.Action, "target", event.Target, "error", err.Error())
+	}
+}
+
+// RecordAuditEvent records an action taken outside of services, such as an admin resetting a user's
+// password through an auth scheme. Failures are logged, but not returned.
+func RecordAuditEvent(ctx context.Context, db *database.Connection, action, targetType, target string) {
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     action,
+		TargetType: targetType,
+		Target:     target,
+	})
+}
+
+func auditStateToJSON(state interface{}) (*string, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(data)
+	return &encoded, nil
+}
+
+// ListAuditEvents retrieves a page of audit events, most recent first. Events can be filtered by the
+// acting user, the affected operation, the action taken, and a time range. For use in admin views only.
+func ListAuditEvents(ctx context.Context, db *database.Connection, i ListAuditEventsInput) (*dtos.PaginationWrapper, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to list audit events", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	var events []struct {
+		models.AuditEvent
+		ActorSlug      *string `db:"actor_slug"`
+		ActorFirstName string  `db:"actor_first_name"`
+		ActorLastName  string  `db:"actor_last_name"`
+		OperationSlug  *string `db:"operation_slug"`
+	}
+
+	sb := sq.Select("audit_events.*",
+		"users.slug AS actor_slug",
+		"COALESCE(users.first_name, '') AS actor_first_name",
+		"COALESCE(users.last_name, '') AS actor_last_name",
+		"operations.slug AS operation_slug").
+		From("audit_events").
+		LeftJoin("users ON users.id = audit_events.actor_id").
+		LeftJoin("operations ON operations.id = audit_events.operation_id").
+		OrderBy("audit_events.created_at DESC", "audit_events.id DESC")
+
+	if i.UserSlug != "" {
+		sb = sb.Where(sq.Eq{"users.slug": i.UserSlug})
+	}
+	if i.OperationSlug != "" {
+		// deleted operations can only be found by the recorded target
+		sb = sb.Where(sq.Or{
+			sq.Eq{"operations.slug": i.OperationSlug},
+			sq.Eq{"audit_events.target_type": AuditTargetOperation, "audit_events.target": i.OperationSlug},
+		})
+	}
+	if i.Action != "" {
+		sb = sb.Where(sq.Eq{"audit_events.action": i.Action})
+	}
+	if i.From != nil {
+		sb = sb.Where(sq.GtOrEq{"audit_events.created_at": *i.From})
+	}
+	if i.To != nil {
+		sb = sb.Where(sq.LtOrEq{"audit_events.created_at": *i.To})
+	}
+
+	err := i.Pagination.Select(ctx, db, &events, sb)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list audit events", errorwrap.DatabaseErr(err))
+	}
+
+	eventsDTO := make([]*dtos.AuditEvent, len(events))
+	for idx, event := range events {
+		eventDTO := &dtos.AuditEvent{
+			ID:            event.ID,
+			Action:        event.Action,
+			TargetType:    event.TargetType,
+			Target:        event.Target,
+			OperationSlug: event.OperationSlug,
+			RequestID:     event.RequestID,
+			CreatedAt:     event.CreatedAt,
+		}
+		if event.ActorSlug != nil {
+			eventDTO.Actor = &dtos.User{
+				Slug:      *event.ActorSlug,
+				FirstName: event.ActorFirstName,
+				LastName:  event.ActorLastName,
+			}
+		}
+		if event.BeforeState != nil {
+			eventDTO.Before = json.RawMessage(*event.BeforeState)
+		}
+		if event.AfterState != nil {
+			eventDTO.After = json.RawMessage(*event.AfterState)
+		}
+		eventsDTO[idx] = eventDTO
+	}
+
+	return i.Pagination.WrapData(eventsDTO), nil
+}
@@ -0,0 +1,182 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAuditEvents(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, seed TestSeedData) {
+		memStore := createPopulatedMemStore(seed)
+		adminCtx := contextForUser(UserDumbledore, db)
+
+		// generate some audit events
+		err := services.DeleteEvidence(contextForUser(UserRon, db), db, memStore, services.DeleteEvidenceInput{
+			OperationSlug: OpChamberOfSecrets.Slug,
+			EvidenceUUID:  EviFlyingCar.UUID,
+		})
+		require.NoError(t, err)
+
+		err = services.SetUserFlags(adminCtx, db, services.SetUserFlagsInput{
+			Slug:     UserDraco.Slug,
+			Disabled: helpers.PTrue(),
+		})
+		require.NoError(t, err)
+
+		// verify non-admins cannot read the audit log
+		_, err = services.ListAuditEvents(contextForUser(UserRon, db), db, services.ListAuditEventsInput{Pagination: services.Pagination{PageSize: 10}})
+		require.Error(t, err)
+
+		// verify all events are listed, most recent first
+		wrapper, err := services.ListAuditEvents(adminCtx, db, services.ListAuditEventsInput{Pagination: services.Pagination{PageSize: 10}})
+		require.NoError(t, err)
+		events := wrapper.Content.([]*dtos.AuditEvent)
+		require.Len(t, events, 2)
+		require.Equal(t, services.AuditActionSetUserFlags, events[0].Action)
+		require.Equal(t, UserDraco.Slug, events[0].Target)
+		require.Equal(t, UserDumbledore.Slug, events[0].Actor.Slug)
+		require.Equal(t, services.AuditActionDeleteEvidence, events[1].Action)
+		require.Equal(t, EviFlyingCar.UUID, events[1].Target)
+		require.Equal(t, OpChamberOfSecrets.Slug, *events[1].OperationSlug)
+		require.NotEmpty(t, events[1].Before)
+
+		// verify filters
+		wrapper, err = services.ListAuditEvents(adminCtx, db, services.ListAuditEventsInput{
+			Pagination: services.Pagination{PageSize: 10},
+			UserSlug:   UserRon.Slug,
+		})
+		require.NoError(t, err)
+		events = wrapper.Content.([]*dtos.AuditEvent)
+		require.Len(t, events, 1)
+		require.Equal(t, services.AuditActionDeleteEvidence, events[0].Action)
+
+		wrapper, err = services.ListAuditEvents(adminCtx, db, services.ListAuditEventsInput{
+			Pagination:    services.Pagination{PageSize: 10},
+			OperationSlug: OpChamberOfSecrets.Slug,
+		})
+		require.NoError(t, err)
+		require.Len(t, wrapper.Content.([]*dtos.AuditEvent), 1)
+
+		future := time.Now().Add(time.Hour)
+		wrapper, err = services.ListAuditEvents(adminCtx, db, services.ListAuditEventsInput{
+			Pagination: services.Pagination{PageSize: 10},
+			From:       &future,
+		})
+		require.NoError(t, err)
+		require.Len(t, wrapper.Content.([]*dtos.AuditEvent), 0)
+	})
+}
+
+func TestAdminWritesAreAudited(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, seed TestSeedData) {
+		memStore := createPopulatedMemStore(seed)
+		adminCtx := contextForUser(UserDumbledore, db)
+		webhook, err := services.CreateWebhook(adminCtx, db, services.CreateWebhookInput{
+			Name:   "Owl Post",
+			URL:    "https://owls.example.com/hook",
+			Events: []string{webhooks.EventEvidenceCreated},
+		})
+		require.NoError(t, err)
+
+		cases := []struct {
+			action string
+			write  func(ctx context.Context) error
+		}{
+			{services.AuditActionCreateFindingCategory, func(ctx context.Context) error {
+				_, err := services.CreateFindingCategory(ctx, db, "Magical")
+				return err
+			}},
+			{services.AuditActionUpdateFindingCategory, func(ctx context.Context) error {
+				return services.UpdateFindingCategory(ctx, db, services.UpdateFindingCategoryInput{ID: ProductFindingCategory.ID, Category: "Products"})
+			}},
+			{services.AuditActionDeleteFindingCategory, func(ctx context.Context) error {
+				return services.DeleteFindingCategory(ctx, db, services.DeleteFindingCategoryInput{FindingCategoryID: VendorFindingCategory.ID, DoDelete: true})
+			}},
+			{services.AuditActionRestoreFindingCategory, func(ctx context.Context) error {
+				return services.DeleteFindingCategory(ctx, db, services.DeleteFindingCategoryInput{FindingCategoryID: DeletedCategory.ID, DoDelete: false})
+			}},
+			{services.AuditActionCreateGlobalVar, func(ctx context.Context) error {
+				_, err := services.CreateGlobalVar(ctx, db, services.CreateGlobalVarInput{Name: "LUMOS", Value: "light"})
+				return err
+			}},
+			{services.AuditActionUpdateGlobalVar, func(ctx context.Context) error {
+				return services.UpdateGlobalVar(ctx, db, services.UpdateGlobalVarInput{Name: VarAlohomora.Name, Value: "open doors"})
+			}},
+			{services.AuditActionDeleteGlobalVar, func(ctx context.Context) error {
+				return services.DeleteGlobalVar(ctx, db, VarAscendio.Name)
+			}},
+			{services.AuditActionCreateServiceWorker, func(ctx context.Context) error {
+				return services.CreateServiceWorker(ctx, db, services.CreateServiceWorkerInput{Name: "Owl", Config: DemoServiceWorker.Config})
+			}},
+			{services.AuditActionUpdateServiceWorker, func(ctx context.Context) error {
+				return services.UpdateServiceWorker(ctx, db, services.UpdateServiceWorkerInput{ID: DemoServiceWorker.ID, Name: "Demo 2", Config: DemoServiceWorker.Config})
+			}},
+			{services.AuditActionDeleteServiceWorker, func(ctx context.Context) error {
+				return services.DeleteServiceWorker(ctx, db, services.DeleteServiceWorkerInput{ID: DemoServiceWorker.ID, DoDelete: true})
+			}},
+			{services.AuditActionRestoreServiceWorker, func(ctx context.Context) error {
+				return services.DeleteServiceWorker(ctx, db, services.DeleteServiceWorkerInput{ID: DemoServiceWorker.ID, DoDelete: false})
+			}},
+			{services.AuditActionCreateDefaultTag, func(ctx context.Context) error {
+				_, err := services.CreateDefaultTag(ctx, db, services.CreateDefaultTagInput{Name: "How", ColorName: "lightRed"})
+				return err
+			}},
+			{services.AuditActionUpdateDefaultTag, func(ctx context.Context) error {
+				return services.UpdateDefaultTag(ctx, db, services.UpdateDefaultTagInput{ID: DefaultTagWho.ID, Name: "Whom", ColorName: DefaultTagWho.ColorName})
+			}},
+			{services.AuditActionCreateUserGroup, func(ctx context.Context) error {
+				_, err := services.CreateUserGroup(ctx, db, services.CreateUserGroupInput{Name: "Order of the Phoenix", Slug: "order", UserSlugs: []string{UserHarry.Slug}})
+				return err
+			}},
+			{services.AuditActionUpdateUserGroup, func(ctx context.Context) error {
+				_, err := services.ModifyUserGroup(ctx, db, services.ModifyUserGroupInput{Name: UserGroupGryffindor.Name, Slug: UserGroupGryffindor.Slug, UsersToAdd: []string{UserNeville.Slug}})
+				return err
+			}},
+			{services.AuditActionCreateOperation, func(ctx context.Context) error {
+				_, err := services.CreateOperation(ctx, db, services.CreateOperationInput{Slug: "hpdh", OwnerID: UserDumbledore.ID, Name: "Harry Potter and the Deathly Hallows"})
+				return err
+			}},
+			{services.AuditActionSetUserOperationRole, func(ctx context.Context) error {
+				return services.SetUserOperationRole(ctx, db, services.SetUserOperationRoleInput{OperationSlug: OpChamberOfSecrets.Slug, UserSlug: UserHarry.Slug, Role: policy.OperationRoleAdmin})
+			}},
+			{services.AuditActionSetUserGroupOperationRole, func(ctx context.Context) error {
+				return services.SetUserGroupOperationRole(ctx, db, services.SetUserGroupOperationRoleInput{OperationSlug: OpSorcerersStone.Slug, UserGroupSlug: UserGroupGryffindor.Slug, Role: policy.OperationRoleWrite})
+			}},
+			{services.AuditActionDeleteAuthSchemeUsers, func(ctx context.Context) error {
+				return services.DeleteAuthSchemeUsers(ctx, db, "oidc")
+			}},
+			{services.AuditActionDeleteUserSessions, func(ctx context.Context) error {
+				return services.DeleteSessionsForUserSlug(ctx, db, UserRon.Slug)
+			}},
+			{services.AuditActionRebuildSearchIndex, func(ctx context.Context) error {
+				return services.RebuildSearchIndex(ctx, db, memStore)
+			}},
+			{services.AuditActionUpdateWebhook, func(ctx context.Context) error {
+				return services.UpdateWebhook(ctx, db, services.UpdateWebhookInput{ID: webhook.ID, Name: "Owl Post", URL: "https://owls.example.com/hook", Events: []string{webhooks.EventFindingCreated}})
+			}},
+		}
+
+		for _, tc := range cases {
+			before := countAuditEvents(t, db, tc.action)
+			require.NoError(t, tc.write(adminCtx), tc.action)
+			require.Equal(t, before+1, countAuditEvents(t, db, tc.action), "expected exactly one %v event", tc.action)
+		}
+	})
+}
+
+func countAuditEvents(t *testing.T, db *database.Connection, action string) int64 {
+	var count int64
+	err := db.Get(&count, sq.Select("COUNT(*)").From("audit_events").Where(sq.Eq{"action": action}))
+	require.NoError(t, err)
+	return count
+}
@@ -42,7 +42,17 @@ func DeleteAuthScheme(ctx context.Context, db *database.Connection, i DeleteAuth
 		return errorwrap.WrapError("Unwilling to delete auth scheme", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	err = db.Delete(sq.Delete("auth_scheme_data").Where(sq.Eq{"user_id": userID, "auth_scheme": i.SchemeName}))
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Delete(sq.Delete("auth_scheme_data").Where(sq.Eq{"user_id": userID, "auth_scheme": i.SchemeName}))
+		var userSlug string
+		tx.Get(&userSlug, sq.Select("slug").From("users").Where(sq.Eq{"id": userID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionDeleteUserAuthScheme,
+			TargetType: AuditTargetUser,
+			Target:     userSlug,
+			Before:     map[string]interface{}{"authScheme": i.SchemeName},
+		})
+	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete auth scheme", errorwrap.DatabaseErr(err))
 	}
@@ -56,7 +66,14 @@ func DeleteAuthSchemeUsers(ctx context.Context, db *database.Connection, schemeC
 		return errorwrap.WrapError("Unwilling to remove auth schemes for all users", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	err := db.Delete(sq.Delete("auth_scheme_data").Where(sq.Eq{"auth_scheme": schemeCode}))
+	err := db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Delete(sq.Delete("auth_scheme_data").Where(sq.Eq{"auth_scheme": schemeCode}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionDeleteAuthSchemeUsers,
+			TargetType: AuditTargetAuthScheme,
+			Target:     schemeCode,
+		})
+	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot remove auth schemes for all users", errorwrap.DatabaseErr(err))
 	}
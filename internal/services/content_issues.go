@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Content issue types recorded in the content_issues table
+const (
+	// ContentIssueOrphan reflects stored content that is not referenced by any evidence
+	ContentIssueOrphan = "orphan"
+	// ContentIssueDangling reflects evidence that references content missing from the store
+	ContentIssueDangling = "dangling"
+)
+
+// ListContentIssues retrieves the orphaned content and dangling evidence references found during the
+// most recent content reconciliation. For use in admin views only.
+func ListContentIssues(ctx context.Context, db *database.Connection) (*dtos.ContentIssueReport, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to list content issues", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	var issues []struct {
+		models.ContentIssue
+		EvidenceUUID  *string `db:"evidence_uuid"`
+		OperationSlug *string `db:"operation_slug"`
+	}
+	err := db.Select(&issues, sq.Select("content_issues.*",
+		"evidence.uuid AS evidence_uuid",
+		"operations.slug AS operation_slug").
+		From("content_issues").
+		LeftJoin("evidence ON evidence.id = content_issues.evidence_id").
+		LeftJoin("operations ON operations.id = evidence.operation_id").
+		OrderBy("content_issues.first_seen_at", "content_issues.id"))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list content issues", errorwrap.DatabaseErr(err))
+	}
+
+	report := dtos.ContentIssueReport{
+		Orphans:  []dtos.ContentIssue{},
+		Dangling: []dtos.ContentIssue{},
+	}
+	for _, issue := range issues {
+		issueDTO := dtos.ContentIssue{
+			ContentKey:     issue.ContentKey,
+			EvidenceUUID:   issue.EvidenceUUID,
+			OperationSlug:  issue.OperationSlug,
+			FirstSeenAt:    issue.FirstSeenAt,
+			LastSeenAt:     issue.LastSeenAt,
+			DeleteAttempts: issue.DeleteAttempts,
+			LastError:      issue.LastError,
+		}
+		switch issue.IssueType {
+		case ContentIssueOrphan:
+			report.Orphans = append(report.Orphans, issueDTO)
+		case ContentIssueDangling:
+			report.Dangling = append(report.Dangling, issueDTO)
+		}
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,37 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListContentIssues(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		_, err := db.Insert("content_issues", map[string]interface{}{
+			"issue_type":  services.ContentIssueOrphan,
+			"content_key": "orphan",
+		})
+		require.NoError(t, err)
+		_, err = db.Insert("content_issues", map[string]interface{}{
+			"issue_type":  services.ContentIssueDangling,
+			"content_key": EviFlyingCar.FullImageKey,
+			"evidence_id": EviFlyingCar.ID,
+		})
+		require.NoError(t, err)
+
+		// verify non-admins cannot view the report
+		_, err = services.ListContentIssues(contextForUser(UserRon, db), db)
+		require.Error(t, err)
+
+		report, err := services.ListContentIssues(contextForUser(UserDumbledore, db), db)
+		require.NoError(t, err)
+		require.Len(t, report.Orphans, 1)
+		require.Equal(t, "orphan", report.Orphans[0].ContentKey)
+		require.Len(t, report.Dangling, 1)
+		require.Equal(t, EviFlyingCar.UUID, *report.Dangling[0].EvidenceUUID)
+		require.Equal(t, OpChamberOfSecrets.Slug, *report.Dangling[0].OperationSlug)
+	})
+}
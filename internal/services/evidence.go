@@ -1,22 +1,27 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
 	"time"
 
+	"github.com/ashirt-ops/ashirt-server/internal/config"
 	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/har"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers/filter"
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
 	"github.com/google/uuid"
 
 	sq "github.com/Masterminds/squirrel"
@@ -101,23 +106,43 @@ func CreateEvidence(ctx context.Context, db *database.Connection, contentStore c
 	}
 
 	keys := contentstore.ContentKeys{}
+	var contentHash *string
+	var contentSize int64
+	var harSummary *har.Summary
+	var codeblockContent *bytes.Buffer
 
 	if i.Content != nil {
+		i.Content, contentSize, err = measureEvidenceContent(i.Content, config.MaxEvidenceSize(i.ContentType))
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to upload evidence", errorwrap.UploadErr(err))
+		}
+		if err := ensureEvidenceSizeAllowed(db, operation.ID, i.ContentType, contentSize, 0); err != nil {
+			return nil, errorwrap.WrapError("Unable to create evidence", err)
+		}
+
+		hashingReader := contentstore.NewHashingReader(i.Content)
 		var content contentstore.Storable
 		switch i.ContentType {
 		case "http-request-cycle":
-			fallthrough
-		case "terminal-recording":
-			fallthrough
+			content, harSummary, err = prepareHAR(ctx, hashingReader)
+			if err != nil {
+				return nil, errorwrap.WrapError("Unable to upload evidence", errorwrap.UploadErr(err))
+			}
+
 		case "codeblock":
+			// keep a copy of the code, so that it can be indexed for search
+			codeblockContent = &bytes.Buffer{}
+			content = contentstore.NewBlob(io.TeeReader(hashingReader, codeblockContent))
+
+		case "terminal-recording":
 			fallthrough
 		case "event":
-			content = contentstore.NewBlob(i.Content)
+			content = contentstore.NewBlob(hashingReader)
 
 		case "image":
 			fallthrough
 		default:
-			content = contentstore.NewImage(i.Content)
+			content = contentstore.NewImage(hashingReader)
 		}
 
 		keys, err = content.ProcessPreviewAndUpload(contentStore)
@@ -127,11 +152,19 @@ func CreateEvidence(ctx context.Context, db *database.Connection, contentStore c
 			}
 			return nil, errorwrap.WrapError("Unable to upload evidence", errorwrap.UploadErr(err))
 		}
+		sum := hashingReader.Sum()
+		contentHash = &sum
 	}
 
 	evidenceUUID := uuid.New().String()
 	var evidenceID int64
 	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		if i.Content != nil {
+			if err := ensureStorageAvailable(tx, operation.ID, contentSize, 0); err != nil {
+				tx.FailTransaction(err)
+				return
+			}
+		}
 		evidenceID, _ = tx.Insert("evidence", map[string]interface{}{
 			"uuid":            evidenceUUID,
 			"description":     i.Description,
@@ -142,16 +175,27 @@ func CreateEvidence(ctx context.Context, db *database.Connection, contentStore c
 			"operator_id":     middleware.UserID(ctx),
 			"full_image_key":  keys.Full,
 			"thumb_image_key": keys.Thumbnail,
+			"content_hash":    contentHash,
+			"content_size":    contentSize,
 		})
+		insertHarEntries(tx, evidenceID, harSummary)
 		tx.BatchInsert("tag_evidence_map", len(i.TagIDs), func(idx int) map[string]interface{} {
 			return map[string]interface{}{
 				"tag_id":      i.TagIDs[idx],
 				"evidence_id": evidenceID,
 			}
 		})
+		search.IndexEvidence(tx, []int64{evidenceID})
+		if codeblockContent != nil {
+			search.IndexEvidenceContent(tx, models.Evidence{ID: evidenceID, OperationID: operation.ID, ContentType: i.ContentType}, codeblockContent.Bytes())
+		}
 	})
 
 	if err != nil {
+		removeUnrecordedContent(ctx, contentStore, keys)
+		if httpErr, ok := err.(*errorwrap.HTTPError); ok {
+			return nil, errorwrap.WrapError("Unable to create evidence", httpErr)
+		}
 		return nil, errorwrap.WrapError("Could not create evidence and tags", errorwrap.DatabaseErr(err))
 	}
 
@@ -161,6 +205,18 @@ func CreateEvidence(ctx context.Context, db *database.Connection, contentStore c
 		logging.ReqLogger(ctx).Error("Unable to run workers", "error", err.Error())
 	}
 
+	var operatorSlug string
+	if err := db.Get(&operatorSlug, sq.Select("slug").From("users").Where(sq.Eq{"id": middleware.UserID(ctx)})); err != nil {
+		logging.ReqLogger(ctx).Warn("Unable to lookup evidence operator for webhooks", "error", err.Error())
+	}
+	sendWebhookEvent(ctx, db, operation.ID, i.OperationSlug, webhooks.EventEvidenceCreated, webhooks.EvidenceData{
+		UUID:         evidenceUUID,
+		Description:  i.Description,
+		ContentType:  i.ContentType,
+		OccurredAt:   i.OccurredAt,
+		OperatorSlug: operatorSlug,
+	})
+
 	return &dtos.Evidence{
 		UUID:        evidenceUUID,
 		Description: i.Description,
@@ -181,15 +237,32 @@ func DeleteEvidence(ctx context.Context, db *database.Connection, contentStore c
 
 	err = db.WithTx(ctx, func(tx *database.Transactable) {
 		if i.DeleteAssociatedFindings {
+			var findingIDs []int64
+			tx.Select(&findingIDs, sq.Select("finding_id").From("evidence_finding_map").Where(sq.Eq{"evidence_id": evidence.ID}))
+			search.RemoveDocuments(tx, search.DocumentFinding, findingIDs)
 			tx.Exec(sq.Expr("DELETE findings FROM findings INNER JOIN evidence_finding_map ON findings.id = evidence_finding_map.finding_id WHERE evidence_id = ?", evidence.ID))
 		}
 		tx.Delete(sq.Delete("evidence_finding_map").Where(sq.Eq{"evidence_id": evidence.ID}))
 		tx.Delete(sq.Delete("evidence_metadata").Where(sq.Eq{"evidence_id": evidence.ID}))
+		tx.Delete(sq.Delete("har_entries").Where(sq.Eq{"evidence_id": evidence.ID}))
+		tx.Delete(sq.Delete("service_worker_jobs").Where(sq.Eq{"evidence_id": evidence.ID}))
+		tx.Delete(sq.Delete("service_worker_callbacks").Where(sq.Eq{"evidence_id": evidence.ID}))
 		tx.Delete(sq.Delete("evidence").Where(sq.Eq{"id": evidence.ID}))
+		search.RemoveDocuments(tx, search.DocumentEvidence, []int64{evidence.ID})
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionDeleteEvidence,
+			TargetType:  AuditTargetEvidence,
+			Target:      evidence.UUID,
+			OperationID: &operation.ID,
+			Before:      evidence,
+			After:       map[string]interface{}{"deleteAssociatedFindings": i.DeleteAssociatedFindings},
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete evidence", errorwrap.DatabaseErr(err))
 	}
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventEvidenceDeleted,
+		enhancementservices.BuildDeletedEvidencePayloads(operation.Slug, []models.Evidence{*evidence}))
 
 	if err = deleteEvidenceContent(contentStore, *evidence); err != nil {
 		return errorwrap.WrapError("Cannot delete evidence content", errorwrap.DeleteErr(err))
@@ -235,7 +308,7 @@ func ListEvidenceForFinding(ctx context.Context, db *database.Connection, conten
 	}
 
 	usingS3 := false
-	if _, ok := contentStore.(*contentstore.S3Store); ok {
+	if _, ok := contentstore.AsS3Store(contentStore); ok {
 		usingS3 = true
 	}
 
@@ -314,7 +387,10 @@ func ListEvidenceForOperation(ctx context.Context, db *database.Connection, cont
 		sb = sb.OrderBy("COALESCE(adjusted_at, occurred_at) DESC")
 	}
 
-	sb = buildListEvidenceWhereClause(sb, operation.ID, i.Filters)
+	sb, err = buildListEvidenceWhereClause(sb, operation.ID, i.Filters)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list evidence for an operation", err)
+	}
 
 	err = db.Select(&evidence, sb)
 	if err != nil {
@@ -338,7 +414,7 @@ func ListEvidenceForOperation(ctx context.Context, db *database.Connection, cont
 	evidenceDTO := make([]*dtos.Evidence, len(evidence))
 
 	usingS3 := false
-	if _, ok := contentStore.(*contentstore.S3Store); ok {
+	if _, ok := contentstore.AsS3Store(contentStore); ok {
 		usingS3 = true
 	}
 
@@ -437,19 +513,47 @@ func UpdateEvidence(ctx context.Context, db *database.Connection, contentStore c
 	}
 
 	var keys *contentstore.ContentKeys
+	var contentHash string
+	var contentSize int64
+	var harSummary *har.Summary
+	var codeblockContent *bytes.Buffer
 	if i.Content != nil {
+		i.Content, contentSize, err = measureEvidenceContent(i.Content, config.MaxEvidenceSize(evidence.ContentType))
+		if err != nil {
+			return errorwrap.WrapError("Cannot update evidence content", errorwrap.UploadErr(err))
+		}
+		if err := ensureEvidenceSizeAllowed(db, operation.ID, evidence.ContentType, contentSize, evidence.ID); err != nil {
+			return errorwrap.WrapError("Cannot update evidence content", err)
+		}
+
 		switch evidence.ContentType {
 		case "http-request-cycle":
-			fallthrough
+			hashingReader := contentstore.NewHashingReader(i.Content)
+			content, summary, err := prepareHAR(ctx, hashingReader)
+			if err != nil {
+				return errorwrap.WrapError("Cannot update evidence content", errorwrap.BadInputErr(err, "Failed to process content"))
+			}
+			processedKeys, err := content.ProcessPreviewAndUpload(contentStore)
+			if err != nil {
+				return errorwrap.WrapError("Cannot update evidence content", errorwrap.BadInputErr(err, "Failed to process content"))
+			}
+			keys = &processedKeys
+			contentHash = hashingReader.Sum()
+			harSummary = summary
+
 		case "codeblock":
+			codeblockContent = &bytes.Buffer{}
+			i.Content = io.TeeReader(i.Content, codeblockContent)
 			fallthrough
 		case "terminal-recording":
-			content := contentstore.NewBlob(i.Content)
+			hashingReader := contentstore.NewHashingReader(i.Content)
+			content := contentstore.NewBlob(hashingReader)
 			processedKeys, err := content.ProcessPreviewAndUpload(contentStore)
 			if err != nil {
 				return errorwrap.WrapError("Cannot update evidence content", errorwrap.BadInputErr(err, "Failed to process content"))
 			}
 			keys = &processedKeys
+			contentHash = hashingReader.Sum()
 
 		case "image":
 			fallthrough
@@ -460,6 +564,12 @@ func UpdateEvidence(ctx context.Context, db *database.Connection, contentStore c
 	}
 
 	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		if keys != nil {
+			if err := ensureStorageAvailable(tx, operation.ID, contentSize, evidence.ID); err != nil {
+				tx.FailTransaction(err)
+				return
+			}
+		}
 		ub := sq.Update("evidence").Where(sq.Eq{"id": evidence.ID})
 		if i.Description != nil {
 			ub = ub.Set("description", i.Description)
@@ -468,7 +578,13 @@ func UpdateEvidence(ctx context.Context, db *database.Connection, contentStore c
 			ub = ub.SetMap(map[string]interface{}{
 				"full_image_key":  keys.Full,
 				"thumb_image_key": keys.Thumbnail,
+				"content_hash":    contentHash,
+				"content_size":    contentSize,
 			})
+			if evidence.ContentType == "http-request-cycle" {
+				tx.Delete(sq.Delete("har_entries").Where(sq.Eq{"evidence_id": evidence.ID}))
+				insertHarEntries(tx, evidence.ID, harSummary)
+			}
 		}
 		if i.AdjustedAt != nil {
 			ub = ub.Set("adjusted_at", i.AdjustedAt)
@@ -488,16 +604,50 @@ func UpdateEvidence(ctx context.Context, db *database.Connection, contentStore c
 				}
 			})
 		}
+
+		if i.Description != nil {
+			search.IndexEvidence(tx, []int64{evidence.ID})
+		}
+		if codeblockContent != nil {
+			search.IndexEvidenceContent(tx, *evidence, codeblockContent.Bytes())
+		}
 	})
 	if err != nil {
+		if keys != nil {
+			removeUnrecordedContent(ctx, contentStore, *keys)
+		}
+		if httpErr, ok := err.(*errorwrap.HTTPError); ok {
+			return errorwrap.WrapError("Cannot update evidence content", httpErr)
+		}
 		return errorwrap.WrapError("Cannot update evidence", errorwrap.DatabaseErr(err))
 	}
 
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventEvidenceUpdated,
+		enhancementservices.BuildEvidencePayloads(enhancementservices.EventEvidenceUpdated, []int64{evidence.ID}))
+	if len(i.TagsToAdd) > 0 {
+		sendServiceWorkerEvent(ctx, db, enhancementservices.EventEvidenceTagged,
+			enhancementservices.BuildEvidenceTaggedPayloads(evidence.ID, i.TagsToAdd))
+	}
+
 	return nil
 }
 
-func buildListEvidenceWhereClause(sb sq.SelectBuilder, operationID int64, filters helpers.TimelineFilters) sq.SelectBuilder {
-	sb = sb.Where(sq.Eq{"evidence.operation_id": operationID})
+func buildListEvidenceWhereClause(sb sq.SelectBuilder, operationID int64, filters helpers.TimelineFilters) (sq.SelectBuilder, error) {
+	return addEvidenceFilters(sb.Where(sq.Eq{"evidence.operation_id": operationID}), filters)
+}
+
+// addEvidenceFilters restricts the evidence selected by sb to those matching the timeline filters. An
+// error is returned if the filters cannot be applied to evidence.
+func addEvidenceFilters(sb sq.SelectBuilder, filters helpers.TimelineFilters) (sq.SelectBuilder, error) {
+	if filters.Expression != nil {
+		condition, err := compileTimelineExpression(filters.Expression, evidenceTermWhere)
+		if err != nil {
+			return sb, err
+		}
+		if condition != nil {
+			sb = sb.Where(condition)
+		}
+	}
 	if len(filters.UUID) > 0 {
 		sb = addWhereAndNot(sb, filters.UUID, evidenceUUIDWhere)
 	}
@@ -545,6 +695,18 @@ func buildListEvidenceWhereClause(sb sq.SelectBuilder, operationID int64, filter
 		sb = addWhereAndNot(sb, filters.Operator, evidenceOperatorWhere)
 	}
 
+	if len(filters.Host) > 0 {
+		sb = addHarEntryWhere(sb, filters.Host, harEntryHostWhere)
+	}
+
+	if len(filters.Status) > 0 {
+		sb = addHarEntryWhere(sb, filters.Status, harEntryStatusWhere)
+	}
+
+	if len(filters.URL) > 0 {
+		sb = addHarEntryWhere(sb, filters.URL, harEntryURLWhere)
+	}
+
 	if len(filters.Tags) > 0 {
 		sb = addWhereAndNot(sb, filters.Tags, evidenceTagOrWhere)
 	}
@@ -553,6 +715,40 @@ func buildListEvidenceWhereClause(sb sq.SelectBuilder, operationID int64, filter
 		sb = addWhereAndNot(sb, filters.Type, evidenceTypeWhere)
 	}
 
+	if len(filters.Finding) > 0 {
+		splitValues := filters.Finding.SplitByModifier()
+		if values := splitValues[filter.Normal]; len(values) > 0 {
+			sb = sb.Where(evidenceFindingWhere(true), values, values)
+		}
+		if values := splitValues[filter.Not]; len(values) > 0 {
+			sb = sb.Where(evidenceFindingWhere(false), values, values)
+		}
+	}
+
+	if len(filters.HasMetadata) > 0 {
+		sb = addWhereAndNot(sb, filters.HasMetadata, evidenceInSubqueryWhere(eviMetadataSourceSubquery))
+	}
+
+	if len(filters.MetadataStatus) > 0 {
+		sb = addWhereAndNot(sb, filters.MetadataStatus, evidenceInSubqueryWhere(eviMetadataStatusSubquery))
+	}
+
+	if filters.CreatedByWorker != nil {
+		sb = sb.Where(evidenceInSubqueryWhere(eviWorkerMetadataSubquery)(*filters.CreatedByWorker))
+	}
+
+	if filters.Adjusted != nil {
+		sb = sb.Where(evidenceAdjustedWhere(*filters.Adjusted))
+	}
+
+	if filters.After != nil {
+		sb = sb.Where(sq.GtOrEq{"evidence.occurred_at": *filters.After})
+	}
+
+	if filters.Before != nil {
+		sb = sb.Where(sq.LtOrEq{"evidence.occurred_at": *filters.Before})
+	}
+
 	if filters.Linked != nil {
 		query := "evidence.id"
 		if *filters.Linked {
@@ -564,11 +760,94 @@ func buildListEvidenceWhereClause(sb sq.SelectBuilder, operationID int64, filter
 		sb = sb.Where(query)
 	}
 
-	return sb
+	return sb, nil
 }
 
 const eviLinkedSubquery = "(SELECT evidence_id FROM evidence_finding_map)"
 
+// subqueries selecting the IDs of evidence with particular metadata. These are shared with findings
+// filters, which match findings with any such evidence.
+const eviMetadataSourceSubquery = "(SELECT evidence_id FROM evidence_metadata WHERE source IN (?))"
+const eviMetadataStatusSubquery = "(SELECT evidence_id FROM evidence_metadata WHERE status IN (?))"
+
+// eviWorkerMetadataSubquery selects evidence with metadata from an enhancement worker. Only workers
+// record a status; metadata added directly via the API has none.
+const eviWorkerMetadataSubquery = "(SELECT evidence_id FROM evidence_metadata WHERE status IS NOT NULL)"
+
+// evidenceTermWhere builds the condition for a single filter of a boolean timeline query
+func evidenceTermWhere(term *helpers.QueryExpr) sq.Sqlizer {
+	value := term.Value.Value
+	switch term.Key {
+	case "":
+		return sq.Like{"description": "%" + value + "%"}
+	case "meta":
+		return sq.Expr("evidence.id IN (SELECT evidence_id FROM evidence_metadata WHERE body LIKE ?)", "%"+value+"%")
+	case "uuid":
+		return sq.Expr(evidenceUUIDWhere(true), []string{value})
+	case "operator":
+		return sq.Expr(evidenceOperatorWhere(true), []string{value})
+	case "tag":
+		return sq.Expr(evidenceTagOrWhere(true), []string{value})
+	case "type":
+		return sq.Expr(evidenceTypeWhere(true), []string{value})
+	case "host":
+		return harEntryTermWhere(harEntryHostWhere(value))
+	case "status":
+		return harEntryTermWhere(harEntryStatusWhere(value))
+	case "url":
+		return harEntryTermWhere(harEntryURLWhere(value))
+	case "range":
+		return sq.And{
+			sq.GtOrEq{"evidence.occurred_at": term.DateRange.From},
+			sq.LtOrEq{"evidence.occurred_at": term.DateRange.To},
+		}
+	case "after":
+		return sq.GtOrEq{"evidence.occurred_at": term.DateRange.From}
+	case "before":
+		return sq.LtOrEq{"evidence.occurred_at": term.DateRange.To}
+	case "linked":
+		if value == "all" {
+			return matchAll
+		}
+		return sq.Expr("evidence.id " + inOrNotIn(value == "true") + " " + eviLinkedSubquery)
+	case "finding":
+		return sq.Expr(evidenceFindingWhere(true), []string{value}, []string{value})
+	case "has-metadata":
+		return sq.Expr(evidenceInSubqueryWhere(eviMetadataSourceSubquery)(true), []string{value})
+	case "metadata-status":
+		return sq.Expr(evidenceInSubqueryWhere(eviMetadataStatusSubquery)(true), []string{value})
+	case "created-by-worker":
+		return sq.Expr(evidenceInSubqueryWhere(eviWorkerMetadataSubquery)(value == "true"))
+	case "adjusted":
+		return sq.Expr(evidenceAdjustedWhere(value == "true"))
+	}
+	return nil
+}
+
+// evidenceInSubqueryWhere returns a where function that matches evidence (not) selected by the subquery
+func evidenceInSubqueryWhere(subquery string) func(bool) string {
+	return func(in bool) string {
+		return "evidence.id " + inOrNotIn(in) + " " + subquery
+	}
+}
+
+// evidenceFindingWhere matches evidence linked to findings with the given UUIDs or titles. The values
+// must be provided twice: once for the UUIDs, and once for the titles.
+func evidenceFindingWhere(in bool) string {
+	return "evidence.id " + inOrNotIn(in) + " (" +
+		"  SELECT evidence_id FROM evidence_finding_map" +
+		"  INNER JOIN findings ON findings.id = evidence_finding_map.finding_id" +
+		"  WHERE findings.uuid IN (?) OR findings.title IN (?)" +
+		")"
+}
+
+func evidenceAdjustedWhere(adjusted bool) string {
+	if adjusted {
+		return "evidence.adjusted_at IS NOT NULL"
+	}
+	return "evidence.adjusted_at IS NULL"
+}
+
 func evidenceUUIDWhere(in bool) string {
 	return "evidence.uuid " + inOrNotIn(in) + " (?)"
 }
@@ -614,6 +893,15 @@ func addWhereAndNot(sb sq.SelectBuilder, vals filter.Values, whereFunc func(bool
 	return sb
 }
 
+// removeUnrecordedContent removes newly uploaded content that could not be recorded against its
+// evidence (e.g. because the operation ran out of storage), so that it does not linger in the store
+func removeUnrecordedContent(ctx context.Context, contentStore contentstore.Store, keys contentstore.ContentKeys) {
+	err := deleteEvidenceContent(contentStore, models.Evidence{FullImageKey: keys.Full, ThumbImageKey: keys.Thumbnail})
+	if err != nil {
+		logging.ReqLogger(ctx).Warn("Unable to remove unrecorded evidence content", "error", err.Error())
+	}
+}
+
 func deleteEvidenceContent(contentStore contentstore.Store, evidence models.Evidence) error {
 	keys := make([]string, 0, 2)
 	if evidence.FullImageKey != "" {
@@ -664,12 +952,25 @@ func MoveEvidence(ctx context.Context, db *database.Connection, i MoveEvidenceIn
 	}
 
 	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		// ensure the destination has room for the evidence
+		if destinationOperation.ID != sourceOperation.ID {
+			var contentSize int64
+			if evidence.ContentSize != nil {
+				contentSize = *evidence.ContentSize
+			}
+			if err := ensureStorageAvailable(tx, destinationOperation.ID, contentSize, 0); err != nil {
+				tx.FailTransaction(err)
+				return
+			}
+		}
 		// remove findings
 		tx.Delete(sq.Delete("evidence_finding_map").Where(sq.Eq{"evidence_id": evidence.ID}))
 		// remove tags
 		tx.Delete(sq.Delete("tag_evidence_map").Where(sq.Eq{"evidence_id": evidence.ID}))
 		// reassociate evidence with new operation
 		tx.Update(sq.Update("evidence").Set("operation_id", destinationOperation.ID).Where(sq.Eq{"id": evidence.ID}))
+		// follow the evidence in the search index
+		search.IndexEvidence(tx, []int64{evidence.ID})
 		// associate with common tags
 		tx.BatchInsert("tag_evidence_map", len(tagDifferences.Included), func(idx int) map[string]interface{} {
 			pair := tagDifferences.Included[idx]
@@ -678,10 +979,20 @@ func MoveEvidence(ctx context.Context, db *database.Connection, i MoveEvidenceIn
 				"evidence_id": evidence.ID,
 			}
 		})
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionMoveEvidence,
+			TargetType:  AuditTargetEvidence,
+			Target:      evidence.UUID,
+			OperationID: &destinationOperation.ID,
+			Before:      map[string]interface{}{"operationSlug": i.SourceOperationSlug},
+			After:       map[string]interface{}{"operationSlug": i.TargetOperationSlug},
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot move evidence", err)
 	}
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventEvidenceUpdated,
+		enhancementservices.BuildEvidencePayloads(enhancementservices.EventEvidenceUpdated, []int64{evidence.ID}))
 
 	return nil
 }
@@ -25,23 +25,28 @@ func TestBuildListEvidenceWhereClause(t *testing.T) {
 		_, v, _ := s.ToSql()
 		return v
 	}
+	buildWhereClause := func(filters helpers.TimelineFilters) sq.SelectBuilder {
+		sb, err := buildListEvidenceWhereClause(base, opID, filters)
+		require.NoError(t, err)
+		return sb
+	}
 
-	noFilterBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{})
+	noFilterBuilder := buildWhereClause(helpers.TimelineFilters{})
 	require.Equal(t, " WHERE evidence.operation_id = ?", toWhere(noFilterBuilder))
 	require.Equal(t, []interface{}{opID}, toWhereValues(noFilterBuilder))
 
 	uuids := filter.Values{filter.Val("a")}
-	uuidBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{UUID: uuids})
+	uuidBuilder := buildWhereClause(helpers.TimelineFilters{UUID: uuids})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND evidence.uuid IN (?)", toWhere(uuidBuilder))
 	require.Equal(t, []interface{}{opID, uuids.Values()}, toWhereValues(uuidBuilder))
 
 	text := []string{"one", "two"}
-	descBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{Text: text})
+	descBuilder := buildWhereClause(helpers.TimelineFilters{Text: text})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND description LIKE ? AND description LIKE ?", toWhere(descBuilder))
 	require.Equal(t, []interface{}{opID, "%" + text[0] + "%", "%" + text[1] + "%"}, toWhereValues(descBuilder))
 
 	meta := []string{"one", "two"}
-	metaBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{Metadata: meta})
+	metaBuilder := buildWhereClause(helpers.TimelineFilters{Metadata: meta})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND evidence.id IN (SELECT evidence_id FROM evidence_metadata WHERE body LIKE ? AND body LIKE ?)", toWhere(metaBuilder))
 	require.Equal(t, []interface{}{opID, "%" + meta[0] + "%", "%" + meta[1] + "%"}, toWhereValues(metaBuilder))
 
@@ -50,7 +55,7 @@ func TestBuildListEvidenceWhereClause(t *testing.T) {
 		filter.DateVal(filter.DateRange{From: start, To: end}),
 	}
 	datePart := "(evidence.occurred_at >= ? AND evidence.occurred_at <= ?)"
-	singleDateBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{DateRanges: singleDate})
+	singleDateBuilder := buildWhereClause(helpers.TimelineFilters{DateRanges: singleDate})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND ("+datePart+")", toWhere(singleDateBuilder))
 	require.Equal(t, []interface{}{opID, start, end}, toWhereValues(singleDateBuilder))
 
@@ -59,17 +64,60 @@ func TestBuildListEvidenceWhereClause(t *testing.T) {
 		filter.DateVal(filter.DateRange{From: start, To: end}),
 		filter.DateVal(filter.DateRange{From: start2, To: end2}),
 	}
-	multiDateBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{DateRanges: dates})
+	multiDateBuilder := buildWhereClause(helpers.TimelineFilters{DateRanges: dates})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND ("+datePart+" OR "+datePart+")", toWhere(multiDateBuilder))
 	require.Equal(t, []interface{}{opID, start, end, start2, end2}, toWhereValues(multiDateBuilder))
 
 	operators := filter.Values{filter.Val("Johnny 5")}
-	operatorBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{Operator: operators})
+	operatorBuilder := buildWhereClause(helpers.TimelineFilters{Operator: operators})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND "+evidenceOperatorWhere(true), toWhere(operatorBuilder))
 	require.Equal(t, []interface{}{opID, operators.Values()}, toWhereValues(operatorBuilder))
 
 	tags := filter.Values{filter.Val("alpha"), filter.Val("beta"), filter.Val("gamma")}
-	tagBuilder := buildListEvidenceWhereClause(base, opID, helpers.TimelineFilters{Tags: tags})
+	tagBuilder := buildWhereClause(helpers.TimelineFilters{Tags: tags})
 	require.Equal(t, " WHERE evidence.operation_id = ? AND "+evidenceTagOrWhere(true), toWhere(tagBuilder))
 	require.Equal(t, []interface{}{opID, tags.Values()}, toWhereValues(tagBuilder))
+
+	filters, err := helpers.ParseTimelineQuery(`(tag:web OR tag:api) AND !operator:bob "some text" OR NOT linked:true`)
+	require.NoError(t, err)
+	exprBuilder := buildWhereClause(filters)
+	require.Equal(t, " WHERE evidence.operation_id = ? AND "+
+		"((("+evidenceTagOrWhere(true)+" OR "+evidenceTagOrWhere(true)+") AND NOT ("+evidenceOperatorWhere(true)+") AND description LIKE ?) OR "+
+		"NOT (evidence.id IN "+eviLinkedSubquery+"))", toWhere(exprBuilder))
+	require.Equal(t, []interface{}{opID, []string{"web"}, []string{"api"}, []string{"bob"}, "%some text%"}, toWhereValues(exprBuilder))
+
+	filters, err = helpers.ParseTimelineQuery(`host:cdn.example.com OR status:!5xx`)
+	require.NoError(t, err)
+	harBuilder := buildWhereClause(filters)
+	require.Equal(t, " WHERE evidence.operation_id = ? AND "+
+		"(evidence.id IN (SELECT evidence_id FROM har_entries WHERE host = ?) OR "+
+		"NOT (evidence.id IN (SELECT evidence_id FROM har_entries WHERE status BETWEEN ? AND ?)))", toWhere(harBuilder))
+	require.Equal(t, []interface{}{opID, "cdn.example.com", 500, 599}, toWhereValues(harBuilder))
+
+	findings := filter.Values{filter.Val("SQLi"), filter.NotVal("XSS")}
+	metadataBuilder := buildWhereClause(helpers.TimelineFilters{
+		Finding:         findings,
+		HasMetadata:     filter.Values{filter.Val("ocr")},
+		MetadataStatus:  filter.Values{filter.Val("Error")},
+		CreatedByWorker: helpers.PTrue(),
+		Adjusted:        helpers.PFalse(),
+		After:           &start,
+		Before:          &end,
+	})
+	require.Equal(t, " WHERE evidence.operation_id = ? AND "+
+		evidenceFindingWhere(true)+" AND "+evidenceFindingWhere(false)+" AND "+
+		"evidence.id IN "+eviMetadataSourceSubquery+" AND "+
+		"evidence.id IN "+eviMetadataStatusSubquery+" AND "+
+		"evidence.id IN "+eviWorkerMetadataSubquery+" AND "+
+		"evidence.adjusted_at IS NULL AND "+
+		"evidence.occurred_at >= ? AND evidence.occurred_at <= ?", toWhere(metadataBuilder))
+	require.Equal(t, []interface{}{opID, []string{"SQLi"}, []string{"SQLi"}, []string{"XSS"}, []string{"XSS"}, []string{"ocr"}, []string{"Error"}, start, end},
+		toWhereValues(metadataBuilder))
+
+	filters, err = helpers.ParseTimelineQuery(`finding:SQLi OR !adjusted:true OR created-by-worker:false`)
+	require.NoError(t, err)
+	newKeysBuilder := buildWhereClause(filters)
+	require.Equal(t, " WHERE evidence.operation_id = ? AND "+
+		"("+evidenceFindingWhere(true)+" OR NOT (evidence.adjusted_at IS NOT NULL) OR evidence.id NOT IN "+eviWorkerMetadataSubquery+")", toWhere(newKeysBuilder))
+	require.Equal(t, []interface{}{opID, []string{"SQLi"}, []string{"SQLi"}}, toWhereValues(newKeysBuilder))
 }
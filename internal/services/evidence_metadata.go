@@ -10,6 +10,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
 
 	sq "github.com/Masterminds/squirrel"
@@ -58,6 +59,7 @@ func CreateEvidenceMetadata(ctx context.Context, db *database.Connection, i Edit
 
 		return errorwrap.WrapError("Could not create evidence metadata", errorwrap.DatabaseErr(err))
 	}
+	logSearchIndexError(ctx, search.IndexEvidence(db, []int64{evidence.ID}))
 
 	return nil
 }
@@ -82,6 +84,7 @@ func UpdateEvidenceMetadata(ctx context.Context, db *database.Connection, i Edit
 	if err != nil {
 		return errorwrap.WrapError("Could not edit evidence metadata", errorwrap.DatabaseErr(err))
 	}
+	logSearchIndexError(ctx, search.IndexEvidence(db, []int64{evidence.ID}))
 
 	return nil
 }
@@ -125,6 +128,7 @@ func UpsertEvidenceMetadata(ctx context.Context, db *database.Connection, i Upse
 					"source":      i.Source,
 				}))
 		}
+		search.IndexEvidence(tx, []int64{evidence.ID})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Could not edit evidence metadata", errorwrap.DatabaseErr(err))
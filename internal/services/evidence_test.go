@@ -2,6 +2,7 @@ package services_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"testing"
 	"time"
@@ -62,6 +63,72 @@ func TestCreateEvidence(t *testing.T) {
 	})
 }
 
+func TestCreateHAREvidence(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		memStore, _ := contentstore.NewMemStore()
+		ctx := contextForUser(UserRon, db)
+		op := OpChamberOfSecrets
+
+		harContent := []byte(`{"log": {"entries": [
+			{"request": {"method": "GET", "url": "https://gringotts.com/vaults/713"}, "response": {"status": 200}},
+			{"request": {"method": "POST", "url": "https://Ministry.gov/floo/register"}, "response": {"status": 503}}
+		]}}`)
+		harInput := services.CreateEvidenceInput{
+			OperationSlug: op.Slug,
+			Description:   "some har",
+			ContentType:   "http-request-cycle",
+			Content:       bytes.NewReader(harContent),
+		}
+		harEvi, err := services.CreateEvidence(ctx, db, memStore, harInput)
+		require.NoError(t, err)
+		validateInsertedEvidence(t, harEvi, harInput, UserRon, op, memStore, db, harContent)
+
+		// verify the preview is a text summary of the entries
+		fullEvidence := getEvidenceByUUID(t, db, harEvi.UUID)
+		previewReader, err := memStore.Read(fullEvidence.ThumbImageKey)
+		require.NoError(t, err)
+		preview, _ := io.ReadAll(previewReader)
+		require.Equal(t, "GET 200 https://gringotts.com/vaults/713\nPOST 503 https://Ministry.gov/floo/register\n", string(preview))
+
+		var entries []models.HarEntry
+		err = db.Select(&entries, sq.Select("*").From("har_entries").Where(sq.Eq{"evidence_id": fullEvidence.ID}).OrderBy("entry_index"))
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, "ministry.gov", entries[1].Host)
+
+		// verify the entries can be searched
+		search := func(query string) []string {
+			filters, err := helpers.ParseTimelineQuery(query)
+			require.NoError(t, err)
+			found, err := services.ListEvidenceForOperation(ctx, db, memStore, services.ListEvidenceForOperationInput{
+				OperationSlug: op.Slug,
+				Filters:       filters,
+			})
+			require.NoError(t, err)
+			uuids := make([]string, len(found))
+			for i, evi := range found {
+				uuids[i] = evi.UUID
+			}
+			return uuids
+		}
+		require.Equal(t, []string{harEvi.UUID}, search("host:ministry.gov"))
+		require.Equal(t, []string{harEvi.UUID}, search("status:5xx url:/vaults/"))
+		require.Empty(t, search("status:404"))
+		require.NotContains(t, search("host:!gringotts.com"), harEvi.UUID)
+
+		// verify invalid HAR content is still accepted, without a summary
+		badInput := services.CreateEvidenceInput{
+			OperationSlug: op.Slug,
+			Description:   "not really a har",
+			ContentType:   "http-request-cycle",
+			Content:       bytes.NewReader([]byte("not json")),
+		}
+		badEvi, err := services.CreateEvidence(ctx, db, memStore, badInput)
+		require.NoError(t, err)
+		validateInsertedEvidence(t, badEvi, badInput, UserRon, op, memStore, db, []byte("not json"))
+	})
+}
+
 func TestHeadlessUserAccess(t *testing.T) {
 	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
 		memStore, _ := contentstore.NewMemStore()
@@ -112,6 +179,11 @@ func TestDeleteEvidenceNoPropogate(t *testing.T) {
 		getEvidenceCount := makeDBRowCounter(t, db, "evidence", "uuid=?", i.EvidenceUUID)
 		require.Equal(t, int64(1), getEvidenceCount(), "Database should have evidence to delete")
 
+		// subscribe a worker, so that the deletion is announced
+		require.NoError(t, db.Update(sq.Update("service_workers").
+			Set("config", `{"type": "web", "version": 1, "url": "http://demo:8080/process", "events": ["evidence_deleted"]}`).
+			Where(sq.Eq{"id": DemoServiceWorker.ID})))
+
 		ctx := contextForUser(UserRon, db)
 		err := services.DeleteEvidence(ctx, db, memStore, i)
 		require.NoError(t, err)
@@ -119,6 +191,14 @@ func TestDeleteEvidenceNoPropogate(t *testing.T) {
 		require.Equal(t, int64(0), getAssociatedTagCount(), "Database should have deleted associated tags")
 		_, err = memStore.Read(contentStoreKey)
 		require.Error(t, err)
+
+		var payloads []string
+		require.NoError(t, db.Select(&payloads, sq.Select("payload").From("service_worker_jobs").Where(sq.Eq{"event_type": "evidence_deleted"})))
+		require.Len(t, payloads, 1)
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(payloads[0]), &payload))
+		require.Equal(t, masterEvidence.UUID, payload["evidenceUuid"])
+		require.Equal(t, op.Slug, payload["operationSlug"])
 	})
 }
 
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"golang.org/x/sync/errgroup"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Evidence verification statuses
+const (
+	// EvidenceVerified indicates that the stored content matches the hash recorded at upload
+	EvidenceVerified = "verified"
+	// EvidenceHashMismatch indicates that the stored content has been altered since upload
+	EvidenceHashMismatch = "mismatch"
+	// EvidenceContentMissing indicates that the stored content could not be read
+	EvidenceContentMissing = "missing"
+	// EvidenceUnhashed indicates that no hash was recorded for the evidence (e.g. legacy evidence)
+	EvidenceUnhashed = "unhashed"
+)
+
+// maxConcurrentVerifications limits how many pieces of content are read from the content store at
+// once while verifying an entire operation
+const maxConcurrentVerifications = 8
+
+type VerifyEvidenceInput struct {
+	OperationSlug string
+	EvidenceUUID  string
+}
+
+// VerifyEvidence re-reads the content for a single piece of evidence and compares it against the
+// hash recorded when the content was uploaded
+func VerifyEvidence(ctx context.Context, db *database.Connection, contentStore contentstore.Store, i VerifyEvidenceInput) (*dtos.EvidenceVerification, error) {
+	operation, evidence, err := lookupOperationEvidence(db, i.OperationSlug, i.EvidenceUUID)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to verify evidence", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	if err := policy.Require(middleware.Policy(ctx), policy.CanReadOperation{OperationID: operation.ID}); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to verify evidence", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	result := verifyEvidenceContent(contentStore, *evidence)
+	return &result, nil
+}
+
+// VerifyOperationEvidence verifies the content of every piece of evidence in an operation. The report
+// contains only the evidence that failed verification (either altered or missing content), along with
+// totals for the entire operation.
+func VerifyOperationEvidence(ctx context.Context, db *database.Connection, contentStore contentstore.Store, operationSlug string) (*dtos.OperationVerificationReport, error) {
+	operation, err := lookupOperation(db, operationSlug)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to verify operation evidence", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	if err := policyRequireWithAdminBypass(ctx, policy.CanReadOperation{OperationID: operation.ID}); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to verify operation evidence", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	var evidence []models.Evidence
+	err = db.Select(&evidence, sq.Select("*").
+		From("evidence").
+		Where(sq.Eq{"operation_id": operation.ID}).
+		OrderBy("id"))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list evidence to verify", errorwrap.DatabaseErr(err))
+	}
+
+	report := dtos.OperationVerificationReport{
+		OperationSlug: operationSlug,
+		VerifiedAt:    time.Now(),
+		TotalCount:    int64(len(evidence)),
+		Problems:      []dtos.EvidenceVerification{},
+	}
+
+	results := make([]dtos.EvidenceVerification, len(evidence))
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentVerifications)
+	for idx, evi := range evidence {
+		g.Go(func() error {
+			results[idx] = verifyEvidenceContent(contentStore, evi)
+			return nil
+		})
+	}
+	g.Wait()
+
+	for _, result := range results {
+		switch result.Status {
+		case EvidenceVerified:
+			report.VerifiedCount++
+		case EvidenceUnhashed:
+			report.UnhashedCount++
+		default:
+			report.Problems = append(report.Problems, result)
+		}
+	}
+
+	return &report, nil
+}
+
+func verifyEvidenceContent(contentStore contentstore.Store, evidence models.Evidence) dtos.EvidenceVerification {
+	result := dtos.EvidenceVerification{
+		UUID:         evidence.UUID,
+		ExpectedHash: evidence.ContentHash,
+	}
+
+	if evidence.ContentHash == nil || evidence.FullImageKey == "" {
+		result.Status = EvidenceUnhashed
+		return result
+	}
+
+	content, err := contentStore.Read(evidence.FullImageKey)
+	if err != nil {
+		result.Status = EvidenceContentMissing
+		return result
+	}
+	if closer, ok := content.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	actualHash, err := contentstore.HashContent(content)
+	if err != nil {
+		result.Status = EvidenceContentMissing
+		return result
+	}
+
+	result.ActualHash = &actualHash
+	if actualHash == *evidence.ContentHash {
+		result.Status = EvidenceVerified
+	} else {
+		result.Status = EvidenceHashMismatch
+	}
+	return result
+}
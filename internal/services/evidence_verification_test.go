@@ -0,0 +1,71 @@
+package services_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEvidence(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, seed TestSeedData) {
+		memStore := createPopulatedMemStore(seed)
+		ctx := contextForUser(UserRon, db)
+		op := OpChamberOfSecrets
+
+		content := []byte("I'm a codeblock!")
+		expectedHash, _ := contentstore.HashContent(bytes.NewReader(content))
+		created, err := services.CreateEvidence(ctx, db, memStore, services.CreateEvidenceInput{
+			OperationSlug: op.Slug,
+			Description:   "some codeblock",
+			ContentType:   "codeblock",
+			Content:       bytes.NewReader(content),
+		})
+		require.NoError(t, err)
+		evidence := getEvidenceByUUID(t, db, created.UUID)
+		require.NotNil(t, evidence.ContentHash)
+		require.Equal(t, expectedHash, *evidence.ContentHash)
+
+		verify := func(uuid string) string {
+			result, err := services.VerifyEvidence(ctx, db, memStore, services.VerifyEvidenceInput{
+				OperationSlug: op.Slug,
+				EvidenceUUID:  uuid,
+			})
+			require.NoError(t, err)
+			return result.Status
+		}
+
+		// verify untouched content
+		require.Equal(t, services.EvidenceVerified, verify(created.UUID))
+
+		// verify legacy evidence without a hash
+		require.Equal(t, services.EvidenceUnhashed, verify(EviFlyingCar.UUID))
+
+		// verify the operation report is clean
+		report, err := services.VerifyOperationEvidence(ctx, db, memStore, op.Slug)
+		require.NoError(t, err)
+		require.Empty(t, report.Problems)
+		require.Equal(t, int64(1), report.VerifiedCount)
+
+		// verify altered content
+		require.NoError(t, memStore.UploadWithName(evidence.FullImageKey, bytes.NewReader([]byte("altered"))))
+		require.Equal(t, services.EvidenceHashMismatch, verify(created.UUID))
+
+		// verify missing content
+		require.NoError(t, memStore.Delete(evidence.FullImageKey))
+		require.Equal(t, services.EvidenceContentMissing, verify(created.UUID))
+
+		report, err = services.VerifyOperationEvidence(ctx, db, memStore, op.Slug)
+		require.NoError(t, err)
+		require.Len(t, report.Problems, 1)
+		require.Equal(t, created.UUID, report.Problems[0].UUID)
+		require.Equal(t, services.EvidenceContentMissing, report.Problems[0].Status)
+
+		// verify users without access cannot verify
+		_, err = services.VerifyOperationEvidence(contextForUser(UserDraco, db), db, memStore, op.Slug)
+		require.Error(t, err)
+	})
+}
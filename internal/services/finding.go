@@ -10,12 +10,15 @@ import (
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers/filter"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
 	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 
@@ -89,7 +92,7 @@ func CreateFinding(ctx context.Context, db *database.Connection, i CreateFinding
 	}
 
 	findingUUID := uuid.New().String()
-	_, err = db.Insert("findings", map[string]interface{}{
+	findingID, err := db.Insert("findings", map[string]interface{}{
 		"uuid":         findingUUID,
 		"operation_id": operation.ID,
 		"category_id":  useCategoryID,
@@ -99,6 +102,14 @@ func CreateFinding(ctx context.Context, db *database.Connection, i CreateFinding
 	if err != nil {
 		return nil, errorwrap.WrapError("Unable to insert finding", errorwrap.DatabaseErr(err))
 	}
+	logSearchIndexError(ctx, search.IndexFindings(db, []int64{findingID}))
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventFindingCreated,
+		enhancementservices.BuildFindingPayloads(enhancementservices.EventFindingCreated, []int64{findingID}))
+	sendWebhookEvent(ctx, db, operation.ID, i.OperationSlug, webhooks.EventFindingCreated, webhooks.FindingData{
+		UUID:     findingUUID,
+		Title:    i.Title,
+		Category: i.Category,
+	})
 
 	return &dtos.Finding{
 		UUID:        findingUUID,
@@ -120,6 +131,14 @@ func DeleteFinding(ctx context.Context, db *database.Connection, i DeleteFinding
 	err = db.WithTx(ctx, func(tx *database.Transactable) {
 		tx.Delete(sq.Delete("evidence_finding_map").Where(sq.Eq{"finding_id": finding.ID}))
 		tx.Delete(sq.Delete("findings").Where(sq.Eq{"id": finding.ID}))
+		search.RemoveDocuments(tx, search.DocumentFinding, []int64{finding.ID})
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionDeleteFinding,
+			TargetType:  AuditTargetFinding,
+			Target:      finding.UUID,
+			OperationID: &operation.ID,
+			Before:      finding,
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete finding", errorwrap.DatabaseErr(err))
@@ -138,7 +157,10 @@ func ListFindingsForOperation(ctx context.Context, db *database.Connection, i Li
 		return nil, errorwrap.WrapError("Unwilling to list findings for operation", errorwrap.UnauthorizedReadErr(err))
 	}
 
-	whereClause, whereValues := buildListFindingsWhereClause(operation.ID, i.Filters)
+	whereClause, whereValues, err := buildListFindingsWhereClause(operation.ID, i.Filters)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to list findings for operation", err)
+	}
 	var findings []struct {
 		models.Finding
 		NumEvidence     int        `db:"num_evidence"`
@@ -274,11 +296,26 @@ func UpdateFinding(ctx context.Context, db *database.Connection, i UpdateFinding
 				"ready_to_report": i.ReadyToReport,
 			}).
 			Where(sq.Eq{"id": finding.ID}))
+		search.IndexFindings(tx, []int64{finding.ID})
 	})
 
 	if err != nil {
 		return errorwrap.WrapError("Unable to update database", errorwrap.UnauthorizedWriteErr(err))
 	}
+
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventFindingUpdated,
+		enhancementservices.BuildFindingPayloads(enhancementservices.EventFindingUpdated, []int64{finding.ID}))
+	if i.ReadyToReport && !finding.ReadyToReport {
+		sendServiceWorkerEvent(ctx, db, enhancementservices.EventFindingReadyToReport,
+			enhancementservices.BuildFindingPayloads(enhancementservices.EventFindingReadyToReport, []int64{finding.ID}))
+		sendWebhookEvent(ctx, db, operation.ID, i.OperationSlug, webhooks.EventFindingReadyToReport, webhooks.FindingData{
+			UUID:          i.FindingUUID,
+			Title:         i.Title,
+			Category:      i.Category,
+			ReadyToReport: true,
+			TicketLink:    i.TicketLink,
+		})
+	}
 	return nil
 }
 
@@ -298,6 +335,8 @@ func AddEvidenceToFinding(ctx context.Context, db *database.Connection, i AddEvi
 	if err = g.Wait(); err != nil {
 		return errorwrap.WrapError("Unable to add evidence to finding", errorwrap.UnauthorizedWriteErr(err))
 	}
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventFindingUpdated,
+		enhancementservices.BuildFindingPayloads(enhancementservices.EventFindingUpdated, []int64{finding.ID}))
 
 	return nil
 }
@@ -353,9 +392,22 @@ func batchRemoveEvidenceFromFinding(db *database.Connection, evidenceUUIDs []str
 const findingsTextWhereComponent = "(findings.title LIKE ? OR findings.description LIKE ?)"
 const findingsOperationIDWhereComponent = "findings.operation_id = ?"
 
-func buildListFindingsWhereClause(operationID int64, filters helpers.TimelineFilters) (string, []interface{}) {
-	queryFilters := []string{findingsOperationIDWhereComponent}
-	queryValues := []interface{}{operationID}
+func buildListFindingsWhereClause(operationID int64, filters helpers.TimelineFilters) (string, []interface{}, error) {
+	queryFilters, queryValues, err := buildFindingFilters(filters)
+	if err != nil {
+		return "", nil, err
+	}
+	queryFilters = append([]string{findingsOperationIDWhereComponent}, queryFilters...)
+	queryValues = append([]interface{}{operationID}, queryValues...)
+
+	return strings.Join(queryFilters, " AND "), queryValues, nil
+}
+
+// buildFindingFilters builds the conditions (to be joined with AND) that restrict findings to those
+// matching the timeline filters
+func buildFindingFilters(filters helpers.TimelineFilters) ([]string, []interface{}, error) {
+	queryFilters := []string{}
+	queryValues := []interface{}{}
 
 	addWhere := func(vals filter.Values, whereFunc func(bool) string) {
 		findingAddWhereAndNot(&queryFilters, &queryValues, vals, whereFunc)
@@ -392,7 +444,118 @@ func buildListFindingsWhereClause(operationID int64, filters helpers.TimelineFil
 		addWhere(filters.WithEvidenceUUID, findingEvidenceUUIDWhere)
 	}
 
-	return strings.Join(queryFilters, " AND "), queryValues
+	if len(filters.Finding) > 0 {
+		splitValues := filters.Finding.SplitByModifier()
+		if values := splitValues[filter.Normal]; len(values) > 0 {
+			queryFilters = append(queryFilters, findingSelfWhere(true))
+			queryValues = append(queryValues, values, values)
+		}
+		if values := splitValues[filter.Not]; len(values) > 0 {
+			queryFilters = append(queryFilters, findingSelfWhere(false))
+			queryValues = append(queryValues, values, values)
+		}
+	}
+
+	if len(filters.HasMetadata) > 0 {
+		addWhere(filters.HasMetadata, findingWithEvidenceWhere(eviMetadataSourceSubquery))
+	}
+
+	if len(filters.MetadataStatus) > 0 {
+		addWhere(filters.MetadataStatus, findingWithEvidenceWhere(eviMetadataStatusSubquery))
+	}
+
+	if filters.CreatedByWorker != nil {
+		queryFilters = append(queryFilters, findingWithEvidenceWhere(eviWorkerMetadataSubquery)(*filters.CreatedByWorker))
+	}
+
+	if filters.Adjusted != nil {
+		queryFilters = append(queryFilters, findingWithEvidenceWhere(eviAdjustedSubquery)(*filters.Adjusted))
+	}
+
+	if filters.After != nil {
+		queryFilters = append(queryFilters, findingWithEvidenceWhere(eviAfterSubquery)(true))
+		queryValues = append(queryValues, *filters.After)
+	}
+
+	if filters.Before != nil {
+		queryFilters = append(queryFilters, findingWithEvidenceWhere(eviBeforeSubquery)(true))
+		queryValues = append(queryValues, *filters.Before)
+	}
+
+	if filters.Expression != nil {
+		condition, err := compileTimelineExpression(filters.Expression, findingTermWhere)
+		if err != nil {
+			return nil, nil, err
+		}
+		if condition != nil {
+			q, v, err := condition.ToSql()
+			if err != nil {
+				return nil, nil, errorwrap.BadInputErr(err, "Unable to apply the query")
+			}
+			queryFilters = append(queryFilters, "("+q+")")
+			queryValues = append(queryValues, v...)
+		}
+	}
+
+	return queryFilters, queryValues, nil
+}
+
+// findingTermWhere builds the condition for a single filter of a boolean timeline query
+func findingTermWhere(term *helpers.QueryExpr) sq.Sqlizer {
+	value := term.Value.Value
+	switch term.Key {
+	case "":
+		return sq.Expr(findingsTextWhereComponent, "%"+value+"%", "%"+value+"%")
+	case "uuid":
+		return sq.Expr(findingUUIDWhere(true), []string{value})
+	case "operator":
+		return sq.Expr(findingOperatorWhere(true), []string{value})
+	case "tag":
+		return sq.Expr(findingTagOrWhere(true), []string{value})
+	case "with-evidence":
+		return sq.Expr(findingEvidenceUUIDWhere(true), []string{value})
+	case "range":
+		return sq.Expr(findingDateRangeWhere(true), term.DateRange.From, term.DateRange.To)
+	case "after":
+		return sq.Expr(findingWithEvidenceWhere(eviAfterSubquery)(true), term.DateRange.From)
+	case "before":
+		return sq.Expr(findingWithEvidenceWhere(eviBeforeSubquery)(true), term.DateRange.To)
+	case "finding":
+		return sq.Expr(findingSelfWhere(true), []string{value}, []string{value})
+	case "has-metadata":
+		return sq.Expr(findingWithEvidenceWhere(eviMetadataSourceSubquery)(true), []string{value})
+	case "metadata-status":
+		return sq.Expr(findingWithEvidenceWhere(eviMetadataStatusSubquery)(true), []string{value})
+	case "created-by-worker":
+		return sq.Expr(findingWithEvidenceWhere(eviWorkerMetadataSubquery)(value == "true"))
+	case "adjusted":
+		return sq.Expr(findingWithEvidenceWhere(eviAdjustedSubquery)(value == "true"))
+	}
+	return nil
+}
+
+// subqueries selecting the IDs of evidence, for use with findingWithEvidenceWhere
+const eviAdjustedSubquery = "(SELECT id FROM evidence WHERE adjusted_at IS NOT NULL)"
+const eviAfterSubquery = "(SELECT id FROM evidence WHERE occurred_at >= ?)"
+const eviBeforeSubquery = "(SELECT id FROM evidence WHERE occurred_at <= ?)"
+
+// findingWithEvidenceWhere returns a where function that matches findings with (or without) any of
+// the evidence selected by the subquery
+func findingWithEvidenceWhere(evidenceSubquery string) func(bool) string {
+	return func(in bool) string {
+		return "findings.id " + inOrNotIn(in) + " (" +
+			"  SELECT finding_id FROM evidence_finding_map" +
+			"  WHERE evidence_id IN " + evidenceSubquery +
+			")"
+	}
+}
+
+// findingSelfWhere matches findings by UUID or title. The values must be provided twice: once for the
+// UUIDs, and once for the titles.
+func findingSelfWhere(in bool) string {
+	return "findings.id " + inOrNotIn(in) + " (" +
+		"  SELECT id FROM findings WHERE uuid IN (?) OR title IN (?)" +
+		")"
 }
 
 func buildTags(tagsByID map[int64]dtos.Tag, tagIDs *string) []dtos.Tag {
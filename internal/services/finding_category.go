@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
 
 	sq "github.com/Masterminds/squirrel"
 )
@@ -33,6 +35,12 @@ func CreateFindingCategory(ctx context.Context, db *database.Connection, newCate
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionCreateFindingCategory,
+		TargetType: AuditTargetFindingCategory,
+		Target:     strconv.FormatInt(id, 10),
+		After:      map[string]interface{}{"category": newCategory},
+	})
 
 	return &dtos.FindingCategory{ID: id, Category: newCategory}, nil
 }
@@ -43,18 +51,31 @@ func DeleteFindingCategory(ctx context.Context, db *database.Connection, i Delet
 		return errorwrap.WrapError("Unable to delete a finding category", errorwrap.UnauthorizedWriteErr(err))
 	}
 
+	category, err := lookupFindingCategory(db, i.FindingCategoryID)
+	if err != nil {
+		return errorwrap.WrapError("Cannot delete finding category", errorwrap.DatabaseErr(err))
+	}
+
 	query := sq.Update("finding_categories").
 		Where(sq.Eq{"id": i.FindingCategoryID})
 
+	action := AuditActionDeleteFindingCategory
 	if i.DoDelete {
 		query = query.Set("deleted_at", time.Now())
 	} else {
 		query = query.Set("deleted_at", nil)
+		action = AuditActionRestoreFindingCategory
 	}
 
 	if err := db.Update(query); err != nil {
 		return errorwrap.WrapError("Cannot delete finding category", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     action,
+		TargetType: AuditTargetFindingCategory,
+		Target:     strconv.FormatInt(i.FindingCategoryID, 10),
+		Before:     map[string]interface{}{"category": category.Category},
+	})
 
 	return nil
 }
@@ -103,7 +124,12 @@ func UpdateFindingCategory(ctx context.Context, db *database.Connection, i Updat
 		return errorwrap.WrapError("Unable to update the finding category", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	err := db.Update(sq.Update("finding_categories").
+	category, err := lookupFindingCategory(db, i.ID)
+	if err != nil {
+		return errorwrap.WrapError("Cannot update finding category", errorwrap.DatabaseErr(err))
+	}
+
+	err = db.Update(sq.Update("finding_categories").
 		SetMap(map[string]interface{}{
 			"category": i.Category,
 		}).
@@ -112,5 +138,18 @@ func UpdateFindingCategory(ctx context.Context, db *database.Connection, i Updat
 	if err != nil {
 		return errorwrap.WrapError("Cannot update finding category", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionUpdateFindingCategory,
+		TargetType: AuditTargetFindingCategory,
+		Target:     strconv.FormatInt(i.ID, 10),
+		Before:     map[string]interface{}{"category": category.Category},
+		After:      map[string]interface{}{"category": i.Category},
+	})
 	return nil
 }
+
+func lookupFindingCategory(db *database.Connection, id int64) (*models.FindingCategory, error) {
+	var category models.FindingCategory
+	err := db.Get(&category, sq.Select("*").From("finding_categories").Where(sq.Eq{"id": id}))
+	return &category, err
+}
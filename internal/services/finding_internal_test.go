@@ -93,7 +93,8 @@ func TestBuildTags(t *testing.T) {
 func TestBuildListFindingsWhereClause(t *testing.T) {
 	test := func(filters helpers.TimelineFilters, queryParts []string, queryValues []interface{}) {
 		targetQuery := strings.Join(append([]string{findingsOperationIDWhereComponent}, queryParts...), " AND ")
-		query, values := buildListFindingsWhereClause(1, filters)
+		query, values, err := buildListFindingsWhereClause(1, filters)
+		require.NoError(t, err)
 		require.Equal(t, targetQuery, query)
 		require.Equal(t, append([]interface{}{int64(1)}, queryValues...), values)
 	}
@@ -115,6 +116,31 @@ func TestBuildListFindingsWhereClause(t *testing.T) {
 	test(helpers.TimelineFilters{Operator: val}, []string{findingOperatorWhere(true)}, []interface{}{val.Values()})
 	val = filter.Values{filter.Val("abc")}
 	test(helpers.TimelineFilters{WithEvidenceUUID: val}, []string{findingEvidenceUUIDWhere(true)}, []interface{}{val.Values()})
+
+	// boolean queries; filters that only apply to evidence are dropped when ANDed, but rejected under OR/NOT
+	filters, err := helpers.ParseTimelineQuery(`(tag:web OR tag:api) AND !operator:bob type:image`)
+	require.NoError(t, err)
+	test(filters,
+		[]string{"(((" + findingTagOrWhere(true) + " OR " + findingTagOrWhere(true) + ") AND NOT (" + findingOperatorWhere(true) + ")))"},
+		[]interface{}{[]string{"web"}, []string{"api"}, []string{"bob"}})
+	filters, err = helpers.ParseTimelineQuery(`(tag:web OR tag:api) AND NOT type:image`)
+	require.NoError(t, err)
+	_, _, err = buildListFindingsWhereClause(1, filters)
+	require.ErrorContains(t, err, "(at position 30)")
+
+	val = filter.Values{filter.Val("SQLi")}
+	test(helpers.TimelineFilters{Finding: val}, []string{findingSelfWhere(true)}, []interface{}{val.Values(), val.Values()})
+	val = filter.Values{filter.NotVal("ocr")}
+	test(helpers.TimelineFilters{HasMetadata: val}, []string{findingWithEvidenceWhere(eviMetadataSourceSubquery)(false)}, []interface{}{val.Values()})
+	test(helpers.TimelineFilters{Adjusted: helpers.PTrue(), After: &start},
+		[]string{findingWithEvidenceWhere(eviAdjustedSubquery)(true), findingWithEvidenceWhere(eviAfterSubquery)(true)},
+		[]interface{}{start})
+
+	filters, err = helpers.ParseTimelineQuery(`metadata-status:error OR before:2019-08-05`)
+	require.NoError(t, err)
+	test(filters,
+		[]string{"((" + findingWithEvidenceWhere(eviMetadataStatusSubquery)(true) + " OR " + findingWithEvidenceWhere(eviBeforeSubquery)(true) + "))"},
+		[]interface{}{[]string{"Error"}, time.Date(2019, 8, 5, 23, 59, 59, 0, time.UTC)})
 }
 
 // TestAllTagsByID is a unit-test suite for the allTagsByID function.
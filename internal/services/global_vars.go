@@ -50,6 +50,11 @@ func CreateGlobalVar(ctx context.Context, db *database.Connection, i CreateGloba
 		}
 		return nil, errorwrap.WrapError("Unable to add new global variable", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionCreateGlobalVar,
+		TargetType: AuditTargetGlobalVar,
+		Target:     formattedName,
+	})
 
 	return &dtos.GlobalVar{
 		Name:  formattedName,
@@ -66,6 +71,11 @@ func DeleteGlobalVar(ctx context.Context, db *database.Connection, name string)
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete global variable", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionDeleteGlobalVar,
+		TargetType: AuditTargetGlobalVar,
+		Target:     name,
+	})
 
 	return nil
 }
@@ -134,6 +144,14 @@ func UpdateGlobalVar(ctx context.Context, db *database.Connection, i UpdateGloba
 		}
 		return errorwrap.WrapError("Cannot update global variable", errorwrap.DatabaseErr(err))
 	}
+	// values are not recorded, since they may be secrets
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionUpdateGlobalVar,
+		TargetType: AuditTargetGlobalVar,
+		Target:     name,
+		Before:     map[string]interface{}{"name": globalVar.Name},
+		After:      map[string]interface{}{"name": name, "valueChanged": val != globalVar.Value},
+	})
 
 	return nil
 }
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/har"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers/filter"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// prepareHAR reads HAR content, returning a Storable for it, along with its summary. Content that
+// cannot be parsed is still stored (without a summary), as it may still be viewable as a raw file.
+func prepareHAR(ctx context.Context, content io.Reader) (contentstore.Storable, *har.Summary, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary, err := har.Parse(data)
+	if err != nil {
+		logging.ReqLogger(ctx).Warn("Unable to summarize HAR content", "error", err.Error())
+		return contentstore.NewBlob(bytes.NewReader(data)), nil, nil
+	}
+	return contentstore.NewBlobWithPreview(bytes.NewReader(data), strings.NewReader(summary.Preview())), summary, nil
+}
+
+// insertHarEntries records the entries of a HAR summary against the given evidence. Nothing is
+// recorded when the summary is nil.
+func insertHarEntries(tx *database.Transactable, evidenceID int64, summary *har.Summary) {
+	if summary == nil {
+		return
+	}
+	tx.BatchInsert("har_entries", len(summary.Entries), func(idx int) map[string]interface{} {
+		entry := summary.Entries[idx]
+		return map[string]interface{}{
+			"evidence_id": evidenceID,
+			"entry_index": idx,
+			"method":      entry.Method,
+			"url":         entry.URL,
+			"host":        entry.Host,
+			"status":      entry.Status,
+		}
+	})
+}
+
+// addHarEntryWhere restricts the evidence to those with (or, for negated values, without) at least one
+// HAR entry matching any of the provided values
+func addHarEntryWhere(sb sq.SelectBuilder, vals filter.Values, entryWhere func(string) sq.Sqlizer) sq.SelectBuilder {
+	for modifier, values := range vals.SplitByModifier() {
+		if len(values) == 0 {
+			continue
+		}
+		conditions := make(sq.Or, len(values))
+		for i, v := range values {
+			conditions[i] = entryWhere(v)
+		}
+		q, v, err := sq.Select("evidence_id").From("har_entries").Where(conditions).ToSql()
+		if err != nil {
+			continue
+		}
+		sb = sb.Where("evidence.id "+inOrNotIn(modifier != filter.Not)+" ("+q+")", v...)
+	}
+	return sb
+}
+
+// harEntryTermWhere restricts the evidence to those with at least one HAR entry matching the condition
+func harEntryTermWhere(entryWhere sq.Sqlizer) sq.Sqlizer {
+	return sq.Expr("evidence.id IN (?)", sq.Select("evidence_id").From("har_entries").Where(entryWhere))
+}
+
+func harEntryHostWhere(host string) sq.Sqlizer {
+	return sq.Eq{"host": strings.ToLower(host)}
+}
+
+func harEntryURLWhere(url string) sq.Sqlizer {
+	return sq.Like{"url": "%" + url + "%"}
+}
+
+// harEntryStatusWhere matches either an exact status code (e.g. 404), or a class of status codes
+// (e.g. 4xx). Values are expected to have been validated by the query parser.
+func harEntryStatusWhere(status string) sq.Sqlizer {
+	if class, ok := strings.CutSuffix(strings.ToLower(status), "xx"); ok {
+		base, _ := strconv.Atoi(class)
+		return sq.Expr("status BETWEEN ? AND ?", base*100, base*100+99)
+	}
+	code, _ := strconv.Atoi(status)
+	return sq.Eq{"status": code}
+}
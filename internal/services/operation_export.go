@@ -0,0 +1,392 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// OperationArchiveVersion is the version of the archive format written by ExportOperation
+const OperationArchiveVersion = 1
+
+// OperationArchiveManifestName is the name of the manifest file inside of an operation archive
+const OperationArchiveManifestName = "manifest.json"
+
+// OperationArchiveManifest describes the full contents of an operation archive. Evidence content is
+// stored alongside the manifest in the archive, and is referenced via the Full/ThumbnailContent
+// paths on each evidence entry.
+type OperationArchiveManifest struct {
+	Version    int64                      `json:"version"`
+	ExportedAt time.Time                  `json:"exportedAt"`
+	Operation  OperationArchiveOperation  `json:"operation"`
+	Users      []OperationArchiveUser     `json:"users"`
+	Tags       []OperationArchiveTag      `json:"tags"`
+	Evidence   []OperationArchiveEvidence `json:"evidence"`
+	Findings   []OperationArchiveFinding  `json:"findings"`
+	// MissingContent lists the content that could not be read from the content store at export time.
+	// Evidence that referenced this content is exported without it.
+	MissingContent []string `json:"missingContent,omitempty"`
+}
+
+type OperationArchiveOperation struct {
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OperationArchiveUser represents a user that created evidence within the exported operation
+type OperationArchiveUser struct {
+	Slug      string `json:"slug"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Headless  bool   `json:"headless"`
+}
+
+// OperationArchiveTag represents a tag of the exported operation. The ID is only meaningful within
+// the archive, and is used to link evidence to tags.
+type OperationArchiveTag struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	ColorName   string  `json:"colorName"`
+	Description *string `json:"description"`
+}
+
+type OperationArchiveEvidence struct {
+	UUID             string                             `json:"uuid"`
+	OperatorSlug     string                             `json:"operatorSlug"`
+	Description      string                             `json:"description"`
+	ContentType      string                             `json:"contentType"`
+	ContentHash      *string                            `json:"contentHash"`
+	OccurredAt       time.Time                          `json:"occurredAt"`
+	AdjustedAt       *time.Time                         `json:"adjustedAt"`
+	CreatedAt        time.Time                          `json:"createdAt"`
+	TagIDs           []int64                            `json:"tagIds"`
+	FullContent      string                             `json:"fullContent,omitempty"`
+	ThumbnailContent string                             `json:"thumbnailContent,omitempty"`
+	Metadata         []OperationArchiveEvidenceMetadata `json:"metadata"`
+}
+
+type OperationArchiveEvidenceMetadata struct {
+	Source         string                   `json:"source"`
+	Body           string                   `json:"body"`
+	Status         *evidencemetadata.Status `json:"status"`
+	LastRunMessage *string                  `json:"lastRunMessage"`
+	CanProcess     *bool                    `json:"canProcess"`
+}
+
+type OperationArchiveFinding struct {
+	UUID          string    `json:"uuid"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Category      string    `json:"category"`
+	ReadyToReport bool      `json:"readyToReport"`
+	TicketLink    *string   `json:"ticketLink"`
+	CreatedAt     time.Time `json:"createdAt"`
+	EvidenceUUIDs []string  `json:"evidenceUuids"`
+}
+
+type ExportOperationOutput struct {
+	Filename string
+	Archive  io.Reader
+}
+
+// archiveContentFile is a single piece of evidence content that needs to be copied from the content
+// store into the archive
+type archiveContentFile struct {
+	storeKey    string
+	archivePath string
+}
+
+// ExportOperation gathers all of the data for an operation (operation details, tags, evidence,
+// evidence metadata and findings) and produces a zip archive containing a json manifest, along with
+// the raw evidence content. The archive is streamed as it is read, so content is only pulled from the
+// content store as needed.
+func ExportOperation(ctx context.Context, db *database.Connection, contentStore contentstore.Store, operationSlug string) (*ExportOperationOutput, error) {
+	operation, err := lookupOperation(db, operationSlug)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to export operation", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	if !config.EnableEvidenceExport() {
+		return nil, errorwrap.WrapError("Unable to export operation", errorwrap.NotFoundErr(errors.New("evidence export is not enabled")))
+	}
+
+	if err := policyRequireWithAdminBypass(ctx, policy.CanExportOperationData{OperationID: operation.ID}); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to export operation", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	manifest, files, err := buildOperationArchiveManifest(ctx, db, operation.ID)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot export operation", errorwrap.DatabaseErr(err))
+	}
+
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:      AuditActionExportOperation,
+		TargetType:  AuditTargetOperation,
+		Target:      operationSlug,
+		OperationID: &operation.ID,
+	})
+
+	log := logging.ReqLogger(ctx)
+	reader, writer := io.Pipe()
+	go func() {
+		err := writeOperationArchive(ctx, writer, contentStore, manifest, files)
+		if err != nil {
+			log.Error("Unable to write operation archive", "operationSlug", operationSlug, "error", err.Error())
+		} else if len(manifest.MissingContent) > 0 {
+			log.Warn("Operation archive is missing evidence content", "operationSlug", operationSlug, "missingContent", manifest.MissingContent)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return &ExportOperationOutput{
+		Filename: operationSlug + ".zip",
+		Archive:  reader,
+	}, nil
+}
+
+func buildOperationArchiveManifest(ctx context.Context, db *database.Connection, operationID int64) (*OperationArchiveManifest, []archiveContentFile, error) {
+	var operation models.Operation
+	var tags []models.Tag
+	var evidence []struct {
+		models.Evidence
+		OperatorSlug string `db:"operator_slug"`
+	}
+	var users []models.User
+	var metadata []models.EvidenceMetadata
+	var tagEvidenceMap []models.TagEvidenceMap
+	var findings []struct {
+		models.Finding
+		Category *string `db:"category"`
+	}
+	var evidenceFindingMap []struct {
+		FindingID    int64  `db:"finding_id"`
+		EvidenceUUID string `db:"uuid"`
+	}
+
+	err := db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Get(&operation, sq.Select("*").From("operations").Where(sq.Eq{"id": operationID}))
+		tx.Select(&tags, sq.Select("*").From("tags").Where(sq.Eq{"operation_id": operationID}).OrderBy("id"))
+		tx.Select(&evidence, sq.Select("evidence.*", "users.slug AS operator_slug").
+			From("evidence").
+			LeftJoin("users ON users.id = evidence.operator_id").
+			Where(sq.Eq{"operation_id": operationID}).
+			OrderBy("evidence.id"))
+		tx.Select(&users, sq.Select("users.*").
+			From("users").
+			Where(sq.Expr("id IN (SELECT DISTINCT operator_id FROM evidence WHERE operation_id = ?)", operationID)))
+		tx.Select(&metadata, sq.Select("evidence_metadata.*").
+			From("evidence_metadata").
+			Join("evidence ON evidence.id = evidence_metadata.evidence_id").
+			Where(sq.Eq{"evidence.operation_id": operationID}).
+			OrderBy("evidence_metadata.id"))
+		tx.Select(&tagEvidenceMap, sq.Select("tag_evidence_map.*").
+			From("tag_evidence_map").
+			Join("evidence ON evidence.id = tag_evidence_map.evidence_id").
+			Where(sq.Eq{"evidence.operation_id": operationID}).
+			OrderBy("tag_evidence_map.tag_id"))
+		tx.Select(&findings, sq.Select("findings.*", "finding_categories.category AS category").
+			From("findings").
+			LeftJoin("finding_categories ON finding_categories.id = findings.category_id").
+			Where(sq.Eq{"operation_id": operationID}).
+			OrderBy("findings.id"))
+		tx.Select(&evidenceFindingMap, sq.Select("finding_id", "evidence.uuid").
+			From("evidence_finding_map").
+			Join("evidence ON evidence.id = evidence_finding_map.evidence_id").
+			Where(sq.Eq{"evidence.operation_id": operationID}).
+			OrderBy("evidence.id"))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := OperationArchiveManifest{
+		Version:    OperationArchiveVersion,
+		ExportedAt: time.Now(),
+		Operation: OperationArchiveOperation{
+			Slug:      operation.Slug,
+			Name:      operation.Name,
+			CreatedAt: operation.CreatedAt,
+		},
+		Users:    make([]OperationArchiveUser, len(users)),
+		Tags:     make([]OperationArchiveTag, len(tags)),
+		Evidence: make([]OperationArchiveEvidence, len(evidence)),
+		Findings: make([]OperationArchiveFinding, len(findings)),
+	}
+
+	for i, user := range users {
+		manifest.Users[i] = OperationArchiveUser{
+			Slug:      user.Slug,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Headless:  user.Headless,
+		}
+	}
+
+	for i, tag := range tags {
+		manifest.Tags[i] = OperationArchiveTag{
+			ID:          tag.ID,
+			Name:        tag.Name,
+			ColorName:   tag.ColorName,
+			Description: tag.Description,
+		}
+	}
+
+	tagIDsByEvidenceID := map[int64][]int64{}
+	for _, row := range tagEvidenceMap {
+		tagIDsByEvidenceID[row.EvidenceID] = append(tagIDsByEvidenceID[row.EvidenceID], row.TagID)
+	}
+
+	metadataByEvidenceID := map[int64][]OperationArchiveEvidenceMetadata{}
+	for _, row := range metadata {
+		metadataByEvidenceID[row.EvidenceID] = append(metadataByEvidenceID[row.EvidenceID], OperationArchiveEvidenceMetadata{
+			Source:         row.Source,
+			Body:           row.Body,
+			Status:         row.Status,
+			LastRunMessage: row.LastRunMessage,
+			CanProcess:     row.CanProcess,
+		})
+	}
+
+	files := make([]archiveContentFile, 0, len(evidence)*2)
+	for i, evi := range evidence {
+		entry := OperationArchiveEvidence{
+			UUID:         evi.UUID,
+			OperatorSlug: evi.OperatorSlug,
+			Description:  evi.Description,
+			ContentType:  evi.ContentType,
+			ContentHash:  evi.ContentHash,
+			OccurredAt:   evi.OccurredAt,
+			AdjustedAt:   evi.AdjustedAt,
+			CreatedAt:    evi.CreatedAt,
+			TagIDs:       tagIDsByEvidenceID[evi.ID],
+			Metadata:     metadataByEvidenceID[evi.ID],
+		}
+		if entry.TagIDs == nil {
+			entry.TagIDs = []int64{}
+		}
+		if entry.Metadata == nil {
+			entry.Metadata = []OperationArchiveEvidenceMetadata{}
+		}
+
+		if evi.FullImageKey != "" {
+			entry.FullContent = archiveContentPath(evi.UUID, "full")
+			files = append(files, archiveContentFile{storeKey: evi.FullImageKey, archivePath: entry.FullContent})
+		}
+		if evi.ThumbImageKey != "" {
+			if evi.ThumbImageKey == evi.FullImageKey {
+				entry.ThumbnailContent = entry.FullContent
+			} else {
+				entry.ThumbnailContent = archiveContentPath(evi.UUID, "thumbnail")
+				files = append(files, archiveContentFile{storeKey: evi.ThumbImageKey, archivePath: entry.ThumbnailContent})
+			}
+		}
+		manifest.Evidence[i] = entry
+	}
+
+	evidenceUUIDsByFindingID := map[int64][]string{}
+	for _, row := range evidenceFindingMap {
+		evidenceUUIDsByFindingID[row.FindingID] = append(evidenceUUIDsByFindingID[row.FindingID], row.EvidenceUUID)
+	}
+
+	for i, finding := range findings {
+		category := ""
+		if finding.Category != nil {
+			category = *finding.Category
+		}
+		evidenceUUIDs := evidenceUUIDsByFindingID[finding.ID]
+		if evidenceUUIDs == nil {
+			evidenceUUIDs = []string{}
+		}
+		manifest.Findings[i] = OperationArchiveFinding{
+			UUID:          finding.UUID,
+			Title:         finding.Title,
+			Description:   finding.Description,
+			Category:      category,
+			ReadyToReport: finding.ReadyToReport,
+			TicketLink:    finding.TicketLink,
+			CreatedAt:     finding.CreatedAt,
+			EvidenceUUIDs: evidenceUUIDs,
+		}
+	}
+
+	return &manifest, files, nil
+}
+
+// writeOperationArchive writes the manifest, followed by each content file, into a zip archive
+func writeOperationArchive(ctx context.Context, w io.Writer, contentStore contentstore.Store, manifest *OperationArchiveManifest, files []archiveContentFile) error {
+	archive := zip.NewWriter(w)
+
+	for _, file := range files {
+		// stop once the requester has gone away (e.g. the client disconnected)
+		if err := ctx.Err(); err != nil {
+			return errorwrap.WrapError("Archive export was cancelled", err)
+		}
+		// the archive is already being streamed to the requester by now, so content that can't be read
+		// is skipped (and noted in the manifest) rather than leaving them with a truncated archive
+		content, err := contentStore.Read(file.storeKey)
+		if err != nil {
+			omitArchiveContent(manifest, file.archivePath)
+			continue
+		}
+		fileWriter, err := archive.Create(file.archivePath)
+		if err == nil {
+			_, err = io.Copy(fileWriter, content)
+		}
+		if closer, ok := content.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			return errorwrap.WrapError("Unable to write evidence content for "+file.archivePath, err)
+		}
+	}
+
+	// the manifest is written last, so that it can record any content that was skipped
+	manifestWriter, err := archive.Create(OperationArchiveManifestName)
+	if err != nil {
+		return errorwrap.WrapError("Unable to add manifest to archive", err)
+	}
+	encoder := json.NewEncoder(manifestWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return errorwrap.WrapError("Unable to write manifest", err)
+	}
+
+	return archive.Close()
+}
+
+// omitArchiveContent records that the given archive content is missing, and removes any references
+// to it from the manifest's evidence
+func omitArchiveContent(manifest *OperationArchiveManifest, archivePath string) {
+	manifest.MissingContent = append(manifest.MissingContent, archivePath)
+	for i := range manifest.Evidence {
+		evi := &manifest.Evidence[i]
+		if evi.FullContent == archivePath {
+			evi.FullContent = ""
+		}
+		if evi.ThumbnailContent == archivePath {
+			evi.ThumbnailContent = ""
+		}
+	}
+}
+
+func archiveContentPath(evidenceUUID, variant string) string {
+	return path.Join("content", evidenceUUID, variant)
+}
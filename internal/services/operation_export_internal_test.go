@@ -0,0 +1,96 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOperationArchive(t *testing.T) {
+	store, err := contentstore.NewMemStore()
+	require.NoError(t, err)
+	fullKey, err := store.Upload(strings.NewReader("full content"))
+	require.NoError(t, err)
+	thumbKey, err := store.Upload(strings.NewReader("thumb content"))
+	require.NoError(t, err)
+
+	manifest := &OperationArchiveManifest{
+		Version:   OperationArchiveVersion,
+		Operation: OperationArchiveOperation{Slug: "op", Name: "Operation"},
+		Evidence: []OperationArchiveEvidence{
+			{
+				UUID:             "evi-uuid",
+				FullContent:      archiveContentPath("evi-uuid", "full"),
+				ThumbnailContent: archiveContentPath("evi-uuid", "thumbnail"),
+			},
+		},
+	}
+	files := []archiveContentFile{
+		{storeKey: fullKey, archivePath: manifest.Evidence[0].FullContent},
+		{storeKey: thumbKey, archivePath: manifest.Evidence[0].ThumbnailContent},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeOperationArchive(context.Background(), &buf, store, manifest, files))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	contents := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		contents[f.Name] = data
+	}
+
+	require.Len(t, contents, 3)
+	var readManifest OperationArchiveManifest
+	require.NoError(t, json.Unmarshal(contents[OperationArchiveManifestName], &readManifest))
+	require.Equal(t, "op", readManifest.Operation.Slug)
+	require.Equal(t, "full content", string(contents["content/evi-uuid/full"]))
+	require.Equal(t, "thumb content", string(contents["content/evi-uuid/thumbnail"]))
+
+	require.Empty(t, readManifest.MissingContent)
+
+	// missing content should be skipped, and recorded in the manifest
+	missingManifest := &OperationArchiveManifest{
+		Version: OperationArchiveVersion,
+		Evidence: []OperationArchiveEvidence{
+			{UUID: "x", FullContent: "content/x/full", ThumbnailContent: "content/x/full"},
+			{UUID: "evi-uuid", FullContent: files[0].archivePath},
+		},
+	}
+	buf.Reset()
+	err = writeOperationArchive(context.Background(), &buf, store, missingManifest, []archiveContentFile{
+		{storeKey: "nope", archivePath: "content/x/full"},
+		files[0],
+	})
+	require.NoError(t, err)
+	archive, err = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	rc, err := archive.Open(OperationArchiveManifestName)
+	require.NoError(t, err)
+	readManifest = OperationArchiveManifest{}
+	require.NoError(t, json.NewDecoder(rc).Decode(&readManifest))
+	rc.Close()
+	require.Equal(t, []string{"content/x/full"}, readManifest.MissingContent)
+	require.Empty(t, readManifest.Evidence[0].FullContent)
+	require.Empty(t, readManifest.Evidence[0].ThumbnailContent)
+	require.Equal(t, files[0].archivePath, readManifest.Evidence[1].FullContent)
+
+	// a cancelled export should stop before reading any further content
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = writeOperationArchive(ctx, io.Discard, store, manifest, files)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,491 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/har"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/google/uuid"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// importPlaceholderUserSlug is the slug of the headless user that owns imported evidence when the
+// original operator cannot be found on this instance
+const importPlaceholderUserSlug = "imported.operator"
+
+type ImportOperationInput struct {
+	Archive     io.ReaderAt
+	ArchiveSize int64
+	// Slug and Name optionally override the values recorded in the archive
+	Slug string
+	Name string
+}
+
+// ImportOperation recreates an operation from an archive produced by ExportOperation. All evidence and
+// findings are assigned new UUIDs, and tags are recreated for the new operation. Evidence operators are
+// matched against existing users by slug, then by email. Any operator that cannot be matched is
+// replaced with a placeholder headless user. The requesting admin is made an admin of the new operation.
+func ImportOperation(ctx context.Context, db *database.Connection, contentStore contentstore.Store, i ImportOperationInput) (*dtos.Operation, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unable to import operation", errorwrap.UnauthorizedWriteErr(err))
+	}
+
+	if i.Archive == nil {
+		return nil, errorwrap.MissingValueErr("Archive")
+	}
+
+	maxSize := config.MaxImportSize()
+	if maxSize > 0 && i.ArchiveSize > maxSize {
+		return nil, errorwrap.TooLargeErr(fmt.Errorf("archive is %v bytes", i.ArchiveSize), fmt.Sprintf("Archive exceeds the %v byte limit", maxSize))
+	}
+
+	archive, err := zip.NewReader(i.Archive, i.ArchiveSize)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to import operation", errorwrap.BadInputErr(err, "Archive is not a valid zip file"))
+	}
+	archiveFiles := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		archiveFiles[f.Name] = f
+	}
+
+	manifest, err := readOperationArchiveManifest(archiveFiles)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to import operation", err)
+	}
+	if err := checkOperationArchiveSizes(archiveFiles, manifest, maxSize); err != nil {
+		return nil, errorwrap.WrapError("Unable to import operation", err)
+	}
+
+	slug := manifest.Operation.Slug
+	if i.Slug != "" {
+		slug = i.Slug
+	}
+	name := manifest.Operation.Name
+	if i.Name != "" {
+		name = i.Name
+	}
+	if name == "" {
+		return nil, errorwrap.MissingValueErr("Name")
+	}
+	cleanSlug := SanitizeSlug(slug)
+	if cleanSlug == "" {
+		return nil, errorwrap.BadInputErr(errors.New("Unable to import operation. Invalid operation slug"), "Slug must contain english letters or numbers")
+	}
+
+	contentKeys, err := uploadOperationArchiveContent(ctx, contentStore, archiveFiles, manifest)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to import operation", err)
+	}
+	harSummaries := readOperationArchiveHARs(ctx, archiveFiles, manifest)
+	codeblocks := readOperationArchiveCodeblocks(archiveFiles, manifest)
+
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		operationID, _ := tx.Insert("operations", map[string]interface{}{
+			"name": name,
+			"slug": cleanSlug,
+		})
+		tx.Insert("user_operation_permissions", map[string]interface{}{
+			"user_id":      middleware.UserID(ctx),
+			"operation_id": operationID,
+			"role":         policy.OperationRoleAdmin,
+		})
+
+		tagIDMap := make(map[int64]int64, len(manifest.Tags))
+		for _, tag := range manifest.Tags {
+			tagIDMap[tag.ID], _ = tx.Insert("tags", map[string]interface{}{
+				"operation_id": operationID,
+				"name":         tag.Name,
+				"color_name":   tag.ColorName,
+				"description":  tag.Description,
+			})
+		}
+
+		userIDMap := mapOperationArchiveUsers(tx, manifest)
+
+		evidenceIDMap := make(map[string]int64, len(manifest.Evidence))
+		evidenceIDs := make([]int64, 0, len(manifest.Evidence))
+		for _, evi := range manifest.Evidence {
+			var contentSize int64
+			if file, ok := archiveFiles[evi.FullContent]; ok {
+				contentSize = int64(file.UncompressedSize64)
+			}
+			evidenceID, _ := tx.Insert("evidence", map[string]interface{}{
+				"uuid":            uuid.New().String(),
+				"operation_id":    operationID,
+				"operator_id":     userIDMap[evi.OperatorSlug],
+				"description":     evi.Description,
+				"content_type":    evi.ContentType,
+				"full_image_key":  contentKeys[evi.FullContent],
+				"thumb_image_key": contentKeys[evi.ThumbnailContent],
+				"content_hash":    evi.ContentHash,
+				"content_size":    contentSize,
+				"occurred_at":     evi.OccurredAt,
+				"adjusted_at":     evi.AdjustedAt,
+			})
+			evidenceIDMap[evi.UUID] = evidenceID
+			evidenceIDs = append(evidenceIDs, evidenceID)
+			insertHarEntries(tx, evidenceID, harSummaries[evi.UUID])
+			if codeblock, ok := codeblocks[evi.UUID]; ok {
+				search.IndexEvidenceContent(tx, models.Evidence{ID: evidenceID, OperationID: operationID, ContentType: evi.ContentType}, codeblock)
+			}
+
+			tx.BatchInsert("tag_evidence_map", len(evi.TagIDs), func(idx int) map[string]interface{} {
+				newTagID, ok := tagIDMap[evi.TagIDs[idx]]
+				if !ok {
+					tx.FailTransaction(fmt.Errorf("evidence %v references unknown tag %v", evi.UUID, evi.TagIDs[idx]))
+				}
+				return map[string]interface{}{
+					"tag_id":      newTagID,
+					"evidence_id": evidenceID,
+				}
+			})
+
+			tx.BatchInsert("evidence_metadata", len(evi.Metadata), func(idx int) map[string]interface{} {
+				meta := evi.Metadata[idx]
+				return map[string]interface{}{
+					"evidence_id":      evidenceID,
+					"source":           meta.Source,
+					"body":             meta.Body,
+					"status":           importedMetadataStatus(meta.Status),
+					"last_run_message": importedMetadataMessage(meta.Status, meta.LastRunMessage),
+					"can_process":      meta.CanProcess,
+				}
+			})
+		}
+
+		findingIDs := make([]int64, 0, len(manifest.Findings))
+		for _, finding := range manifest.Findings {
+			var categoryID *int64
+			if finding.Category != "" {
+				categoryID, _ = getFindingCategoryID(finding.Category, tx.Select)
+				if categoryID == nil {
+					newCategoryID, _ := tx.Insert("finding_categories", map[string]interface{}{
+						"category": finding.Category,
+					})
+					categoryID = &newCategoryID
+				}
+			}
+
+			findingID, _ := tx.Insert("findings", map[string]interface{}{
+				"uuid":            uuid.New().String(),
+				"operation_id":    operationID,
+				"category_id":     categoryID,
+				"title":           finding.Title,
+				"description":     finding.Description,
+				"ready_to_report": finding.ReadyToReport,
+				"ticket_link":     finding.TicketLink,
+			})
+			findingIDs = append(findingIDs, findingID)
+
+			tx.BatchInsert("evidence_finding_map", len(finding.EvidenceUUIDs), func(idx int) map[string]interface{} {
+				evidenceID, ok := evidenceIDMap[finding.EvidenceUUIDs[idx]]
+				if !ok {
+					tx.FailTransaction(fmt.Errorf("finding %v references unknown evidence %v", finding.UUID, finding.EvidenceUUIDs[idx]))
+				}
+				return map[string]interface{}{
+					"finding_id":  findingID,
+					"evidence_id": evidenceID,
+				}
+			})
+		}
+
+		search.IndexEvidence(tx, evidenceIDs)
+		search.IndexFindings(tx, findingIDs)
+
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionImportOperation,
+			TargetType:  AuditTargetOperation,
+			Target:      cleanSlug,
+			OperationID: &operationID,
+			After: map[string]interface{}{
+				"sourceSlug":  manifest.Operation.Slug,
+				"numEvidence": len(manifest.Evidence),
+				"numFindings": len(manifest.Findings),
+			},
+		})
+	})
+	if err != nil {
+		removeImportedContent(ctx, contentStore, contentKeys)
+		if database.IsAlreadyExistsError(err) {
+			return nil, errorwrap.WrapError("Unable to import operation. Operation slug already exists.", errorwrap.BadInputErr(err, "An operation with this slug already exists"))
+		}
+		return nil, errorwrap.WrapError("Unable to import operation", errorwrap.DatabaseErr(err))
+	}
+
+	return &dtos.Operation{
+		Slug:        cleanSlug,
+		Name:        name,
+		NumUsers:    1,
+		NumEvidence: len(manifest.Evidence),
+		NumTags:     len(manifest.Tags),
+	}, nil
+}
+
+func readOperationArchiveManifest(archiveFiles map[string]*zip.File) (*OperationArchiveManifest, error) {
+	manifestFile, ok := archiveFiles[OperationArchiveManifestName]
+	if !ok {
+		return nil, errorwrap.BadInputErr(errors.New("archive has no manifest"), "Archive is missing "+OperationArchiveManifestName)
+	}
+	reader, err := manifestFile.Open()
+	if err != nil {
+		return nil, errorwrap.BadInputErr(err, "Unable to read archive manifest")
+	}
+	defer reader.Close()
+
+	var manifest OperationArchiveManifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, errorwrap.BadInputErr(err, "Archive manifest is not valid")
+	}
+	if manifest.Version != OperationArchiveVersion {
+		return nil, errorwrap.BadInputErr(
+			fmt.Errorf("unsupported archive version: %v", manifest.Version),
+			fmt.Sprintf("Archive version %v is not supported", manifest.Version),
+		)
+	}
+	return &manifest, nil
+}
+
+// checkOperationArchiveSizes verifies, using the sizes recorded in the archive, that each piece of
+// evidence content is within the evidence size limit for its content type, and that the total
+// uncompressed content is within maxSize (when positive)
+func checkOperationArchiveSizes(archiveFiles map[string]*zip.File, manifest *OperationArchiveManifest, maxSize int64) error {
+	var totalSize uint64
+	for _, f := range archiveFiles {
+		totalSize += f.UncompressedSize64
+	}
+	if maxSize > 0 && totalSize > uint64(maxSize) {
+		return errorwrap.TooLargeErr(fmt.Errorf("archive content is %v bytes", totalSize), fmt.Sprintf("Archive content exceeds the %v byte limit", maxSize))
+	}
+
+	for _, evi := range manifest.Evidence {
+		maxEvidenceSize := config.MaxEvidenceSize(evi.ContentType)
+		if maxEvidenceSize <= 0 {
+			continue
+		}
+		for _, archivePath := range []string{evi.FullContent, evi.ThumbnailContent} {
+			if file, ok := archiveFiles[archivePath]; ok && file.UncompressedSize64 > uint64(maxEvidenceSize) {
+				return errorwrap.TooLargeErr(fmt.Errorf("%v exceeds %v bytes", archivePath, maxEvidenceSize), "Archive contains evidence that is too large")
+			}
+		}
+	}
+	return nil
+}
+
+// importedMetadataStatus determines the status of imported evidence metadata. Work that was queued or
+// in progress when the operation was exported will never complete here, so is marked as an error
+// instead, allowing it to be re-run.
+func importedMetadataStatus(status *evidencemetadata.Status) *evidencemetadata.Status {
+	if status != nil && (*status == evidencemetadata.StatusQueued || *status == evidencemetadata.StatusProcessing) {
+		return helpers.Ptr(evidencemetadata.StatusError)
+	}
+	return status
+}
+
+// importedMetadataMessage explains any status change made by importedMetadataStatus
+func importedMetadataMessage(status *evidencemetadata.Status, lastRunMessage *string) *string {
+	if importedMetadataStatus(status) != status {
+		return helpers.Ptr("Processing had not completed when this evidence was exported. Re-run the service worker to process it.")
+	}
+	return lastRunMessage
+}
+
+// uploadOperationArchiveContent stores each piece of evidence content referenced by the manifest,
+// returning a mapping of archive path to content store key. If any upload fails, the content that
+// was already uploaded is removed.
+func uploadOperationArchiveContent(ctx context.Context, contentStore contentstore.Store, archiveFiles map[string]*zip.File, manifest *OperationArchiveManifest) (map[string]string, error) {
+	contentKeys := map[string]string{"": ""}
+
+	upload := func(archivePath string, contentType string) error {
+		if _, ok := contentKeys[archivePath]; ok {
+			return nil
+		}
+		file, ok := archiveFiles[archivePath]
+		if !ok {
+			return errorwrap.BadInputErr(fmt.Errorf("archive is missing %v", archivePath), "Archive is missing evidence content")
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return errorwrap.BadInputErr(err, "Unable to read evidence content")
+		}
+		defer reader.Close()
+
+		// the recorded size was already checked, but is not trusted while reading
+		maxSize := config.MaxEvidenceSize(contentType)
+		content, size, err := measureEvidenceContent(reader, maxSize)
+		if err != nil {
+			return errorwrap.BadInputErr(err, "Unable to read evidence content")
+		}
+		if maxSize > 0 && size > maxSize {
+			return errorwrap.TooLargeErr(fmt.Errorf("%v exceeds %v bytes", archivePath, maxSize), "Archive contains evidence that is too large")
+		}
+
+		key, err := contentStore.Upload(content)
+		if err != nil {
+			return errorwrap.UploadErr(err)
+		}
+		contentKeys[archivePath] = key
+		return nil
+	}
+
+	for _, evi := range manifest.Evidence {
+		for _, archivePath := range []string{evi.FullContent, evi.ThumbnailContent} {
+			if err := upload(archivePath, evi.ContentType); err != nil {
+				removeImportedContent(ctx, contentStore, contentKeys)
+				return nil, err
+			}
+		}
+	}
+	return contentKeys, nil
+}
+
+// readOperationArchiveHARs summarizes the HAR content of any http-request-cycle evidence in the
+// archive, returning a map of archive evidence UUID to summary. Content that cannot be read or parsed
+// is skipped, as the evidence can still be imported without a summary.
+func readOperationArchiveHARs(ctx context.Context, archiveFiles map[string]*zip.File, manifest *OperationArchiveManifest) map[string]*har.Summary {
+	summaries := map[string]*har.Summary{}
+	for _, evi := range manifest.Evidence {
+		file, ok := archiveFiles[evi.FullContent]
+		if evi.ContentType != "http-request-cycle" || !ok {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			continue
+		}
+		summary, err := har.Parse(data)
+		if err != nil {
+			logging.ReqLogger(ctx).Warn("Unable to summarize imported HAR content", "evidenceUUID", evi.UUID, "error", err.Error())
+			continue
+		}
+		summaries[evi.UUID] = summary
+	}
+	return summaries
+}
+
+// readOperationArchiveCodeblocks reads the content of each codeblock in the archive, so that it can be
+// indexed for search. Returns a map of (archive) evidence uuid to content.
+func readOperationArchiveCodeblocks(archiveFiles map[string]*zip.File, manifest *OperationArchiveManifest) map[string][]byte {
+	codeblocks := map[string][]byte{}
+	for _, evi := range manifest.Evidence {
+		file, ok := archiveFiles[evi.FullContent]
+		if evi.ContentType != "codeblock" || !ok {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			continue
+		}
+		codeblocks[evi.UUID] = data
+	}
+	return codeblocks
+}
+
+// mapOperationArchiveUsers determines which local user should own evidence created by each archive
+// user, returning a map of archive user slug to local user id
+func mapOperationArchiveUsers(tx *database.Transactable, manifest *OperationArchiveManifest) map[string]int64 {
+	slugs := make([]string, 0, len(manifest.Users))
+	emails := make([]string, 0, len(manifest.Users))
+	for _, user := range manifest.Users {
+		slugs = append(slugs, user.Slug)
+		if user.Email != "" {
+			emails = append(emails, strings.ToLower(user.Email))
+		}
+	}
+
+	var knownUsers []models.User
+	if len(manifest.Users) > 0 {
+		tx.Select(&knownUsers, sq.Select("*").
+			From("users").
+			Where(sq.Eq{"deleted_at": nil}).
+			Where(sq.Or{sq.Eq{"slug": slugs}, sq.Eq{"LOWER(email)": emails}}))
+	}
+	usersBySlug := map[string]int64{}
+	usersByEmail := map[string]int64{}
+	for _, user := range knownUsers {
+		usersBySlug[user.Slug] = user.ID
+		usersByEmail[strings.ToLower(user.Email)] = user.ID
+	}
+
+	var placeholderID *int64
+	placeholder := func() int64 {
+		if placeholderID == nil {
+			id := findOrCreateImportPlaceholderUser(tx)
+			placeholderID = &id
+		}
+		return *placeholderID
+	}
+
+	userIDMap := make(map[string]int64, len(manifest.Users))
+	for _, user := range manifest.Users {
+		if id, ok := usersBySlug[user.Slug]; ok {
+			userIDMap[user.Slug] = id
+		} else if id, ok := usersByEmail[strings.ToLower(user.Email)]; ok && user.Email != "" {
+			userIDMap[user.Slug] = id
+		} else {
+			userIDMap[user.Slug] = placeholder()
+		}
+	}
+	// evidence may reference operators that were not listed in the archive's user list
+	for _, evi := range manifest.Evidence {
+		if _, ok := userIDMap[evi.OperatorSlug]; !ok {
+			userIDMap[evi.OperatorSlug] = placeholder()
+		}
+	}
+
+	return userIDMap
+}
+
+func findOrCreateImportPlaceholderUser(tx *database.Transactable) int64 {
+	var existing []int64
+	tx.Select(&existing, sq.Select("id").From("users").Where(sq.Eq{"slug": importPlaceholderUserSlug}))
+	if len(existing) > 0 {
+		return existing[0]
+	}
+	id, _ := tx.Insert("users", map[string]interface{}{
+		"slug":       importPlaceholderUserSlug,
+		"first_name": "Imported",
+		"last_name":  "Operator",
+		"email":      importPlaceholderUserSlug,
+		"headless":   true,
+	})
+	return id
+}
+
+func removeImportedContent(ctx context.Context, contentStore contentstore.Store, contentKeys map[string]string) {
+	for _, key := range contentKeys {
+		if key == "" {
+			continue
+		}
+		if err := contentStore.Delete(key); err != nil {
+			logging.ReqLogger(ctx).Warn("Unable to remove imported content", "key", key, "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOperationArchiveSizes(t *testing.T) {
+	t.Setenv("DB_URI", "unused")
+	t.Setenv("APP_MAX_EVIDENCE_SIZE", "10")
+	t.Setenv("APP_MAX_EVIDENCE_SIZES", "codeblock:20")
+	require.NoError(t, config.LoadAPIConfig())
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, size := range map[string]int{"small": 5, "medium": 15, "large": 25} {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(bytes.Repeat([]byte("a"), size))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	archiveFiles := map[string]*zip.File{}
+	for _, f := range reader.File {
+		archiveFiles[f.Name] = f
+	}
+
+	check := func(contentType, archivePath string, maxSize int64) error {
+		manifest := &OperationArchiveManifest{Evidence: []OperationArchiveEvidence{
+			{ContentType: contentType, FullContent: archivePath},
+		}}
+		return checkOperationArchiveSizes(archiveFiles, manifest, maxSize)
+	}
+
+	require.NoError(t, check("image", "small", 0))
+	require.Error(t, check("image", "medium", 0))
+	require.NoError(t, check("codeblock", "medium", 0))
+	require.Error(t, check("codeblock", "large", 0))
+
+	// verify the total size of the archive's content is limited
+	require.NoError(t, check("image", "small", 45))
+	require.Error(t, check("image", "small", 44))
+}
+
+func TestImportedMetadataStatus(t *testing.T) {
+	for _, status := range []evidencemetadata.Status{evidencemetadata.StatusQueued, evidencemetadata.StatusProcessing} {
+		imported := importedMetadataStatus(&status)
+		require.Equal(t, evidencemetadata.StatusError, *imported)
+		require.NotNil(t, importedMetadataMessage(&status, nil))
+	}
+
+	completed := evidencemetadata.StatusCompleted
+	require.Equal(t, &completed, importedMetadataStatus(&completed))
+	require.Equal(t, "done", *importedMetadataMessage(&completed, helpers.Ptr("done")))
+	require.Nil(t, importedMetadataStatus(nil))
+	require.Nil(t, importedMetadataMessage(nil, nil))
+}
@@ -0,0 +1,125 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestOperationArchive(t *testing.T, manifest services.OperationArchiveManifest, content map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create(services.OperationArchiveManifestName)
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(w).Encode(manifest))
+	for name, data := range content {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestImportOperation(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		memStore, _ := contentstore.NewMemStore()
+
+		manifest := services.OperationArchiveManifest{
+			Version:   services.OperationArchiveVersion,
+			Operation: services.OperationArchiveOperation{Slug: "imported-op", Name: "Imported Op"},
+			Users: []services.OperationArchiveUser{
+				{Slug: UserHarry.Slug, Email: UserHarry.Email},
+				{Slug: "someone.else", Email: "someone.else@example.com"},
+			},
+			Tags: []services.OperationArchiveTag{{ID: 7, Name: "Imported Tag", ColorName: "blue"}},
+			Evidence: []services.OperationArchiveEvidence{
+				{
+					UUID: "a", OperatorSlug: UserHarry.Slug, Description: "Harry's", ContentType: "codeblock",
+					OccurredAt: time.Now(), TagIDs: []int64{7}, FullContent: "content/a/full", ThumbnailContent: "content/a/full",
+				},
+				{
+					UUID: "b", OperatorSlug: "someone.else", Description: "Unknown", ContentType: "event",
+					OccurredAt: time.Now(), TagIDs: []int64{},
+					Metadata: []services.OperationArchiveEvidenceMetadata{
+						{Source: "ocr", Status: helpers.Ptr(evidencemetadata.StatusProcessing)},
+					},
+				},
+			},
+			Findings: []services.OperationArchiveFinding{
+				{UUID: "f", Title: "Finding", Description: "desc", Category: "Brand New Category", EvidenceUUIDs: []string{"a", "b"}},
+			},
+		}
+		content := map[string]string{"content/a/full": "some code"}
+
+		// verify non-admins cannot import
+		ctx := contextForUser(UserRon, db)
+		archive := buildTestOperationArchive(t, manifest, content)
+		_, err := services.ImportOperation(ctx, db, memStore, services.ImportOperationInput{Archive: archive, ArchiveSize: archive.Size()})
+		require.Error(t, err)
+
+		// verify missing content is rejected
+		ctx = contextForUser(UserDumbledore, db)
+		archive = buildTestOperationArchive(t, manifest, map[string]string{})
+		_, err = services.ImportOperation(ctx, db, memStore, services.ImportOperationInput{Archive: archive, ArchiveSize: archive.Size()})
+		require.Error(t, err)
+
+		// verify a successful import
+		archive = buildTestOperationArchive(t, manifest, content)
+		out, err := services.ImportOperation(ctx, db, memStore, services.ImportOperationInput{Archive: archive, ArchiveSize: archive.Size()})
+		require.NoError(t, err)
+		require.Equal(t, "imported-op", out.Slug)
+
+		op := getOperationFromSlug(t, db, out.Slug)
+		require.Equal(t, "Imported Op", op.Name)
+
+		tags := getTagFromOperationID(t, db, op.ID)
+		require.Len(t, tags, 1)
+		require.Equal(t, "Imported Tag", tags[0].Name)
+
+		evidence := getEvidenceForOperation(t, db, op.ID)
+		require.Len(t, evidence, 2)
+		placeholder := getUserBySlug(t, db, "imported.operator")
+		require.True(t, placeholder.Headless)
+		for _, evi := range evidence {
+			require.NotContains(t, []string{"a", "b"}, evi.UUID)
+			if evi.Description == "Harry's" {
+				require.Equal(t, UserHarry.ID, evi.OperatorID)
+				require.Equal(t, []int64{tags[0].ID}, getTagIDsFromEvidenceID(t, db, evi.ID))
+				reader, err := memStore.Read(evi.FullImageKey)
+				require.NoError(t, err)
+				data, _ := io.ReadAll(reader)
+				require.Equal(t, "some code", string(data))
+			} else {
+				require.Equal(t, placeholder.ID, evi.OperatorID)
+				// unfinished processing can't complete once imported, so is left re-runnable instead
+				var metadata []models.EvidenceMetadata
+				require.NoError(t, db.Select(&metadata, sq.Select("*").From("evidence_metadata").Where(sq.Eq{"evidence_id": evi.ID})))
+				require.Len(t, metadata, 1)
+				require.Equal(t, evidencemetadata.StatusError, *metadata[0].Status)
+			}
+		}
+
+		findings := getFindingsByOperationID(t, db, op.ID)
+		require.Len(t, findings, 1)
+		require.Len(t, getEvidenceIDsFromFinding(t, db, findings[0].ID), 2)
+
+		// verify slug collisions are rejected
+		archive = buildTestOperationArchive(t, manifest, content)
+		_, err = services.ImportOperation(ctx, db, memStore, services.ImportOperationInput{Archive: archive, ArchiveSize: archive.Size()})
+		require.Error(t, err)
+	})
+}
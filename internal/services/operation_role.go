@@ -43,42 +43,46 @@ func SetUserOperationRole(ctx context.Context, db *database.Connection, i SetUse
 		return errorwrap.WrapError("Unwilling to set user role", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	if i.Role == "" {
-		err := db.Delete(sq.Delete("user_operation_permissions").Where(sq.Eq{"user_id": userID, "operation_id": operation.ID}))
-
-		if err != nil {
-			return errorwrap.WrapError("Cannot delete user role", errorwrap.DatabaseErr(err))
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		var permissions []models.UserOperationPermission
+		tx.Select(&permissions, sq.Select("*").
+			From("user_operation_permissions").
+			Where(sq.Eq{
+				"user_id":      userID,
+				"operation_id": operation.ID,
+			}))
+		var previousRole policy.OperationRole
+		if len(permissions) > 0 {
+			previousRole = permissions[0].Role
 		}
-		return nil
-	}
-
-	var permission models.UserOperationPermission
-	err = db.Get(&permission, sq.Select("*").
-		From("user_operation_permissions").
-		Where(sq.Eq{
-			"user_id":      userID,
-			"operation_id": operation.ID,
-		}))
-	if err != nil {
-		_, err = db.Insert("user_operation_permissions", map[string]interface{}{
-			"user_id":      userID,
-			"operation_id": operation.ID,
-			"role":         i.Role,
-		})
-		if err != nil {
-			return errorwrap.WrapError("Unable to add user role", errorwrap.DatabaseErr(err))
+		if previousRole == i.Role {
+			return
 		}
-		return nil
-	}
 
-	if permission.Role != i.Role {
-		err = db.Update(sq.Update("user_operation_permissions").
-			Set("role", i.Role).
-			Where(sq.Eq{"user_id": userID, "operation_id": operation.ID}))
-
-		if err != nil {
-			return errorwrap.WrapError("Unable to alter user role", errorwrap.DatabaseErr(err))
+		if i.Role == "" {
+			tx.Delete(sq.Delete("user_operation_permissions").Where(sq.Eq{"user_id": userID, "operation_id": operation.ID}))
+		} else if previousRole == "" {
+			tx.Insert("user_operation_permissions", map[string]interface{}{
+				"user_id":      userID,
+				"operation_id": operation.ID,
+				"role":         i.Role,
+			})
+		} else {
+			tx.Update(sq.Update("user_operation_permissions").
+				Set("role", i.Role).
+				Where(sq.Eq{"user_id": userID, "operation_id": operation.ID}))
 		}
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionSetUserOperationRole,
+			TargetType:  AuditTargetUser,
+			Target:      i.UserSlug,
+			OperationID: &operation.ID,
+			Before:      map[string]interface{}{"role": previousRole},
+			After:       map[string]interface{}{"role": i.Role},
+		})
+	})
+	if err != nil {
+		return errorwrap.WrapError("Unable to set user role", errorwrap.DatabaseErr(err))
 	}
 	return nil
 }
@@ -102,34 +106,43 @@ func SetUserGroupOperationRole(ctx context.Context, db *database.Connection, i S
 		return errorwrap.WrapError("Unwilling to set user group role", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	if i.Role == "" {
-		err := db.Delete(sq.Delete("user_group_operation_permissions").Where(sq.Eq{"group_id": userGroupID, "operation_id": operation.ID}))
-
-		if err != nil {
-			return errorwrap.WrapError("Cannot delete user group role", errorwrap.DatabaseErr(err))
-		}
-		return nil
-	}
-
-	var permissions []models.UserGroupOperationPermission
-	err = db.WithTx(context.Background(), func(tx *database.Transactable) {
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		var permissions []models.UserGroupOperationPermission
 		tx.Select(&permissions, sq.Select("*").
 			From("user_group_operation_permissions").
 			Where(sq.Eq{
 				"group_id":     userGroupID,
 				"operation_id": operation.ID,
 			}))
-		if len(permissions) == 0 {
+		var previousRole policy.OperationRole
+		if len(permissions) > 0 {
+			previousRole = permissions[0].Role
+		}
+		if previousRole == i.Role {
+			return
+		}
+
+		if i.Role == "" {
+			tx.Delete(sq.Delete("user_group_operation_permissions").Where(sq.Eq{"group_id": userGroupID, "operation_id": operation.ID}))
+		} else if previousRole == "" {
 			tx.Insert("user_group_operation_permissions", map[string]interface{}{
 				"group_id":     userGroupID,
 				"operation_id": operation.ID,
 				"role":         i.Role,
 			})
-		} else if permissions[0].Role != i.Role {
+		} else {
 			tx.Update(sq.Update("user_group_operation_permissions").
 				Set("role", i.Role).
 				Where(sq.Eq{"group_id": userGroupID, "operation_id": operation.ID}))
 		}
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionSetUserGroupOperationRole,
+			TargetType:  AuditTargetUserGroup,
+			Target:      i.UserGroupSlug,
+			OperationID: &operation.ID,
+			Before:      map[string]interface{}{"role": previousRole},
+			After:       map[string]interface{}{"role": i.Role},
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Unable to add user role", errorwrap.DatabaseErr(err))
@@ -9,6 +9,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/logging"
@@ -64,8 +65,9 @@ func CreateOperation(ctx context.Context, db *database.Connection, i CreateOpera
 		return nil, errorwrap.BadInputErr(errors.New("Unable to create operation. Invalid operation slug"), "Slug must contain english letters or numbers")
 	}
 
+	var operationID int64
 	err := db.WithTx(ctx, func(tx *database.Transactable) {
-		operationID, _ := tx.Insert("operations", map[string]interface{}{
+		operationID, _ = tx.Insert("operations", map[string]interface{}{
 			"name": i.Name,
 			"slug": cleanSlug,
 		})
@@ -74,6 +76,13 @@ func CreateOperation(ctx context.Context, db *database.Connection, i CreateOpera
 			"operation_id": operationID,
 			"role":         policy.OperationRoleAdmin,
 		})
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionCreateOperation,
+			TargetType:  AuditTargetOperation,
+			Target:      cleanSlug,
+			OperationID: &operationID,
+			After:       map[string]interface{}{"name": i.Name, "ownerID": i.OwnerID, "ownerRole": policy.OperationRoleAdmin},
+		})
 
 		// Copy default tags into new operation
 		tx.Exec(sq.Insert("tags").
@@ -94,6 +103,8 @@ func CreateOperation(ctx context.Context, db *database.Connection, i CreateOpera
 		}
 		return nil, errorwrap.WrapError("Unable to add new operation", errorwrap.DatabaseErr(err))
 	}
+	sendServiceWorkerEvent(ctx, db, enhancementservices.EventOperationCreated,
+		enhancementservices.BuildOperationPayloads(enhancementservices.EventOperationCreated, []int64{operationID}))
 
 	return &dtos.Operation{
 		Slug:     cleanSlug,
@@ -154,6 +165,13 @@ func DeleteOperation(ctx context.Context, db *database.Connection, contentStore
 
 			// remove evidence metadata
 			tx.Delete(sq.Delete("evidence_metadata").Where(sq.Eq{"evidence_id": evidenceIDs}))
+			// remove HAR summaries
+			tx.Delete(sq.Delete("har_entries").Where(sq.Eq{"evidence_id": evidenceIDs}))
+			// remove outstanding service worker jobs and callbacks
+			tx.Delete(sq.Delete("service_worker_jobs").Where(sq.Eq{"evidence_id": evidenceIDs}))
+			tx.Delete(sq.Delete("service_worker_callbacks").Where(sq.Eq{"evidence_id": evidenceIDs}))
+			// remove search index entries
+			tx.Delete(sq.Delete("search_index").Where(sq.Eq{"operation_id": operation.ID}))
 
 			// remove all evidence
 			tx.Delete(sq.Delete("evidence").Where(sq.Eq{"id": evidenceIDs}))
@@ -164,8 +182,23 @@ func DeleteOperation(ctx context.Context, db *database.Connection, contentStore
 			tx.Delete(sq.Delete("user_operation_preferences").Where(sq.Eq{"operation_id": operation.ID}))
 			// remove operation variables map
 			tx.Delete(sq.Delete("var_operation_map").Where(sq.Eq{"operation_id": operation.ID}))
+			// remove webhooks and their delivery history
+			var webhookIDs []int64
+			tx.Select(&webhookIDs, sq.Select("id").From("webhooks").Where(sq.Eq{"operation_id": operation.ID}))
+			tx.Delete(sq.Delete("webhook_deliveries").Where(sq.Eq{"webhook_id": webhookIDs}))
+			tx.Delete(sq.Delete("webhooks").Where(sq.Eq{"id": webhookIDs}))
+			// remove subscriptions to the operation's saved queries
+			tx.Delete(sq.Delete("query_subscriptions").
+				Where("query_id IN (SELECT id FROM queries WHERE operation_id = ?)", operation.ID))
 
 			tx.Delete(sq.Delete("operations").Where(sq.Eq{"id": operation.ID}))
+			recordAuditEvent(ctx, tx, auditEvent{
+				Action:      AuditActionDeleteOperation,
+				TargetType:  AuditTargetOperation,
+				Target:      slug,
+				OperationID: &operation.ID,
+				Before:      map[string]interface{}{"name": operation.Name, "numEvidence": len(evidence)},
+			})
 		})
 		if err != nil {
 			log.Error(
@@ -320,11 +353,21 @@ func UpdateOperation(ctx context.Context, db *database.Connection, i UpdateOpera
 		return errorwrap.WrapError("Unwilling to update operation", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	err = db.Update(sq.Update("operations").
-		SetMap(map[string]interface{}{
-			"name": i.Name,
-		}).
-		Where(sq.Eq{"id": operation.ID}))
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Update(sq.Update("operations").
+			SetMap(map[string]interface{}{
+				"name": i.Name,
+			}).
+			Where(sq.Eq{"id": operation.ID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionUpdateOperation,
+			TargetType:  AuditTargetOperation,
+			Target:      i.OperationSlug,
+			OperationID: &operation.ID,
+			Before:      map[string]interface{}{"name": operation.Name},
+			After:       map[string]interface{}{"name": i.Name},
+		})
+	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot update operation", errorwrap.DatabaseErr(err))
 	}
@@ -87,7 +87,11 @@ func DeleteQuery(ctx context.Context, db *database.Connection, i DeleteQueryInpu
 		return errorwrap.WrapError("Unwilling to delete query", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	err = db.Delete(sq.Delete("queries").Where(sq.Eq{"id": i.ID, "operation_id": operation.ID}))
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Delete(sq.Delete("query_subscriptions").
+			Where("query_id IN (SELECT id FROM queries WHERE id = ? AND operation_id = ?)", i.ID, operation.ID))
+		tx.Delete(sq.Delete("queries").Where(sq.Eq{"id": i.ID, "operation_id": operation.ID}))
+	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete query", errorwrap.DatabaseErr(err))
 	}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	// QueryDigestDaily sends a digest once a day, at the schedule's send hour
+	QueryDigestDaily = "daily"
+	// QueryDigestWeekly sends a digest once a week, at the schedule's send hour and weekday
+	QueryDigestWeekly = "weekly"
+)
+
+// maxQueryDigestItems limits how many new matches are listed for each query in a digest. The digest
+// still reports the total number of new matches, and links to the full results.
+const maxQueryDigestItems = 20
+
+type QuerySubscriptionInput struct {
+	OperationSlug string
+	QueryID       int64
+}
+
+type SetQueryDigestScheduleInput struct {
+	Frequency   string
+	SendHour    int64
+	SendWeekday int64
+}
+
+// SubscribeToQuery subscribes the current user to a saved query. New evidence or findings matching
+// the query are included in the user's digest emails. Subscribing to a query more than once has no effect.
+func SubscribeToQuery(ctx context.Context, db *database.Connection, i QuerySubscriptionInput) error {
+	query, err := lookupSubscribableQuery(ctx, db, i)
+	if err != nil {
+		return errorwrap.WrapError("Unable to subscribe to query", err)
+	}
+
+	userID := middleware.UserID(ctx)
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Insert("query_subscriptions", map[string]interface{}{
+			"query_id": query.ID,
+			"user_id":  userID,
+		}, "ON DUPLICATE KEY UPDATE query_id=query_id")
+		// every subscriber needs a schedule; new schedules use the default frequency and time
+		tx.Insert("query_digest_schedules", map[string]interface{}{
+			"user_id": userID,
+		}, "ON DUPLICATE KEY UPDATE user_id=user_id")
+	})
+	if err != nil {
+		return errorwrap.WrapError("Cannot subscribe to query", errorwrap.DatabaseErr(err))
+	}
+	return nil
+}
+
+// UnsubscribeFromQuery removes the current user's subscription to a saved query
+func UnsubscribeFromQuery(ctx context.Context, db *database.Connection, i QuerySubscriptionInput) error {
+	query, err := lookupSubscribableQuery(ctx, db, i)
+	if err != nil {
+		return errorwrap.WrapError("Unable to unsubscribe from query", err)
+	}
+
+	err = db.Delete(sq.Delete("query_subscriptions").Where(sq.Eq{
+		"query_id": query.ID,
+		"user_id":  middleware.UserID(ctx),
+	}))
+	if err != nil {
+		return errorwrap.WrapError("Cannot unsubscribe from query", errorwrap.DatabaseErr(err))
+	}
+	return nil
+}
+
+// ListQuerySubscriptions retrieves the saved queries the current user is subscribed to
+func ListQuerySubscriptions(ctx context.Context, db *database.Connection) ([]*dtos.QuerySubscription, error) {
+	var subscriptions []subscribedQuery
+	err := db.Select(&subscriptions, selectSubscribedQueries().
+		Where(sq.Eq{"query_subscriptions.user_id": middleware.UserID(ctx)}))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list query subscriptions", errorwrap.DatabaseErr(err))
+	}
+
+	subscriptionsDTO := make([]*dtos.QuerySubscription, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		if !middleware.Policy(ctx).Check(policy.CanReadOperation{OperationID: sub.OperationID}) {
+			continue
+		}
+		subscriptionsDTO = append(subscriptionsDTO, &dtos.QuerySubscription{
+			QueryID:       sub.QueryID,
+			QueryName:     sub.QueryName,
+			Query:         sub.Query,
+			Type:          sub.Type,
+			OperationSlug: sub.OperationSlug,
+			OperationName: sub.OperationName,
+			LastRunAt:     sub.LastRunAt,
+		})
+	}
+	return subscriptionsDTO, nil
+}
+
+// ReadQueryDigestSchedule retrieves when the current user's saved query digests are sent. Users
+// without a schedule receive the default schedule.
+func ReadQueryDigestSchedule(ctx context.Context, db *database.Connection) (*dtos.QueryDigestSchedule, error) {
+	var schedule models.QueryDigestSchedule
+	err := db.Get(&schedule, sq.Select("*").
+		From("query_digest_schedules").
+		Where(sq.Eq{"user_id": middleware.UserID(ctx)}))
+	if database.IsEmptyResultSetError(err) {
+		schedule = defaultQueryDigestSchedule(time.Now())
+	} else if err != nil {
+		return nil, errorwrap.WrapError("Cannot read query digest schedule", errorwrap.DatabaseErr(err))
+	}
+
+	return &dtos.QueryDigestSchedule{
+		Frequency:   schedule.Frequency,
+		SendHour:    schedule.SendHour,
+		SendWeekday: schedule.SendWeekday,
+		LastSentAt:  schedule.LastSentAt,
+		NextSendAt:  nextQueryDigestAt(schedule),
+	}, nil
+}
+
+// SetQueryDigestSchedule changes when the current user's saved query digests are sent
+func SetQueryDigestSchedule(ctx context.Context, db *database.Connection, i SetQueryDigestScheduleInput) error {
+	if i.Frequency != QueryDigestDaily && i.Frequency != QueryDigestWeekly {
+		err := fmt.Errorf("Bad frequency: %s", i.Frequency)
+		return errorwrap.BadInputErr(err, "Frequency must be either daily or weekly")
+	}
+	if i.SendHour < 0 || i.SendHour > 23 {
+		err := fmt.Errorf("Bad send hour: %d", i.SendHour)
+		return errorwrap.BadInputErr(err, "Send hour must be between 0 and 23")
+	}
+	if i.SendWeekday < 0 || i.SendWeekday > 6 {
+		err := fmt.Errorf("Bad send weekday: %d", i.SendWeekday)
+		return errorwrap.BadInputErr(err, "Send weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+
+	_, err := db.Insert("query_digest_schedules", map[string]interface{}{
+		"user_id":      middleware.UserID(ctx),
+		"frequency":    i.Frequency,
+		"send_hour":    i.SendHour,
+		"send_weekday": i.SendWeekday,
+	}, "ON DUPLICATE KEY UPDATE frequency=VALUES(frequency), send_hour=VALUES(send_hour), send_weekday=VALUES(send_weekday)")
+	if err != nil {
+		return errorwrap.WrapError("Cannot set query digest schedule", errorwrap.DatabaseErr(err))
+	}
+	return nil
+}
+
+// QueueQueryDigests queues a digest email, using the given email template, for each user whose digest
+// is due at the provided time. Each digest contains the evidence or findings created since the previous
+// digest that match the user's subscribed queries. Users without any new matches are not emailed. Returns
+// the number of emails queued.
+func QueueQueryDigests(ctx context.Context, db *database.Connection, emailTemplate string, now time.Time) (int, error) {
+	var schedules []models.QueryDigestSchedule
+	err := db.Select(&schedules, sq.Select("*").
+		From("query_digest_schedules").
+		Where("user_id IN (SELECT user_id FROM query_subscriptions)"))
+	if err != nil {
+		return 0, errorwrap.WrapError("Cannot list query digest schedules", errorwrap.DatabaseErr(err))
+	}
+
+	queued := 0
+	for _, schedule := range schedules {
+		if now.Before(nextQueryDigestAt(schedule)) {
+			continue
+		}
+		sent, err := queueQueryDigest(ctx, db, emailTemplate, schedule, now)
+		if err != nil {
+			logging.ReqLogger(ctx).Error("Unable to queue query digest", "userID", schedule.UserID, "error", err.Error())
+			continue
+		}
+		if sent {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// queueQueryDigest builds and queues the digest for a single user, then records that the digest was sent.
+// Returns true if an email was queued. Digests may be queued by several servers at once, so the schedule
+// is claimed when the email is queued: if another server has sent the digest since the schedule was read,
+// nothing is queued.
+func queueQueryDigest(ctx context.Context, db *database.Connection, emailTemplate string, schedule models.QueryDigestSchedule, now time.Time) (bool, error) {
+	user, err := db.RetrieveUserByID(schedule.UserID)
+	if err != nil {
+		return false, err
+	}
+	if user.Disabled || user.DeletedAt != nil {
+		return false, nil
+	}
+	userPolicy := middleware.Policy(middleware.BuildContextForUser(ctx, db, user.ID, user.Admin, user.Headless))
+
+	var subscriptions []subscribedQuery
+	err = db.Select(&subscriptions, selectSubscribedQueries().Where(sq.Eq{"query_subscriptions.user_id": user.ID}))
+	if err != nil {
+		return false, err
+	}
+
+	digest := dtos.QueryDigest{Sections: []dtos.QueryDigestSection{}}
+	subscriptionIDs := []int64{}
+	for _, sub := range subscriptions {
+		// subscriptions to operations the user can no longer read are kept, but skipped
+		if !userPolicy.Check(policy.CanReadOperation{OperationID: sub.OperationID}) {
+			continue
+		}
+		subscriptionIDs = append(subscriptionIDs, sub.ID)
+
+		filters, err := helpers.ParseTimelineQuery(sub.Query)
+		if err != nil {
+			logging.ReqLogger(ctx).Warn("Skipping unparsable subscribed query", "queryID", sub.QueryID, "error", err.Error())
+			continue
+		}
+		section, err := readQueryDigestSection(db, sub, filters, now)
+		if err != nil {
+			return false, err
+		}
+		if section.Total > 0 {
+			digest.Sections = append(digest.Sections, *section)
+		}
+	}
+
+	var templateData []byte
+	if len(digest.Sections) > 0 {
+		if templateData, err = json.Marshal(digest); err != nil {
+			return false, err
+		}
+	}
+
+	claimed := true
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		var current models.QueryDigestSchedule
+		err := tx.Get(&current, sq.Select("*").
+			From("query_digest_schedules").
+			Where(sq.Eq{"id": schedule.ID}).
+			Suffix("FOR UPDATE"))
+		if err != nil {
+			tx.FailTransaction(err)
+			return
+		}
+		if !sameTime(current.LastSentAt, schedule.LastSentAt) {
+			claimed = false
+			return
+		}
+
+		if templateData != nil {
+			tx.Insert("email_queue", map[string]interface{}{
+				"to_email":      user.Email,
+				"user_id":       user.ID,
+				"template":      emailTemplate,
+				"template_data": string(templateData),
+			})
+		}
+		tx.Update(sq.Update("query_subscriptions").
+			Set("last_run_at", now).
+			Where(sq.Eq{"id": subscriptionIDs}))
+		tx.Update(sq.Update("query_digest_schedules").
+			Set("last_sent_at", now).
+			Where(sq.Eq{"id": schedule.ID}))
+	})
+	return claimed && templateData != nil && err == nil, err
+}
+
+// sameTime returns true if both times are unset, or both are set to the same instant
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// readQueryDigestSection finds the evidence or findings matching a subscribed query that were created
+// after the subscription last ran, up to (and including) now
+func readQueryDigestSection(db *database.Connection, sub subscribedQuery, filters helpers.TimelineFilters, now time.Time) (*dtos.QueryDigestSection, error) {
+	section := &dtos.QueryDigestSection{
+		OperationSlug: sub.OperationSlug,
+		OperationName: sub.OperationName,
+		QueryName:     sub.QueryName,
+		Query:         sub.Query,
+		Type:          sub.Type,
+		Since:         sub.LastRunAt,
+		Items:         []dtos.QueryDigestItem{},
+	}
+
+	if sub.Type == "findings" {
+		createdWithin := sq.And{
+			sq.Eq{"findings.operation_id": sub.OperationID},
+			sq.Gt{"findings.created_at": sub.LastRunAt},
+			sq.LtOrEq{"findings.created_at": now},
+		}
+		queryFilters, queryValues, err := buildFindingFilters(filters)
+		if err != nil {
+			return nil, err
+		}
+		addFilters := func(sb sq.SelectBuilder) sq.SelectBuilder {
+			sb = sb.From("findings").Where(createdWithin)
+			if len(queryFilters) > 0 {
+				sb = sb.Where(strings.Join(queryFilters, " AND "), queryValues...)
+			}
+			return sb
+		}
+		if err := db.Get(&section.Total, addFilters(sq.Select("COUNT(*)"))); err != nil {
+			return nil, err
+		}
+		var findings []models.Finding
+		err = db.Select(&findings, addFilters(sq.Select("findings.uuid", "findings.title", "findings.created_at")).
+			OrderBy("findings.created_at DESC", "findings.id DESC").
+			Limit(maxQueryDigestItems))
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			section.Items = append(section.Items, dtos.QueryDigestItem{
+				UUID:       finding.UUID,
+				Title:      finding.Title,
+				OccurredAt: finding.CreatedAt,
+			})
+		}
+		return section, nil
+	}
+
+	createdWithin := sq.And{
+		sq.Gt{"evidence.created_at": sub.LastRunAt},
+		sq.LtOrEq{"evidence.created_at": now},
+	}
+	filtered, err := buildListEvidenceWhereClause(sq.Select().From("evidence").Where(createdWithin), sub.OperationID, filters)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Get(&section.Total, filtered.Columns("COUNT(*)")); err != nil {
+		return nil, err
+	}
+	var evidence []models.Evidence
+	err = db.Select(&evidence, filtered.Columns("evidence.uuid", "description", "occurred_at").
+		OrderBy("evidence.created_at DESC", "evidence.id DESC").
+		Limit(maxQueryDigestItems))
+	if err != nil {
+		return nil, err
+	}
+	for _, evi := range evidence {
+		section.Items = append(section.Items, dtos.QueryDigestItem{
+			UUID:       evi.UUID,
+			Title:      evi.Description,
+			OccurredAt: evi.OccurredAt,
+		})
+	}
+	return section, nil
+}
+
+// subscribedQuery is a query subscription, along with the query and operation it refers to
+type subscribedQuery struct {
+	ID            int64     `db:"id"`
+	QueryID       int64     `db:"query_id"`
+	LastRunAt     time.Time `db:"last_run_at"`
+	QueryName     string    `db:"query_name"`
+	Query         string    `db:"query"`
+	Type          string    `db:"type"`
+	OperationID   int64     `db:"operation_id"`
+	OperationSlug string    `db:"operation_slug"`
+	OperationName string    `db:"operation_name"`
+}
+
+func selectSubscribedQueries() sq.SelectBuilder {
+	return sq.Select(
+		"query_subscriptions.id",
+		"query_subscriptions.query_id",
+		"query_subscriptions.last_run_at",
+		"queries.name AS query_name",
+		"queries.query",
+		"queries.type",
+		"queries.operation_id",
+		"operations.slug AS operation_slug",
+		"operations.name AS operation_name",
+	).
+		From("query_subscriptions").
+		Join("queries ON queries.id = query_subscriptions.query_id").
+		Join("operations ON operations.id = queries.operation_id").
+		OrderBy("operations.name ASC", "queries.name ASC")
+}
+
+// lookupSubscribableQuery retrieves the saved query identified by the input, provided the current user
+// can read the query's operation
+func lookupSubscribableQuery(ctx context.Context, db *database.Connection, i QuerySubscriptionInput) (*models.Query, error) {
+	operation, err := lookupOperation(db, i.OperationSlug)
+	if err != nil {
+		return nil, errorwrap.UnauthorizedReadErr(err)
+	}
+	if err := policy.Require(middleware.Policy(ctx), policy.CanReadOperation{OperationID: operation.ID}); err != nil {
+		return nil, errorwrap.UnauthorizedReadErr(err)
+	}
+
+	var query models.Query
+	err = db.Get(&query, sq.Select("*").
+		From("queries").
+		Where(sq.Eq{"id": i.QueryID, "operation_id": operation.ID}))
+	if err != nil {
+		return nil, errorwrap.NotFoundErr(err)
+	}
+	return &query, nil
+}
+
+func defaultQueryDigestSchedule(createdAt time.Time) models.QueryDigestSchedule {
+	return models.QueryDigestSchedule{
+		Frequency:   QueryDigestDaily,
+		SendHour:    8,
+		SendWeekday: int64(time.Monday),
+		CreatedAt:   createdAt,
+	}
+}
+
+// nextQueryDigestAt determines when the next digest for the given schedule should be sent: the first
+// scheduled time (in UTC) after the last digest was sent, or after the schedule was created, if no
+// digest has been sent yet
+func nextQueryDigestAt(schedule models.QueryDigestSchedule) time.Time {
+	after := schedule.CreatedAt
+	if schedule.LastSentAt != nil {
+		after = *schedule.LastSentAt
+	}
+	after = after.UTC()
+
+	next := time.Date(after.Year(), after.Month(), after.Day(), int(schedule.SendHour), 0, 0, 0, time.UTC)
+	for !next.After(after) ||
+		(schedule.Frequency == QueryDigestWeekly && next.Weekday() != time.Weekday(schedule.SendWeekday)) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
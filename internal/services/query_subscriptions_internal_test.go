@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextQueryDigestAt(t *testing.T) {
+	// Thursday, March 14, 2024
+	created := time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)
+	schedule := func(frequency string, hour, weekday int64, lastSent *time.Time) models.QueryDigestSchedule {
+		return models.QueryDigestSchedule{
+			Frequency:   frequency,
+			SendHour:    hour,
+			SendWeekday: weekday,
+			LastSentAt:  lastSent,
+			CreatedAt:   created,
+		}
+	}
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+
+	// daily, before and after the send hour
+	require.Equal(t, at(14, 18), nextQueryDigestAt(schedule(QueryDigestDaily, 18, 0, nil)))
+	require.Equal(t, at(15, 8), nextQueryDigestAt(schedule(QueryDigestDaily, 8, 0, nil)))
+
+	// a digest sent exactly at the send hour schedules the next one for the following day
+	sent := at(15, 8)
+	require.Equal(t, at(16, 8), nextQueryDigestAt(schedule(QueryDigestDaily, 8, 0, &sent)))
+
+	// weekly digests wait for the send weekday
+	require.Equal(t, at(18, 8), nextQueryDigestAt(schedule(QueryDigestWeekly, 8, int64(time.Monday), nil)))
+	require.Equal(t, at(14, 18), nextQueryDigestAt(schedule(QueryDigestWeekly, 18, int64(time.Thursday), nil)))
+	sent = at(14, 18)
+	require.Equal(t, at(21, 18), nextQueryDigestAt(schedule(QueryDigestWeekly, 18, int64(time.Thursday), &sent)))
+
+	// times are compared in UTC, regardless of the stored location
+	sent = time.Date(2024, 3, 14, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60)) // 04:30 UTC, March 15
+	require.Equal(t, at(15, 8), nextQueryDigestAt(schedule(QueryDigestDaily, 8, 0, &sent)))
+}
@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySubscriptions(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		query, err := services.CreateQuery(contextForUser(UserHarry, db), db, services.CreateQueryInput{
+			OperationSlug: OpChamberOfSecrets.Slug,
+			Name:          "Spiders",
+			Query:         "spider",
+			Type:          "evidence",
+		})
+		require.NoError(t, err)
+		input := services.QuerySubscriptionInput{OperationSlug: OpChamberOfSecrets.Slug, QueryID: query.ID}
+
+		// verify permissions
+		require.Error(t, services.SubscribeToQuery(contextForUser(UserDraco, db), db, input))
+		require.Error(t, services.SubscribeToQuery(contextForUser(UserRon, db), db, services.QuerySubscriptionInput{
+			OperationSlug: OpSorcerersStone.Slug, QueryID: query.ID, // query belongs to another operation
+		}))
+
+		// verify subscribe (repeatedly)
+		ctx := contextForUser(UserRon, db)
+		require.NoError(t, services.SubscribeToQuery(ctx, db, input))
+		require.NoError(t, services.SubscribeToQuery(ctx, db, input))
+		subscriptions, err := services.ListQuerySubscriptions(ctx, db)
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+		require.Equal(t, query.ID, subscriptions[0].QueryID)
+		require.Equal(t, OpChamberOfSecrets.Slug, subscriptions[0].OperationSlug)
+
+		// verify schedule
+		schedule, err := services.ReadQueryDigestSchedule(ctx, db)
+		require.NoError(t, err)
+		require.Equal(t, services.QueryDigestDaily, schedule.Frequency)
+		require.Error(t, services.SetQueryDigestSchedule(ctx, db, services.SetQueryDigestScheduleInput{Frequency: "hourly"}))
+		require.Error(t, services.SetQueryDigestSchedule(ctx, db, services.SetQueryDigestScheduleInput{Frequency: services.QueryDigestDaily, SendHour: 24}))
+		require.NoError(t, services.SetQueryDigestSchedule(ctx, db, services.SetQueryDigestScheduleInput{
+			Frequency: services.QueryDigestWeekly, SendHour: 6, SendWeekday: 5,
+		}))
+		schedule, err = services.ReadQueryDigestSchedule(ctx, db)
+		require.NoError(t, err)
+		require.Equal(t, services.QueryDigestWeekly, schedule.Frequency)
+		require.Equal(t, time.Friday, schedule.NextSendAt.Weekday())
+		require.Equal(t, 6, schedule.NextSendAt.Hour())
+
+		// verify digests: only new, matching evidence is included
+		// seeded evidence is created "now", so the last run is placed just after that
+		lastRun := time.Now().Add(time.Second).Truncate(time.Second)
+		require.NoError(t, db.Update(sq.Update("query_subscriptions").Set("last_run_at", lastRun)))
+		require.NoError(t, db.Update(sq.Update("query_digest_schedules").Set("created_at", lastRun.AddDate(0, 0, -8))))
+		newEvidence := func(uuid, description string) {
+			_, err := db.Insert("evidence", map[string]interface{}{
+				"uuid":         uuid,
+				"operation_id": OpChamberOfSecrets.ID,
+				"operator_id":  UserHarry.ID,
+				"description":  description,
+				"content_type": "none",
+				"occurred_at":  lastRun,
+				"created_at":   lastRun.Add(30 * time.Minute),
+			})
+			require.NoError(t, err)
+		}
+		newEvidence("b1f0d2a3-0000-4000-8000-000000000001", "A spider in the forbidden forest")
+		newEvidence("b1f0d2a3-0000-4000-8000-000000000002", "A snake in the pipes")
+
+		// several servers may queue digests at once, but each digest is sent only once
+		now := lastRun.Add(time.Hour)
+		var wg sync.WaitGroup
+		results := make([]int, 3)
+		errs := make([]error, len(results))
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = services.QueueQueryDigests(ctx, db, "query-digest-email", now)
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+		require.Equal(t, 1, results[0]+results[1]+results[2])
+
+		var emails []models.QueuedEmail
+		require.NoError(t, db.Select(&emails, sq.Select("*").From("email_queue").Where(sq.Eq{"template": "query-digest-email"})))
+		require.Len(t, emails, 1)
+		require.Equal(t, UserRon.Email, emails[0].ToEmail)
+		var digest dtos.QueryDigest
+		require.NoError(t, json.Unmarshal([]byte(*emails[0].TemplateData), &digest))
+		require.Len(t, digest.Sections, 1)
+		require.Equal(t, int64(1), digest.Sections[0].Total)
+		require.Equal(t, "b1f0d2a3-0000-4000-8000-000000000001", digest.Sections[0].Items[0].UUID)
+
+		// the next digest is not due yet
+		queued, err := services.QueueQueryDigests(ctx, db, "query-digest-email", now.Add(time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, 0, queued)
+
+		// verify unsubscribe
+		require.NoError(t, services.UnsubscribeFromQuery(ctx, db, input))
+		subscriptions, err = services.ListQuerySubscriptions(ctx, db)
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 0)
+
+		// verify deleting the query removes its subscriptions
+		require.NoError(t, services.SubscribeToQuery(ctx, db, input))
+		require.NoError(t, services.DeleteQuery(contextForUser(UserHarry, db), db, services.DeleteQueryInput{
+			OperationSlug: OpChamberOfSecrets.Slug, ID: query.ID,
+		}))
+		var remaining int64
+		require.NoError(t, db.Get(&remaining, sq.Select("COUNT(*)").From("query_subscriptions")))
+		require.Equal(t, int64(0), remaining)
+	})
+}
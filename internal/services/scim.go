@@ -0,0 +1,559 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/scim"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// SCIMMaxResults is the most resources returned by a single SCIM list request
+const SCIMMaxResults = 1000
+
+// SCIMAuthScheme identifies the authentication scheme that users provisioned through SCIM log in with.
+// A SCIM user's userName is their username within this scheme.
+type SCIMAuthScheme struct {
+	Name string
+	Type string
+}
+
+type ListSCIMResourcesInput struct {
+	Filter     string
+	StartIndex int
+	Count      int
+}
+
+type scimUserRow struct {
+	models.User
+	UserName string `db:"username"`
+}
+
+// ListSCIMUsers lists the users that log in with the SCIM auth scheme, matching the provided filter.
+// Filtering is done after retrieving every user, which is acceptable given the number of users a
+// single ASHIRT instance is expected to have.
+//
+// Admin only
+func ListSCIMUsers(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, i ListSCIMResourcesInput) (*scim.ListResponse, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to list SCIM users", errorwrap.UnauthorizedReadErr(err))
+	}
+	filter, err := scim.ParseFilter(i.Filter)
+	if err != nil {
+		return nil, errorwrap.BadInputErr(err, "Invalid filter: "+err.Error())
+	}
+
+	users, err := loadSCIMUsers(db, scheme, nil)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list SCIM users", errorwrap.DatabaseErr(err))
+	}
+	matched := slices.DeleteFunc(users, func(u scim.User) bool { return !filter.Matches(u) })
+
+	list := scim.NewListResponse(scim.Paginate(matched, i.StartIndex, min(i.Count, SCIMMaxResults)), len(matched), max(i.StartIndex, 1))
+	return &list, nil
+}
+
+// ReadSCIMUser retrieves the SCIM representation of the user with the given slug
+//
+// Admin only
+func ReadSCIMUser(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, id string) (*scim.User, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to read SCIM user", errorwrap.UnauthorizedReadErr(err))
+	}
+	return lookupSCIMUser(db, scheme, id)
+}
+
+// CreateSCIMUser provisions a user that logs in with the SCIM auth scheme. If a user with the same
+// email already exists, but cannot yet log in with the scheme, that user is linked to the scheme
+// rather than creating a new user.
+//
+// Admin only
+func CreateSCIMUser(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, u scim.User) (*scim.User, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to create SCIM user", errorwrap.UnauthorizedWriteErr(err))
+	}
+	profile, err := scimUserProfile(u)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureSCIMUserNameAvailable(db, scheme, u.UserName); err != nil {
+		return nil, err
+	}
+
+	var existing []models.User
+	err = db.Select(&existing, sq.Select("*").From("users").Where(sq.Eq{"email": profile.Email}))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot create SCIM user", errorwrap.DatabaseErr(err))
+	}
+
+	var userID int64
+	var slug string
+	if len(existing) > 0 {
+		var linked int64
+		err := db.Get(&linked, sq.Select("COUNT(*)").
+			From("auth_scheme_data").
+			Where(sq.Eq{"auth_scheme": scheme.Name, "user_id": existing[0].ID}))
+		if err != nil {
+			return nil, errorwrap.WrapError("Cannot create SCIM user", errorwrap.DatabaseErr(err))
+		}
+		if existing[0].DeletedAt != nil || linked > 0 {
+			return nil, errorwrap.ConflictErr(
+				fmt.Errorf("Email %v already belongs to user %v", profile.Email, existing[0].Slug),
+				"A user with this email already exists",
+			)
+		}
+		userID, slug = existing[0].ID, existing[0].Slug
+	} else {
+		profile.Slug = u.UserName
+		created, err := CreateUser(db, profile)
+		if err != nil {
+			return nil, errorwrap.WrapError("Cannot create SCIM user", err)
+		}
+		userID, slug = created.UserID, created.RealSlug
+	}
+
+	_, err = db.Insert("auth_scheme_data", map[string]interface{}{
+		"auth_scheme": scheme.Name,
+		"auth_type":   scheme.Type,
+		"username":    u.UserName,
+		"user_id":     userID,
+	})
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot link SCIM user to auth scheme", errorwrap.DatabaseErr(err))
+	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionProvisionUser,
+		TargetType: AuditTargetUser,
+		Target:     slug,
+		After:      map[string]interface{}{"userName": u.UserName, "firstName": profile.FirstName, "lastName": profile.LastName, "email": profile.Email},
+	})
+
+	if u.Active != nil && !*u.Active {
+		disabled := true
+		if err := SetUserFlags(ctx, db, SetUserFlagsInput{Slug: slug, Disabled: &disabled}); err != nil {
+			return nil, errorwrap.WrapError("Cannot disable SCIM user", err)
+		}
+	}
+	return lookupSCIMUser(db, scheme, slug)
+}
+
+// ReplaceSCIMUser updates the user's profile, userName and active state to match the provided user.
+// An omitted active state is left unchanged.
+//
+// Admin only
+func ReplaceSCIMUser(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, id string, u scim.User) (*scim.User, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to update SCIM user", errorwrap.UnauthorizedWriteErr(err))
+	}
+	current, err := lookupSCIMUser(db, scheme, id)
+	if err != nil {
+		return nil, err
+	}
+	return updateSCIMUser(ctx, db, scheme, *current, u)
+}
+
+// PatchSCIMUser applies PATCH operations to the user. Setting active to false disables the user,
+// which immediately ends their sessions.
+//
+// Admin only
+func PatchSCIMUser(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, id string, patch scim.PatchRequest) (*scim.User, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to update SCIM user", errorwrap.UnauthorizedWriteErr(err))
+	}
+	current, err := lookupSCIMUser(db, scheme, id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *current
+	updated.Emails = slices.Clone(current.Emails)
+	if err := updated.ApplyPatch(patch.Operations); err != nil {
+		return nil, errorwrap.BadInputErr(err, "Invalid patch: "+err.Error())
+	}
+	return updateSCIMUser(ctx, db, scheme, *current, updated)
+}
+
+// DeleteSCIMUser deletes the user (see DeleteUser)
+//
+// Admin only
+func DeleteSCIMUser(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, id string) error {
+	if err := isAdmin(ctx); err != nil {
+		return errorwrap.WrapError("Unwilling to delete SCIM user", errorwrap.UnauthorizedWriteErr(err))
+	}
+	if _, err := lookupSCIMUser(db, scheme, id); err != nil {
+		return err
+	}
+	return DeleteUser(ctx, db, id)
+}
+
+// ListSCIMGroups lists the user groups matching the provided filter. Group members are identified by
+// user slug.
+//
+// Admin only
+func ListSCIMGroups(ctx context.Context, db *database.Connection, i ListSCIMResourcesInput) (*scim.ListResponse, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to list SCIM groups", errorwrap.UnauthorizedReadErr(err))
+	}
+	filter, err := scim.ParseFilter(i.Filter)
+	if err != nil {
+		return nil, errorwrap.BadInputErr(err, "Invalid filter: "+err.Error())
+	}
+
+	groups, err := loadSCIMGroups(db, nil)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list SCIM groups", errorwrap.DatabaseErr(err))
+	}
+	matched := slices.DeleteFunc(groups, func(g scim.Group) bool { return !filter.Matches(g) })
+
+	list := scim.NewListResponse(scim.Paginate(matched, i.StartIndex, min(i.Count, SCIMMaxResults)), len(matched), max(i.StartIndex, 1))
+	return &list, nil
+}
+
+// ReadSCIMGroup retrieves the SCIM representation of the user group with the given slug
+//
+// Admin only
+func ReadSCIMGroup(ctx context.Context, db *database.Connection, id string) (*scim.Group, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to read SCIM group", errorwrap.UnauthorizedReadErr(err))
+	}
+	return lookupSCIMGroup(db, id)
+}
+
+// CreateSCIMGroup creates a user group, using a slug derived from the group's display name
+//
+// Admin only
+func CreateSCIMGroup(ctx context.Context, db *database.Connection, g scim.Group) (*scim.Group, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to create SCIM group", errorwrap.UnauthorizedWriteErr(err))
+	}
+	if g.DisplayName == "" {
+		return nil, errorwrap.MissingValueErr("displayName")
+	}
+
+	var existing []models.UserGroup
+	err := db.Select(&existing, sq.Select("*").From("user_groups").Where(sq.Or{
+		sq.Eq{"slug": SanitizeSlug(g.DisplayName)},
+		sq.Eq{"name": g.DisplayName},
+	}))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot create SCIM group", errorwrap.DatabaseErr(err))
+	}
+	if len(existing) > 0 {
+		return nil, errorwrap.ConflictErr(
+			fmt.Errorf("User group %v already exists", existing[0].Slug),
+			"A user group with this name already exists",
+		)
+	}
+
+	group, err := CreateUserGroup(ctx, db, CreateUserGroupInput{
+		Name:      g.DisplayName,
+		Slug:      g.DisplayName,
+		UserSlugs: g.MemberValues(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lookupSCIMGroup(db, SanitizeSlug(group.Slug))
+}
+
+// ReplaceSCIMGroup updates the group's name and members to match the provided group. The group's
+// slug is not changed.
+//
+// Admin only
+func ReplaceSCIMGroup(ctx context.Context, db *database.Connection, id string, g scim.Group) (*scim.Group, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to update SCIM group", errorwrap.UnauthorizedWriteErr(err))
+	}
+	current, err := lookupSCIMGroup(db, id)
+	if err != nil {
+		return nil, err
+	}
+	return updateSCIMGroup(ctx, db, *current, g)
+}
+
+// PatchSCIMGroup applies PATCH operations to the group
+//
+// Admin only
+func PatchSCIMGroup(ctx context.Context, db *database.Connection, id string, patch scim.PatchRequest) (*scim.Group, error) {
+	if err := isAdmin(ctx); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to update SCIM group", errorwrap.UnauthorizedWriteErr(err))
+	}
+	current, err := lookupSCIMGroup(db, id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *current
+	updated.Members = slices.Clone(current.Members)
+	if err := updated.ApplyPatch(patch.Operations); err != nil {
+		return nil, errorwrap.BadInputErr(err, "Invalid patch: "+err.Error())
+	}
+	return updateSCIMGroup(ctx, db, *current, updated)
+}
+
+// DeleteSCIMGroup deletes the user group (see DeleteUserGroup)
+//
+// Admin only
+func DeleteSCIMGroup(ctx context.Context, db *database.Connection, id string) error {
+	if err := isAdmin(ctx); err != nil {
+		return errorwrap.WrapError("Unwilling to delete SCIM group", errorwrap.UnauthorizedWriteErr(err))
+	}
+	if _, err := lookupSCIMGroup(db, id); err != nil {
+		return err
+	}
+	return DeleteUserGroup(ctx, db, id)
+}
+
+// updateSCIMUser applies the differences between the current and updated representations of a user
+func updateSCIMUser(ctx context.Context, db *database.Connection, scheme SCIMAuthScheme, current, updated scim.User) (*scim.User, error) {
+	profile, err := scimUserProfile(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	currentProfile, _ := scimUserProfile(current)
+	if updated.UserName != current.UserName {
+		if err := ensureSCIMUserNameAvailable(db, scheme, updated.UserName); err != nil {
+			return nil, err
+		}
+		err := db.Update(sq.Update("auth_scheme_data").
+			Set("username", updated.UserName).
+			Where(sq.Eq{"auth_scheme": scheme.Name, "username": current.UserName}))
+		if err != nil {
+			return nil, errorwrap.WrapError("Cannot update SCIM userName", errorwrap.DatabaseErr(err))
+		}
+	}
+
+	if profile != currentProfile {
+		err := UpdateUserProfile(ctx, db, UpdateUserProfileInput{
+			UserSlug:  current.ID,
+			FirstName: profile.FirstName,
+			LastName:  profile.LastName,
+			Email:     profile.Email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if updated.Active != nil && *updated.Active != *current.Active {
+		disabled := !*updated.Active
+		if err := SetUserFlags(ctx, db, SetUserFlagsInput{Slug: current.ID, Disabled: &disabled}); err != nil {
+			return nil, err
+		}
+	}
+
+	// changes to the active state are audited by SetUserFlags
+	if updated.UserName != current.UserName || profile != currentProfile {
+		recordAuditEvent(ctx, db, auditEvent{
+			Action:     AuditActionUpdateProvisionedUser,
+			TargetType: AuditTargetUser,
+			Target:     current.ID,
+			Before:     map[string]interface{}{"userName": current.UserName, "firstName": currentProfile.FirstName, "lastName": currentProfile.LastName, "email": currentProfile.Email},
+			After:      map[string]interface{}{"userName": updated.UserName, "firstName": profile.FirstName, "lastName": profile.LastName, "email": profile.Email},
+		})
+	}
+	return lookupSCIMUser(db, scheme, current.ID)
+}
+
+// updateSCIMGroup applies the differences between the current and updated representations of a group
+func updateSCIMGroup(ctx context.Context, db *database.Connection, current, updated scim.Group) (*scim.Group, error) {
+	currentMembers := current.MemberValues()
+	updatedMembers := updated.MemberValues()
+	input := ModifyUserGroupInput{
+		Slug:          current.ID,
+		UsersToAdd:    slices.DeleteFunc(slices.Clone(updatedMembers), func(s string) bool { return slices.Contains(currentMembers, s) }),
+		UsersToRemove: slices.DeleteFunc(slices.Clone(currentMembers), func(s string) bool { return slices.Contains(updatedMembers, s) }),
+	}
+	if updated.DisplayName != current.DisplayName {
+		input.Name = updated.DisplayName
+	}
+
+	if input.Name != "" || len(input.UsersToAdd) > 0 || len(input.UsersToRemove) > 0 {
+		if _, err := ModifyUserGroup(ctx, db, input); err != nil {
+			return nil, err
+		}
+	}
+	return lookupSCIMGroup(db, current.ID)
+}
+
+// scimUserProfile converts a SCIM user into the profile ASHIRT stores. The name is taken from the
+// name components if provided, and otherwise split from the display name. If no email is provided,
+// the userName is used when it is an email address.
+func scimUserProfile(u scim.User) (CreateUserInput, error) {
+	if u.UserName == "" {
+		return CreateUserInput{}, errorwrap.MissingValueErr("userName")
+	}
+	profile := CreateUserInput{
+		FirstName: u.Name.GivenName,
+		LastName:  u.Name.FamilyName,
+		Email:     u.PrimaryEmail(),
+	}
+	if profile.FirstName == "" && profile.LastName == "" {
+		profile.FirstName, profile.LastName, _ = strings.Cut(strings.TrimSpace(u.DisplayName), " ")
+	}
+	if profile.Email == "" && strings.Contains(u.UserName, "@") {
+		profile.Email = u.UserName
+	}
+	if profile.Email == "" {
+		return CreateUserInput{}, errorwrap.MissingValueErr("emails")
+	}
+	return profile, nil
+}
+
+func ensureSCIMUserNameAvailable(db *database.Connection, scheme SCIMAuthScheme, userName string) error {
+	var count int64
+	err := db.Get(&count, sq.Select("COUNT(*)").
+		From("auth_scheme_data").
+		Where(sq.Eq{"auth_scheme": scheme.Name, "username": userName}))
+	if err != nil {
+		return errorwrap.WrapError("Cannot check SCIM userName", errorwrap.DatabaseErr(err))
+	}
+	if count > 0 {
+		return errorwrap.ConflictErr(
+			fmt.Errorf("Username %v already exists for scheme %v", userName, scheme.Name),
+			"A user with this userName already exists",
+		)
+	}
+	return nil
+}
+
+func lookupSCIMUser(db *database.Connection, scheme SCIMAuthScheme, slug string) (*scim.User, error) {
+	users, err := loadSCIMUsers(db, scheme, sq.Eq{"users.slug": slug})
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot read SCIM user", errorwrap.DatabaseErr(err))
+	}
+	if len(users) == 0 {
+		return nil, errorwrap.NotFoundErr(fmt.Errorf("No SCIM user with slug %v", slug))
+	}
+	return &users[0], nil
+}
+
+// loadSCIMUsers retrieves the non-deleted users that log in with the scheme, along with their groups
+func loadSCIMUsers(db *database.Connection, scheme SCIMAuthScheme, where sq.Sqlizer) ([]scim.User, error) {
+	query := sq.Select("users.*", "auth_scheme_data.username").
+		From("users").
+		Join("auth_scheme_data ON auth_scheme_data.user_id = users.id").
+		Where(sq.Eq{"auth_scheme_data.auth_scheme": scheme.Name, "users.deleted_at": nil}).
+		OrderBy("users.id")
+	if where != nil {
+		query = query.Where(where)
+	}
+	var rows []scimUserRow
+	if err := db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []scim.User{}, nil
+	}
+
+	var memberships []struct {
+		UserID    int64  `db:"user_id"`
+		GroupSlug string `db:"slug"`
+		GroupName string `db:"name"`
+	}
+	err := db.Select(&memberships, sq.Select("group_user_map.user_id", "user_groups.slug", "user_groups.name").
+		From("group_user_map").
+		Join("user_groups ON user_groups.id = group_user_map.group_id").
+		Where(sq.Eq{
+			"group_user_map.user_id": helpers.Map(rows, func(row scimUserRow) int64 { return row.ID }),
+			"user_groups.deleted_at": nil,
+		}).
+		OrderBy("user_groups.slug"))
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]scim.User, len(rows))
+	for i, row := range rows {
+		active := !row.Disabled
+		users[i] = scim.User{
+			Schemas:     []string{scim.SchemaUser},
+			ID:          row.Slug,
+			UserName:    row.UserName,
+			Name:        scim.Name{GivenName: row.FirstName, FamilyName: row.LastName},
+			DisplayName: strings.TrimSpace(row.FirstName + " " + row.LastName),
+			Emails:      []scim.MultiValue{{Value: row.Email, Type: "work", Primary: true}},
+			Active:      &active,
+			Groups:      []scim.MultiValue{},
+			Meta:        &scim.Meta{ResourceType: "User", Created: row.CreatedAt, LastModified: row.UpdatedAt},
+		}
+		for _, m := range memberships {
+			if m.UserID == row.ID {
+				users[i].Groups = append(users[i].Groups, scim.MultiValue{Value: m.GroupSlug, Display: m.GroupName})
+			}
+		}
+	}
+	return users, nil
+}
+
+func lookupSCIMGroup(db *database.Connection, slug string) (*scim.Group, error) {
+	groups, err := loadSCIMGroups(db, sq.Eq{"slug": slug})
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot read SCIM group", errorwrap.DatabaseErr(err))
+	}
+	if len(groups) == 0 {
+		return nil, errorwrap.NotFoundErr(errors.New("No user group with slug " + slug))
+	}
+	return &groups[0], nil
+}
+
+// loadSCIMGroups retrieves the non-deleted user groups, along with their (non-deleted) members
+func loadSCIMGroups(db *database.Connection, where sq.Sqlizer) ([]scim.Group, error) {
+	query := sq.Select("*").
+		From("user_groups").
+		Where(sq.Eq{"deleted_at": nil}).
+		OrderBy("id")
+	if where != nil {
+		query = query.Where(where)
+	}
+	var rows []models.UserGroup
+	if err := db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []scim.Group{}, nil
+	}
+
+	groupIDs := helpers.Map(rows, func(row models.UserGroup) int64 { return row.ID })
+	var members []struct {
+		GroupID   int64  `db:"group_id"`
+		Slug      string `db:"slug"`
+		FirstName string `db:"first_name"`
+		LastName  string `db:"last_name"`
+	}
+	err := db.Select(&members, sq.Select("group_user_map.group_id", "users.slug", "users.first_name", "users.last_name").
+		From("group_user_map").
+		Join("users ON users.id = group_user_map.user_id").
+		Where(sq.Eq{"group_user_map.group_id": groupIDs, "users.deleted_at": nil}).
+		OrderBy("users.slug"))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]scim.Group, len(rows))
+	for i, row := range rows {
+		groups[i] = scim.Group{
+			Schemas:     []string{scim.SchemaGroup},
+			ID:          row.Slug,
+			DisplayName: row.Name,
+			Members:     []scim.MultiValue{},
+			Meta:        &scim.Meta{ResourceType: "Group", Created: row.CreatedAt, LastModified: row.UpdatedAt},
+		}
+		for _, m := range members {
+			if m.GroupID == row.ID {
+				groups[i].Members = append(groups[i].Members, scim.MultiValue{
+					Value:   m.Slug,
+					Display: strings.TrimSpace(m.FirstName + " " + m.LastName),
+				})
+			}
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/scim"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/stretchr/testify/require"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func TestSCIMUsers(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		scheme := services.SCIMAuthScheme{Name: "hogwarts-idp", Type: "oidc"}
+		ctx := contextForUser(UserRon, db)
+		adminCtx := contextForUser(UserDumbledore, db)
+		luna := scim.User{
+			UserName: "luna.lovegood@hogwarts.edu",
+			Name:     scim.Name{GivenName: "Luna", FamilyName: "Lovegood"},
+			Emails:   []scim.MultiValue{{Value: "luna.lovegood@hogwarts.edu", Primary: true}},
+		}
+
+		// verify only admins can provision users
+		_, err := services.CreateSCIMUser(ctx, db, scheme, luna)
+		require.Error(t, err)
+
+		// verify new users are created, and linked to the auth scheme
+		created, err := services.CreateSCIMUser(adminCtx, db, scheme, luna)
+		require.NoError(t, err)
+		require.Equal(t, luna.UserName, created.UserName)
+		require.Equal(t, "Luna", created.Name.GivenName)
+		require.True(t, *created.Active)
+		var username string
+		require.NoError(t, db.Get(&username, sq.Select("username").From("auth_scheme_data").Where(sq.Eq{"auth_scheme": scheme.Name})))
+		require.Equal(t, luna.UserName, username)
+
+		_, err = services.CreateSCIMUser(adminCtx, db, scheme, luna)
+		require.Error(t, err)
+		require.Equal(t, http.StatusConflict, err.(*errorwrap.HTTPError).HTTPStatus)
+
+		// verify existing users with the same email are linked, rather than duplicated
+		linked, err := services.CreateSCIMUser(adminCtx, db, scheme, scim.User{
+			UserName: "harry@hogwarts.edu",
+			Name:     scim.Name{GivenName: "Harry", FamilyName: "Potter"},
+			Emails:   []scim.MultiValue{{Value: UserHarry.Email}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, UserHarry.Slug, linked.ID)
+		require.Equal(t, []scim.MultiValue{{Value: UserGroupGryffindor.Slug, Display: UserGroupGryffindor.Name}}, linked.Groups)
+
+		// verify listing only includes users of the scheme, and can be filtered
+		list, err := services.ListSCIMUsers(adminCtx, db, scheme, services.ListSCIMResourcesInput{StartIndex: 1, Count: 10})
+		require.NoError(t, err)
+		require.Equal(t, 2, list.TotalResults)
+		list, err = services.ListSCIMUsers(adminCtx, db, scheme, services.ListSCIMResourcesInput{Filter: `userName eq "LUNA.lovegood@hogwarts.edu"`, StartIndex: 1, Count: 10})
+		require.NoError(t, err)
+		require.Equal(t, 1, list.TotalResults)
+		require.Equal(t, created.ID, list.Resources.([]scim.User)[0].ID)
+		_, err = services.ListSCIMUsers(adminCtx, db, scheme, services.ListSCIMResourcesInput{Filter: `userName eq`})
+		require.Error(t, err)
+
+		// verify deactivating a user disables them
+		patched, err := services.PatchSCIMUser(adminCtx, db, scheme, created.ID, scim.PatchRequest{
+			Operations: []scim.PatchOperation{
+				{Op: "Replace", Path: "active", Value: []byte(`"False"`)},
+				{Op: "replace", Path: "name.familyName", Value: []byte(`"Scamander"`)},
+			},
+		})
+		require.NoError(t, err)
+		require.False(t, *patched.Active)
+		user := getUserBySlug(t, db, created.ID)
+		require.True(t, user.Disabled)
+		require.Equal(t, "Scamander", user.LastName)
+
+		// verify replacing a user can reactivate them
+		luna.Active = helpers.Ptr(true)
+		_, err = services.ReplaceSCIMUser(adminCtx, db, scheme, created.ID, luna)
+		require.NoError(t, err)
+		user = getUserBySlug(t, db, created.ID)
+		require.False(t, user.Disabled)
+		require.Equal(t, "Lovegood", user.LastName)
+
+		// verify deleting a user removes them from SCIM
+		require.NoError(t, services.DeleteSCIMUser(adminCtx, db, scheme, created.ID))
+		_, err = services.ReadSCIMUser(adminCtx, db, scheme, created.ID)
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, err.(*errorwrap.HTTPError).HTTPStatus)
+	})
+}
+
+func TestSCIMGroups(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		ctx := contextForUser(UserRon, db)
+		adminCtx := contextForUser(UserDumbledore, db)
+
+		// verify only admins can manage groups
+		_, err := services.ListSCIMGroups(ctx, db, services.ListSCIMResourcesInput{StartIndex: 1, Count: 10})
+		require.Error(t, err)
+
+		// verify groups are created with a slug derived from the name
+		group, err := services.CreateSCIMGroup(adminCtx, db, scim.Group{
+			DisplayName: "Order of the Phoenix",
+			Members:     []scim.MultiValue{{Value: UserHarry.Slug}, {Value: UserRon.Slug}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "order-of-the-phoenix", group.ID)
+		require.Equal(t, []string{UserHarry.Slug, UserRon.Slug}, group.MemberValues())
+
+		_, err = services.CreateSCIMGroup(adminCtx, db, scim.Group{DisplayName: UserGroupGryffindor.Name})
+		require.Error(t, err)
+		require.Equal(t, http.StatusConflict, err.(*errorwrap.HTTPError).HTTPStatus)
+
+		// verify members can be patched
+		group, err = services.PatchSCIMGroup(adminCtx, db, group.ID, scim.PatchRequest{
+			Operations: []scim.PatchOperation{
+				{Op: "add", Path: "members", Value: []byte(`[{"value": "` + UserHermione.Slug + `"}]`)},
+				{Op: "remove", Path: `members[value eq "` + UserRon.Slug + `"]`},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{UserHarry.Slug, UserHermione.Slug}, group.MemberValues())
+
+		// verify replacing a group renames it, and sets its members
+		group, err = services.ReplaceSCIMGroup(adminCtx, db, group.ID, scim.Group{
+			DisplayName: "Dumbledore's Army",
+			Members:     []scim.MultiValue{{Value: UserNeville.Slug}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "order-of-the-phoenix", group.ID)
+		require.Equal(t, "Dumbledore's Army", group.DisplayName)
+		require.Equal(t, []string{UserNeville.Slug}, group.MemberValues())
+
+		// verify listing excludes deleted groups, and can be filtered
+		list, err := services.ListSCIMGroups(adminCtx, db, services.ListSCIMResourcesInput{Filter: `displayName sw "dumbledore"`, StartIndex: 1, Count: 10})
+		require.NoError(t, err)
+		require.Equal(t, 1, list.TotalResults)
+		list, err = services.ListSCIMGroups(adminCtx, db, services.ListSCIMResourcesInput{Filter: `id eq "` + UserGroupOtherHouse.Slug + `"`, StartIndex: 1, Count: 10})
+		require.NoError(t, err)
+		require.Equal(t, 0, list.TotalResults)
+
+		require.NoError(t, services.DeleteSCIMGroup(adminCtx, db, group.ID))
+		_, err = services.ReadSCIMGroup(adminCtx, db, group.ID)
+		require.Error(t, err)
+	})
+}
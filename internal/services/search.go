@@ -0,0 +1,443 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// reindexBatchSize limits how many documents are reindexed in a single transaction
+const reindexBatchSize = 100
+
+type SearchOperationInput struct {
+	Pagination
+	OperationSlug string
+	Query         string
+}
+
+// SearchOperation performs a full-text search over the evidence and findings of an operation,
+// returning the best matches first
+func SearchOperation(ctx context.Context, db *database.Connection, i SearchOperationInput) (*dtos.PaginationWrapper, error) {
+	operation, err := lookupOperation(db, i.OperationSlug)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to search operation", errorwrap.UnauthorizedReadErr(err))
+	}
+	if err := policy.Require(middleware.Policy(ctx), policy.CanReadOperation{OperationID: operation.ID}); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to search operation", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	i.Pagination.constrain()
+	results, total, err := search.Search(db, search.Query{
+		Text:         i.Query,
+		OperationIDs: []int64{operation.ID},
+		Limit:        uint64(i.PageSize),
+		Offset:       uint64(i.PageSize * (i.Page - 1)),
+	})
+	if errors.Is(err, search.ErrEmptyQuery) {
+		return nil, errorwrap.BadInputErr(err, "Search for at least one word of three or more characters")
+	}
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot search operation", errorwrap.DatabaseErr(err))
+	}
+
+	resultsDTO, err := searchResultsToDTOs(db, results)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot search operation", errorwrap.DatabaseErr(err))
+	}
+	i.Pagination.TotalCount = total
+	return i.Pagination.WrapData(resultsDTO), nil
+}
+
+type SearchOperationsInput struct {
+	Pagination
+	Filters helpers.TimelineFilters
+}
+
+// operationMatches records how much evidence and how many findings within an operation match a search
+type operationMatches struct {
+	OperationID   int64
+	OperationSlug string
+	OperationName string
+	NumEvidence   int64
+	NumFindings   int64
+}
+
+// operationMatchesPage describes which of an operation's matches appear on a page of results
+type operationMatchesPage struct {
+	operationMatches
+	EvidenceOffset uint64
+	EvidenceLimit  uint64
+	FindingOffset  uint64
+	FindingLimit   uint64
+}
+
+// SearchOperations runs a timeline query over every operation the contextual user can read. Results
+// are grouped by operation (in name order), with each operation's evidence listed before its findings.
+// Pagination applies to the individual matches, so a single operation's results may span several pages.
+func SearchOperations(ctx context.Context, db *database.Connection, i SearchOperationsInput) (*dtos.PaginationWrapper, error) {
+	var operations []models.Operation
+	err := db.Select(&operations, sq.Select("id", "slug", "name").
+		From("operations").
+		OrderBy("name ASC", "id ASC"))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot search operations", errorwrap.DatabaseErr(err))
+	}
+
+	operationIDs := []int64{}
+	matchesByID := map[int64]*operationMatches{}
+	for _, operation := range operations {
+		if middleware.Policy(ctx).Check(policy.CanReadOperation{OperationID: operation.ID}) {
+			operationIDs = append(operationIDs, operation.ID)
+			matchesByID[operation.ID] = &operationMatches{
+				OperationID:   operation.ID,
+				OperationSlug: operation.Slug,
+				OperationName: operation.Name,
+			}
+		}
+	}
+
+	i.Pagination.constrain()
+	if len(operationIDs) == 0 {
+		return i.Pagination.WrapData([]*dtos.OperationSearchResults{}), nil
+	}
+
+	if err := countOperationMatches(db, operationIDs, i.Filters, matchesByID); err != nil {
+		return nil, errorwrap.WrapError("Cannot search operations", errorwrap.DatabaseErr(err))
+	}
+
+	matches := []operationMatches{}
+	for _, id := range operationIDs {
+		m := matchesByID[id]
+		if m.NumEvidence+m.NumFindings > 0 {
+			matches = append(matches, *m)
+		}
+		i.Pagination.TotalCount += m.NumEvidence + m.NumFindings
+	}
+
+	pages := pageOperationMatches(matches, uint64(i.PageSize*(i.Page-1)), uint64(i.PageSize))
+	resultsDTO := make([]*dtos.OperationSearchResults, len(pages))
+	for idx, page := range pages {
+		resultsDTO[idx], err = readOperationMatchesPage(db, page, i.Filters)
+		if err != nil {
+			return nil, errorwrap.WrapError("Cannot search operations", errorwrap.DatabaseErr(err))
+		}
+	}
+
+	return i.Pagination.WrapData(resultsDTO), nil
+}
+
+// countOperationMatches records the number of matching evidence and findings for each operation
+func countOperationMatches(db *database.Connection, operationIDs []int64, filters helpers.TimelineFilters, matchesByID map[int64]*operationMatches) error {
+	var counts []struct {
+		OperationID int64 `db:"operation_id"`
+		Count       int64 `db:"count"`
+	}
+
+	evidenceQuery := sq.Select("evidence.operation_id", "COUNT(*) AS count").
+		From("evidence").
+		Where(sq.Eq{"evidence.operation_id": operationIDs}).
+		GroupBy("evidence.operation_id")
+	evidenceQuery, err := addEvidenceFilters(evidenceQuery, filters)
+	if err != nil {
+		return err
+	}
+	if err := db.Select(&counts, evidenceQuery); err != nil {
+		return err
+	}
+	for _, count := range counts {
+		matchesByID[count.OperationID].NumEvidence = count.Count
+	}
+
+	counts = nil
+	findingsQuery := sq.Select("findings.operation_id", "COUNT(*) AS count").
+		From("findings").
+		Where(sq.Eq{"findings.operation_id": operationIDs}).
+		GroupBy("findings.operation_id")
+	queryFilters, queryValues, err := buildFindingFilters(filters)
+	if err != nil {
+		return err
+	}
+	if len(queryFilters) > 0 {
+		findingsQuery = findingsQuery.Where(strings.Join(queryFilters, " AND "), queryValues...)
+	}
+	if err := db.Select(&counts, findingsQuery); err != nil {
+		return err
+	}
+	for _, count := range counts {
+		matchesByID[count.OperationID].NumFindings = count.Count
+	}
+	return nil
+}
+
+// pageOperationMatches determines which matches fall within the page starting at offset. Matches are
+// ordered by operation, and within each operation, evidence comes before findings.
+func pageOperationMatches(matches []operationMatches, offset, limit uint64) []operationMatchesPage {
+	pages := []operationMatchesPage{}
+	end := offset + limit
+
+	// overlap returns the part of [start, start+count) that is on the page, relative to start
+	overlap := func(start, count uint64) (uint64, uint64) {
+		from, to := max(start, offset), min(start+count, end)
+		if to <= from {
+			return 0, 0
+		}
+		return from - start, to - from
+	}
+
+	var position uint64
+	for _, m := range matches {
+		evidenceStart := position
+		findingStart := evidenceStart + uint64(m.NumEvidence)
+		position = findingStart + uint64(m.NumFindings)
+
+		if position <= offset {
+			continue
+		}
+		if evidenceStart >= end {
+			break
+		}
+		page := operationMatchesPage{operationMatches: m}
+		page.EvidenceOffset, page.EvidenceLimit = overlap(evidenceStart, uint64(m.NumEvidence))
+		page.FindingOffset, page.FindingLimit = overlap(findingStart, uint64(m.NumFindings))
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// readOperationMatchesPage retrieves the evidence and findings described by the page
+func readOperationMatchesPage(db *database.Connection, page operationMatchesPage, filters helpers.TimelineFilters) (*dtos.OperationSearchResults, error) {
+	results := &dtos.OperationSearchResults{
+		OperationSlug: page.OperationSlug,
+		OperationName: page.OperationName,
+		NumEvidence:   page.NumEvidence,
+		NumFindings:   page.NumFindings,
+		Evidence:      []*dtos.SearchResult{},
+		Findings:      []*dtos.SearchResult{},
+	}
+
+	if page.EvidenceLimit > 0 {
+		var evidence []models.Evidence
+		sb := sq.Select("evidence.uuid", "description", "evidence.content_type", "occurred_at").
+			From("evidence").
+			Limit(page.EvidenceLimit).
+			Offset(page.EvidenceOffset)
+		if filters.SortAsc {
+			sb = sb.OrderBy("COALESCE(adjusted_at, occurred_at) ASC", "evidence.id ASC")
+		} else {
+			sb = sb.OrderBy("COALESCE(adjusted_at, occurred_at) DESC", "evidence.id DESC")
+		}
+		sb, err := buildListEvidenceWhereClause(sb, page.OperationID, filters)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Select(&evidence, sb); err != nil {
+			return nil, err
+		}
+		for _, evi := range evidence {
+			results.Evidence = append(results.Evidence, &dtos.SearchResult{
+				Type:        search.DocumentEvidence,
+				UUID:        evi.UUID,
+				Title:       evi.Description,
+				ContentType: evi.ContentType,
+				OccurredAt:  evi.OccurredAt,
+				Snippets:    []dtos.SearchSnippet{},
+			})
+		}
+	}
+
+	if page.FindingLimit > 0 {
+		var findings []models.Finding
+		whereClause, whereValues, err := buildListFindingsWhereClause(page.OperationID, filters)
+		if err != nil {
+			return nil, err
+		}
+		sb := sq.Select("findings.uuid", "findings.title", "findings.created_at").
+			From("findings").
+			LeftJoin("evidence_finding_map ON findings.id = finding_id").
+			LeftJoin("evidence ON evidence_id = evidence.id").
+			Where(whereClause, whereValues...).
+			GroupBy("findings.id").
+			Limit(page.FindingLimit).
+			Offset(page.FindingOffset)
+		if filters.SortAsc {
+			sb = sb.OrderBy("MAX(evidence.occurred_at) ASC", "MIN(evidence.occurred_at) ASC", "findings.id ASC")
+		} else {
+			sb = sb.OrderBy("MAX(evidence.occurred_at) DESC", "MIN(evidence.occurred_at) DESC", "findings.id DESC")
+		}
+		if err := db.Select(&findings, sb); err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			results.Findings = append(results.Findings, &dtos.SearchResult{
+				Type:       search.DocumentFinding,
+				UUID:       finding.UUID,
+				Title:      finding.Title,
+				OccurredAt: finding.CreatedAt,
+				Snippets:   []dtos.SearchSnippet{},
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// RebuildSearchIndex reindexes every piece of evidence and every finding, including the content of
+// codeblocks. This is only needed to index content created before search was introduced, or to repair
+// the index. For use by admins only.
+func RebuildSearchIndex(ctx context.Context, db *database.Connection, contentStore contentstore.Store) error {
+	if err := isAdmin(ctx); err != nil {
+		return errorwrap.WrapError("Unwilling to rebuild search index", errorwrap.UnauthorizedWriteErr(err))
+	}
+
+	var evidence []models.Evidence
+	err := db.Select(&evidence, sq.Select("id", "operation_id", "content_type", "full_image_key").From("evidence"))
+	if err != nil {
+		return errorwrap.WrapError("Cannot rebuild search index", errorwrap.DatabaseErr(err))
+	}
+	var findingIDs []int64
+	if err := db.Select(&findingIDs, sq.Select("id").From("findings")); err != nil {
+		return errorwrap.WrapError("Cannot rebuild search index", errorwrap.DatabaseErr(err))
+	}
+
+	for start := 0; start < len(evidence); start += reindexBatchSize {
+		batch := evidence[start:min(start+reindexBatchSize, len(evidence))]
+		ids := make([]int64, len(batch))
+		for idx, evi := range batch {
+			ids[idx] = evi.ID
+		}
+		if err := search.IndexEvidence(db, ids); err != nil {
+			return errorwrap.WrapError("Cannot rebuild search index", errorwrap.DatabaseErr(err))
+		}
+	}
+	for _, evi := range evidence {
+		if evi.ContentType != "codeblock" {
+			continue
+		}
+		content, err := readAllContent(contentStore, evi.FullImageKey)
+		if err != nil {
+			// missing content is reported by content reconciliation; it shouldn't stop the rebuild
+			logging.ReqLogger(ctx).Warn("Unable to read codeblock content for search", "evidenceID", evi.ID, "error", err.Error())
+			continue
+		}
+		if err := search.IndexEvidenceContent(db, evi, content); err != nil {
+			return errorwrap.WrapError("Cannot rebuild search index", errorwrap.DatabaseErr(err))
+		}
+	}
+	for start := 0; start < len(findingIDs); start += reindexBatchSize {
+		if err := search.IndexFindings(db, findingIDs[start:min(start+reindexBatchSize, len(findingIDs))]); err != nil {
+			return errorwrap.WrapError("Cannot rebuild search index", errorwrap.DatabaseErr(err))
+		}
+	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionRebuildSearchIndex,
+		TargetType: AuditTargetSearchIndex,
+		After:      map[string]interface{}{"evidence": len(evidence), "findings": len(findingIDs)},
+	})
+	return nil
+}
+
+// searchResultsToDTOs resolves the documents referenced by search results, preserving the result order.
+// Results for documents that no longer exist are dropped.
+func searchResultsToDTOs(db *database.Connection, results []search.Result) ([]*dtos.SearchResult, error) {
+	var evidenceIDs, findingIDs []int64
+	for _, result := range results {
+		switch result.DocumentType {
+		case search.DocumentEvidence:
+			evidenceIDs = append(evidenceIDs, result.DocumentID)
+		case search.DocumentFinding:
+			findingIDs = append(findingIDs, result.DocumentID)
+		}
+	}
+
+	documents := map[string]map[int64]dtos.SearchResult{
+		search.DocumentEvidence: {},
+		search.DocumentFinding:  {},
+	}
+	if len(evidenceIDs) > 0 {
+		var evidence []models.Evidence
+		err := db.Select(&evidence, sq.Select("id", "uuid", "description", "content_type", "occurred_at").
+			From("evidence").
+			Where(sq.Eq{"id": evidenceIDs}))
+		if err != nil {
+			return nil, err
+		}
+		for _, evi := range evidence {
+			documents[search.DocumentEvidence][evi.ID] = dtos.SearchResult{
+				UUID:        evi.UUID,
+				Title:       evi.Description,
+				ContentType: evi.ContentType,
+				OccurredAt:  evi.OccurredAt,
+			}
+		}
+	}
+	if len(findingIDs) > 0 {
+		var findings []models.Finding
+		err := db.Select(&findings, sq.Select("id", "uuid", "title", "created_at").
+			From("findings").
+			Where(sq.Eq{"id": findingIDs}))
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			documents[search.DocumentFinding][finding.ID] = dtos.SearchResult{
+				UUID:       finding.UUID,
+				Title:      finding.Title,
+				OccurredAt: finding.CreatedAt,
+			}
+		}
+	}
+
+	resultsDTO := make([]*dtos.SearchResult, 0, len(results))
+	for _, result := range results {
+		document, ok := documents[result.DocumentType][result.DocumentID]
+		if !ok {
+			continue
+		}
+		document.Type = result.DocumentType
+		document.Score = result.Score
+		document.Snippets = make([]dtos.SearchSnippet, len(result.Snippets))
+		for idx, snippet := range result.Snippets {
+			fragments := make([]dtos.SearchFragment, len(snippet.Fragments))
+			for fragIdx, fragment := range snippet.Fragments {
+				fragments[fragIdx] = dtos.SearchFragment{Text: fragment.Text, Highlight: fragment.Highlight}
+			}
+			document.Snippets[idx] = dtos.SearchSnippet{Field: snippet.Field, Fragments: fragments}
+		}
+		resultsDTO = append(resultsDTO, &document)
+	}
+	return resultsDTO, nil
+}
+
+// logSearchIndexError records failures to update the search index. These are logged rather than
+// returned, since the change that prompted the update has already been saved, and the index can be
+// rebuilt later.
+func logSearchIndexError(ctx context.Context, err error) {
+	if err != nil {
+		logging.ReqLogger(ctx).Warn("Unable to update search index", "error", err.Error())
+	}
+}
+
+func readAllContent(contentStore contentstore.Store, key string) ([]byte, error) {
+	reader, err := contentStore.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageOperationMatches(t *testing.T) {
+	matches := []operationMatches{
+		{OperationID: 1, NumEvidence: 3, NumFindings: 1},
+		{OperationID: 2, NumEvidence: 0, NumFindings: 2},
+		{OperationID: 3, NumEvidence: 4, NumFindings: 0},
+	}
+	page := func(m operationMatches, eviOffset, eviLimit, findingOffset, findingLimit uint64) operationMatchesPage {
+		return operationMatchesPage{
+			operationMatches: m,
+			EvidenceOffset:   eviOffset,
+			EvidenceLimit:    eviLimit,
+			FindingOffset:    findingOffset,
+			FindingLimit:     findingLimit,
+		}
+	}
+
+	// everything fits on one page
+	require.Equal(t, []operationMatchesPage{
+		page(matches[0], 0, 3, 0, 1),
+		page(matches[1], 0, 0, 0, 2),
+		page(matches[2], 0, 4, 0, 0),
+	}, pageOperationMatches(matches, 0, 25))
+
+	// first page ends within the first operation's evidence
+	require.Equal(t, []operationMatchesPage{
+		page(matches[0], 0, 2, 0, 0),
+	}, pageOperationMatches(matches, 0, 2))
+
+	// page spans an operation's evidence and findings, and the start of the next operation
+	require.Equal(t, []operationMatchesPage{
+		page(matches[0], 2, 1, 0, 1),
+		page(matches[1], 0, 0, 0, 1),
+	}, pageOperationMatches(matches, 2, 3))
+
+	// page starts partway through an operation's findings
+	require.Equal(t, []operationMatchesPage{
+		page(matches[1], 0, 0, 1, 1),
+		page(matches[2], 0, 2, 0, 0),
+	}, pageOperationMatches(matches, 5, 3))
+
+	// past the end
+	require.Equal(t, []operationMatchesPage{}, pageOperationMatches(matches, 10, 5))
+	require.Equal(t, []operationMatchesPage{}, pageOperationMatches(nil, 0, 5))
+}
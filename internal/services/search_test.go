@@ -0,0 +1,138 @@
+package services_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/search"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchOperation(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		memStore, _ := contentstore.NewMemStore()
+		ctx := contextForUser(UserRon, db)
+		op := OpChamberOfSecrets
+
+		searchFor := func(query string) []*dtos.SearchResult {
+			page, err := services.SearchOperation(ctx, db, services.SearchOperationInput{
+				Pagination:    services.Pagination{Page: 1, PageSize: 10},
+				OperationSlug: op.Slug,
+				Query:         query,
+			})
+			require.NoError(t, err)
+			return page.Content.([]*dtos.SearchResult)
+		}
+
+		// seeded content is indexed
+		results := searchFor("spider")
+		require.Len(t, results, 1)
+		require.Equal(t, EviSpiderAragog.UUID, results[0].UUID)
+		require.Equal(t, search.DocumentEvidence, results[0].Type)
+
+		// verify create (including codeblock content), with snippets
+		evi, err := services.CreateEvidence(ctx, db, memStore, services.CreateEvidenceInput{
+			OperationSlug: op.Slug,
+			Description:   "Entrance to the chamber",
+			ContentType:   "codeblock",
+			Content:       bytes.NewReader([]byte(`{"contentSubtype":"python","content":"def open_chamber(): speak('Parseltongue')"}`)),
+		})
+		require.NoError(t, err)
+		results = searchFor("parsel")
+		require.Len(t, results, 1)
+		require.Equal(t, evi.UUID, results[0].UUID)
+		require.Equal(t, "codeblock", results[0].ContentType)
+		require.Len(t, results[0].Snippets, 1)
+		require.Equal(t, search.FieldContent, results[0].Snippets[0].Field)
+		_, highlighted := helpers.Find(results[0].Snippets[0].Fragments, func(f dtos.SearchFragment) bool { return f.Highlight })
+		require.NotNil(t, highlighted)
+		require.Equal(t, "Parsel", highlighted.Text)
+
+		// verify update
+		newDescription := "Hidden behind the sinks"
+		err = services.UpdateEvidence(ctx, db, memStore, services.UpdateEvidenceInput{
+			OperationSlug: op.Slug,
+			EvidenceUUID:  evi.UUID,
+			Description:   &newDescription,
+		})
+		require.NoError(t, err)
+		require.Len(t, searchFor("entrance"), 0)
+		require.Len(t, searchFor(`"behind the sinks"`), 1)
+		require.Len(t, searchFor("parseltongue"), 1, "content remains indexed when only the description changes")
+
+		// verify findings
+		finding, err := services.CreateFinding(ctx, db, services.CreateFindingInput{
+			OperationSlug: op.Slug,
+			Category:      VendorFindingCategory.Category,
+			Title:         "Basilisk sighting",
+			Description:   "Seen in the chamber, near the sinks",
+		})
+		require.NoError(t, err)
+		results = searchFor("sinks")
+		require.Len(t, results, 2)
+		require.ElementsMatch(t, []string{evi.UUID, finding.UUID}, []string{results[0].UUID, results[1].UUID})
+
+		require.NoError(t, services.DeleteFinding(ctx, db, services.DeleteFindingInput{OperationSlug: op.Slug, FindingUUID: finding.UUID}))
+		require.Len(t, searchFor("basilisk"), 0)
+
+		// verify delete
+		require.NoError(t, services.DeleteEvidence(ctx, db, memStore, services.DeleteEvidenceInput{OperationSlug: op.Slug, EvidenceUUID: evi.UUID}))
+		require.Len(t, searchFor("parseltongue"), 0)
+
+		// other operations' content is not included
+		require.Len(t, searchFor("mirror"), 0)
+
+		// verify bad input and permissions
+		_, err = services.SearchOperation(ctx, db, services.SearchOperationInput{OperationSlug: op.Slug, Query: "a b"})
+		require.Error(t, err)
+		_, err = services.SearchOperation(contextForUser(UserDraco, db), db, services.SearchOperationInput{OperationSlug: op.Slug, Query: "spider"})
+		require.Error(t, err)
+	})
+}
+
+func TestSearchOperations(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		searchFor := func(user models.User, query string, page int64) *dtos.PaginationWrapper {
+			filters, err := helpers.ParseTimelineQuery(query)
+			require.NoError(t, err)
+			results, err := services.SearchOperations(contextForUser(user, db), db, services.SearchOperationsInput{
+				Pagination: services.Pagination{Page: page, PageSize: 2},
+				Filters:    filters,
+			})
+			require.NoError(t, err)
+			return results
+		}
+
+		// results are grouped by operation, in name order, with evidence before findings
+		page := searchFor(UserRon, "spider OR mirror", 1)
+		require.Equal(t, int64(3), page.TotalCount)
+		require.Equal(t, int64(2), page.TotalPages)
+		groups := page.Content.([]*dtos.OperationSearchResults)
+		require.Len(t, groups, 1)
+		require.Equal(t, OpChamberOfSecrets.Slug, groups[0].OperationSlug)
+		require.Equal(t, int64(1), groups[0].NumEvidence)
+		require.Equal(t, int64(1), groups[0].NumFindings)
+		require.Len(t, groups[0].Evidence, 1)
+		require.Equal(t, EviSpiderAragog.UUID, groups[0].Evidence[0].UUID)
+		require.Len(t, groups[0].Findings, 1)
+		require.Equal(t, FindingBook2SpiderFear.UUID, groups[0].Findings[0].UUID)
+
+		groups = searchFor(UserRon, "spider OR mirror", 2).Content.([]*dtos.OperationSearchResults)
+		require.Len(t, groups, 1)
+		require.Equal(t, OpSorcerersStone.Slug, groups[0].OperationSlug)
+		require.Len(t, groups[0].Evidence, 1)
+		require.Equal(t, EviMirrorOfErised.UUID, groups[0].Evidence[0].UUID)
+		require.Len(t, groups[0].Findings, 0)
+
+		// operations the user cannot read are excluded
+		page = searchFor(UserDraco, "spider OR mirror", 1)
+		require.Equal(t, int64(0), page.TotalCount)
+		require.Len(t, page.Content.([]*dtos.OperationSearchResults), 0)
+	})
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/ashirt-ops/ashirt-server/internal/database"
@@ -75,8 +76,11 @@ func CreateServiceWorker(ctx context.Context, db *database.Connection, i CreateS
 	if err := policy.Require(middleware.Policy(ctx), policy.AdminUsersOnly{}); err != nil {
 		return errorwrap.WrapError("Insufficient access to create a service worker", errorwrap.UnauthorizedWriteErr(err))
 	}
+	if err := enhancementservices.ValidateWorkerConfig(i.Name, i.Config); err != nil {
+		return errorwrap.BadInputErr(err, err.Error())
+	}
 
-	_, err := db.Insert("service_workers", map[string]interface{}{
+	id, err := db.Insert("service_workers", map[string]interface{}{
 		"name":   i.Name,
 		"config": i.Config,
 	})
@@ -84,6 +88,13 @@ func CreateServiceWorker(ctx context.Context, db *database.Connection, i CreateS
 	if err != nil {
 		return errorwrap.WrapError("Could not create a service worker", errorwrap.DatabaseErr(err))
 	}
+	// configs are not recorded, since they may contain credentials
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionCreateServiceWorker,
+		TargetType: AuditTargetServiceWorker,
+		Target:     strconv.FormatInt(id, 10),
+		After:      map[string]interface{}{"name": i.Name},
+	})
 
 	return nil
 }
@@ -92,6 +103,9 @@ func UpdateServiceWorker(ctx context.Context, db *database.Connection, i UpdateS
 	if err := policy.Require(middleware.Policy(ctx), policy.AdminUsersOnly{}); err != nil {
 		return errorwrap.WrapError("Insufficient access to update the service worker", errorwrap.UnauthorizedWriteErr(err))
 	}
+	if err := enhancementservices.ValidateWorkerConfig(i.Name, i.Config); err != nil {
+		return errorwrap.BadInputErr(err, err.Error())
+	}
 
 	err := db.Update(
 		sq.Update("service_workers").
@@ -105,6 +119,12 @@ func UpdateServiceWorker(ctx context.Context, db *database.Connection, i UpdateS
 	if err != nil {
 		return errorwrap.WrapError("Could not update the service worker", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionUpdateServiceWorker,
+		TargetType: AuditTargetServiceWorker,
+		Target:     strconv.FormatInt(i.ID, 10),
+		After:      map[string]interface{}{"name": i.Name},
+	})
 
 	return nil
 }
@@ -113,18 +133,30 @@ func DeleteServiceWorker(ctx context.Context, db *database.Connection, i DeleteS
 	if err := policy.Require(middleware.Policy(ctx), policy.AdminUsersOnly{}); err != nil {
 		return errorwrap.WrapError("Insufficient access to create a service worker", errorwrap.UnauthorizedWriteErr(err))
 	}
+	var worker models.ServiceWorker
+	if err := db.Get(&worker, sq.Select("*").From("service_workers").Where(sq.Eq{"id": i.ID})); err != nil {
+		return errorwrap.WrapError("Could not delete the service worker", errorwrap.DatabaseErr(err))
+	}
 	query := sq.Update("service_workers").Where(sq.Eq{"id": i.ID})
 
+	action := AuditActionDeleteServiceWorker
 	if i.DoDelete {
 		query = query.Set("deleted_at", time.Now())
 	} else {
 		query = query.Set("deleted_at", nil)
+		action = AuditActionRestoreServiceWorker
 	}
 
 	err := db.Update(query)
 	if err != nil {
 		return errorwrap.WrapError("Could not delete the service worker", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     action,
+		TargetType: AuditTargetServiceWorker,
+		Target:     strconv.FormatInt(i.ID, 10),
+		Before:     map[string]interface{}{"name": worker.Name},
+	})
 
 	return nil
 }
@@ -164,6 +196,17 @@ func BatchRunServiceWorker(ctx context.Context, db *database.Connection, i Batch
 	return enhancementservices.SendEvidenceCreatedEvent(db, logging.ReqLogger(ctx), operation.ID, i.EvidenceUUIDs, i.WorkerNames)
 }
 
+// sendServiceWorkerEvent notifies all service workers that subscribe to the event. Payloads are
+// produced by the builder (see the enhancementservices Build*Payloads functions).
+func sendServiceWorkerEvent(ctx context.Context, db *database.Connection, eventType string, builder func(database.ConnectionProxy) ([]interface{}, error)) {
+	enhancementservices.SendServiceWorkerEvent(db, enhancementservices.SendServiceWorkerEventInput{
+		Logger:      logging.ReqLogger(ctx),
+		WorkerNames: enhancementservices.AllWorkers(),
+		Builder:     builder,
+		EventType:   eventType,
+	})
+}
+
 func TestServiceWorker(ctx context.Context, db *database.Connection, serviceWorkerID int64) (*dtos.ServiceWorkerTestOutput, error) {
 	if err := policy.Require(middleware.Policy(ctx), policy.AdminUsersOnly{}); err != nil {
 		return nil, errorwrap.WrapError("Insufficient access to test a service worker", errorwrap.UnauthorizedReadErr(err))
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+)
+
+type RecordServiceWorkerCallbackInput struct {
+	// AccessKey identifies the callback being delivered (i.e. from the callback URL)
+	AccessKey string
+	// SignedAccessKey and Signature are the values provided in the request's Authorization header
+	SignedAccessKey string
+	Signature       []byte
+	// BuildSignature computes the expected signature of the request for the given secret key
+	BuildSignature func(secretKey []byte) []byte
+	Body           []byte
+}
+
+// RecordServiceWorkerCallback records the result of deferred work, as delivered by a service worker
+// to the callback it was issued. The request must be signed with the callback's keys, and the result
+// is processed in the same manner as an immediate response from the worker.
+func RecordServiceWorkerCallback(ctx context.Context, db *database.Connection, i RecordServiceWorkerCallbackInput) error {
+	// Defer checking the lookup error to avoid revealing which callbacks exist
+	callback, lookupErr := enhancementservices.LookupCallback(db, i.AccessKey)
+	expectedSignature := i.BuildSignature(callback.SecretKey)
+	if i.SignedAccessKey != i.AccessKey || !hmac.Equal(i.Signature, expectedSignature) {
+		return errorwrap.WrapError("Unable to verify callback", errorwrap.UnauthorizedWriteErr(errors.New("Bad HMAC")))
+	}
+	if lookupErr != nil {
+		if errors.Is(lookupErr, enhancementservices.ErrCallbackUnavailable) {
+			return errorwrap.WrapError("Callback is expired or already used", errorwrap.UnauthorizedWriteErr(lookupErr))
+		}
+		return errorwrap.WrapError("Unable to retrieve callback", errorwrap.DatabaseErr(lookupErr))
+	}
+
+	var result enhancementservices.ProcessResponse
+	if err := json.Unmarshal(i.Body, &result); err != nil {
+		return errorwrap.BadInputErr(err, "Unable to parse callback result")
+	}
+
+	model, err := enhancementservices.CompleteCallback(db, callback, result)
+	if err != nil {
+		if errors.Is(err, enhancementservices.ErrCallbackUnavailable) {
+			return errorwrap.WrapError("Callback is expired or already used", errorwrap.UnauthorizedWriteErr(err))
+		}
+		return errorwrap.WrapError("Unable to record callback result", errorwrap.DatabaseErr(err))
+	}
+
+	logging.ReqLogger(ctx).Info("Recorded deferred service worker result",
+		"worker", callback.WorkerName,
+		"evidenceID", callback.EvidenceID,
+		"status", *model.Status,
+	)
+	return nil
+}
@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/ashirt-ops/ashirt-server/internal/workers"
+	"github.com/ashirt-ops/ashirt-server/signer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordServiceWorkerCallback(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, seed TestSeedData) {
+		worker := DemoServiceWorker
+		evi := EviDobby
+		op := seed.OperationForEvidence(evi)
+
+		// the worker defers all work, handing the callback back to the test
+		callbacks := make(chan enhancementservices.WorkerCallback, 1)
+		mockHandler := enhancementservices.RequestFn(func(method, url string, body io.Reader, updateRequest helpers.ModifyReqFunc) (*http.Response, error) {
+			var payload enhancementservices.NewEvidencePayload
+			require.NoError(t, json.NewDecoder(body).Decode(&payload))
+			require.NotNil(t, payload.Callback)
+			callbacks <- *payload.Callback
+
+			w := httptest.NewRecorder()
+			w.WriteHeader(http.StatusAccepted)
+			return w.Result(), nil
+		})
+		enhancementservices.SetWebRequestFunctionForWorker(worker.Name, &mockHandler)
+		defer enhancementservices.SetWebRequestFunctionForWorker(worker.Name, nil)
+
+		memStore, _ := contentstore.NewMemStore()
+		runner := workers.MakeServiceWorkerRunner(db, memStore, logging.NewNopLogger())
+		runner.PollInterval = 10 * time.Millisecond
+		runner.Callbacks.BaseURL = "http://ashirt.test/api/"
+		runner.Start()
+		t.Cleanup(runner.Stop)
+
+		ctx := contextForUser(UserRon, db)
+		require.NoError(t, services.RunServiceWorker(ctx, db, services.RunServiceWorkerInput{
+			OperationSlug: op.Slug,
+			EvidenceUUID:  evi.UUID,
+			WorkerName:    worker.Name,
+		}))
+
+		var callback enhancementservices.WorkerCallback
+		select {
+		case callback = <-callbacks:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "worker was not called")
+		}
+		require.Equal(t, "http://ashirt.test/api"+enhancementservices.CallbackPath(callback.AccessKey), callback.URL)
+		secretKey, err := base64.StdEncoding.DecodeString(callback.SecretKey)
+		require.NoError(t, err)
+
+		deliver := func(accessKey string, key []byte, body string) error {
+			req := httptest.NewRequest("POST", callback.URL, strings.NewReader(body))
+			req.Header.Set("Date", time.Now().In(time.FixedZone("GMT", 0)).Format(time.RFC1123))
+			return services.RecordServiceWorkerCallback(ctx, db, services.RecordServiceWorkerCallbackInput{
+				AccessKey:       callback.AccessKey,
+				SignedAccessKey: accessKey,
+				Signature:       signer.BuildRequestHMAC(req, strings.NewReader(body), key),
+				BuildSignature: func(secretKey []byte) []byte {
+					return signer.BuildRequestHMAC(req, strings.NewReader(body), secretKey)
+				},
+				Body: []byte(body),
+			})
+		}
+		getWorkerMetadata := func() models.EvidenceMetadata {
+			_, match := helpers.Find(getEvidenceMetadataByEvidenceID(t, db, evi.ID), func(m models.EvidenceMetadata) bool {
+				return m.Source == worker.Name
+			})
+			require.NotNil(t, match)
+			return *match
+		}
+		require.Eventually(t, func() bool {
+			metadata := getWorkerMetadata()
+			return *metadata.Status == evidencemetadata.StatusQueued
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// verify signatures are required
+		require.Error(t, deliver(callback.AccessKey, []byte("not-the-secret"), `{"action": "processed", "content": "forged"}`))
+		require.Error(t, deliver("AS-someOtherKey", secretKey, `{"action": "processed", "content": "forged"}`))
+		require.Error(t, deliver(callback.AccessKey, secretKey, `not json`))
+
+		// verify deferring again leaves the callback usable
+		require.NoError(t, deliver(callback.AccessKey, secretKey, `{"action": "deferred"}`))
+		require.Equal(t, evidencemetadata.StatusQueued, *getWorkerMetadata().Status)
+
+		// verify result
+		require.NoError(t, deliver(callback.AccessKey, secretKey, `{"action": "processed", "content": "the result"}`))
+		metadata := getWorkerMetadata()
+		require.Equal(t, evidencemetadata.StatusCompleted, *metadata.Status)
+		require.Equal(t, "the result", metadata.Body)
+
+		// verify callbacks are single use
+		require.Error(t, deliver(callback.AccessKey, secretKey, `{"action": "error", "content": "late"}`))
+		require.Equal(t, evidencemetadata.StatusCompleted, *getWorkerMetadata().Status)
+	})
+}
@@ -6,13 +6,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
 	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/internal/workers"
 	"github.com/stretchr/testify/require"
 )
 
@@ -61,6 +65,12 @@ func TestCreateServiceWorker(t *testing.T) {
 		require.NoError(t, tryCreate(UserDumbledore, input))
 		svc := getServiceWorkerByName(t, db, input.Name)
 		require.JSONEq(t, svc.Config, input.Config)
+
+		// verify exec workers are rejected unless the server allows the executable
+		require.Error(t, tryCreate(UserDumbledore, services.CreateServiceWorkerInput{
+			Name:   "Shell",
+			Config: `{"type": "exec", "version": 1, "path": "/bin/sh", "args": ["-c", "cat /etc/passwd >&2; exit 1"]}`,
+		}))
 	})
 }
 
@@ -197,7 +207,7 @@ func TestRunServiceWorker(t *testing.T) {
 			ctx := contextForUser(u, db)
 			return services.RunServiceWorker(ctx, db, input)
 		}
-		allWorkersCalled := makeNotifierChannel()
+		allWorkersCalled := makeNotifierChannel(t, db)
 
 		evi := EviDobby
 		op := seed.OperationForEvidence(evi)
@@ -244,7 +254,7 @@ func TestBatchRunServiceWorker(t *testing.T) {
 		setOfEvidence := seed.EvidenceForOperation(op.ID)
 		expectedCalls := len(setOfEvidence) * numWorkers
 		calledCh := make(chan bool, expectedCalls*2) // making extra room, in case there are extras (which would be a failure)
-		allWorkersCalled := makeNotifierChannel()
+		allWorkersCalled := makeNotifierChannel(t, db)
 
 		tryBatchRun := func(u models.User, input services.BatchRunServiceWorkerInput) error {
 			ctx := contextForUser(u, db)
@@ -278,6 +288,76 @@ func TestBatchRunServiceWorker(t *testing.T) {
 	})
 }
 
+func TestServiceWorkerEventSubscriptions(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, seed TestSeedData) {
+		// pre-test: create a worker that is only interested in some finding events
+		adminCtx := contextForUser(UserDumbledore, db)
+		require.NoError(t, services.CreateServiceWorker(adminCtx, db, services.CreateServiceWorkerInput{
+			Name:   "TicketBridge",
+			Config: `{"type": "web", "version": 1, "url": "http://test:1234", "events": ["finding_created", "finding_ready_to_report"]}`,
+		}))
+
+		// record the events each worker receives
+		received := make(chan string, 10)
+		for _, name := range []string{DemoServiceWorker.Name, "TicketBridge"} {
+			workerName := name
+			mockHandler := enhancementservices.RequestFn(func(method, url string, body io.Reader, updateRequest helpers.ModifyReqFunc) (*http.Response, error) {
+				var payload struct {
+					Type string `json:"type"`
+				}
+				require.NoError(t, json.NewDecoder(body).Decode(&payload))
+				received <- workerName + ":" + payload.Type
+
+				w := httptest.NewRecorder()
+				w.WriteHeader(http.StatusOK)
+				return w.Result(), nil
+			})
+			enhancementservices.SetWebRequestFunctionForWorker(workerName, &mockHandler)
+			defer enhancementservices.SetWebRequestFunctionForWorker(workerName, nil)
+		}
+		allWorkersCalled := makeNotifierChannel(t, db)
+		requireEvents := func(expected ...string) {
+			<-allWorkersCalled // wait for the work to complete
+			actual := helpers.ChanToSlice(&received)
+			require.ElementsMatch(t, expected, actual)
+		}
+
+		ctx := contextForUser(UserRon, db)
+		op := OpChamberOfSecrets
+
+		// verify only subscribed workers receive the event
+		finding, err := services.CreateFinding(ctx, db, services.CreateFindingInput{
+			OperationSlug: op.Slug,
+			Category:      VendorFindingCategory.Category,
+			Title:         "Basilisk in the plumbing",
+		})
+		require.NoError(t, err)
+		requireEvents("TicketBridge:finding_created")
+
+		// verify unsubscribed events (finding_updated) are not delivered, and that ready to report is
+		// sent when the finding becomes ready
+		updateInput := services.UpdateFindingInput{
+			OperationSlug: op.Slug,
+			FindingUUID:   finding.UUID,
+			Category:      VendorFindingCategory.Category,
+			Title:         "Basilisk in the plumbing",
+		}
+		require.NoError(t, services.UpdateFinding(ctx, db, updateInput))
+		updateInput.ReadyToReport = true
+		require.NoError(t, services.UpdateFinding(ctx, db, updateInput))
+		requireEvents("TicketBridge:finding_ready_to_report")
+
+		// verify ready to report is not sent again, and that evidence_created remains the default
+		// subscription
+		require.NoError(t, services.UpdateFinding(ctx, db, updateInput))
+		require.NoError(t, services.RunServiceWorker(ctx, db, services.RunServiceWorkerInput{
+			OperationSlug: op.Slug,
+			EvidenceUUID:  seed.EvidenceForOperation(op.ID)[0].UUID,
+		}))
+		requireEvents(DemoServiceWorker.Name + ":evidence_created")
+	})
+}
+
 func buildRequestMock(writeResponse func(*httptest.ResponseRecorder)) enhancementservices.RequestFn {
 	return func(method, url string, body io.Reader, updateRequest helpers.ModifyReqFunc) (*http.Response, error) {
 		w := httptest.NewRecorder()
@@ -309,8 +389,21 @@ func requireNumberOfWorkers(t *testing.T, db *database.Connection, minWorkers in
 	return knownWorkersList
 }
 
-func makeNotifierChannel() chan bool {
+// makeNotifierChannel starts a service worker runner, returning a channel that receives a value each
+// time the runner has finished all queued jobs. Jobs are not retried, so each job runs exactly once.
+func makeNotifierChannel(t *testing.T, db *database.Connection) chan bool {
 	allWorkersCalled := make(chan bool, 1)
-	enhancementservices.SetNotifyWorkersRunForTest(allWorkersCalled)
+	memStore, _ := contentstore.NewMemStore()
+	runner := workers.MakeServiceWorkerRunner(db, memStore, logging.NewNopLogger())
+	runner.PollInterval = 10 * time.Millisecond
+	runner.MaxAttempts = 1
+	runner.OnIdle = func() {
+		select {
+		case allWorkersCalled <- true:
+		default:
+		}
+	}
+	runner.Start()
+	t.Cleanup(runner.Stop)
 	return allWorkersCalled
 }
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ashirt-ops/ashirt-server/internal/config"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type SetOperationStorageQuotaInput struct {
+	OperationSlug string
+	Quota         *int64
+}
+
+// ReadOperationStorage retrieves the storage used by an operation's evidence, along with the
+// operation's storage quota, if any
+func ReadOperationStorage(ctx context.Context, db *database.Connection, operationSlug string) (*dtos.OperationStorage, error) {
+	operation, err := lookupOperation(db, operationSlug)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to read operation storage", errorwrap.UnauthorizedReadErr(err))
+	}
+	if err := policy.Require(middleware.Policy(ctx), policy.CanReadOperation{OperationID: operation.ID}); err != nil {
+		return nil, errorwrap.WrapError("Unwilling to read operation storage", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	quota, err := readOperationStorageQuota(db, operation.ID)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot read operation storage quota", errorwrap.DatabaseErr(err))
+	}
+	used, err := readOperationStorageUsed(db, operation.ID, 0)
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot read operation storage", errorwrap.DatabaseErr(err))
+	}
+	return &dtos.OperationStorage{Used: used, Quota: quota}, nil
+}
+
+// SetOperationStorageQuota limits the total size of the evidence content in an operation. A nil quota
+// removes the limit. Evidence already in the operation is kept, even if it exceeds the new quota.
+//
+// Admin only
+func SetOperationStorageQuota(ctx context.Context, db *database.Connection, i SetOperationStorageQuotaInput) error {
+	if err := isAdmin(ctx); err != nil {
+		return errorwrap.WrapError("Unwilling to set operation storage quota", errorwrap.UnauthorizedWriteErr(err))
+	}
+	if i.Quota != nil && *i.Quota < 0 {
+		return errorwrap.BadInputErr(errors.New("Negative storage quota"), "Storage quota cannot be negative")
+	}
+
+	operation, err := lookupOperation(db, i.OperationSlug)
+	if err != nil {
+		return errorwrap.WrapError("Unable to set operation storage quota", errorwrap.NotFoundErr(err))
+	}
+	quota, err := readOperationStorageQuota(db, operation.ID)
+	if err != nil {
+		return errorwrap.WrapError("Cannot read operation storage quota", errorwrap.DatabaseErr(err))
+	}
+
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Update(sq.Update("operations").
+			Set("storage_quota", i.Quota).
+			Where(sq.Eq{"id": operation.ID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionSetOperationStorageQuota,
+			TargetType:  AuditTargetOperation,
+			Target:      i.OperationSlug,
+			OperationID: &operation.ID,
+			Before:      map[string]interface{}{"storageQuota": quota},
+			After:       map[string]interface{}{"storageQuota": i.Quota},
+		})
+	})
+	if err != nil {
+		return errorwrap.WrapError("Cannot set operation storage quota", errorwrap.DatabaseErr(err))
+	}
+	return nil
+}
+
+// measureEvidenceContent determines the size, in bytes, of uploaded evidence content, and returns a
+// reader for the content. Uploaded files can seek, and so are measured without being read. Other
+// content is buffered in memory, reading no more than one byte past maxSize (when set), which is
+// enough to know that the content is too large.
+func measureEvidenceContent(content io.Reader, maxSize int64) (io.Reader, int64, error) {
+	if seeker, ok := content.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		return content, end - start, nil
+	}
+
+	if maxSize > 0 {
+		content = io.LimitReader(content, maxSize+1)
+	}
+	buffered := &bytes.Buffer{}
+	size, err := io.Copy(buffered, content)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buffered, size, nil
+}
+
+// ensureEvidenceSizeAllowed checks that evidence content of the given type and size is within the
+// configured size limit for the content type, and fits within the operation's storage quota.
+// replacedEvidenceID names evidence whose content is being replaced, and so is not counted against
+// the quota. Use 0 for new evidence.
+//
+// Note: this check is made before the content is uploaded, to avoid uploading content that cannot be
+// kept. The quota must be checked again (via ensureStorageAvailable) within the transaction that
+// records the content, since concurrent uploads may have used the space in the meantime.
+func ensureEvidenceSizeAllowed(db database.ConnectionProxy, operationID int64, contentType string, size int64, replacedEvidenceID int64) error {
+	if maxSize := config.MaxEvidenceSize(contentType); maxSize > 0 && size > maxSize {
+		return errorwrap.TooLargeErr(
+			fmt.Errorf("%v evidence of %d bytes exceeds the limit of %d bytes", contentType, size, maxSize),
+			fmt.Sprintf("Evidence of this type can be at most %d bytes", maxSize),
+		)
+	}
+	return ensureStorageAvailable(db, operationID, size, replacedEvidenceID)
+}
+
+// ensureStorageAvailable checks that size more bytes of evidence content fit within the operation's
+// storage quota, not counting the content of replacedEvidenceID (if non-zero).
+//
+// The operation's row is locked while reading its quota. When called as the first statement of the
+// transaction that records the evidence, this serializes concurrent uploads to the operation, so
+// each sees the storage used by the others, and together they cannot exceed the quota.
+func ensureStorageAvailable(db database.ConnectionProxy, operationID int64, size int64, replacedEvidenceID int64) error {
+	var quota *int64
+	err := db.Get(&quota, sq.Select("storage_quota").
+		From("operations").
+		Where(sq.Eq{"id": operationID}).
+		Suffix("FOR UPDATE"))
+	if err != nil {
+		return errorwrap.DatabaseErr(err)
+	}
+	if quota == nil {
+		return nil
+	}
+	used, err := readOperationStorageUsed(db, operationID, replacedEvidenceID)
+	if err != nil {
+		return errorwrap.DatabaseErr(err)
+	}
+	if used+size > *quota {
+		return errorwrap.TooLargeErr(
+			fmt.Errorf("Operation %d would use %d bytes, exceeding its quota of %d bytes", operationID, used+size, *quota),
+			"This operation does not have enough storage remaining for this evidence",
+		)
+	}
+	return nil
+}
+
+func readOperationStorageQuota(db *database.Connection, operationID int64) (*int64, error) {
+	var quota *int64
+	err := db.Get(&quota, sq.Select("storage_quota").From("operations").Where(sq.Eq{"id": operationID}))
+	return quota, err
+}
+
+// readOperationStorageUsed sums the content size of the operation's evidence, excluding the evidence
+// with the given ID (if non-zero)
+func readOperationStorageUsed(db database.ConnectionProxy, operationID int64, excludeEvidenceID int64) (int64, error) {
+	where := sq.And{sq.Eq{"operation_id": operationID}}
+	if excludeEvidenceID != 0 {
+		where = append(where, sq.NotEq{"id": excludeEvidenceID})
+	}
+	var used int64
+	err := db.Get(&used, sq.Select("COALESCE(SUM(content_size), 0)").From("evidence").Where(where))
+	return used, err
+}
+
+// MeasureUnsizedEvidenceOutput summarizes a batch of MeasureUnsizedEvidence
+type MeasureUnsizedEvidenceOutput struct {
+	// LastID is the ID of the last evidence examined, or 0 if no unmeasured evidence remains
+	LastID   int64
+	Measured int
+	// Failed lists the IDs of evidence whose content could not be read. These remain unmeasured.
+	Failed []int64
+}
+
+// MeasureUnsizedEvidence records the content size of up to limit evidence whose size is unknown (i.e.
+// evidence created before content sizes were tracked), starting after the evidence with ID afterID.
+// Sizes are measured by reading the content from the content store. Until all evidence has been
+// measured, storage used (and so quotas) undercounts the content of unmeasured evidence.
+func MeasureUnsizedEvidence(db *database.Connection, contentStore contentstore.Store, afterID int64, limit uint64) (*MeasureUnsizedEvidenceOutput, error) {
+	var evidence []models.Evidence
+	err := db.Select(&evidence, sq.Select("*").
+		From("evidence").
+		Where(sq.Eq{"content_size": nil}).
+		Where(sq.Gt{"id": afterID}).
+		OrderBy("id").
+		Limit(limit))
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to find unmeasured evidence", errorwrap.DatabaseErr(err))
+	}
+
+	out := &MeasureUnsizedEvidenceOutput{}
+	for _, evi := range evidence {
+		out.LastID = evi.ID
+		size, err := measureStoredContent(contentStore, evi.FullImageKey)
+		if err != nil {
+			out.Failed = append(out.Failed, evi.ID)
+			continue
+		}
+		err = db.Update(sq.Update("evidence").
+			Set("content_size", size).
+			Where(sq.Eq{"id": evi.ID, "content_size": nil}))
+		if err != nil {
+			return nil, errorwrap.WrapError("Unable to record evidence content size", errorwrap.DatabaseErr(err))
+		}
+		out.Measured++
+	}
+	return out, nil
+}
+
+// measureStoredContent determines the size of the content stored under the given key. Evidence
+// without content (i.e. no key) has a size of 0.
+func measureStoredContent(contentStore contentstore.Store, key string) (int64, error) {
+	if key == "" {
+		return 0, nil
+	}
+	content, err := contentStore.Read(key)
+	if err != nil {
+		return 0, err
+	}
+	if closer, ok := content.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return io.Copy(io.Discard, content)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasureEvidenceContent(t *testing.T) {
+	readAll := func(r io.Reader) string {
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	// seekable content is measured from its current position, and rewound
+	seekable := strings.NewReader("skip:content")
+	seekable.Seek(5, io.SeekStart)
+	content, size, err := measureEvidenceContent(seekable, 3)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), size)
+	require.Equal(t, "content", readAll(content))
+
+	// other content is buffered
+	content, size, err = measureEvidenceContent(io.MultiReader(bytes.NewBufferString("some "), bytes.NewBufferString("content")), 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), size)
+	require.Equal(t, "some content", readAll(content))
+
+	// but only just past the max size
+	_, size, err = measureEvidenceContent(io.MultiReader(strings.NewReader("some content")), 4)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), size)
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationStorageQuota(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		memStore, _ := contentstore.NewMemStore()
+		op := OpChamberOfSecrets
+		ctx := contextForUser(UserRon, db)
+		adminCtx := contextForUser(UserDumbledore, db)
+		createCodeblock := func(content string) error {
+			_, err := services.CreateEvidence(ctx, db, memStore, services.CreateEvidenceInput{
+				OperationSlug: op.Slug,
+				Description:   "some codeblock",
+				ContentType:   "codeblock",
+				Content:       bytes.NewReader([]byte(content)),
+			})
+			return err
+		}
+
+		// verify only admins can set quotas
+		err := services.SetOperationStorageQuota(ctx, db, services.SetOperationStorageQuotaInput{OperationSlug: op.Slug, Quota: helpers.Ptr(int64(10))})
+		require.Error(t, err)
+		err = services.SetOperationStorageQuota(adminCtx, db, services.SetOperationStorageQuotaInput{OperationSlug: op.Slug, Quota: helpers.Ptr(int64(-1))})
+		require.Error(t, err)
+		err = services.SetOperationStorageQuota(adminCtx, db, services.SetOperationStorageQuotaInput{OperationSlug: op.Slug, Quota: helpers.Ptr(int64(10))})
+		require.NoError(t, err)
+
+		// verify uploads within the quota are counted
+		require.NoError(t, createCodeblock("123456"))
+		storage, err := services.ReadOperationStorage(ctx, db, op.Slug)
+		require.NoError(t, err)
+		require.Equal(t, int64(6), storage.Used)
+		require.Equal(t, helpers.Ptr(int64(10)), storage.Quota)
+
+		// verify uploads beyond the quota are rejected
+		err = createCodeblock("12345")
+		require.Error(t, err)
+		require.Equal(t, http.StatusRequestEntityTooLarge, err.(*errorwrap.HTTPError).HTTPStatus)
+		require.NoError(t, createCodeblock("1234"))
+
+		// verify removing the quota allows further uploads
+		err = services.SetOperationStorageQuota(adminCtx, db, services.SetOperationStorageQuotaInput{OperationSlug: op.Slug, Quota: nil})
+		require.NoError(t, err)
+		require.NoError(t, createCodeblock("12345"))
+		storage, err = services.ReadOperationStorage(ctx, db, op.Slug)
+		require.NoError(t, err)
+		require.Equal(t, int64(15), storage.Used)
+		require.Nil(t, storage.Quota)
+
+		// verify users outside of the operation cannot read its storage
+		_, err = services.ReadOperationStorage(contextForUser(UserDraco, db), db, op.Slug)
+		require.Error(t, err)
+	})
+}
+
+func TestOperationStorageQuotaConcurrentUploads(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		memStore, _ := contentstore.NewMemStore()
+		op := OpChamberOfSecrets
+		ctx := contextForUser(UserRon, db)
+		err := services.SetOperationStorageQuota(contextForUser(UserDumbledore, db), db, services.SetOperationStorageQuotaInput{OperationSlug: op.Slug, Quota: helpers.Ptr(int64(10))})
+		require.NoError(t, err)
+
+		// verify that racing uploads cannot together exceed the quota
+		var wg sync.WaitGroup
+		errs := make([]error, 5)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = services.CreateEvidence(ctx, db, memStore, services.CreateEvidenceInput{
+					OperationSlug: op.Slug,
+					Description:   "some codeblock",
+					ContentType:   "codeblock",
+					Content:       bytes.NewReader([]byte("1234")),
+				})
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+				continue
+			}
+			require.Equal(t, http.StatusRequestEntityTooLarge, err.(*errorwrap.HTTPError).HTTPStatus)
+		}
+		require.Equal(t, 2, created)
+		storage, err := services.ReadOperationStorage(ctx, db, op.Slug)
+		require.NoError(t, err)
+		require.Equal(t, int64(8), storage.Used)
+	})
+}
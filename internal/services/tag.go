@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"time"
 
@@ -127,6 +128,12 @@ func CreateDefaultTag(ctx context.Context, db *database.Connection, i CreateDefa
 	if err != nil {
 		return nil, errorwrap.WrapError("Cannot add new tag", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionCreateDefaultTag,
+		TargetType: AuditTargetDefaultTag,
+		Target:     strconv.FormatInt(tagID, 10),
+		After:      map[string]interface{}{"name": i.Name, "colorName": i.ColorName, "description": i.Description},
+	})
 	return &dtos.DefaultTag{
 		ID:          tagID,
 		Name:        i.Name,
@@ -153,13 +160,24 @@ func MergeDefaultTags(ctx context.Context, db *database.Connection, i []CreateDe
 		tagsToInsert = append(tagsToInsert, t)
 	}
 
-	err := db.BatchInsert("default_tags", len(tagsToInsert), func(idx int) map[string]interface{} {
-		return map[string]interface{}{
-			"name":        tagsToInsert[idx].Name,
-			"color_name":  tagsToInsert[idx].ColorName,
-			"description": tagsToInsert[idx].Description,
-		}
-	}, "ON DUPLICATE KEY UPDATE color_name=VALUES(color_name)")
+	err := db.WithTx(ctx, func(tx *database.Transactable) {
+		var existingTags []models.DefaultTag
+		tx.Select(&existingTags, sq.Select("*").From("default_tags").Where(sq.Eq{"name": currentTagNames}))
+		tx.BatchInsert("default_tags", len(tagsToInsert), func(idx int) map[string]interface{} {
+			return map[string]interface{}{
+				"name":        tagsToInsert[idx].Name,
+				"color_name":  tagsToInsert[idx].ColorName,
+				"description": tagsToInsert[idx].Description,
+			}
+		}, "ON DUPLICATE KEY UPDATE color_name=VALUES(color_name)")
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionMergeDefaultTags,
+			TargetType: AuditTargetDefaultTag,
+			Target:     strings.Join(currentTagNames, ","),
+			Before:     existingTags,
+			After:      tagsToInsert,
+		})
+	})
 
 	if err != nil {
 		return errorwrap.WrapError("Cannot update default tag", errorwrap.DatabaseErr(err))
@@ -179,8 +197,17 @@ func DeleteTag(ctx context.Context, db *database.Connection, i DeleteTagInput) e
 	}
 
 	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		var tags []models.Tag
+		tx.Select(&tags, sq.Select("*").From("tags").Where(sq.Eq{"id": i.ID}))
 		tx.Delete(sq.Delete("tag_evidence_map").Where(sq.Eq{"tag_id": i.ID}))
 		tx.Delete(sq.Delete("tags").Where(sq.Eq{"id": i.ID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionDeleteTag,
+			TargetType:  AuditTargetTag,
+			Target:      strconv.FormatInt(i.ID, 10),
+			OperationID: &operation.ID,
+			Before:      tags,
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete tag", errorwrap.DatabaseErr(err))
@@ -195,7 +222,17 @@ func DeleteDefaultTag(ctx context.Context, db *database.Connection, i DeleteDefa
 		return errorwrap.WrapError("Unwilling to delete default tag", errorwrap.UnauthorizedWriteErr(err))
 	}
 
-	err := db.Delete(sq.Delete("default_tags").Where(sq.Eq{"id": i.ID}))
+	err := db.WithTx(ctx, func(tx *database.Transactable) {
+		var tags []models.DefaultTag
+		tx.Select(&tags, sq.Select("*").From("default_tags").Where(sq.Eq{"id": i.ID}))
+		tx.Delete(sq.Delete("default_tags").Where(sq.Eq{"id": i.ID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionDeleteDefaultTag,
+			TargetType: AuditTargetDefaultTag,
+			Target:     strconv.FormatInt(i.ID, 10),
+			Before:     tags,
+		})
+	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete default tag", errorwrap.DatabaseErr(err))
 	}
@@ -420,6 +457,11 @@ func UpdateDefaultTag(ctx context.Context, db *database.Connection, i UpdateDefa
 		return errorwrap.WrapError("Unwilling to update default tag", errorwrap.UnauthorizedWriteErr(err))
 	}
 
+	var tag models.DefaultTag
+	if err := db.Get(&tag, sq.Select("*").From("default_tags").Where(sq.Eq{"id": i.ID})); err != nil {
+		return errorwrap.WrapError("Cannot update default tag", errorwrap.DatabaseErr(err))
+	}
+
 	err := db.Update(sq.Update("default_tags").
 		SetMap(map[string]interface{}{
 			"name":        i.Name,
@@ -431,5 +473,12 @@ func UpdateDefaultTag(ctx context.Context, db *database.Connection, i UpdateDefa
 	if err != nil {
 		return errorwrap.WrapError("Cannot update default tag", errorwrap.DatabaseErr(err))
 	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionUpdateDefaultTag,
+		TargetType: AuditTargetDefaultTag,
+		Target:     strconv.FormatInt(i.ID, 10),
+		Before:     map[string]interface{}{"name": tag.Name, "colorName": tag.ColorName, "description": tag.Description},
+		After:      map[string]interface{}{"name": i.Name, "colorName": i.ColorName, "description": i.Description},
+	})
 	return nil
 }
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers/filter"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// matchAll is the condition used for filters that deliberately match everything (e.g. linked:all)
+var matchAll = sq.Expr("1 = 1")
+
+// compileTimelineExpression converts a boolean timeline query into a condition, using termWhere to build
+// the condition for each individual filter. termWhere returns nil for filters that do not apply to a
+// particular timeline (e.g. filtering findings by evidence type). Such filters are dropped when they
+// only narrow the results (i.e. are joined by AND), mirroring how they are ignored in plain queries.
+// Within an OR or NOT, dropping the filter would change the meaning of the query, so a
+// QuerySyntaxError is returned instead. Returns nil if no part of the expression applies.
+func compileTimelineExpression(expr *helpers.QueryExpr, termWhere func(term *helpers.QueryExpr) sq.Sqlizer) (sq.Sqlizer, error) {
+	condition, err := compileTimelineNode(expr, termWhere, true)
+	if err != nil {
+		return nil, errorwrap.BadInputErr(err, err.Error())
+	}
+	return condition, nil
+}
+
+// compileTimelineNode compiles the expression. droppable indicates whether inapplicable filters can be
+// safely ignored.
+func compileTimelineNode(expr *helpers.QueryExpr, termWhere func(term *helpers.QueryExpr) sq.Sqlizer, droppable bool) (sq.Sqlizer, error) {
+	switch expr.Operator {
+	case helpers.QueryAnd:
+		conditions, err := compileTimelineChildren(expr.Children, termWhere, droppable)
+		if err != nil || len(conditions) <= 1 {
+			return singleCondition(conditions), err
+		}
+		return sq.And(conditions), nil
+
+	case helpers.QueryOr:
+		conditions, err := compileTimelineChildren(expr.Children, termWhere, false)
+		if err != nil || len(conditions) <= 1 {
+			return singleCondition(conditions), err
+		}
+		return sq.Or(conditions), nil
+
+	case helpers.QueryNot:
+		condition, err := compileTimelineNode(expr.Children[0], termWhere, false)
+		return notWhere(condition), err
+
+	default:
+		condition := termWhere(expr)
+		if condition == nil && !droppable {
+			return nil, &helpers.QuerySyntaxError{
+				Position: expr.Position,
+				Message:  fmt.Sprintf("'%v' filters do not apply here, so cannot be combined with OR or NOT", expr.Key),
+			}
+		}
+		if expr.Value.Modifier == filter.Not {
+			return notWhere(condition), nil
+		}
+		return condition, nil
+	}
+}
+
+// compileTimelineChildren compiles each of the expressions, omitting those that do not apply
+func compileTimelineChildren(children []*helpers.QueryExpr, termWhere func(term *helpers.QueryExpr) sq.Sqlizer, droppable bool) ([]sq.Sqlizer, error) {
+	conditions := make([]sq.Sqlizer, 0, len(children))
+	for _, child := range children {
+		condition, err := compileTimelineNode(child, termWhere, droppable)
+		if err != nil {
+			return nil, err
+		}
+		if condition != nil {
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions, nil
+}
+
+// singleCondition returns the only condition, or nil if there are none
+func singleCondition(conditions []sq.Sqlizer) sq.Sqlizer {
+	if len(conditions) == 0 {
+		return nil
+	}
+	return conditions[0]
+}
+
+// notWhere negates the condition. Conditions that do not apply (i.e. nil) remain inapplicable.
+func notWhere(condition sq.Sqlizer) sq.Sqlizer {
+	if condition == nil {
+		return nil
+	}
+	return sq.Expr("NOT (?)", condition)
+}
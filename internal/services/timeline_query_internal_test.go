@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/stretchr/testify/require"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func TestCompileTimelineExpressionInapplicableTerms(t *testing.T) {
+	compileWith := func(query string, termWhere func(*helpers.QueryExpr) sq.Sqlizer) (string, error) {
+		filters, err := helpers.ParseTimelineQuery(query)
+		require.NoError(t, err)
+		require.NotNil(t, filters.Expression, query)
+		condition, err := compileTimelineExpression(filters.Expression, termWhere)
+		if err != nil || condition == nil {
+			return "", err
+		}
+		sql, _, err := condition.ToSql()
+		require.NoError(t, err)
+		return sql, nil
+	}
+	compile := func(query string) string {
+		sql, err := compileWith(query, findingTermWhere)
+		require.NoError(t, err, query)
+		return sql
+	}
+	tagOnly := compile("tag:web OR tag:web")
+
+	// type only applies to evidence, so is dropped from findings queries where it would only narrow
+	// the results, as in plain queries
+	require.Equal(t, tagOnly, compile("(tag:web OR tag:web) AND type:image"))
+	require.Equal(t, tagOnly, compile("(tag:web OR tag:web) AND type:!image"))
+	require.Equal(t, "", compile("type:image AND (type:terminal-recording)"))
+
+	// within an OR or NOT, dropping it would change the meaning of the query, so it is rejected
+	for query, position := range map[string]int{
+		"tag:web OR type:image":                   12,
+		"(tag:web OR tag:web) AND NOT type:image": 30,
+		"tag:web OR type:!image":                  12,
+		"!(tag:web AND type:image)":               15,
+	} {
+		_, err := compileWith(query, findingTermWhere)
+		require.ErrorContains(t, err, fmt.Sprintf("(at position %v)", position), query)
+	}
+
+	// with-evidence only applies to findings
+	evidenceTagOnly, err := compileWith("tag:web OR tag:web", evidenceTermWhere)
+	require.NoError(t, err)
+	sql, err := compileWith("(tag:web OR tag:web) AND with-evidence:!abc", evidenceTermWhere)
+	require.NoError(t, err)
+	require.Equal(t, evidenceTagOnly, sql)
+	_, err = compileWith("tag:web OR with-evidence:abc", evidenceTermWhere)
+	require.Error(t, err)
+}
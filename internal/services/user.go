@@ -91,7 +91,16 @@ func CreateHeadlessUser(ctx context.Context, db *database.Connection, i CreateUs
 		return nil, errorwrap.WrapError("Unable to create new headless user", errorwrap.UnauthorizedWriteErr(err))
 	}
 	i.Headless = true
-	return CreateUser(db, i)
+	out, err := CreateUser(db, i)
+	if err == nil {
+		recordAuditEvent(ctx, db, auditEvent{
+			Action:     AuditActionCreateHeadlessUser,
+			TargetType: AuditTargetUser,
+			Target:     out.RealSlug,
+			After:      map[string]interface{}{"firstName": i.FirstName, "lastName": i.LastName, "email": i.Email},
+		})
+	}
+	return out, err
 }
 
 // CreateUser generates an entry in the users table in the database. No more is done here, but it is expected
@@ -194,6 +203,11 @@ func DeleteUser(ctx context.Context, db *database.Connection, slug string) error
 		tx.Delete(sq.Delete("auth_scheme_data").Where(sq.Eq{"user_id": userID}))
 		tx.Delete(sq.Delete("user_operation_permissions").Where(sq.Eq{"user_id": userID}))
 		tx.Update(sq.Update("users").Set("deleted_at", time.Now()).Where(sq.Eq{"slug": slug}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionDeleteUser,
+			TargetType: AuditTargetUser,
+			Target:     slug,
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete user", errorwrap.DatabaseErr(err))
@@ -489,7 +503,15 @@ func DeleteSessionsForUserSlug(ctx context.Context, db *database.Connection, use
 		return errorwrap.WrapError("Unable to delete user session", errorwrap.DatabaseErr(err))
 	}
 
-	return deleteSessionsForUserID(db, userID)
+	if err := deleteSessionsForUserID(db, userID); err != nil {
+		return err
+	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:     AuditActionDeleteUserSessions,
+		TargetType: AuditTargetUser,
+		Target:     userSlug,
+	})
+	return nil
 }
 
 // SetUserFlags updates flags for the indicated user, namely: admin and disabled.
@@ -520,7 +542,16 @@ func SetUserFlags(ctx context.Context, db *database.Connection, i SetUserFlagsIn
 	}
 
 	if len(valuesToUpdate) > 0 {
-		err := db.Update(sq.Update("users").SetMap(valuesToUpdate).Where(sq.Eq{"slug": i.Slug}))
+		err := db.WithTx(ctx, func(tx *database.Transactable) {
+			tx.Update(sq.Update("users").SetMap(valuesToUpdate).Where(sq.Eq{"slug": i.Slug}))
+			recordAuditEvent(ctx, tx, auditEvent{
+				Action:     AuditActionSetUserFlags,
+				TargetType: AuditTargetUser,
+				Target:     i.Slug,
+				Before:     map[string]interface{}{"disabled": targetUser.Disabled, "admin": targetUser.Admin},
+				After:      valuesToUpdate,
+			})
+		})
 		if err != nil {
 			return errorwrap.DatabaseErr(err)
 		}
@@ -13,6 +13,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/policy"
 	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
@@ -109,6 +110,12 @@ func CreateUserGroup(ctx context.Context, db *database.Connection, i CreateUserG
 			"name": i.Name,
 		})
 		AddUsersToGroup(tx, i.UserSlugs, id)
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionCreateUserGroup,
+			TargetType: AuditTargetUserGroup,
+			Target:     cleanSlug,
+			After:      map[string]interface{}{"name": i.Name, "users": i.UserSlugs},
+		})
 	})
 
 	if err != nil {
@@ -154,6 +161,17 @@ func ModifyUserGroup(ctx context.Context, db *database.Connection, i ModifyUserG
 			tx.Exec(sq.Expr(sqlStatement, interfaceSlice...))
 		}
 		AddUsersToGroup(tx, i.UsersToAdd, userGroup.ID)
+		change := map[string]interface{}{"usersAdded": i.UsersToAdd, "usersRemoved": i.UsersToRemove}
+		if i.Name != "" {
+			change["name"] = i.Name
+		}
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionUpdateUserGroup,
+			TargetType: AuditTargetUserGroup,
+			Target:     userGroup.Slug,
+			Before:     map[string]interface{}{"name": userGroup.Name},
+			After:      change,
+		})
 	})
 	if err != nil {
 		return nil, errorwrap.WrapError("Error creating user group", errorwrap.BadInputErr(err, "A user group with this name already exists; please choose another name"))
@@ -177,6 +195,11 @@ func DeleteUserGroup(ctx context.Context, db *database.Connection, slug string)
 	err = db.WithTx(context.Background(), func(tx *database.Transactable) {
 		tx.Delete(sq.Delete("user_group_operation_permissions").Where(sq.Eq{"group_id": userGroup.ID}))
 		tx.Update(sq.Update("user_groups").Set("deleted_at", time.Now()).Where(sq.Eq{"slug": slug}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionDeleteUserGroup,
+			TargetType: AuditTargetUserGroup,
+			Target:     slug,
+		})
 	})
 	if err != nil {
 		return errorwrap.WrapError("Cannot delete user group", errorwrap.DatabaseErr(err))
@@ -390,3 +413,109 @@ func ListUserGroups(ctx context.Context, db *database.Connection, i ListUserGrou
 	}
 	return userGroupsDTO, nil
 }
+
+// SyncUserGroupsInput describes the user group memberships an identity provider asserts for a user
+type SyncUserGroupsInput struct {
+	UserID int64
+	// ManagedGroupSlugs are the user groups whose membership is controlled by the identity provider.
+	// Membership in any other user group is left untouched.
+	ManagedGroupSlugs []string
+	// GroupSlugs are the managed user groups the user should belong to
+	GroupSlugs []string
+	// Admin is the admin status the user should have. If nil, admin status is left untouched.
+	Admin *bool
+}
+
+// SyncUserGroups adds the user to, and removes the user from, the managed user groups so that
+// their membership matches the identity provider. Managed groups that do not exist (or have been
+// deleted) are ignored. If the user's admin status changes, their existing sessions are removed.
+//
+// Note: this is performed on behalf of the identity provider while the user logs in, and so does
+// not require an admin
+func SyncUserGroups(ctx context.Context, db *database.Connection, i SyncUserGroupsInput) error {
+	user, err := db.RetrieveUserByID(i.UserID)
+	if err != nil {
+		return errorwrap.WrapError("Unable to sync user groups", errorwrap.DatabaseErr(err))
+	}
+
+	var groups []models.UserGroup
+	var memberOf []int64
+	if len(i.ManagedGroupSlugs) > 0 {
+		err = db.Select(&groups, sq.Select("*").
+			From("user_groups").
+			Where(sq.Eq{"slug": i.ManagedGroupSlugs, "deleted_at": nil}).
+			OrderBy("slug"))
+		if err != nil {
+			return errorwrap.WrapError("Unable to sync user groups", errorwrap.DatabaseErr(err))
+		}
+	}
+	if len(groups) > 0 {
+		err = db.Select(&memberOf, sq.Select("group_id").
+			From("group_user_map").
+			Where(sq.Eq{
+				"user_id":  user.ID,
+				"group_id": helpers.Map(groups, func(g models.UserGroup) int64 { return g.ID }),
+			}))
+		if err != nil {
+			return errorwrap.WrapError("Unable to sync user groups", errorwrap.DatabaseErr(err))
+		}
+	}
+
+	groupsBefore, groupsAfter := []string{}, []string{}
+	var groupsToAdd, groupsToRemove []int64
+	for _, group := range groups {
+		isMember := helpers.ContainsMatch(memberOf, group.ID)
+		shouldBeMember := helpers.ContainsMatch(i.GroupSlugs, group.Slug)
+		if isMember {
+			groupsBefore = append(groupsBefore, group.Slug)
+		}
+		if shouldBeMember {
+			groupsAfter = append(groupsAfter, group.Slug)
+		}
+		if shouldBeMember && !isMember {
+			groupsToAdd = append(groupsToAdd, group.ID)
+		}
+		if isMember && !shouldBeMember {
+			groupsToRemove = append(groupsToRemove, group.ID)
+		}
+	}
+	// headless users can never be admins, so their admin status is never synced
+	updateAdmin := i.Admin != nil && *i.Admin != user.Admin && !user.Headless
+
+	if len(groupsToAdd) == 0 && len(groupsToRemove) == 0 && !updateAdmin {
+		return nil
+	}
+
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		before := map[string]interface{}{"groups": groupsBefore}
+		after := map[string]interface{}{"groups": groupsAfter}
+		for _, groupID := range groupsToAdd {
+			tx.Insert("group_user_map", map[string]interface{}{
+				"user_id":  user.ID,
+				"group_id": groupID,
+			})
+		}
+		if len(groupsToRemove) > 0 {
+			tx.Delete(sq.Delete("group_user_map").Where(sq.Eq{"user_id": user.ID, "group_id": groupsToRemove}))
+		}
+		if updateAdmin {
+			tx.Update(sq.Update("users").Set("admin", *i.Admin).Where(sq.Eq{"id": user.ID}))
+			before["admin"] = user.Admin
+			after["admin"] = *i.Admin
+		}
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:     AuditActionSyncUserGroups,
+			TargetType: AuditTargetUser,
+			Target:     user.Slug,
+			Before:     before,
+			After:      after,
+		})
+	})
+	if err != nil {
+		return errorwrap.WrapError("Unable to sync user groups", errorwrap.DatabaseErr(err))
+	}
+	if updateAdmin {
+		return deleteSessionsForUserID(db, user.ID)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/ashirt-ops/ashirt-server/internal/database"
 	"github.com/ashirt-ops/ashirt-server/internal/dtos"
 	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
 	"github.com/ashirt-ops/ashirt-server/internal/models"
 	"github.com/ashirt-ops/ashirt-server/internal/services"
 	"github.com/stretchr/testify/assert"
@@ -170,6 +171,57 @@ func TestDeleteUserGroup(t *testing.T) {
 	})
 }
 
+func TestSyncUserGroups(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		ctx := context.Background()
+		managedGroups := []string{UserGroupGryffindor.Slug, UserGroupRavenclaw.Slug, UserGroupOtherHouse.Slug, "durmstrang"}
+
+		// verify membership of managed groups is synced, ignoring deleted and unknown groups
+		err := services.SyncUserGroups(ctx, db, services.SyncUserGroupsInput{
+			UserID:            UserHarry.ID,
+			ManagedGroupSlugs: managedGroups,
+			GroupSlugs:        []string{UserGroupRavenclaw.Slug, UserGroupOtherHouse.Slug, "durmstrang"},
+			Admin:             helpers.Ptr(true),
+		})
+		require.NoError(t, err)
+
+		gryffindorUserIDs, err := getUserIDsFromGroup(db, UserGroupGryffindor.Slug)
+		require.NoError(t, err)
+		require.NotContains(t, gryffindorUserIDs, UserHarry.ID)
+		require.Contains(t, gryffindorUserIDs, UserRon.ID)
+		ravenclawUserIDs, err := getUserIDsFromGroup(db, UserGroupRavenclaw.Slug)
+		require.NoError(t, err)
+		require.Contains(t, ravenclawUserIDs, UserHarry.ID)
+		otherHouseUserIDs, err := getUserIDsFromGroup(db, UserGroupOtherHouse.Slug)
+		require.NoError(t, err)
+		require.NotContains(t, otherHouseUserIDs, UserHarry.ID)
+		require.True(t, getUserBySlug(t, db, UserHarry.Slug).Admin)
+
+		// verify admin status is left alone when not managed
+		err = services.SyncUserGroups(ctx, db, services.SyncUserGroupsInput{
+			UserID:            UserHarry.ID,
+			ManagedGroupSlugs: managedGroups,
+			GroupSlugs:        []string{UserGroupGryffindor.Slug},
+		})
+		require.NoError(t, err)
+		gryffindorUserIDs, err = getUserIDsFromGroup(db, UserGroupGryffindor.Slug)
+		require.NoError(t, err)
+		require.Contains(t, gryffindorUserIDs, UserHarry.ID)
+		ravenclawUserIDs, err = getUserIDsFromGroup(db, UserGroupRavenclaw.Slug)
+		require.NoError(t, err)
+		require.NotContains(t, ravenclawUserIDs, UserHarry.ID)
+		require.True(t, getUserBySlug(t, db, UserHarry.Slug).Admin)
+
+		// verify admin status can be revoked
+		err = services.SyncUserGroups(ctx, db, services.SyncUserGroupsInput{
+			UserID: UserHarry.ID,
+			Admin:  helpers.Ptr(false),
+		})
+		require.NoError(t, err)
+		require.False(t, getUserBySlug(t, db, UserHarry.Slug).Admin)
+	})
+}
+
 func TestListUserGroupsForAdmin(t *testing.T) {
 	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
 		nonAdminUser := UserRon
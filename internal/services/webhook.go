@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/url"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/dtos"
+	"github.com/ashirt-ops/ashirt-server/internal/errorwrap"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/policy"
+	"github.com/ashirt-ops/ashirt-server/internal/server/middleware"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// maxListedDeliveries limits the delivery history returned for a webhook
+const maxListedDeliveries = 100
+
+// WebhookScope identifies the webhooks being managed. An empty OperationSlug refers to webhooks that
+// are not scoped to any operation (i.e. that receive events from every operation), which only admins
+// may manage.
+type WebhookScope struct {
+	OperationSlug string
+}
+
+type CreateWebhookInput struct {
+	WebhookScope
+	Name   string
+	URL    string
+	Events []string
+}
+
+type UpdateWebhookInput struct {
+	WebhookScope
+	ID      int64
+	Name    string
+	URL     string
+	Events  []string
+	Enabled bool
+}
+
+type DeleteWebhookInput struct {
+	WebhookScope
+	ID int64
+}
+
+type ListWebhookDeliveriesInput struct {
+	WebhookScope
+	ID int64
+}
+
+func CreateWebhook(ctx context.Context, db *database.Connection, i CreateWebhookInput) (*dtos.CreatedWebhook, error) {
+	operationID, err := requireWebhookScope(ctx, db, i.WebhookScope)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to create webhook", errorwrap.UnauthorizedWriteErr(err))
+	}
+
+	events, err := validateWebhookInput(ctx, i.Name, i.URL, i.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	accessKey := make([]byte, accessKeyLength)
+	if _, err := rand.Read(accessKey); err != nil {
+		return nil, errorwrap.WrapError("Unable to generate webhook access key", err)
+	}
+	prefixedAccessKey := "WH-" + base64.URLEncoding.EncodeToString(accessKey)
+
+	secretKey := make([]byte, secretKeyLength)
+	if _, err := rand.Read(secretKey); err != nil {
+		return nil, errorwrap.WrapError("Unable to generate webhook secret key", err)
+	}
+
+	var webhookID int64
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		webhookID, _ = tx.Insert("webhooks", map[string]interface{}{
+			"operation_id": operationID,
+			"name":         i.Name,
+			"url":          i.URL,
+			"events":       events,
+			"access_key":   prefixedAccessKey,
+			"secret_key":   secretKey,
+		})
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionCreateWebhook,
+			TargetType:  AuditTargetWebhook,
+			Target:      prefixedAccessKey,
+			OperationID: operationID,
+			After:       map[string]interface{}{"name": i.Name, "url": i.URL, "events": i.Events},
+		})
+	})
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to create webhook", errorwrap.DatabaseErr(err))
+	}
+
+	var webhook models.Webhook
+	if err := db.Get(&webhook, sq.Select("*").From("webhooks").Where(sq.Eq{"id": webhookID})); err != nil {
+		return nil, errorwrap.WrapError("Unable to read created webhook", errorwrap.DatabaseErr(err))
+	}
+
+	return &dtos.CreatedWebhook{
+		Webhook:   webhookToDTO(webhook, i.OperationSlug),
+		SecretKey: secretKey,
+	}, nil
+}
+
+func ListWebhooks(ctx context.Context, db *database.Connection, i WebhookScope) ([]*dtos.Webhook, error) {
+	operationID, err := requireWebhookScope(ctx, db, i)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to list webhooks", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	var webhooks []models.Webhook
+	err = db.Select(&webhooks, sq.Select("*").
+		From("webhooks").
+		Where(sq.Eq{"operation_id": operationID}).
+		OrderBy("id"))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list webhooks", errorwrap.DatabaseErr(err))
+	}
+
+	webhooksDTO := make([]*dtos.Webhook, len(webhooks))
+	for idx, webhook := range webhooks {
+		dto := webhookToDTO(webhook, i.OperationSlug)
+		webhooksDTO[idx] = &dto
+	}
+	return webhooksDTO, nil
+}
+
+func UpdateWebhook(ctx context.Context, db *database.Connection, i UpdateWebhookInput) error {
+	webhook, err := lookupWebhook(ctx, db, i.WebhookScope, i.ID)
+	if err != nil {
+		return errorwrap.WrapError("Unable to update webhook", errorwrap.UnauthorizedWriteErr(err))
+	}
+
+	events, err := validateWebhookInput(ctx, i.Name, i.URL, i.Events)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"name":   i.Name,
+		"url":    i.URL,
+		"events": events,
+	}
+	if i.Enabled && webhook.DisabledAt != nil {
+		// re-enabling a webhook gives it a fresh start
+		updates["disabled_at"] = nil
+		updates["consecutive_failures"] = 0
+	} else if !i.Enabled && webhook.DisabledAt == nil {
+		updates["disabled_at"] = sq.Expr("CURRENT_TIMESTAMP()")
+	}
+
+	err = db.Update(sq.Update("webhooks").SetMap(updates).Where(sq.Eq{"id": webhook.ID}))
+	if err != nil {
+		return errorwrap.WrapError("Cannot update webhook", errorwrap.DatabaseErr(err))
+	}
+	recordAuditEvent(ctx, db, auditEvent{
+		Action:      AuditActionUpdateWebhook,
+		TargetType:  AuditTargetWebhook,
+		Target:      webhook.AccessKey,
+		OperationID: webhook.OperationID,
+		Before:      map[string]interface{}{"name": webhook.Name, "url": webhook.URL, "events": webhooks.ParseEvents(webhook.Events), "enabled": webhook.DisabledAt == nil},
+		After:       map[string]interface{}{"name": i.Name, "url": i.URL, "events": webhooks.ParseEvents(events), "enabled": i.Enabled},
+	})
+	return nil
+}
+
+func DeleteWebhook(ctx context.Context, db *database.Connection, i DeleteWebhookInput) error {
+	webhook, err := lookupWebhook(ctx, db, i.WebhookScope, i.ID)
+	if err != nil {
+		return errorwrap.WrapError("Unable to delete webhook", errorwrap.UnauthorizedWriteErr(err))
+	}
+
+	err = db.WithTx(ctx, func(tx *database.Transactable) {
+		tx.Delete(sq.Delete("webhook_deliveries").Where(sq.Eq{"webhook_id": webhook.ID}))
+		tx.Delete(sq.Delete("webhooks").Where(sq.Eq{"id": webhook.ID}))
+		recordAuditEvent(ctx, tx, auditEvent{
+			Action:      AuditActionDeleteWebhook,
+			TargetType:  AuditTargetWebhook,
+			Target:      webhook.AccessKey,
+			OperationID: webhook.OperationID,
+			Before:      map[string]interface{}{"name": webhook.Name, "url": webhook.URL, "events": webhooks.ParseEvents(webhook.Events)},
+		})
+	})
+	if err != nil {
+		return errorwrap.WrapError("Cannot delete webhook", errorwrap.DatabaseErr(err))
+	}
+	return nil
+}
+
+// ListWebhookDeliveries retrieves the most recent deliveries for the indicated webhook, newest first
+func ListWebhookDeliveries(ctx context.Context, db *database.Connection, i ListWebhookDeliveriesInput) ([]*dtos.WebhookDelivery, error) {
+	webhook, err := lookupWebhook(ctx, db, i.WebhookScope, i.ID)
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to list webhook deliveries", errorwrap.UnauthorizedReadErr(err))
+	}
+
+	var deliveries []models.WebhookDelivery
+	err = db.Select(&deliveries, sq.Select("*").
+		From("webhook_deliveries").
+		Where(sq.Eq{"webhook_id": webhook.ID}).
+		OrderBy("id DESC").
+		Limit(maxListedDeliveries))
+	if err != nil {
+		return nil, errorwrap.WrapError("Cannot list webhook deliveries", errorwrap.DatabaseErr(err))
+	}
+
+	deliveriesDTO := make([]*dtos.WebhookDelivery, len(deliveries))
+	for idx, delivery := range deliveries {
+		deliveriesDTO[idx] = &dtos.WebhookDelivery{
+			UUID:        delivery.UUID,
+			EventType:   delivery.EventType,
+			Status:      delivery.Status,
+			Attempts:    delivery.Attempts,
+			StatusCode:  delivery.StatusCode,
+			LastError:   delivery.LastError,
+			CreatedAt:   delivery.CreatedAt,
+			DeliveredAt: delivery.DeliveredAt,
+		}
+	}
+	return deliveriesDTO, nil
+}
+
+// sendWebhookEvent queues a delivery of the event to each webhook that subscribes to it. Failures are
+// logged, but do not affect the caller.
+func sendWebhookEvent(ctx context.Context, db *database.Connection, operationID int64, operationSlug string, eventType string, data interface{}) {
+	webhooks.Enqueue(db, logging.ReqLogger(ctx), operationID, operationSlug, eventType, data)
+}
+
+// requireWebhookScope verifies that the contextual user may manage webhooks in the given scope, and
+// returns the scope's operation ID (nil for webhooks that are not scoped to an operation)
+func requireWebhookScope(ctx context.Context, db *database.Connection, scope WebhookScope) (*int64, error) {
+	if scope.OperationSlug == "" {
+		if err := policy.Require(middleware.Policy(ctx), policy.AdminUsersOnly{}); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	operation, err := lookupOperation(db, scope.OperationSlug)
+	if err != nil {
+		return nil, err
+	}
+	if err := policyRequireWithAdminBypass(ctx, policy.CanModifyWebhooksOfOperation{OperationID: operation.ID}); err != nil {
+		return nil, err
+	}
+	return &operation.ID, nil
+}
+
+// lookupWebhook verifies that the contextual user may manage webhooks in the given scope, and retrieves
+// the indicated webhook, provided it belongs to that scope
+func lookupWebhook(ctx context.Context, db *database.Connection, scope WebhookScope, webhookID int64) (*models.Webhook, error) {
+	operationID, err := requireWebhookScope(ctx, db, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook models.Webhook
+	err = db.Get(&webhook, sq.Select("*").
+		From("webhooks").
+		Where(sq.Eq{"id": webhookID, "operation_id": operationID}))
+	if err != nil {
+		return nil, errorwrap.WrapError("Unable to lookup webhook", err)
+	}
+	return &webhook, nil
+}
+
+// validateWebhookInput checks the user-provided webhook fields, and returns the events in their stored form
+func validateWebhookInput(ctx context.Context, name, webhookURL string, events []string) (string, error) {
+	if name == "" {
+		return "", errorwrap.MissingValueErr("Name")
+	}
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", errorwrap.BadInputErr(errors.New("invalid webhook url"), "The webhook URL must be an http or https URL")
+	}
+	if webhooks.IsDisallowedHost(ctx, parsed.Hostname()) {
+		return "", errorwrap.BadInputErr(webhooks.ErrDisallowedAddress, "The webhook URL may not refer to a loopback, private or link-local address")
+	}
+	storedEvents, err := webhooks.JoinEvents(events)
+	if err != nil {
+		return "", errorwrap.BadInputErr(err, "Unable to subscribe to events: "+err.Error())
+	}
+	return storedEvents, nil
+}
+
+func webhookToDTO(webhook models.Webhook, operationSlug string) dtos.Webhook {
+	var slug *string
+	if webhook.OperationID != nil {
+		slug = &operationSlug
+	}
+	return dtos.Webhook{
+		ID:                  webhook.ID,
+		OperationSlug:       slug,
+		Name:                webhook.Name,
+		URL:                 webhook.URL,
+		Events:              webhooks.ParseEvents(webhook.Events),
+		AccessKey:           webhook.AccessKey,
+		Enabled:             webhook.DisabledAt == nil,
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+		DisabledAt:          webhook.DisabledAt,
+		CreatedAt:           webhook.CreatedAt,
+	}
+}
@@ -0,0 +1,151 @@
+package services_test
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		opScope := services.WebhookScope{OperationSlug: OpSorcerersStone.Slug}
+		input := services.CreateWebhookInput{
+			WebhookScope: opScope,
+			Name:         "Owl Post",
+			URL:          "https://owls.example.com/hook",
+			Events:       []string{webhooks.EventEvidenceCreated, webhooks.EventFindingReadyToReport},
+		}
+
+		// verify permissions
+		_, err := services.CreateWebhook(contextForUser(UserRon, db), db, input) // writer, not admin
+		require.Error(t, err)
+		_, err = services.CreateWebhook(contextForUser(UserHarry, db), db, services.CreateWebhookInput{
+			Name:   input.Name,
+			URL:    input.URL,
+			Events: input.Events,
+		}) // global webhooks are for admins only
+		require.Error(t, err)
+
+		// verify validation
+		badURL := input
+		badURL.URL = "ftp://owls.example.com"
+		_, err = services.CreateWebhook(contextForUser(UserHarry, db), db, badURL)
+		require.Error(t, err)
+		for _, internalURL := range []string{"http://169.254.169.254/latest/meta-data", "http://127.0.0.1:8080/hook", "https://10.0.0.5/hook", "http://[::1]/hook"} {
+			internalHost := input
+			internalHost.URL = internalURL
+			_, err = services.CreateWebhook(contextForUser(UserHarry, db), db, internalHost)
+			require.Error(t, err, "webhooks to internal addresses should be rejected: %v", internalURL)
+		}
+		badEvents := input
+		badEvents.Events = []string{"evidence_vanished"}
+		_, err = services.CreateWebhook(contextForUser(UserHarry, db), db, badEvents)
+		require.Error(t, err)
+
+		// verify create
+		ctx := contextForUser(UserHarry, db)
+		created, err := services.CreateWebhook(ctx, db, input)
+		require.NoError(t, err)
+		require.Equal(t, input.Name, created.Name)
+		require.Equal(t, input.Events, created.Events)
+		require.Equal(t, OpSorcerersStone.Slug, *created.OperationSlug)
+		require.True(t, created.Enabled)
+		require.NotEmpty(t, created.SecretKey)
+
+		list, err := services.ListWebhooks(ctx, db, opScope)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		require.Equal(t, created.AccessKey, list[0].AccessKey)
+
+		_, err = services.ListWebhooks(ctx, db, services.WebhookScope{OperationSlug: OpChamberOfSecrets.Slug})
+		require.Error(t, err) // Harry cannot manage webhooks for operations he does not administer
+
+		// verify update: disabling, then re-enabling, resets failures
+		update := services.UpdateWebhookInput{
+			WebhookScope: opScope,
+			ID:           created.ID,
+			Name:         "Owl Post (disabled)",
+			URL:          input.URL,
+			Events:       []string{webhooks.EventFindingCreated},
+			Enabled:      false,
+		}
+		require.NoError(t, services.UpdateWebhook(ctx, db, update))
+		webhook := getWebhookByID(t, db, created.ID)
+		require.Equal(t, update.Name, webhook.Name)
+		require.Equal(t, webhooks.EventFindingCreated, webhook.Events)
+		require.NotNil(t, webhook.DisabledAt)
+
+		require.NoError(t, db.Update(sq.Update("webhooks").Set("consecutive_failures", 7).Where(sq.Eq{"id": created.ID})))
+		update.Enabled = true
+		require.NoError(t, services.UpdateWebhook(ctx, db, update))
+		webhook = getWebhookByID(t, db, created.ID)
+		require.Nil(t, webhook.DisabledAt)
+		require.Equal(t, int64(0), webhook.ConsecutiveFailures)
+
+		// webhooks can only be managed from their own scope
+		require.Error(t, services.UpdateWebhook(contextForUser(UserDumbledore, db), db, services.UpdateWebhookInput{
+			ID:      created.ID,
+			Name:    "Hijacked",
+			URL:     input.URL,
+			Events:  input.Events,
+			Enabled: true,
+		}))
+
+		// verify deliveries are listed, then removed along with the webhook
+		webhooks.Enqueue(db, logging.NewNopLogger(), OpSorcerersStone.ID, OpSorcerersStone.Slug,
+			webhooks.EventFindingCreated, webhooks.FindingData{UUID: "some-finding"})
+		deliveries, err := services.ListWebhookDeliveries(ctx, db, services.ListWebhookDeliveriesInput{WebhookScope: opScope, ID: created.ID})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, webhooks.EventFindingCreated, deliveries[0].EventType)
+		require.Equal(t, webhooks.DeliveryQueued, deliveries[0].Status)
+
+		require.Error(t, services.DeleteWebhook(contextForUser(UserRon, db), db, services.DeleteWebhookInput{WebhookScope: opScope, ID: created.ID}))
+		require.NoError(t, services.DeleteWebhook(ctx, db, services.DeleteWebhookInput{WebhookScope: opScope, ID: created.ID}))
+
+		var remaining int
+		require.NoError(t, db.Get(&remaining, sq.Select("COUNT(*)").From("webhook_deliveries").Where(sq.Eq{"webhook_id": created.ID})))
+		require.Equal(t, 0, remaining)
+		list, err = services.ListWebhooks(ctx, db, opScope)
+		require.NoError(t, err)
+		require.Len(t, list, 0)
+	})
+}
+
+func TestGlobalWebhooks(t *testing.T) {
+	RunResettableDBTest(t, func(db *database.Connection, _ TestSeedData) {
+		ctx := contextForUser(UserDumbledore, db)
+		created, err := services.CreateWebhook(ctx, db, services.CreateWebhookInput{
+			Name:   "SIEM",
+			URL:    "http://siem.example.com/ingest",
+			Events: webhooks.Events,
+		})
+		require.NoError(t, err)
+		require.Nil(t, created.OperationSlug)
+
+		_, err = services.ListWebhooks(contextForUser(UserHarry, db), db, services.WebhookScope{})
+		require.Error(t, err)
+
+		list, err := services.ListWebhooks(ctx, db, services.WebhookScope{})
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+
+		// global webhooks are not visible from any operation
+		list, err = services.ListWebhooks(ctx, db, services.WebhookScope{OperationSlug: OpSorcerersStone.Slug})
+		require.NoError(t, err)
+		require.Len(t, list, 0)
+	})
+}
+
+func getWebhookByID(t *testing.T, db *database.Connection, id int64) models.Webhook {
+	var webhook models.Webhook
+	err := db.Get(&webhook, sq.Select("*").From("webhooks").Where(sq.Eq{"id": id}))
+	require.NoError(t, err)
+	return webhook
+}
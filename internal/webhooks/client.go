@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrDisallowedAddress is returned when a delivery would connect to an internal address
+var ErrDisallowedAddress = errors.New("webhooks may not be delivered to loopback, private or link-local addresses")
+
+// NewClient constructs the http client deliveries are sent with. Connections to loopback, private and
+// link-local addresses are refused when dialing, after the receiver's host has been resolved, so that
+// webhooks cannot be used to reach internal services (including via DNS rebinding or redirects).
+// Proxies are not used, since the proxy's address, rather than the receiver's, would be checked.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: checkDialAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// IsDisallowedHost resolves the host, and reports whether any of its addresses may not receive
+// deliveries. Hosts that cannot be resolved are allowed here; they are checked again when dialing.
+func IsDisallowedHost(ctx context.Context, host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return IsDisallowedIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if IsDisallowedIP(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDisallowedIP reports whether the address is a loopback, private, link-local or unspecified
+// address, which may not receive deliveries
+func IsDisallowedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// checkDialAddress is a net.Dialer Control function that refuses connections to disallowed addresses
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("unable to parse dial address %q: %w", address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsDisallowedIP(ip) {
+		return fmt.Errorf("unable to connect to %v: %w", host, ErrDisallowedAddress)
+	}
+	return nil
+}
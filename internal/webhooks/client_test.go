@@ -0,0 +1,33 @@
+package webhooks_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal secrets"))
+	}))
+	defer server.Close()
+
+	_, err := webhooks.NewClient(time.Second).Get(server.URL)
+	require.ErrorIs(t, err, webhooks.ErrDisallowedAddress)
+}
+
+func TestIsDisallowedIP(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::ffff:127.0.0.1"} {
+		require.True(t, webhooks.IsDisallowedIP(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		require.False(t, webhooks.IsDisallowedIP(net.ParseIP(addr)), addr)
+	}
+	require.True(t, webhooks.IsDisallowedHost(context.Background(), "169.254.169.254"))
+}
@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/signer"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// maxDrainLength limits how much of a receiver's response is read (and discarded), so that the
+// connection may be reused
+const maxDrainLength = 4096
+
+// Claimed pairs a claimed delivery with the webhook it should be sent to
+type Claimed struct {
+	Delivery models.WebhookDelivery
+	Webhook  models.Webhook
+}
+
+// ClaimDeliveries marks up to limit ready deliveries, for enabled webhooks, as running, and returns
+// them. Deliveries that have been running since before staleBefore (e.g. because the server restarted
+// while they were being sent) are claimed again.
+func ClaimDeliveries(db *database.Connection, limit int, staleBefore time.Time) ([]Claimed, error) {
+	var claimed []Claimed
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		var deliveries []models.WebhookDelivery
+		tx.Select(&deliveries, sq.Select("d.*").
+			From("webhook_deliveries d").
+			Join("webhooks w ON w.id = d.webhook_id").
+			Where(sq.Eq{"w.disabled_at": nil}).
+			Where(sq.Or{
+				sq.And{sq.Eq{"d.status": DeliveryQueued}, sq.LtOrEq{"d.run_after": time.Now()}},
+				sq.And{sq.Eq{"d.status": DeliveryRunning}, sq.Lt{"d.started_at": staleBefore}},
+			}).
+			OrderBy("d.run_after", "d.id").
+			Limit(uint64(limit)).
+			Suffix("FOR UPDATE OF d SKIP LOCKED"))
+		if len(deliveries) == 0 {
+			return
+		}
+
+		var webhooks []models.Webhook
+		tx.Select(&webhooks, sq.Select("*").
+			From("webhooks").
+			Where(sq.Eq{"id": helpers.Map(deliveries, deliveryWebhookID)}))
+
+		now := time.Now()
+		tx.Update(sq.Update("webhook_deliveries").
+			SetMap(map[string]interface{}{
+				"status":     DeliveryRunning,
+				"attempts":   sq.Expr("attempts + 1"),
+				"started_at": now,
+			}).
+			Where(sq.Eq{"id": helpers.Map(deliveries, deliveryID)}))
+
+		for _, delivery := range deliveries {
+			_, webhook := helpers.Find(webhooks, func(w models.Webhook) bool { return w.ID == delivery.WebhookID })
+			if webhook == nil {
+				continue
+			}
+			delivery.Status = DeliveryRunning
+			delivery.Attempts++
+			delivery.StartedAt = &now
+			claimed = append(claimed, Claimed{Delivery: delivery, Webhook: *webhook})
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to claim webhook deliveries: %w", err)
+	}
+	return claimed, nil
+}
+
+// Deliver sends the delivery to its webhook, signed with the webhook's keys in the same way as an API
+// request. The response status code is returned (0 if no response was received), along with an error
+// if the receiver did not acknowledge the delivery with a 2xx response.
+func Deliver(client *http.Client, c Claimed) (int, error) {
+	req, err := http.NewRequest("POST", c.Webhook.URL, strings.NewReader(c.Delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("unable to construct request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Date", time.Now().In(time.FixedZone("GMT", 0)).Format(time.RFC1123))
+	req.Header.Set("X-AShirt-Event", c.Delivery.EventType)
+	req.Header.Set("X-AShirt-Delivery", c.Delivery.UUID)
+	authorization, err := signer.BuildClientRequestAuthorization(req, c.Webhook.AccessKey, c.Webhook.SecretKey)
+	if err != nil {
+		return 0, fmt.Errorf("unable to sign request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the response body is never recorded, since it is visible to the webhook's owner
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainLength))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %v", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// FinishDelivery records the outcome of sending a delivery. Failed deliveries are rescheduled after the
+// given backoff, or marked as failed once maxAttempts has been reached. Once a webhook has failed
+// disableAfter consecutive attempts (across all of its deliveries), it is disabled and its
+// outstanding deliveries are failed.
+func FinishDelivery(db *database.Connection, c Claimed, statusCode int, deliverErr error, maxAttempts int64, backoff time.Duration, disableAfter int64) error {
+	var recordedStatusCode *int
+	if statusCode != 0 {
+		recordedStatusCode = &statusCode
+	}
+
+	return db.WithTx(context.Background(), func(tx *database.Transactable) {
+		if deliverErr == nil {
+			tx.Update(sq.Update("webhook_deliveries").
+				SetMap(map[string]interface{}{
+					"status":       DeliverySucceeded,
+					"status_code":  recordedStatusCode,
+					"last_error":   nil,
+					"delivered_at": time.Now(),
+				}).
+				Where(sq.Eq{"id": c.Delivery.ID}))
+			tx.Update(sq.Update("webhooks").
+				Set("consecutive_failures", 0).
+				Where(sq.Eq{"id": c.Webhook.ID}))
+			return
+		}
+
+		ub := sq.Update("webhook_deliveries").
+			SetMap(map[string]interface{}{
+				"status_code": recordedStatusCode,
+				"last_error":  deliverErr.Error(),
+			}).
+			Where(sq.Eq{"id": c.Delivery.ID})
+		if c.Delivery.Attempts >= maxAttempts {
+			ub = ub.Set("status", DeliveryFailed)
+		} else {
+			ub = ub.SetMap(map[string]interface{}{
+				"status":    DeliveryQueued,
+				"run_after": time.Now().Add(backoff),
+			})
+		}
+		tx.Update(ub)
+
+		tx.Update(sq.Update("webhooks").
+			Set("consecutive_failures", sq.Expr("consecutive_failures + 1")).
+			Where(sq.Eq{"id": c.Webhook.ID}))
+		tx.Update(sq.Update("webhooks").
+			Set("disabled_at", time.Now()).
+			Where(sq.Eq{"id": c.Webhook.ID, "disabled_at": nil}).
+			Where(sq.GtOrEq{"consecutive_failures": disableAfter}))
+
+		// if the webhook is now disabled, fail its outstanding deliveries. This delivery keeps its own error.
+		isDisabled := "webhook_id IN (SELECT id FROM webhooks WHERE disabled_at IS NOT NULL)"
+		tx.Update(sq.Update("webhook_deliveries").
+			Set("status", DeliveryFailed).
+			Where(sq.Eq{"id": c.Delivery.ID, "status": DeliveryQueued}).
+			Where(isDisabled))
+		tx.Update(sq.Update("webhook_deliveries").
+			SetMap(map[string]interface{}{
+				"status":     DeliveryFailed,
+				"last_error": "webhook was disabled after repeated failures",
+			}).
+			Where(sq.Eq{"webhook_id": c.Webhook.ID, "status": DeliveryQueued}).
+			Where(isDisabled))
+	})
+}
+
+func deliveryID(d models.WebhookDelivery) int64 {
+	return d.ID
+}
+
+func deliveryWebhookID(d models.WebhookDelivery) int64 {
+	return d.WebhookID
+}
@@ -0,0 +1,163 @@
+// Package webhooks delivers notifications of operation activity to external systems (e.g. chat bots
+// or a SIEM). Deliveries are queued in the webhook_deliveries table when events occur, and are sent,
+// signed and retried by the webhook worker.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/google/uuid"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// The events that webhooks may subscribe to
+const (
+	EventEvidenceCreated      = "evidence_created"
+	EventFindingCreated       = "finding_created"
+	EventFindingReadyToReport = "finding_ready_to_report"
+)
+
+// Events lists all of the events that webhooks may subscribe to
+var Events = []string{
+	EventEvidenceCreated,
+	EventFindingCreated,
+	EventFindingReadyToReport,
+}
+
+// DeliveryStatus reflects the possible states of a webhook_deliveries row
+type DeliveryStatus = string
+
+const (
+	// DeliveryQueued reflects deliveries that are waiting to be sent (possibly after a retry delay)
+	DeliveryQueued DeliveryStatus = "queued"
+	// DeliveryRunning reflects deliveries that have been claimed by the webhook worker
+	DeliveryRunning DeliveryStatus = "running"
+	// DeliverySucceeded reflects deliveries that the receiver acknowledged with a 2xx response
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed reflects deliveries that have exhausted their retries, or whose webhook was disabled
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Message is the body of each delivery
+type Message struct {
+	// ID uniquely identifies the delivery. Retries of the same delivery share an ID.
+	ID            string      `json:"id"`
+	Event         string      `json:"event"`
+	OperationSlug string      `json:"operationSlug"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	Data          interface{} `json:"data"`
+}
+
+// EvidenceData is the Message data for evidence events
+type EvidenceData struct {
+	UUID         string    `json:"uuid"`
+	Description  string    `json:"description"`
+	ContentType  string    `json:"contentType"`
+	OccurredAt   time.Time `json:"occurredAt"`
+	OperatorSlug string    `json:"operatorSlug"`
+}
+
+// FindingData is the Message data for finding events
+type FindingData struct {
+	UUID          string  `json:"uuid"`
+	Title         string  `json:"title"`
+	Category      string  `json:"category"`
+	ReadyToReport bool    `json:"readyToReport"`
+	TicketLink    *string `json:"ticketLink"`
+}
+
+// deliveriesEnqueued is signalled whenever new deliveries are added, so that an idle worker can start
+// on them without waiting for its next poll
+var deliveriesEnqueued = make(chan struct{}, 1)
+
+// DeliveriesEnqueued returns a channel that receives a value whenever new deliveries have been queued
+func DeliveriesEnqueued() <-chan struct{} {
+	return deliveriesEnqueued
+}
+
+// ParseEvents splits the stored (comma separated) list of subscribed events
+func ParseEvents(events string) []string {
+	if events == "" {
+		return []string{}
+	}
+	return strings.Split(events, ",")
+}
+
+// JoinEvents converts a list of events into its stored form, after verifying that each event is
+// supported. At least one event is required.
+func JoinEvents(events []string) (string, error) {
+	if len(events) == 0 {
+		return "", fmt.Errorf("at least one event is required")
+	}
+	for _, event := range events {
+		if !helpers.ContainsMatch(Events, event) {
+			return "", fmt.Errorf("unsupported webhook event %q", event)
+		}
+	}
+	return strings.Join(events, ","), nil
+}
+
+// Enqueue queues a delivery of the event for each enabled webhook that subscribes to it, including
+// webhooks that are not scoped to any operation. Failures are logged rather than returned, so that
+// webhooks never interfere with the action that produced the event.
+func Enqueue(db *database.Connection, logger *slog.Logger, operationID int64, operationSlug string, eventType string, data interface{}) {
+	var numQueued int
+	err := db.WithTx(context.Background(), func(tx *database.Transactable) {
+		var webhooks []models.Webhook
+		tx.Select(&webhooks, sq.Select("id", "events").
+			From("webhooks").
+			Where(sq.Or{sq.Eq{"operation_id": operationID}, sq.Eq{"operation_id": nil}}).
+			Where(sq.Eq{"disabled_at": nil}))
+		webhooks = helpers.Filter(webhooks, func(w models.Webhook) bool {
+			return helpers.ContainsMatch(ParseEvents(w.Events), eventType)
+		})
+
+		now := time.Now()
+		deliveries := make([]map[string]interface{}, 0, len(webhooks))
+		for _, webhook := range webhooks {
+			message := Message{
+				ID:            uuid.New().String(),
+				Event:         eventType,
+				OperationSlug: operationSlug,
+				CreatedAt:     now,
+				Data:          data,
+			}
+			payload, err := json.Marshal(message)
+			if err != nil {
+				tx.FailTransaction(err)
+				return
+			}
+			deliveries = append(deliveries, map[string]interface{}{
+				"uuid":       message.ID,
+				"webhook_id": webhook.ID,
+				"event_type": eventType,
+				"payload":    string(payload),
+				"status":     DeliveryQueued,
+				"run_after":  now,
+			})
+		}
+		tx.BatchInsert("webhook_deliveries", len(deliveries), func(row int) map[string]interface{} {
+			return deliveries[row]
+		})
+		numQueued = len(deliveries)
+	})
+	if err != nil {
+		logger.Error("Unable to queue webhook deliveries", "eventType", eventType, "error", err.Error())
+		return
+	}
+	if numQueued > 0 {
+		select {
+		case deliveriesEnqueued <- struct{}{}:
+		default: // a notification is already pending
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+)
+
+// ContentReconciler is a struct that periodically compares the content in the content store against
+// the evidence table. Content that is not referenced by any evidence (orphans) is deleted, once it has
+// remained unreferenced for the grace period, and evidence that references missing content (dangling
+// references) is recorded. All outstanding issues are stored in the content_issues table.
+type ContentReconciler struct {
+	db       *database.Connection
+	store    contentstore.Store
+	stopChan chan bool
+	running  bool
+	logger   *slog.Logger
+	// Interval is the time to wait between reconciliation passes
+	Interval time.Duration
+	// GracePeriod is the minimum time content must remain unreferenced before it is deleted. This
+	// protects content that has been uploaded, but whose evidence has not yet been saved.
+	GracePeriod    time.Duration
+	OnPassComplete func()
+}
+
+// MakeContentReconciler constructs a ContentReconciler
+func MakeContentReconciler(db *database.Connection, store contentstore.Store, logger *slog.Logger) ContentReconciler {
+	return ContentReconciler{
+		db:          db,
+		store:       store,
+		stopChan:    make(chan bool),
+		logger:      logger,
+		Interval:    6 * time.Hour,
+		GracePeriod: 24 * time.Hour,
+	}
+}
+
+// Start starts the reconciler's processing. Note that calling this while the reconciler is already
+// running will do nothing
+func (w *ContentReconciler) Start() {
+	if !w.running {
+		w.running = true
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.Error("recovered from worker panic", "error", r)
+			}
+		}()
+		w.logger.Info("Starting worker")
+		go w.run()
+		go func() {
+			<-w.stopChan
+			w.running = false
+		}()
+	}
+}
+
+// Stop stops the reconciler at its next opportunity (between reconciliation passes)
+func (w *ContentReconciler) Stop() {
+	w.stopChan <- true
+}
+
+// IsRunning returns true if the reconciler is running, false otherwise.
+func (w *ContentReconciler) IsRunning() bool {
+	return w.running
+}
+
+func (w *ContentReconciler) run() {
+	for w.running {
+		if err := w.reconcile(); err != nil {
+			w.logger.Error("Unable to reconcile content", "error", err.Error())
+		}
+		if w.OnPassComplete != nil {
+			w.OnPassComplete()
+		}
+		time.Sleep(w.Interval)
+	}
+}
+
+type contentIssueKey struct {
+	issueType  string
+	contentKey string
+	evidenceID int64
+}
+
+func issueKeyOf(issue models.ContentIssue) contentIssueKey {
+	key := contentIssueKey{issueType: issue.IssueType, contentKey: issue.ContentKey}
+	if issue.EvidenceID != nil {
+		key.evidenceID = *issue.EvidenceID
+	}
+	return key
+}
+
+// reconcile performs a single reconciliation pass
+func (w *ContentReconciler) reconcile() error {
+	passStart := time.Now()
+
+	// evidence is read before listing the store, so that content uploaded in the meantime is seen
+	// as (temporarily) orphaned, rather than evidence being seen as dangling
+	var evidence []models.Evidence
+	err := w.db.Select(&evidence, sq.Select("id", "full_image_key", "thumb_image_key").From("evidence"))
+	if err != nil {
+		return fmt.Errorf("unable to list evidence: %w", err)
+	}
+	storedKeys, err := w.store.List()
+	if err != nil {
+		return fmt.Errorf("unable to list stored content: %w", err)
+	}
+
+	stored := make(map[string]bool, len(storedKeys))
+	for _, key := range storedKeys {
+		stored[key] = true
+	}
+
+	found := map[contentIssueKey]bool{}
+	referenced := map[string]bool{}
+	for _, evi := range evidence {
+		for _, key := range []string{evi.FullImageKey, evi.ThumbImageKey} {
+			if key == "" {
+				continue
+			}
+			referenced[key] = true
+			if !stored[key] {
+				found[contentIssueKey{services.ContentIssueDangling, key, evi.ID}] = true
+			}
+		}
+	}
+	for _, key := range storedKeys {
+		if !referenced[key] {
+			found[contentIssueKey{issueType: services.ContentIssueOrphan, contentKey: key}] = true
+		}
+	}
+
+	var orphans []models.ContentIssue
+	err = w.db.WithTx(context.Background(), func(tx *database.Transactable) {
+		var existing []models.ContentIssue
+		tx.Select(&existing, sq.Select("*").From("content_issues"))
+
+		known := map[contentIssueKey]bool{}
+		for _, issue := range existing {
+			key := issueKeyOf(issue)
+			if !found[key] {
+				tx.Delete(sq.Delete("content_issues").Where(sq.Eq{"id": issue.ID}))
+				continue
+			}
+			known[key] = true
+			tx.Update(sq.Update("content_issues").
+				Set("last_seen_at", passStart).
+				Where(sq.Eq{"id": issue.ID}))
+			if issue.IssueType == services.ContentIssueOrphan {
+				orphans = append(orphans, issue)
+			}
+		}
+		for key := range found {
+			if known[key] {
+				continue
+			}
+			var evidenceID *int64
+			if key.evidenceID != 0 {
+				evidenceID = &key.evidenceID
+			}
+			tx.Insert("content_issues", map[string]interface{}{
+				"issue_type":    key.issueType,
+				"content_key":   key.contentKey,
+				"evidence_id":   evidenceID,
+				"first_seen_at": passStart,
+				"last_seen_at":  passStart,
+			})
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("unable to record content issues: %w", err)
+	}
+
+	deleted := 0
+	for _, orphan := range orphans {
+		if passStart.Sub(orphan.FirstSeenAt) < w.GracePeriod {
+			continue
+		}
+		if err := w.store.Delete(orphan.ContentKey); err != nil {
+			w.logger.Warn("Unable to delete orphaned content", "key", orphan.ContentKey,
+				"attempts", orphan.DeleteAttempts+1, "error", err.Error())
+			w.db.Update(sq.Update("content_issues").
+				Set("delete_attempts", sq.Expr("delete_attempts + 1")).
+				Set("last_error", err.Error()).
+				Where(sq.Eq{"id": orphan.ID}))
+			continue
+		}
+		deleted++
+		w.db.Delete(sq.Delete("content_issues").Where(sq.Eq{"id": orphan.ID}))
+	}
+
+	w.logger.Info("Content reconciliation complete", "stored", len(storedKeys), "evidence", len(evidence),
+		"issues", len(found), "orphansDeleted", deleted)
+	return nil
+}
@@ -0,0 +1,59 @@
+package workers_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database/seeding"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+	"github.com/ashirt-ops/ashirt-server/internal/workers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentReconciler(t *testing.T) {
+	db := setupDb(t)
+	memStore, _ := contentstore.NewMemStore()
+	for _, evi := range seeding.HarryPotterSeedData.Evidences {
+		if evi.UUID == seeding.EviFlyingCar.UUID {
+			continue // leave a dangling reference
+		}
+		for _, key := range []string{evi.FullImageKey, evi.ThumbImageKey} {
+			if key != "" {
+				memStore.UploadWithName(key, bytes.NewReader([]byte(key)))
+			}
+		}
+	}
+	memStore.UploadWithName("orphan", bytes.NewReader([]byte("nobody loves me")))
+
+	passes := make(chan bool, 10)
+	reconciler := workers.MakeContentReconciler(db, memStore, logging.NewNopLogger())
+	reconciler.Interval = 10 * time.Millisecond
+	reconciler.GracePeriod = 0
+	reconciler.OnPassComplete = func() {
+		passes <- true
+	}
+
+	reconciler.Start()
+	<-passes // first pass records issues
+	<-passes // second pass deletes orphans
+	reconciler.Stop()
+
+	_, err := memStore.Read("orphan")
+	require.Error(t, err, "orphaned content should be deleted")
+	_, err = memStore.Read(seeding.EviDobby.FullImageKey)
+	require.NoError(t, err, "referenced content should remain")
+
+	var issues []models.ContentIssue
+	err = db.Select(&issues, sq.Select("*").From("content_issues"))
+	require.NoError(t, err)
+	require.NotEmpty(t, issues)
+	for _, issue := range issues {
+		require.Equal(t, services.ContentIssueDangling, issue.IssueType)
+		require.Equal(t, seeding.EviFlyingCar.ID, *issue.EvidenceID)
+	}
+}
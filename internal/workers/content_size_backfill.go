@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"log/slog"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+)
+
+// ContentSizeBackfill is a struct that measures the content of evidence created before content sizes
+// were tracked, so that storage quotas account for it. The backfill makes a single pass over all
+// unmeasured evidence, and then stops. Evidence whose content cannot be read is left unmeasured, and
+// retried the next time the backfill runs (i.e. when the server next starts).
+//
+// Note: storage quotas undercount the content of unmeasured evidence until the backfill completes
+type ContentSizeBackfill struct {
+	db      *database.Connection
+	store   contentstore.Store
+	running bool
+	logger  *slog.Logger
+	// BatchSize is the number of evidence measured between progress updates
+	BatchSize uint64
+	// OnComplete is called once all evidence has been examined
+	OnComplete func()
+}
+
+// MakeContentSizeBackfill constructs a ContentSizeBackfill
+func MakeContentSizeBackfill(db *database.Connection, store contentstore.Store, logger *slog.Logger) ContentSizeBackfill {
+	return ContentSizeBackfill{
+		db:        db,
+		store:     store,
+		logger:    logger,
+		BatchSize: 100,
+	}
+}
+
+// Start starts the backfill in the background. Note that calling this while the backfill is already
+// running will do nothing
+func (w *ContentSizeBackfill) Start() {
+	if !w.running {
+		w.running = true
+		w.logger.Info("Starting worker")
+		go w.run()
+	}
+}
+
+// IsRunning returns true if the backfill is running, false otherwise.
+func (w *ContentSizeBackfill) IsRunning() bool {
+	return w.running
+}
+
+func (w *ContentSizeBackfill) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("recovered from worker panic", "error", r)
+		}
+		w.running = false
+		if w.OnComplete != nil {
+			w.OnComplete()
+		}
+	}()
+
+	var afterID int64
+	measured, failed := 0, 0
+	for {
+		out, err := services.MeasureUnsizedEvidence(w.db, w.store, afterID, w.BatchSize)
+		if err != nil {
+			w.logger.Error("Unable to measure evidence content; storage quotas may undercount usage", "error", err.Error())
+			return
+		}
+		for _, evidenceID := range out.Failed {
+			w.logger.Warn("Unable to read evidence content to measure it", "evidenceID", evidenceID)
+		}
+		measured += out.Measured
+		failed += len(out.Failed)
+		if out.LastID == 0 {
+			break
+		}
+		afterID = out.LastID
+		w.logger.Info("Measured evidence content", "measured", measured, "failed", failed)
+	}
+	if measured > 0 || failed > 0 {
+		w.logger.Info("Finished measuring evidence content", "measured", measured, "failed", failed)
+	}
+}
@@ -0,0 +1,54 @@
+package workers_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database/seeding"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/workers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentSizeBackfill(t *testing.T) {
+	db := setupDb(t)
+	memStore, _ := contentstore.NewMemStore()
+	for _, evi := range seeding.HarryPotterSeedData.Evidences {
+		if evi.UUID == seeding.EviFlyingCar.UUID {
+			continue // leave this content unreadable
+		}
+		if evi.FullImageKey != "" {
+			memStore.UploadWithName(evi.FullImageKey, bytes.NewReader([]byte(evi.FullImageKey)))
+		}
+	}
+	require.NoError(t, db.Exec("UPDATE evidence SET content_size = NULL"))
+
+	done := make(chan bool, 1)
+	backfill := workers.MakeContentSizeBackfill(db, memStore, logging.NewNopLogger())
+	backfill.BatchSize = 2
+	backfill.OnComplete = func() {
+		done <- true
+	}
+	backfill.Start()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("backfill did not complete")
+	}
+	require.False(t, backfill.IsRunning())
+
+	for _, evi := range seeding.HarryPotterSeedData.Evidences {
+		var measured models.Evidence
+		require.NoError(t, db.Get(&measured, sq.Select("*").From("evidence").Where(sq.Eq{"uuid": evi.UUID})))
+		if evi.UUID == seeding.EviFlyingCar.UUID && evi.FullImageKey != "" {
+			require.Nil(t, measured.ContentSize, "unreadable content should remain unmeasured")
+			continue
+		}
+		require.NotNil(t, measured.ContentSize)
+		require.Equal(t, int64(len(evi.FullImageKey)), *measured.ContentSize)
+	}
+}
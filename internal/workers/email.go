@@ -96,10 +96,11 @@ func (w *EmailWorker) IsRunning() bool {
 }
 
 type emailRequest struct {
-	EmailID  int64  `db:"id"`
-	To       string `db:"to_email"`
-	UserID   int64  `db:"user_id"`
-	Template string `db:"template"`
+	EmailID  int64   `db:"id"`
+	To       string  `db:"to_email"`
+	UserID   int64   `db:"user_id"`
+	Template string  `db:"template"`
+	Data     *string `db:"template_data"`
 }
 
 func (w *EmailWorker) run() {
@@ -108,7 +109,7 @@ func (w *EmailWorker) run() {
 		var emails []emailRequest
 
 		// get emails from email queue
-		err := w.db.Select(&emails, sq.Select("id", "to_email", "user_id", "template").
+		err := w.db.Select(&emails, sq.Select("id", "to_email", "user_id", "template", "template_data").
 			From("email_queue").
 			Where(sq.Eq{"email_status": []string{EmailCreated, EmailErrored}}).
 			Where(sq.Expr("error_count < ?", 3)).
@@ -148,6 +149,9 @@ func (w *EmailWorker) queueEmail(email emailRequest) error {
 		UserRecord: &user,
 		DB:         w.db,
 	}
+	if email.Data != nil {
+		templateData.Payload = []byte(*email.Data)
+	}
 	emailContent, err := emailtemplates.BuildEmailContent(email.Template, templateData)
 
 	if err != nil {
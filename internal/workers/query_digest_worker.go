@@ -0,0 +1,77 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/emailtemplates"
+	"github.com/ashirt-ops/ashirt-server/internal/services"
+)
+
+// QueryDigestWorker is a struct that periodically queues saved query digest emails for users whose
+// digests are due. The queued emails are sent by the EmailWorker.
+type QueryDigestWorker struct {
+	db       *database.Connection
+	stopChan chan bool
+	running  bool
+	logger   *slog.Logger
+	// Interval is the time to wait between checking for due digests
+	Interval       time.Duration
+	OnPassComplete func()
+}
+
+// MakeQueryDigestWorker constructs a QueryDigestWorker
+func MakeQueryDigestWorker(db *database.Connection, logger *slog.Logger) QueryDigestWorker {
+	return QueryDigestWorker{
+		db:       db,
+		stopChan: make(chan bool),
+		logger:   logger,
+		Interval: 5 * time.Minute,
+	}
+}
+
+// Start starts the worker's processing. Note that calling this while the worker is already
+// running will do nothing
+func (w *QueryDigestWorker) Start() {
+	if !w.running {
+		w.running = true
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.Error("recovered from worker panic", "error", r)
+			}
+		}()
+		w.logger.Info("Starting worker")
+		go w.run()
+		go func() {
+			<-w.stopChan
+			w.running = false
+		}()
+	}
+}
+
+// Stop stops the worker at its next opportunity (between passes)
+func (w *QueryDigestWorker) Stop() {
+	w.stopChan <- true
+}
+
+// IsRunning returns true if the worker is running, false otherwise.
+func (w *QueryDigestWorker) IsRunning() bool {
+	return w.running
+}
+
+func (w *QueryDigestWorker) run() {
+	for w.running {
+		queued, err := services.QueueQueryDigests(context.Background(), w.db, emailtemplates.EmailQueryDigestTemplate, time.Now())
+		if err != nil {
+			w.logger.Error("Unable to queue query digests", "error", err.Error())
+		} else if queued > 0 {
+			w.logger.Info("Queued query digests", "count", queued)
+		}
+		if w.OnPassComplete != nil {
+			w.OnPassComplete()
+		}
+		time.Sleep(w.Interval)
+	}
+}
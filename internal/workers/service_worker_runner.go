@@ -0,0 +1,222 @@
+package workers
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+)
+
+// ServiceWorkerRunner is a struct that runs the jobs queued for enhancement service workers (in the
+// service_worker_jobs table). Jobs that fail are retried with exponential backoff, and the number of
+// jobs running at once for any single service worker is capped.
+type ServiceWorkerRunner struct {
+	db           *database.Connection
+	contentStore contentstore.Store
+	stopChan     chan bool
+	running      bool
+	logger       *slog.Logger
+	jobDone      chan bool
+
+	mu       sync.Mutex
+	inFlight map[string]int
+	ranJobs  bool
+
+	// PollInterval is the maximum time to wait between checks for new jobs
+	PollInterval time.Duration
+	// MaxConcurrencyPerWorker caps the number of jobs that can run at once for any single worker
+	MaxConcurrencyPerWorker int
+	// MaxAttempts is the number of times a job is attempted before it is marked as failed
+	MaxAttempts int64
+	// RetryBackoff is the delay before the first retry. Each subsequent retry doubles the delay, up
+	// to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// StaleAfter is the time after which a running job is assumed to have been interrupted (e.g. by a
+	// crash, or because it hung). Stale jobs are requeued when the runner starts, and periodically
+	// (every StaleAfter/2) while it runs
+	StaleAfter time.Duration
+	// Callbacks determines whether evidence jobs are offered a callback for delivering deferred results
+	Callbacks enhancementservices.CallbackConfig
+	// OnIdle is called when the runner has finished all available jobs
+	OnIdle func()
+}
+
+// MakeServiceWorkerRunner constructs a ServiceWorkerRunner
+func MakeServiceWorkerRunner(db *database.Connection, contentStore contentstore.Store, logger *slog.Logger) ServiceWorkerRunner {
+	return ServiceWorkerRunner{
+		db:                      db,
+		contentStore:            contentStore,
+		stopChan:                make(chan bool),
+		logger:                  logger,
+		jobDone:                 make(chan bool, 1),
+		inFlight:                map[string]int{},
+		PollInterval:            5 * time.Second,
+		MaxConcurrencyPerWorker: 4,
+		MaxAttempts:             5,
+		RetryBackoff:            30 * time.Second,
+		MaxRetryBackoff:         time.Hour,
+		StaleAfter:              15 * time.Minute,
+		Callbacks: enhancementservices.CallbackConfig{
+			Expiry: 24 * time.Hour,
+		},
+	}
+}
+
+// Start starts the runner's processing. Note that calling this while the runner is already running
+// will do nothing
+func (w *ServiceWorkerRunner) Start() {
+	if !w.running {
+		w.running = true
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.Error("recovered from worker panic", "error", r)
+			}
+		}()
+		w.logger.Info("Starting worker")
+		go w.run()
+	}
+}
+
+// Stop stops the runner from claiming new jobs. Any jobs that are already running will run to completion.
+func (w *ServiceWorkerRunner) Stop() {
+	w.stopChan <- true
+}
+
+// IsRunning returns true if the runner is running, false otherwise.
+func (w *ServiceWorkerRunner) IsRunning() bool {
+	return w.running
+}
+
+func (w *ServiceWorkerRunner) run() {
+	var nextRequeue time.Time
+	for w.running {
+		if now := time.Now(); !now.Before(nextRequeue) {
+			w.requeueStaleJobs()
+			nextRequeue = now.Add(w.StaleAfter / 2)
+		}
+		if w.claimAndRun() > 0 {
+			continue
+		}
+		w.checkIdle()
+
+		select {
+		case <-w.stopChan:
+			w.running = false
+		case <-enhancementservices.JobsEnqueued():
+		case <-w.jobDone:
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// requeueStaleJobs requeues jobs that have been running for longer than StaleAfter, whether they
+// were started by this runner or by another instance
+func (w *ServiceWorkerRunner) requeueStaleJobs() {
+	numRequeued, err := enhancementservices.RequeueStaleJobs(w.db, time.Now().Add(-w.StaleAfter))
+	if err != nil {
+		w.logger.Error("Unable to requeue stale jobs", "error", err.Error())
+	} else if numRequeued > 0 {
+		w.logger.Info("Requeued stale jobs", "count", numRequeued)
+	}
+}
+
+// claimAndRun claims as many jobs as the per-worker caps allow, and starts running them. Returns
+// the number of jobs started.
+func (w *ServiceWorkerRunner) claimAndRun() int {
+	// only this loop claims jobs, so running jobs can only decrease while claiming
+	w.mu.Lock()
+	running := make(map[string]int, len(w.inFlight))
+	for name, count := range w.inFlight {
+		running[name] = count
+	}
+	w.mu.Unlock()
+
+	jobs, err := enhancementservices.ClaimJobs(w.db, func(workerName string) int {
+		return w.MaxConcurrencyPerWorker - running[workerName]
+	})
+	if err != nil {
+		w.logger.Error("Unable to claim jobs", "error", err.Error())
+		return 0
+	}
+
+	w.mu.Lock()
+	for _, job := range jobs {
+		w.inFlight[job.WorkerName]++
+	}
+	w.mu.Unlock()
+	for _, job := range jobs {
+		go w.runJob(job)
+	}
+	return len(jobs)
+}
+
+func (w *ServiceWorkerRunner) runJob(job models.ServiceWorkerJob) {
+	logger := w.logger.With("worker", job.WorkerName, "eventType", job.EventType, "jobID", job.ID, "attempt", job.Attempts)
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("recovered from job panic", "error", r)
+		}
+		w.mu.Lock()
+		w.inFlight[job.WorkerName]--
+		w.ranJobs = true
+		w.mu.Unlock()
+
+		select {
+		case w.jobDone <- true:
+		default:
+		}
+	}()
+
+	runErr := enhancementservices.RunJob(w.db, w.contentStore, job, w.Callbacks)
+	if runErr != nil {
+		if job.Attempts >= w.MaxAttempts {
+			logger.Error("Job failed; no retries remain", "error", runErr.Error())
+		} else {
+			logger.Warn("Job failed; will retry", "error", runErr.Error())
+		}
+	} else {
+		logger.Info("Job completed")
+	}
+
+	if err := enhancementservices.FinishJob(w.db, job, runErr, w.MaxAttempts, w.backoff(job.Attempts)); err != nil {
+		logger.Error("Unable to record job result", "error", err.Error())
+	}
+}
+
+// checkIdle calls OnIdle if jobs have been run since the last time the runner was idle, and no jobs
+// are currently running
+func (w *ServiceWorkerRunner) checkIdle() {
+	w.mu.Lock()
+	idle := w.ranJobs
+	for _, count := range w.inFlight {
+		if count > 0 {
+			idle = false
+		}
+	}
+	if idle {
+		w.ranJobs = false
+	}
+	w.mu.Unlock()
+
+	if idle && w.OnIdle != nil {
+		w.OnIdle()
+	}
+}
+
+// backoff determines how long to wait before retrying a job that has been attempted the given number
+// of times
+func (w *ServiceWorkerRunner) backoff(attempts int64) time.Duration {
+	delay := w.RetryBackoff
+	for i := int64(1); i < attempts && delay < w.MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.MaxRetryBackoff {
+		delay = w.MaxRetryBackoff
+	}
+	return delay
+}
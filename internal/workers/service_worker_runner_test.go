@@ -0,0 +1,239 @@
+package workers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/contentstore"
+	"github.com/ashirt-ops/ashirt-server/internal/database/seeding"
+	"github.com/ashirt-ops/ashirt-server/internal/enhancementservices"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/servicetypes/evidencemetadata"
+	"github.com/ashirt-ops/ashirt-server/internal/workers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceWorkerRunner(t *testing.T) {
+	db := setupDb(t)
+	workerName := seeding.DemoServiceWorker.Name
+	queuedEvidence := []models.Evidence{seeding.EviDobby, seeding.EviFlyingCar}
+	staleEvidence := seeding.EviSpiderAragog
+
+	// each evidence fails on the first attempt, and succeeds on the second
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	running, maxRunning := 0, 0
+	mockHandler := enhancementservices.RequestFn(func(method, url string, body io.Reader, updateRequest helpers.ModifyReqFunc) (*http.Response, error) {
+		var payload enhancementservices.NewEvidencePayload
+		require.NoError(t, json.NewDecoder(body).Decode(&payload))
+
+		mu.Lock()
+		attempts[payload.EvidenceUUID]++
+		attempt := attempts[payload.EvidenceUUID]
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+		w := httptest.NewRecorder()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"action": "processed", "content": "done"}`))
+		}
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return w.Result(), nil
+	})
+	enhancementservices.SetWebRequestFunctionForWorker(workerName, &mockHandler)
+	defer enhancementservices.SetWebRequestFunctionForWorker(workerName, nil)
+
+	// simulate work that was interrupted by a restart
+	_, err := db.Insert("evidence_metadata", map[string]interface{}{
+		"evidence_id":     staleEvidence.ID,
+		"source":          workerName,
+		"body":            "",
+		"status":          evidencemetadata.StatusProcessing,
+		"work_started_at": time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	err = enhancementservices.SendEvidenceCreatedEvent(db, logging.NewNopLogger(), seeding.OpChamberOfSecrets.ID,
+		helpers.Map(queuedEvidence, func(e models.Evidence) string { return e.UUID }), []string{workerName})
+	require.NoError(t, err)
+
+	memStore, _ := contentstore.NewMemStore()
+	runner := workers.MakeServiceWorkerRunner(db, memStore, logging.NewNopLogger())
+	runner.PollInterval = 10 * time.Millisecond
+	runner.RetryBackoff = time.Millisecond
+	runner.MaxRetryBackoff = time.Millisecond
+	runner.MaxConcurrencyPerWorker = 1
+	runner.StaleAfter = time.Minute
+	runner.Start()
+	defer runner.Stop()
+
+	allEvidenceIDs := []int64{staleEvidence.ID, queuedEvidence[0].ID, queuedEvidence[1].ID}
+	require.Eventually(t, func() bool {
+		var metadata []models.EvidenceMetadata
+		err := db.Select(&metadata, sq.Select("*").From("evidence_metadata").Where(sq.Eq{
+			"source":      workerName,
+			"evidence_id": allEvidenceIDs,
+			"status":      evidencemetadata.StatusCompleted,
+		}))
+		return err == nil && len(metadata) == len(allEvidenceIDs)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		var remainingJobs []models.ServiceWorkerJob
+		err := db.Select(&remainingJobs, sq.Select("*").From("service_worker_jobs"))
+		return err == nil && len(remainingJobs) == 0
+	}, time.Second, 10*time.Millisecond, "completed jobs should be removed")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, maxRunning, "concurrency should be capped")
+	require.Len(t, attempts, len(allEvidenceIDs))
+	for _, count := range attempts {
+		require.Equal(t, 2, count)
+	}
+}
+
+func TestServiceWorkerRunnerGivesUp(t *testing.T) {
+	db := setupDb(t)
+	workerName := seeding.DemoServiceWorker.Name
+
+	mockHandler := enhancementservices.RequestFn(func(method, url string, body io.Reader, updateRequest helpers.ModifyReqFunc) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		w.WriteHeader(http.StatusInternalServerError)
+		return w.Result(), nil
+	})
+	enhancementservices.SetWebRequestFunctionForWorker(workerName, &mockHandler)
+	defer enhancementservices.SetWebRequestFunctionForWorker(workerName, nil)
+
+	err := enhancementservices.SendEvidenceCreatedEvent(db, logging.NewNopLogger(), seeding.OpChamberOfSecrets.ID,
+		[]string{seeding.EviDobby.UUID}, []string{workerName})
+	require.NoError(t, err)
+
+	memStore, _ := contentstore.NewMemStore()
+	runner := workers.MakeServiceWorkerRunner(db, memStore, logging.NewNopLogger())
+	runner.PollInterval = 10 * time.Millisecond
+	runner.RetryBackoff = time.Millisecond
+	runner.MaxAttempts = 3
+	runner.Start()
+	defer runner.Stop()
+
+	require.Eventually(t, func() bool {
+		var jobs []models.ServiceWorkerJob
+		err := db.Select(&jobs, sq.Select("*").From("service_worker_jobs").Where(sq.Eq{"status": enhancementservices.JobFailed}))
+		return err == nil && len(jobs) == 1 && jobs[0].Attempts == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var metadata []models.EvidenceMetadata
+	err = db.Select(&metadata, sq.Select("*").From("evidence_metadata").Where(sq.Eq{
+		"source":      workerName,
+		"evidence_id": seeding.EviDobby.ID,
+	}))
+	require.NoError(t, err)
+	require.Len(t, metadata, 1)
+	require.Equal(t, evidencemetadata.StatusError, *metadata[0].Status)
+}
+
+func TestServiceWorkerRunnerRequeuesStaleJobsWhileRunning(t *testing.T) {
+	db := setupDb(t)
+	workerName := seeding.DemoServiceWorker.Name
+
+	mockHandler := enhancementservices.RequestFn(func(method, url string, body io.Reader, updateRequest helpers.ModifyReqFunc) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"action": "processed", "content": "done"}`))
+		return w.Result(), nil
+	})
+	enhancementservices.SetWebRequestFunctionForWorker(workerName, &mockHandler)
+	defer enhancementservices.SetWebRequestFunctionForWorker(workerName, nil)
+
+	memStore, _ := contentstore.NewMemStore()
+	runner := workers.MakeServiceWorkerRunner(db, memStore, logging.NewNopLogger())
+	runner.PollInterval = 10 * time.Millisecond
+	runner.StaleAfter = 200 * time.Millisecond
+	runner.Start()
+	defer runner.Stop()
+
+	// simulate a job left running by another instance that crashed, after this runner has started
+	_, err := db.Insert("evidence_metadata", map[string]interface{}{
+		"evidence_id":     seeding.EviDobby.ID,
+		"source":          workerName,
+		"body":            "",
+		"status":          evidencemetadata.StatusProcessing,
+		"work_started_at": time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = db.Insert("service_worker_jobs", map[string]interface{}{
+		"worker_name": workerName,
+		"event_type":  enhancementservices.EventEvidenceCreated,
+		"evidence_id": seeding.EviDobby.ID,
+		"status":      enhancementservices.JobRunning,
+		"attempts":    1,
+		"started_at":  time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		var metadata []models.EvidenceMetadata
+		err := db.Select(&metadata, sq.Select("*").From("evidence_metadata").Where(sq.Eq{
+			"source":      workerName,
+			"evidence_id": seeding.EviDobby.ID,
+			"status":      evidencemetadata.StatusCompleted,
+		}))
+		return err == nil && len(metadata) == 1
+	}, 5*time.Second, 10*time.Millisecond, "stale jobs should be requeued without restarting the runner")
+}
+
+func TestClaimJobsIsFairAcrossWorkers(t *testing.T) {
+	db := setupDb(t)
+
+	// a backlog for one worker, queued ahead of a single job for another
+	const backlog = 150
+	err := db.BatchInsert("service_worker_jobs", backlog+1, func(row int) map[string]interface{} {
+		workerName, runAfter := "busy", time.Now().Add(-time.Hour)
+		if row == backlog {
+			workerName, runAfter = "quiet", time.Now().Add(-time.Minute)
+		}
+		return map[string]interface{}{
+			"worker_name": workerName,
+			"event_type":  enhancementservices.EventOperationCreated,
+			"payload":     "{}",
+			"status":      enhancementservices.JobQueued,
+			"run_after":   runAfter,
+		}
+	})
+	require.NoError(t, err)
+
+	running := map[string]int{"busy": 1}
+	jobs, err := enhancementservices.ClaimJobs(db, func(workerName string) int {
+		return 4 - running[workerName]
+	})
+	require.NoError(t, err)
+
+	claimed := map[string]int{}
+	for _, job := range jobs {
+		require.Equal(t, enhancementservices.JobRunning, job.Status)
+		claimed[job.WorkerName]++
+	}
+	require.Equal(t, map[string]int{"busy": 3, "quiet": 1}, claimed)
+
+	// saturated workers are skipped entirely
+	jobs, err = enhancementservices.ClaimJobs(db, func(workerName string) int { return 0 })
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+}
@@ -0,0 +1,164 @@
+package workers
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
+)
+
+// WebhookWorker is a struct that sends the deliveries queued for webhooks (in the webhook_deliveries
+// table). Deliveries that fail are retried with exponential backoff, and webhooks that fail repeatedly
+// are disabled.
+type WebhookWorker struct {
+	db        *database.Connection
+	stopChan  chan bool
+	running   bool
+	logger    *slog.Logger
+	delivered bool
+
+	// Client is used to send deliveries. By default, deliveries to internal addresses are refused.
+	Client *http.Client
+	// PollInterval is the maximum time to wait between checks for new deliveries
+	PollInterval time.Duration
+	// MaxAttempts is the number of times a delivery is attempted before it is marked as failed
+	MaxAttempts int64
+	// RetryBackoff is the delay before the first retry. Each subsequent retry doubles the delay, up
+	// to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// DisableAfter is the number of consecutive failed attempts after which a webhook is disabled
+	DisableAfter int64
+	// StaleAfter is the time after which a running delivery is assumed to have been interrupted
+	StaleAfter time.Duration
+	// OnIdle is called when the worker has sent all available deliveries
+	OnIdle func()
+}
+
+// MakeWebhookWorker constructs a WebhookWorker
+func MakeWebhookWorker(db *database.Connection, logger *slog.Logger) WebhookWorker {
+	return WebhookWorker{
+		db:              db,
+		stopChan:        make(chan bool),
+		logger:          logger,
+		Client:          webhooks.NewClient(10 * time.Second),
+		PollInterval:    5 * time.Second,
+		MaxAttempts:     5,
+		RetryBackoff:    30 * time.Second,
+		MaxRetryBackoff: time.Hour,
+		DisableAfter:    15,
+		StaleAfter:      15 * time.Minute,
+	}
+}
+
+// Start starts the worker's processing. Note that calling this while the worker is already running
+// will do nothing
+func (w *WebhookWorker) Start() {
+	if !w.running {
+		w.running = true
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.Error("recovered from worker panic", "error", r)
+			}
+		}()
+		w.logger.Info("Starting worker")
+		go w.run()
+	}
+}
+
+// Stop stops the worker from claiming new deliveries. Deliveries that are being sent will run to completion.
+func (w *WebhookWorker) Stop() {
+	w.stopChan <- true
+}
+
+// IsRunning returns true if the worker is running, false otherwise.
+func (w *WebhookWorker) IsRunning() bool {
+	return w.running
+}
+
+func (w *WebhookWorker) run() {
+	for w.running {
+		if w.claimAndDeliver() > 0 {
+			continue
+		}
+		if w.delivered && w.OnIdle != nil {
+			w.OnIdle()
+		}
+		w.delivered = false
+
+		select {
+		case <-w.stopChan:
+			w.running = false
+		case <-webhooks.DeliveriesEnqueued():
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// claimAndDeliver claims a batch of deliveries, and sends them concurrently. Returns the number of
+// deliveries sent.
+func (w *WebhookWorker) claimAndDeliver() int {
+	const claimBatchSize = 25
+
+	claimed, err := webhooks.ClaimDeliveries(w.db, claimBatchSize, time.Now().Add(-w.StaleAfter))
+	if err != nil {
+		w.logger.Error("Unable to claim webhook deliveries", "error", err.Error())
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range claimed {
+		wg.Add(1)
+		go func(c webhooks.Claimed) {
+			defer wg.Done()
+			w.deliver(c)
+		}(c)
+	}
+	wg.Wait()
+	if len(claimed) > 0 {
+		w.delivered = true
+	}
+	return len(claimed)
+}
+
+func (w *WebhookWorker) deliver(c webhooks.Claimed) {
+	logger := w.logger.With("webhookID", c.Webhook.ID, "eventType", c.Delivery.EventType,
+		"delivery", c.Delivery.UUID, "attempt", c.Delivery.Attempts)
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("recovered from delivery panic", "error", r)
+		}
+	}()
+
+	statusCode, deliverErr := webhooks.Deliver(w.Client, c)
+	if deliverErr != nil {
+		if c.Delivery.Attempts >= w.MaxAttempts {
+			logger.Error("Delivery failed; no retries remain", "statusCode", statusCode, "error", deliverErr.Error())
+		} else {
+			logger.Warn("Delivery failed; will retry", "statusCode", statusCode, "error", deliverErr.Error())
+		}
+	} else {
+		logger.Info("Delivery completed", "statusCode", statusCode)
+	}
+
+	err := webhooks.FinishDelivery(w.db, c, statusCode, deliverErr, w.MaxAttempts, w.backoff(c.Delivery.Attempts), w.DisableAfter)
+	if err != nil {
+		logger.Error("Unable to record delivery result", "error", err.Error())
+	}
+}
+
+// backoff determines how long to wait before retrying a delivery that has been attempted the given
+// number of times
+func (w *WebhookWorker) backoff(attempts int64) time.Duration {
+	delay := w.RetryBackoff
+	for i := int64(1); i < attempts && delay < w.MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.MaxRetryBackoff {
+		delay = w.MaxRetryBackoff
+	}
+	return delay
+}
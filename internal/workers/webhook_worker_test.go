@@ -0,0 +1,167 @@
+package workers_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ashirt-ops/ashirt-server/internal/database"
+	"github.com/ashirt-ops/ashirt-server/internal/database/seeding"
+	"github.com/ashirt-ops/ashirt-server/internal/helpers"
+	"github.com/ashirt-ops/ashirt-server/internal/logging"
+	"github.com/ashirt-ops/ashirt-server/internal/models"
+	"github.com/ashirt-ops/ashirt-server/internal/webhooks"
+	"github.com/ashirt-ops/ashirt-server/internal/workers"
+	"github.com/ashirt-ops/ashirt-server/signer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookWorkerDelivers(t *testing.T) {
+	db := setupDb(t)
+	secretKey := []byte("webhook-secret")
+
+	// the receiver fails the first attempt of each delivery, and acknowledges the second
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var received []webhooks.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		authParts := strings.SplitN(r.Header.Get("Authorization"), ":", 2)
+		require.Len(t, authParts, 2)
+		require.True(t, strings.HasPrefix(authParts[0], "WH-test-"))
+		expected := signer.BuildRequestHMAC(r, bytes.NewReader(body), secretKey)
+		actual, err := base64.StdEncoding.DecodeString(authParts[1])
+		require.NoError(t, err)
+		require.True(t, hmac.Equal(expected, actual), "delivery should be signed with the webhook's secret")
+
+		var message webhooks.Message
+		require.NoError(t, json.Unmarshal(body, &message))
+		require.Equal(t, message.ID, r.Header.Get("X-AShirt-Delivery"))
+		require.Equal(t, message.Event, r.Header.Get("X-AShirt-Event"))
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[message.ID]++
+		if attempts[message.ID] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opWebhookID := insertWebhook(t, db, &seeding.OpChamberOfSecrets.ID, server.URL+"/hook", webhooks.EventEvidenceCreated, secretKey)
+	insertWebhook(t, db, nil, server.URL+"/global", webhooks.EventFindingCreated, secretKey)
+	insertWebhook(t, db, &seeding.OpSorcerersStone.ID, server.URL+"/other", webhooks.EventEvidenceCreated, secretKey)
+
+	webhooks.Enqueue(db, logging.NewNopLogger(), seeding.OpChamberOfSecrets.ID, seeding.OpChamberOfSecrets.Slug,
+		webhooks.EventEvidenceCreated, webhooks.EvidenceData{UUID: seeding.EviDobby.UUID})
+	webhooks.Enqueue(db, logging.NewNopLogger(), seeding.OpChamberOfSecrets.ID, seeding.OpChamberOfSecrets.Slug,
+		webhooks.EventFindingCreated, webhooks.FindingData{UUID: "new-finding"})
+
+	worker := workers.MakeWebhookWorker(db, logging.NewNopLogger())
+	worker.Client = server.Client() // the test receiver runs on a loopback address
+	worker.PollInterval = 10 * time.Millisecond
+	worker.RetryBackoff = time.Millisecond
+	worker.MaxRetryBackoff = time.Millisecond
+	worker.Start()
+	defer worker.Stop()
+
+	require.Eventually(t, func() bool {
+		var deliveries []models.WebhookDelivery
+		err := db.Select(&deliveries, sq.Select("*").From("webhook_deliveries").Where(sq.Eq{"status": webhooks.DeliverySucceeded}))
+		return err == nil && len(deliveries) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2, "only subscribed webhooks for the operation, and global webhooks, receive events")
+	for _, count := range attempts {
+		require.Equal(t, 2, count)
+	}
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.Get(&delivery, sq.Select("*").From("webhook_deliveries").Where(sq.Eq{"webhook_id": opWebhookID})))
+	require.Equal(t, int64(2), delivery.Attempts)
+	require.Equal(t, int64(http.StatusNoContent), *delivery.StatusCode)
+	require.NotNil(t, delivery.DeliveredAt)
+
+	var webhook models.Webhook
+	require.NoError(t, db.Get(&webhook, sq.Select("*").From("webhooks").Where(sq.Eq{"id": opWebhookID})))
+	require.Equal(t, int64(0), webhook.ConsecutiveFailures, "a success resets the failure count")
+}
+
+func TestWebhookWorkerDisablesFailingWebhooks(t *testing.T) {
+	db := setupDb(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("receiver is broken"))
+	}))
+	defer server.Close()
+
+	webhookID := insertWebhook(t, db, &seeding.OpChamberOfSecrets.ID, server.URL, webhooks.EventEvidenceCreated, []byte("secret"))
+	for i := 0; i < 3; i++ {
+		webhooks.Enqueue(db, logging.NewNopLogger(), seeding.OpChamberOfSecrets.ID, seeding.OpChamberOfSecrets.Slug,
+			webhooks.EventEvidenceCreated, webhooks.EvidenceData{})
+	}
+
+	worker := workers.MakeWebhookWorker(db, logging.NewNopLogger())
+	worker.Client = server.Client() // the test receiver runs on a loopback address
+	worker.PollInterval = 10 * time.Millisecond
+	worker.RetryBackoff = time.Hour // only the first attempt of each delivery should be made before disabling
+	worker.MaxRetryBackoff = time.Hour
+	worker.DisableAfter = 3
+	worker.Start()
+	defer worker.Stop()
+
+	require.Eventually(t, func() bool {
+		var webhook models.Webhook
+		err := db.Get(&webhook, sq.Select("*").From("webhooks").Where(sq.Eq{"id": webhookID}))
+		return err == nil && webhook.DisabledAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, db.Select(&deliveries, sq.Select("*").From("webhook_deliveries").Where(sq.Eq{"webhook_id": webhookID})))
+	require.Len(t, deliveries, 3)
+	for _, delivery := range deliveries {
+		require.Equal(t, webhooks.DeliveryFailed, delivery.Status)
+		require.Equal(t, int64(http.StatusInternalServerError), *delivery.StatusCode)
+		require.NotNil(t, delivery.LastError)
+		require.NotContains(t, *delivery.LastError, "receiver is broken", "response bodies should never be recorded")
+	}
+	_, disabling := helpers.Find(deliveries, func(d models.WebhookDelivery) bool {
+		return strings.Contains(*d.LastError, "receiver is broken")
+	})
+	require.NotNil(t, disabling, "the delivery that caused the webhook to be disabled keeps its own error")
+
+	// deliveries for disabled webhooks are not queued
+	webhooks.Enqueue(db, logging.NewNopLogger(), seeding.OpChamberOfSecrets.ID, seeding.OpChamberOfSecrets.Slug,
+		webhooks.EventEvidenceCreated, webhooks.EvidenceData{})
+	var count int
+	require.NoError(t, db.Get(&count, sq.Select("COUNT(*)").From("webhook_deliveries").Where(sq.Eq{"webhook_id": webhookID})))
+	require.Equal(t, 3, count)
+}
+
+func insertWebhook(t *testing.T, db *database.Connection, operationID *int64, url, events string, secretKey []byte) int64 {
+	id, err := db.Insert("webhooks", map[string]interface{}{
+		"operation_id": operationID,
+		"name":         "test hook",
+		"url":          url,
+		"events":       events,
+		"access_key":   "WH-test-" + url,
+		"secret_key":   secretKey,
+	})
+	require.NoError(t, err)
+	return id
+}
@@ -0,0 +1,21 @@
+
+-- +migrate Up
+CREATE TABLE audit_events (
+  id INT AUTO_INCREMENT,
+  actor_id INT,
+  action VARCHAR(255) NOT NULL,
+  target_type VARCHAR(255) NOT NULL,
+  target VARCHAR(255) NOT NULL,
+  operation_id INT,
+  before_state JSON,
+  after_state JSON,
+  request_id VARCHAR(36),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX audit_events__actor_id (actor_id),
+  INDEX audit_events__operation_id (operation_id),
+  INDEX audit_events__created_at (created_at)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE audit_events;
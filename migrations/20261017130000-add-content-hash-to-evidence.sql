@@ -0,0 +1,7 @@
+
+-- +migrate Up
+ALTER TABLE evidence
+	ADD COLUMN `content_hash` CHAR(64) AFTER `thumb_image_key`;
+-- +migrate Down
+ALTER TABLE evidence
+	DROP COLUMN `content_hash`;
@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE content_references (
+  id INT AUTO_INCREMENT,
+  content_key VARCHAR(255) NOT NULL,
+  ref_count INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY content_references__content_key (content_key)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE content_references;
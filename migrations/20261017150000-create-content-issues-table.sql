@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE content_issues (
+  id INT AUTO_INCREMENT,
+  issue_type VARCHAR(31) NOT NULL,
+  content_key VARCHAR(255) NOT NULL,
+  evidence_id INT,
+  first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delete_attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX content_issues__issue_type (issue_type)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE content_issues;
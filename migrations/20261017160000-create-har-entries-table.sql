@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE har_entries (
+  id INT AUTO_INCREMENT,
+  evidence_id INT NOT NULL,
+  entry_index INT NOT NULL,
+  method VARCHAR(15) NOT NULL,
+  url TEXT NOT NULL,
+  host VARCHAR(255) NOT NULL,
+  status INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  FOREIGN KEY (evidence_id) REFERENCES evidence(id),
+  INDEX har_entries__evidence_id (evidence_id),
+  INDEX har_entries__host (host),
+  INDEX har_entries__status (status)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE har_entries;
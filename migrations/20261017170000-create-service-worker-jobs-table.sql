@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE service_worker_jobs (
+  id INT AUTO_INCREMENT,
+  worker_name VARCHAR(255) NOT NULL,
+  event_type VARCHAR(63) NOT NULL,
+  evidence_id INT,
+  payload TEXT,
+  status VARCHAR(15) NOT NULL DEFAULT 'queued',
+  attempts INT NOT NULL DEFAULT 0,
+  run_after TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  started_at TIMESTAMP NULL,
+  last_error TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX service_worker_jobs__status_run_after (status, run_after),
+  INDEX service_worker_jobs__evidence_id (evidence_id)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE service_worker_jobs;
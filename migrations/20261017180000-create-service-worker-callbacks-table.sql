@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE service_worker_callbacks (
+  id INT AUTO_INCREMENT,
+  access_key VARBINARY(255) NOT NULL,
+  secret_key VARBINARY(255) NOT NULL,
+  evidence_id INT NOT NULL,
+  worker_name VARCHAR(255) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (access_key),
+  INDEX service_worker_callbacks__evidence_id_worker_name (evidence_id, worker_name)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE service_worker_callbacks;
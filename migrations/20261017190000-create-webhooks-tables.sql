@@ -0,0 +1,42 @@
+-- +migrate Up
+CREATE TABLE webhooks (
+  id INT AUTO_INCREMENT,
+  operation_id INT,
+  name VARCHAR(255) NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  events VARCHAR(1024) NOT NULL,
+  access_key VARBINARY(255) NOT NULL,
+  secret_key VARBINARY(255) NOT NULL,
+  consecutive_failures INT NOT NULL DEFAULT 0,
+  disabled_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (access_key),
+  INDEX webhooks__operation_id (operation_id)
+) ENGINE=INNODB;
+
+CREATE TABLE webhook_deliveries (
+  id INT AUTO_INCREMENT,
+  uuid VARCHAR(36) NOT NULL,
+  webhook_id INT NOT NULL,
+  event_type VARCHAR(63) NOT NULL,
+  payload TEXT NOT NULL,
+  status VARCHAR(15) NOT NULL DEFAULT 'queued',
+  attempts INT NOT NULL DEFAULT 0,
+  status_code INT,
+  last_error TEXT,
+  run_after TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  started_at TIMESTAMP NULL,
+  delivered_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (uuid),
+  INDEX webhook_deliveries__status_run_after (status, run_after),
+  INDEX webhook_deliveries__webhook_id (webhook_id)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,33 @@
+-- +migrate Up
+CREATE TABLE search_index (
+  id INT AUTO_INCREMENT,
+  operation_id INT NOT NULL,
+  document_type VARCHAR(31) NOT NULL,
+  document_id INT NOT NULL,
+  field VARCHAR(255) NOT NULL,
+  content MEDIUMTEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE INDEX search_index__document_field (document_type, document_id, field),
+  INDEX search_index__operation_id (operation_id),
+  FULLTEXT INDEX search_index__content (content)
+) ENGINE=INNODB;
+
+INSERT INTO search_index (operation_id, document_type, document_id, field, content)
+  SELECT operation_id, 'evidence', id, 'description', description FROM evidence WHERE description != '';
+
+INSERT INTO search_index (operation_id, document_type, document_id, field, content)
+  SELECT e.operation_id, 'evidence', m.evidence_id, CONCAT('metadata:', m.source), m.body
+  FROM evidence_metadata m
+  INNER JOIN evidence e ON e.id = m.evidence_id
+  WHERE m.body != '';
+
+INSERT INTO search_index (operation_id, document_type, document_id, field, content)
+  SELECT operation_id, 'finding', id, 'title', title FROM findings WHERE title != '';
+
+INSERT INTO search_index (operation_id, document_type, document_id, field, content)
+  SELECT operation_id, 'finding', id, 'description', description FROM findings WHERE description != '';
+
+-- +migrate Down
+DROP TABLE search_index;
@@ -0,0 +1,32 @@
+-- +migrate Up
+CREATE TABLE query_subscriptions (
+  id INT AUTO_INCREMENT,
+  query_id INT NOT NULL,
+  user_id INT NOT NULL,
+  last_run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE INDEX query_subscriptions__query_user (query_id, user_id),
+  INDEX query_subscriptions__user_id (user_id)
+) ENGINE=INNODB;
+
+CREATE TABLE query_digest_schedules (
+  id INT AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  frequency VARCHAR(15) NOT NULL DEFAULT 'daily',
+  send_hour INT NOT NULL DEFAULT 8,
+  send_weekday INT NOT NULL DEFAULT 1,
+  last_sent_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE (user_id)
+) ENGINE=INNODB;
+
+ALTER TABLE email_queue ADD COLUMN template_data TEXT AFTER template;
+
+-- +migrate Down
+ALTER TABLE email_queue DROP COLUMN template_data;
+DROP TABLE query_digest_schedules;
+DROP TABLE query_subscriptions;
@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE api_keys
+  ADD COLUMN expires_at TIMESTAMP NULL AFTER last_auth,
+  ADD COLUMN operation_ids VARCHAR(1024) AFTER expires_at,
+  ADD COLUMN scope VARCHAR(15) NOT NULL DEFAULT 'full' AFTER operation_ids;
+
+-- +migrate Down
+ALTER TABLE api_keys
+  DROP COLUMN scope,
+  DROP COLUMN operation_ids,
+  DROP COLUMN expires_at;
@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE evidence
+  ADD COLUMN content_size BIGINT NOT NULL DEFAULT 0 AFTER content_hash;
+
+ALTER TABLE operations
+  ADD COLUMN storage_quota BIGINT NULL AFTER active;
+
+-- +migrate Down
+ALTER TABLE operations
+  DROP COLUMN storage_quota;
+
+ALTER TABLE evidence
+  DROP COLUMN content_size;
@@ -0,0 +1,18 @@
+-- +migrate Up
+-- content_size was added with a default of 0, so evidence created before then appears to use no
+-- storage. NULL marks evidence whose content has not yet been measured, which is done in the
+-- background by the server.
+ALTER TABLE evidence
+  MODIFY COLUMN content_size BIGINT NULL DEFAULT NULL;
+
+UPDATE evidence
+  SET content_size = NULL
+  WHERE content_size = 0 AND content_type <> 'none';
+
+-- +migrate Down
+UPDATE evidence
+  SET content_size = 0
+  WHERE content_size IS NULL;
+
+ALTER TABLE evidence
+  MODIFY COLUMN content_size BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- +migrate Up
+-- HTTP methods may be any token, so captures containing methods longer than 15 characters could
+-- not be saved.
+ALTER TABLE har_entries
+  MODIFY COLUMN method VARCHAR(255) NOT NULL;
+
+-- +migrate Down
+ALTER TABLE har_entries
+  MODIFY COLUMN method VARCHAR(15) NOT NULL;
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- Nonces used with signed API requests, shared between all servers so that a request cannot be
+-- replayed against a different server. Expired nonces are removed by the servers as they run.
+CREATE TABLE api_nonces (
+  access_key VARBINARY(255) NOT NULL,
+  nonce VARBINARY(128) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (access_key, nonce),
+  INDEX api_nonces__expires_at (expires_at)
+) ENGINE=INNODB;
+
+-- +migrate Down
+DROP TABLE api_nonces;
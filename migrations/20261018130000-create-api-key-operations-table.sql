@@ -0,0 +1,44 @@
+-- +migrate Up
+-- The operations an API key is limited to were stored as a comma separated list of ids. They are now
+-- kept in api_key_operations, so that they are removed along with the key or operation. Whether a key
+-- is limited is recorded separately, so that a key does not gain access to every operation once all of
+-- its operations have been deleted.
+CREATE TABLE api_key_operations (
+  api_key_id INT NOT NULL,
+  operation_id INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP,
+  PRIMARY KEY (api_key_id, operation_id),
+  FOREIGN KEY (api_key_id) REFERENCES api_keys(id) ON DELETE CASCADE,
+  FOREIGN KEY (operation_id) REFERENCES operations(id) ON DELETE CASCADE
+) ENGINE=INNODB;
+
+ALTER TABLE api_keys
+  ADD COLUMN restrict_operations BOOLEAN NOT NULL DEFAULT FALSE AFTER expires_at;
+
+UPDATE api_keys
+  SET restrict_operations = TRUE
+  WHERE operation_ids IS NOT NULL;
+
+INSERT INTO api_key_operations (api_key_id, operation_id)
+  SELECT api_keys.id, operations.id
+  FROM api_keys
+  INNER JOIN operations ON FIND_IN_SET(operations.id, api_keys.operation_ids);
+
+ALTER TABLE api_keys
+  DROP COLUMN operation_ids;
+
+-- +migrate Down
+ALTER TABLE api_keys
+  ADD COLUMN operation_ids VARCHAR(1024) AFTER expires_at;
+
+UPDATE api_keys
+  SET operation_ids = COALESCE(
+    (SELECT GROUP_CONCAT(operation_id ORDER BY operation_id) FROM api_key_operations WHERE api_key_id = api_keys.id),
+    '')
+  WHERE restrict_operations;
+
+ALTER TABLE api_keys
+  DROP COLUMN restrict_operations;
+
+DROP TABLE api_key_operations;
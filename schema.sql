@@ -15,6 +15,25 @@
 /*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
 /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
 
+--
+-- Table structure for table `api_key_operations`
+--
+
+DROP TABLE IF EXISTS `api_key_operations`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `api_key_operations` (
+  `api_key_id` int NOT NULL,
+  `operation_id` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`api_key_id`,`operation_id`),
+  KEY `operation_id` (`operation_id`),
+  CONSTRAINT `api_key_operations_ibfk_1` FOREIGN KEY (`api_key_id`) REFERENCES `api_keys` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `api_key_operations_ibfk_2` FOREIGN KEY (`operation_id`) REFERENCES `operations` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `api_keys`
 --
@@ -28,6 +47,9 @@ CREATE TABLE `api_keys` (
   `access_key` varbinary(255) NOT NULL,
   `secret_key` varbinary(255) NOT NULL,
   `last_auth` timestamp NULL DEFAULT NULL,
+  `expires_at` timestamp NULL DEFAULT NULL,
+  `restrict_operations` tinyint(1) NOT NULL DEFAULT '0',
+  `scope` varchar(15) NOT NULL DEFAULT 'full',
   `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT NULL,
   PRIMARY KEY (`id`),
@@ -37,6 +59,49 @@ CREATE TABLE `api_keys` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `api_nonces`
+--
+
+DROP TABLE IF EXISTS `api_nonces`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `api_nonces` (
+  `access_key` varbinary(255) NOT NULL,
+  `nonce` varbinary(128) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`access_key`,`nonce`),
+  KEY `api_nonces__expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `audit_events`
+--
+
+DROP TABLE IF EXISTS `audit_events`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `audit_events` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `actor_id` int DEFAULT NULL,
+  `action` varchar(255) NOT NULL,
+  `target_type` varchar(255) NOT NULL,
+  `target` varchar(255) NOT NULL,
+  `operation_id` int DEFAULT NULL,
+  `before_state` json DEFAULT NULL,
+  `after_state` json DEFAULT NULL,
+  `request_id` varchar(36) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `audit_events__actor_id` (`actor_id`),
+  KEY `audit_events__operation_id` (`operation_id`),
+  KEY `audit_events__created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `auth_scheme_data`
 --
@@ -65,6 +130,47 @@ CREATE TABLE `auth_scheme_data` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `content_issues`
+--
+
+DROP TABLE IF EXISTS `content_issues`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `content_issues` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `issue_type` varchar(31) NOT NULL,
+  `content_key` varchar(255) NOT NULL,
+  `evidence_id` int DEFAULT NULL,
+  `first_seen_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_seen_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `delete_attempts` int NOT NULL DEFAULT '0',
+  `last_error` text,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `content_issues__issue_type` (`issue_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `content_references`
+--
+
+DROP TABLE IF EXISTS `content_references`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `content_references` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `content_key` varchar(255) NOT NULL,
+  `ref_count` int NOT NULL DEFAULT '0',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `content_references__content_key` (`content_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `default_tags`
 --
@@ -96,6 +202,7 @@ CREATE TABLE `email_queue` (
   `to_email` varchar(255) NOT NULL,
   `user_id` int NOT NULL DEFAULT '0',
   `template` varchar(255) NOT NULL,
+  `template_data` text,
   `email_status` varchar(32) NOT NULL DEFAULT 'created',
   `error_count` int NOT NULL DEFAULT '0',
   `error_text` text,
@@ -123,6 +230,8 @@ CREATE TABLE `evidence` (
   `content_type` varchar(31) NOT NULL,
   `full_image_key` varchar(255) DEFAULT NULL,
   `thumb_image_key` varchar(255) DEFAULT NULL,
+  `content_hash` char(64) DEFAULT NULL,
+  `content_size` bigint DEFAULT NULL,
   `occurred_at` timestamp NOT NULL,
   `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT NULL,
@@ -276,6 +385,30 @@ CREATE TABLE `group_user_map` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `har_entries`
+--
+
+DROP TABLE IF EXISTS `har_entries`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `har_entries` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `evidence_id` int NOT NULL,
+  `entry_index` int NOT NULL,
+  `method` varchar(255) NOT NULL,
+  `url` text NOT NULL,
+  `host` varchar(255) NOT NULL,
+  `status` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `har_entries__evidence_id` (`evidence_id`),
+  KEY `har_entries__host` (`host`),
+  KEY `har_entries__status` (`status`),
+  CONSTRAINT `har_entries_ibfk_1` FOREIGN KEY (`evidence_id`) REFERENCES `evidence` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `operation_vars`
 --
@@ -308,6 +441,7 @@ CREATE TABLE `operations` (
   `name` varchar(255) NOT NULL,
   `description` varchar(255) DEFAULT NULL,
   `active` tinyint(1) DEFAULT '1',
+  `storage_quota` bigint DEFAULT NULL,
   `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT NULL,
   PRIMARY KEY (`id`),
@@ -337,6 +471,119 @@ CREATE TABLE `queries` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `query_digest_schedules`
+--
+
+DROP TABLE IF EXISTS `query_digest_schedules`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `query_digest_schedules` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `frequency` varchar(15) NOT NULL DEFAULT 'daily',
+  `send_hour` int NOT NULL DEFAULT '8',
+  `send_weekday` int NOT NULL DEFAULT '1',
+  `last_sent_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `query_subscriptions`
+--
+
+DROP TABLE IF EXISTS `query_subscriptions`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `query_subscriptions` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `query_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `last_run_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `query_subscriptions__query_user` (`query_id`,`user_id`),
+  KEY `query_subscriptions__user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `search_index`
+--
+
+DROP TABLE IF EXISTS `search_index`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `search_index` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `operation_id` int NOT NULL,
+  `document_type` varchar(31) NOT NULL,
+  `document_id` int NOT NULL,
+  `field` varchar(255) NOT NULL,
+  `content` mediumtext NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `search_index__document_field` (`document_type`,`document_id`,`field`),
+  KEY `search_index__operation_id` (`operation_id`),
+  FULLTEXT KEY `search_index__content` (`content`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `service_worker_callbacks`
+--
+
+DROP TABLE IF EXISTS `service_worker_callbacks`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `service_worker_callbacks` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `access_key` varbinary(255) NOT NULL,
+  `secret_key` varbinary(255) NOT NULL,
+  `evidence_id` int NOT NULL,
+  `worker_name` varchar(255) NOT NULL,
+  `expires_at` timestamp NOT NULL,
+  `used_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `access_key` (`access_key`),
+  KEY `service_worker_callbacks__evidence_id_worker_name` (`evidence_id`,`worker_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `service_worker_jobs`
+--
+
+DROP TABLE IF EXISTS `service_worker_jobs`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `service_worker_jobs` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `worker_name` varchar(255) NOT NULL,
+  `event_type` varchar(63) NOT NULL,
+  `evidence_id` int DEFAULT NULL,
+  `payload` text,
+  `status` varchar(15) NOT NULL DEFAULT 'queued',
+  `attempts` int NOT NULL DEFAULT '0',
+  `run_after` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `started_at` timestamp NULL DEFAULT NULL,
+  `last_error` text,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `service_worker_jobs__status_run_after` (`status`,`run_after`),
+  KEY `service_worker_jobs__evidence_id` (`evidence_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `service_workers`
 --
@@ -539,6 +786,60 @@ CREATE TABLE `var_operation_map` (
   CONSTRAINT `var_operation_map_ibfk_2` FOREIGN KEY (`var_id`) REFERENCES `operation_vars` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
 /*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `webhook_deliveries`
+--
+
+DROP TABLE IF EXISTS `webhook_deliveries`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `webhook_deliveries` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `uuid` varchar(36) NOT NULL,
+  `webhook_id` int NOT NULL,
+  `event_type` varchar(63) NOT NULL,
+  `payload` text NOT NULL,
+  `status` varchar(15) NOT NULL DEFAULT 'queued',
+  `attempts` int NOT NULL DEFAULT '0',
+  `status_code` int DEFAULT NULL,
+  `last_error` text,
+  `run_after` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `started_at` timestamp NULL DEFAULT NULL,
+  `delivered_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uuid` (`uuid`),
+  KEY `webhook_deliveries__status_run_after` (`status`,`run_after`),
+  KEY `webhook_deliveries__webhook_id` (`webhook_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `webhooks`
+--
+
+DROP TABLE IF EXISTS `webhooks`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!50503 SET character_set_client = utf8mb4 */;
+CREATE TABLE `webhooks` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `operation_id` int DEFAULT NULL,
+  `name` varchar(255) NOT NULL,
+  `url` varchar(2048) NOT NULL,
+  `events` varchar(1024) NOT NULL,
+  `access_key` varbinary(255) NOT NULL,
+  `secret_key` varbinary(255) NOT NULL,
+  `consecutive_failures` int NOT NULL DEFAULT '0',
+  `disabled_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `access_key` (`access_key`),
+  KEY `webhooks__operation_id` (`operation_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb3;
+/*!40101 SET character_set_client = @saved_cs_client */;
 /*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
 
 /*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
@@ -573,7 +874,7 @@ CREATE TABLE `var_operation_map` (
 
 LOCK TABLES `gorp_migrations` WRITE;
 /*!40000 ALTER TABLE `gorp_migrations` DISABLE KEYS */;
-INSERT INTO `gorp_migrations` VALUES ('20190705190058-create-users-table.sql','2023-10-10 13:44:21'),('20190708185420-create-operations-table.sql','2023-10-10 13:44:21'),('20190708185427-create-events-table.sql','2023-10-10 13:44:21'),('20190708185432-create-evidence-table.sql','2023-10-10 13:44:21'),('20190708185441-create-evidence-event-map-table.sql','2023-10-10 13:44:21'),('20190716190100-create-user-operation-map-table.sql','2023-10-10 13:44:21'),('20190722193434-create-tags-table.sql','2023-10-10 13:44:21'),('20190722193937-create-tag-event-map.sql','2023-10-10 13:44:21'),('20190909183500-add-short-name-to-users-table.sql','2023-10-10 13:44:21'),('20190909190416-add-short-name-index.sql','2023-10-10 13:44:21'),('20190926205116-evidence-name.sql','2023-10-10 13:44:21'),('20190930173342-add-saved-searches.sql','2023-10-10 13:44:21'),('20191001182541-evidence-tags.sql','2023-10-10 13:44:21'),('20191008005212-add-uuid-to-events-evidence.sql','2023-10-10 13:44:21'),('20191015235306-add-slug-to-operations.sql','2023-10-10 13:44:21'),('20191018172105-modular-auth.sql','2023-10-10 13:44:21'),('20191023170906-codeblock.sql','2023-10-10 13:44:21'),('20191101185207-replace-events-with-findings.sql','2023-10-10 13:44:21'),('20191114211948-add-operation-to-tags.sql','2023-10-10 13:44:21'),('20191205182830-create-api-keys-table.sql','2023-10-10 13:44:21'),('20191213222629-users-with-email.sql','2023-10-10 13:44:21'),('20200103194053-rename-short-name-to-slug.sql','2023-10-10 13:44:21'),('20200104013804-rework-ashirt-auth.sql','2023-10-10 13:44:22'),('20200116070736-add-admin-flag.sql','2023-10-10 13:44:22'),('20200130175541-fix-color-truncation.sql','2023-10-10 13:44:22'),('20200205200208-disable-user-support.sql','2023-10-10 13:44:22'),('20200215015330-optional-user-id.sql','2023-10-10 13:44:22'),('20200221195107-deletable-user.sql','2023-10-10 13:44:22'),('20200303215004-move-last-login.sql','2023-10-10 13:44:22'),('20200306221628-add-explicit-headless.sql','2023-10-10 13:44:22'),('20200331155258-finding-status.sql','2023-10-10 13:44:22'),('20200617193248-case-senitive-apikey.sql','2023-10-10 13:44:22'),('20200928160958-add-totp-secret-to-auth-table.sql','2023-10-10 13:44:22'),('20210120205510-create-email-queue-table.sql','2023-10-10 13:44:22'),('20210401220807-dynamic-categories.sql','2023-10-10 13:44:22'),('20210408212206-remove-findings-category.sql','2023-10-10 13:44:22'),('20210730170543-add-auth-type.sql','2023-10-10 13:44:22'),('20220211181557-add-default-tags.sql','2023-10-10 13:44:22'),('20220512174013-evidence-metadata.sql','2023-10-10 13:44:22'),('20220516163424-add-worker-services.sql','2023-10-10 13:44:22'),('20220811153414-webauthn-credentials.sql','2023-10-10 13:44:22'),('20220908193523-switch-to-username.sql','2023-10-10 13:44:22'),('20220912185024-add-is_favorite.sql','2023-10-10 13:44:22'),('20220916190855-remove-null-as-value-for-is_favorite.sql','2023-10-10 13:44:22'),('20221027152757-remove-operation-status.sql','2023-10-10 13:44:22'),('20221111221242-create-user-operation-preferences.sql','2023-10-10 13:44:22'),('20221121165342-add-groups.sql','2023-10-10 13:44:22'),('20221216195811-add-user-group-permissions-table.sql','2023-10-10 13:44:22'),('20230324124303-add-authn-id.sql','2023-10-10 13:44:22'),('20230922175734-add-global-vars.sql','2023-10-10 13:44:22'),('20230922180138-add-project-vars.sql','2023-10-10 13:44:22'),('20230928144308-change-global-var-value-to-text.sql','2023-10-10 13:44:22'),('20231003133006-add-slug-to-op-vars.sql','2023-10-10 13:44:22'),('20231003134124-add-name-to-operation-vars.sql','2023-10-10 13:44:22'),('20231010134210-drop-unique-name-index.sql','2023-10-10 13:44:22'), ('20240219170146-add-adjusted_at-to-evidences.sql','2023-10-10 13:44:21'), ('20240227105806-add-description-to-tags.sql', '2023-10-10 13:44:21'), ('20240228152528-add-description-to-default-tags.sql', '2023-10-10 13:44:21');
+INSERT INTO `gorp_migrations` VALUES ('20190705190058-create-users-table.sql','2023-10-10 13:44:21'),('20190708185420-create-operations-table.sql','2023-10-10 13:44:21'),('20190708185427-create-events-table.sql','2023-10-10 13:44:21'),('20190708185432-create-evidence-table.sql','2023-10-10 13:44:21'),('20190708185441-create-evidence-event-map-table.sql','2023-10-10 13:44:21'),('20190716190100-create-user-operation-map-table.sql','2023-10-10 13:44:21'),('20190722193434-create-tags-table.sql','2023-10-10 13:44:21'),('20190722193937-create-tag-event-map.sql','2023-10-10 13:44:21'),('20190909183500-add-short-name-to-users-table.sql','2023-10-10 13:44:21'),('20190909190416-add-short-name-index.sql','2023-10-10 13:44:21'),('20190926205116-evidence-name.sql','2023-10-10 13:44:21'),('20190930173342-add-saved-searches.sql','2023-10-10 13:44:21'),('20191001182541-evidence-tags.sql','2023-10-10 13:44:21'),('20191008005212-add-uuid-to-events-evidence.sql','2023-10-10 13:44:21'),('20191015235306-add-slug-to-operations.sql','2023-10-10 13:44:21'),('20191018172105-modular-auth.sql','2023-10-10 13:44:21'),('20191023170906-codeblock.sql','2023-10-10 13:44:21'),('20191101185207-replace-events-with-findings.sql','2023-10-10 13:44:21'),('20191114211948-add-operation-to-tags.sql','2023-10-10 13:44:21'),('20191205182830-create-api-keys-table.sql','2023-10-10 13:44:21'),('20191213222629-users-with-email.sql','2023-10-10 13:44:21'),('20200103194053-rename-short-name-to-slug.sql','2023-10-10 13:44:21'),('20200104013804-rework-ashirt-auth.sql','2023-10-10 13:44:22'),('20200116070736-add-admin-flag.sql','2023-10-10 13:44:22'),('20200130175541-fix-color-truncation.sql','2023-10-10 13:44:22'),('20200205200208-disable-user-support.sql','2023-10-10 13:44:22'),('20200215015330-optional-user-id.sql','2023-10-10 13:44:22'),('20200221195107-deletable-user.sql','2023-10-10 13:44:22'),('20200303215004-move-last-login.sql','2023-10-10 13:44:22'),('20200306221628-add-explicit-headless.sql','2023-10-10 13:44:22'),('20200331155258-finding-status.sql','2023-10-10 13:44:22'),('20200617193248-case-senitive-apikey.sql','2023-10-10 13:44:22'),('20200928160958-add-totp-secret-to-auth-table.sql','2023-10-10 13:44:22'),('20210120205510-create-email-queue-table.sql','2023-10-10 13:44:22'),('20210401220807-dynamic-categories.sql','2023-10-10 13:44:22'),('20210408212206-remove-findings-category.sql','2023-10-10 13:44:22'),('20210730170543-add-auth-type.sql','2023-10-10 13:44:22'),('20220211181557-add-default-tags.sql','2023-10-10 13:44:22'),('20220512174013-evidence-metadata.sql','2023-10-10 13:44:22'),('20220516163424-add-worker-services.sql','2023-10-10 13:44:22'),('20220811153414-webauthn-credentials.sql','2023-10-10 13:44:22'),('20220908193523-switch-to-username.sql','2023-10-10 13:44:22'),('20220912185024-add-is_favorite.sql','2023-10-10 13:44:22'),('20220916190855-remove-null-as-value-for-is_favorite.sql','2023-10-10 13:44:22'),('20221027152757-remove-operation-status.sql','2023-10-10 13:44:22'),('20221111221242-create-user-operation-preferences.sql','2023-10-10 13:44:22'),('20221121165342-add-groups.sql','2023-10-10 13:44:22'),('20221216195811-add-user-group-permissions-table.sql','2023-10-10 13:44:22'),('20230324124303-add-authn-id.sql','2023-10-10 13:44:22'),('20230922175734-add-global-vars.sql','2023-10-10 13:44:22'),('20230922180138-add-project-vars.sql','2023-10-10 13:44:22'),('20230928144308-change-global-var-value-to-text.sql','2023-10-10 13:44:22'),('20231003133006-add-slug-to-op-vars.sql','2023-10-10 13:44:22'),('20231003134124-add-name-to-operation-vars.sql','2023-10-10 13:44:22'),('20231010134210-drop-unique-name-index.sql','2023-10-10 13:44:22'), ('20240219170146-add-adjusted_at-to-evidences.sql','2023-10-10 13:44:21'), ('20240227105806-add-description-to-tags.sql', '2023-10-10 13:44:21'), ('20240228152528-add-description-to-default-tags.sql', '2023-10-10 13:44:21'), ('20261017120000-create-audit-events-table.sql', '2023-10-10 13:44:21'), ('20261017130000-add-content-hash-to-evidence.sql', '2023-10-10 13:44:21'), ('20261017140000-create-content-references-table.sql', '2023-10-10 13:44:21'), ('20261017150000-create-content-issues-table.sql', '2023-10-10 13:44:21'), ('20261017160000-create-har-entries-table.sql', '2023-10-10 13:44:21'), ('20261017170000-create-service-worker-jobs-table.sql', '2023-10-10 13:44:21'), ('20261017180000-create-service-worker-callbacks-table.sql', '2023-10-10 13:44:21'), ('20261017190000-create-webhooks-tables.sql', '2023-10-10 13:44:21'), ('20261017200000-create-search-index.sql', '2023-10-10 13:44:21'), ('20261017210000-create-query-subscriptions.sql', '2023-10-10 13:44:21'), ('20261017220000-add-scopes-to-api-keys.sql', '2023-10-10 13:44:21'), ('20261017230000-add-evidence-storage-quotas.sql', '2023-10-10 13:44:21'), ('20261018100000-backfill-evidence-content-size.sql', '2023-10-10 13:44:21'), ('20261018110000-widen-har-entries-method.sql', '2023-10-10 13:44:21'), ('20261018120000-create-api-nonces-table.sql', '2023-10-10 13:44:21'), ('20261018130000-create-api-key-operations-table.sql', '2023-10-10 13:44:21');
 /*!40000 ALTER TABLE `gorp_migrations` ENABLE KEYS */;
 UNLOCK TABLES;
 /*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
@@ -3,12 +3,20 @@ package signer
 import (
 	"bytes"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"io"
 	"net/http"
 )
 
+// NonceHeader names the header holding a random, single-use value for each signed request. Servers
+// reject signed requests that reuse a nonce, which prevents intercepted requests from being replayed.
+const NonceHeader = "X-Ashirt-Nonce"
+
+// nonceLength is the number of random bytes in a nonce generated by AddNonce
+const nonceLength = 18
+
 // BuildRequestHMAC builds a request HMAC from a secret key to authenticate a request for /api endpoints
 // This function is shared by both client code to authenticate requests, and server to validate requests
 //
@@ -17,9 +25,13 @@ import (
 //	base64(hmac-sha-256(VERB + "\n" +
 //	                    REQUEST_PATH + "\n" +
 //	                    DATE + "\n" +
+//	                    NONCE + "\n" +
 //	                    sha256(REQUEST_BODY)
 //	))
 //
+// The NONCE line is only present when the request has a NonceHeader. Requests without one are signed
+// as they were before nonces were introduced, so that older clients remain compatible.
+//
 // It uses a separate requestBody argument instead of r.Body since reading from r.Body
 // in both client & server will prevent reading the body again.
 // Therefore it is the caller's responsibility to provide a separate request body reader.
@@ -36,6 +48,10 @@ func BuildRequestHMAC(r *http.Request, requestBody io.Reader, key []byte) []byte
 	m.WriteString("\n")
 	m.WriteString(r.Header.Get("Date"))
 	m.WriteString("\n")
+	if nonce := r.Header.Get(NonceHeader); nonce != "" {
+		m.WriteString(nonce)
+		m.WriteString("\n")
+	}
 	m.Write(requestBodySHA256.Sum(nil))
 
 	mac := hmac.New(sha256.New, key)
@@ -43,6 +59,16 @@ func BuildRequestHMAC(r *http.Request, requestBody io.Reader, key []byte) []byte
 	return mac.Sum(nil)
 }
 
+// AddNonce sets a new, random NonceHeader on the request. This must be done before the request is signed.
+func AddNonce(r *http.Request) error {
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	r.Header.Set(NonceHeader, base64.URLEncoding.EncodeToString(nonce))
+	return nil
+}
+
 func BuildClientRequestAuthorization(r *http.Request, accessKey string, secretKey []byte) (string, error) {
 	var body io.Reader
 	if r.Method == "GET" {